@@ -0,0 +1,191 @@
+// Package calendar knows when the US stock market trades.
+//
+// A Calendar answers "is this a trading day" and "when is the previous/next session" from the built-in NYSE holiday
+// rules. Calendars created with NewWithPolygon also load the upcoming holidays and early closes announced by Polygon,
+// which take precedence over the built-in rules, and report the live market status.
+package calendar
+
+import (
+	"sync"
+	"time"
+	_ "time/tzdata" // The exchange's time zone must resolve even in images without a zoneinfo database
+)
+
+const dateLayout = "2006-01-02"
+
+// The exchange's time zone, in which sessions and dates are expressed
+var Location = mustLoadLocation("America/New_York")
+
+// Regular and early-close trading hours, as offsets from midnight in Location
+const (
+	RegularOpen       = 9*time.Hour + 30*time.Minute
+	RegularClose      = 16 * time.Hour
+	EarlyClose        = 13 * time.Hour
+	PreMarketOpen     = 4 * time.Hour
+	AfterHoursClose   = 20 * time.Hour
+	maxSessionLookups = 30 // Longest run of days without a session the calendar will search through
+)
+
+// Values of Day.Status
+const (
+	StatusClosed     = "closed"
+	StatusEarlyClose = "early-close"
+)
+
+// Day is a holiday or an early close
+type Day struct {
+	Date   time.Time `json:"date"` // Midnight in Location
+	Name   string    `json:"name"`
+	Status string    `json:"status"` // StatusClosed or StatusEarlyClose
+	// The trading hours of an early close, unset for closed days
+	Open  time.Time `json:"open,omitempty"`
+	Close time.Time `json:"close,omitempty"`
+}
+
+// Session is a single trading day
+type Session struct {
+	Date       time.Time `json:"date"` // Midnight in Location
+	Open       time.Time `json:"open"`
+	Close      time.Time `json:"close"`
+	EarlyClose bool      `json:"early_close"`
+}
+
+// Calendar answers questions about trading days. It is safe for concurrent use.
+type Calendar struct {
+	mu sync.RWMutex
+	// Holidays announced by Polygon, keyed by date
+	announced   map[string]Day
+	source      marketStatusSource
+	refreshedAt time.Time
+	now         func() time.Time
+}
+
+// New returns a calendar using only the built-in NYSE holiday rules
+func New() *Calendar {
+	return &Calendar{announced: map[string]Day{}, now: time.Now}
+}
+
+func mustLoadLocation(name string) *time.Location {
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return location
+}
+
+// Returns midnight of t's date in Location
+func dateOf(t time.Time) time.Time {
+	year, month, day := t.In(Location).Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, Location)
+}
+
+// DateOf returns midnight in Location of the date t has in its own location.
+// Dates parsed as UTC midnights (e.g. with time.Parse("2006-01-02", ...)) must go through DateOf,
+// since converting them to Location would move them to the evening of the previous day.
+func DateOf(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, Location)
+}
+
+// Holiday returns the holiday or early close on t's date in Location, if there is one
+func (calendar *Calendar) Holiday(t time.Time) (Day, bool) {
+	date := dateOf(t)
+	calendar.mu.RLock()
+	day, ok := calendar.announced[date.Format(dateLayout)]
+	calendar.mu.RUnlock()
+	if ok {
+		return day, true
+	}
+	return nyseHoliday(date)
+}
+
+// IsTradingDay reports whether the market has a session on t's date in Location
+func (calendar *Calendar) IsTradingDay(t time.Time) bool {
+	_, ok := calendar.Session(t)
+	return ok
+}
+
+// Session returns the trading session on t's date in Location, and false if the market is closed that day
+func (calendar *Calendar) Session(t time.Time) (Session, bool) {
+	date := dateOf(t)
+	if !isWeekday(date) {
+		return Session{}, false
+	}
+
+	session := Session{Date: date, Open: date.Add(RegularOpen), Close: date.Add(RegularClose)}
+	if holiday, ok := calendar.Holiday(date); ok {
+		if holiday.Status == StatusClosed {
+			return Session{}, false
+		}
+		session.EarlyClose = true
+		session.Close = date.Add(EarlyClose)
+		if !holiday.Open.IsZero() && !holiday.Close.IsZero() {
+			session.Open, session.Close = holiday.Open.In(Location), holiday.Close.In(Location)
+		}
+	}
+	return session, true
+}
+
+// NextSession returns the first session on a date after t's date
+func (calendar *Calendar) NextSession(t time.Time) Session {
+	date := dateOf(t)
+	for i := 1; i <= maxSessionLookups; i++ {
+		if session, ok := calendar.Session(date.AddDate(0, 0, i)); ok {
+			return session
+		}
+	}
+	return Session{}
+}
+
+// PreviousSession returns the last session on a date before t's date
+func (calendar *Calendar) PreviousSession(t time.Time) Session {
+	date := dateOf(t)
+	for i := 1; i <= maxSessionLookups; i++ {
+		if session, ok := calendar.Session(date.AddDate(0, 0, -i)); ok {
+			return session
+		}
+	}
+	return Session{}
+}
+
+// LastClosedSession returns the most recent session that has closed at t
+func (calendar *Calendar) LastClosedSession(t time.Time) Session {
+	if session, ok := calendar.Session(t); ok && !t.Before(session.Close) {
+		return session
+	}
+	return calendar.PreviousSession(t)
+}
+
+// SessionsBetween returns the sessions whose dates are between the dates of start and end, inclusive
+func (calendar *Calendar) SessionsBetween(start, end time.Time) []Session {
+	sessions := []Session{}
+	for date := dateOf(start); !date.After(dateOf(end)); date = date.AddDate(0, 0, 1) {
+		if session, ok := calendar.Session(date); ok {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions
+}
+
+// HasTradingDay reports whether any date between the dates of start and end, inclusive, is a trading day
+func (calendar *Calendar) HasTradingDay(start, end time.Time) bool {
+	for date := dateOf(start); !date.After(dateOf(end)); date = date.AddDate(0, 0, 1) {
+		if calendar.IsTradingDay(date) {
+			return true
+		}
+	}
+	return false
+}
+
+// AddSessions returns the session n sessions after (or before, for negative n) t's date.
+// t's own date is not counted, so AddSessions(t, -1) is PreviousSession(t), and n = 0 only sets Date.
+func (calendar *Calendar) AddSessions(t time.Time, n int) Session {
+	session := Session{Date: dateOf(t)}
+	for ; n > 0; n-- {
+		session = calendar.NextSession(session.Date)
+	}
+	for ; n < 0; n++ {
+		session = calendar.PreviousSession(session.Date)
+	}
+	return session
+}
@@ -0,0 +1,213 @@
+package calendar
+
+import (
+	"context"
+	"financial-helper/polygon"
+	"financial-helper/polygon/polygontest"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, Location)
+}
+
+func at(year int, month time.Month, day, hour, minute int) time.Time {
+	return time.Date(year, month, day, hour, minute, 0, 0, Location)
+}
+
+func TestBuiltInHolidays(t *testing.T) {
+	closed := map[int][]time.Time{
+		2022: {date(2022, 1, 17), date(2022, 2, 21), date(2022, 4, 15), date(2022, 5, 30), date(2022, 6, 20), date(2022, 7, 4), date(2022, 9, 5), date(2022, 11, 24), date(2022, 12, 26)},
+		2023: {date(2023, 1, 2), date(2023, 1, 16), date(2023, 2, 20), date(2023, 4, 7), date(2023, 5, 29), date(2023, 6, 19), date(2023, 7, 4), date(2023, 9, 4), date(2023, 11, 23), date(2023, 12, 25)},
+		2024: {date(2024, 1, 1), date(2024, 1, 15), date(2024, 2, 19), date(2024, 3, 29), date(2024, 5, 27), date(2024, 6, 19), date(2024, 7, 4), date(2024, 9, 2), date(2024, 11, 28), date(2024, 12, 25)},
+		2025: {date(2025, 1, 1), date(2025, 1, 9), date(2025, 1, 20), date(2025, 2, 17), date(2025, 4, 18), date(2025, 5, 26), date(2025, 6, 19), date(2025, 7, 4), date(2025, 9, 1), date(2025, 11, 27), date(2025, 12, 25)},
+	}
+	earlyCloses := map[int][]time.Time{
+		2022: {date(2022, 11, 25)},
+		2023: {date(2023, 7, 3), date(2023, 11, 24)},
+		2024: {date(2024, 7, 3), date(2024, 11, 29), date(2024, 12, 24)},
+		2025: {date(2025, 7, 3), date(2025, 11, 28), date(2025, 12, 24)},
+	}
+
+	calendar := New()
+	for year := 2022; year <= 2025; year++ {
+		expectedClosed := map[string]bool{}
+		for _, day := range closed[year] {
+			expectedClosed[day.Format(dateLayout)] = true
+		}
+		expectedEarly := map[string]bool{}
+		for _, day := range earlyCloses[year] {
+			expectedEarly[day.Format(dateLayout)] = true
+		}
+
+		for day := date(year, 1, 1); day.Year() == year; day = day.AddDate(0, 0, 1) {
+			key := day.Format(dateLayout)
+			session, open := calendar.Session(day)
+			switch {
+			case !isWeekday(day) || expectedClosed[key]:
+				if open {
+					t.Fatalf("expected the market to be closed on %s", key)
+				}
+			case expectedEarly[key]:
+				if !open || !session.EarlyClose || !session.Close.Equal(at(year, day.Month(), day.Day(), 13, 0)) {
+					t.Fatalf("expected a 1pm close on %s, got %+v", key, session)
+				}
+			default:
+				if !open || session.EarlyClose || !session.Open.Equal(at(year, day.Month(), day.Day(), 9, 30)) {
+					t.Fatalf("expected a regular session on %s, got %+v (open: %t)", key, session, open)
+				}
+			}
+		}
+	}
+}
+
+func TestNewYearOnSaturdayIsNotObserved(t *testing.T) {
+	calendar := New()
+	// January 1st 2022 was a Saturday, the market stayed open on Friday December 31st 2021
+	if !calendar.IsTradingDay(date(2021, 12, 31)) {
+		t.Fatalf("expected December 31st 2021 to be a trading day")
+	}
+	// July 4th 2020 was a Saturday and was observed on Friday July 3rd, with no early close on July 2nd
+	if calendar.IsTradingDay(date(2020, 7, 3)) {
+		t.Fatalf("expected July 3rd 2020 to be a holiday")
+	}
+	if session, _ := calendar.Session(date(2020, 7, 2)); session.EarlyClose {
+		t.Fatalf("expected a regular session on July 2nd 2020")
+	}
+}
+
+func TestPreviousAndNextSession(t *testing.T) {
+	calendar := New()
+
+	// Thanksgiving 2024 is on Thursday the 28th, followed by an early close
+	if next := calendar.NextSession(date(2024, 11, 27)); !next.Date.Equal(date(2024, 11, 29)) || !next.EarlyClose {
+		t.Fatalf("expected the next session to be the early close of November 29th, got %+v", next)
+	}
+	// Monday after a weekend and the Good Friday holiday
+	if previous := calendar.PreviousSession(date(2024, 4, 1)); !previous.Date.Equal(date(2024, 3, 28)) {
+		t.Fatalf("expected the previous session to be March 28th, got %s", previous.Date)
+	}
+	if sessions := calendar.SessionsBetween(date(2024, 12, 23), date(2024, 12, 27)); len(sessions) != 4 {
+		t.Fatalf("expected 4 sessions in Christmas week, got %d", len(sessions))
+	}
+	if calendar.HasTradingDay(date(2024, 11, 30), date(2024, 12, 1)) {
+		t.Fatalf("expected no trading day on a weekend")
+	}
+	if session := calendar.AddSessions(date(2024, 12, 2), -5); !session.Date.Equal(date(2024, 11, 22)) {
+		t.Fatalf("expected 5 sessions before December 2nd to be November 22nd, got %s", session.Date)
+	}
+
+	// Before the close of a session, the last closed session is the previous one
+	if session := calendar.LastClosedSession(at(2024, 10, 15, 12, 0)); !session.Date.Equal(date(2024, 10, 14)) {
+		t.Fatalf("expected October 14th, got %s", session.Date)
+	}
+	if session := calendar.LastClosedSession(at(2024, 10, 15, 16, 0)); !session.Date.Equal(date(2024, 10, 15)) {
+		t.Fatalf("expected October 15th, got %s", session.Date)
+	}
+}
+
+func TestDateOf(t *testing.T) {
+	// A date parsed as a UTC midnight is the evening before in New York
+	parsed, _ := time.Parse(dateLayout, "2024-11-30")
+	if New().IsTradingDay(parsed) != true {
+		t.Fatalf("expected the New York time of a UTC midnight to fall on the previous day")
+	}
+	if New().IsTradingDay(DateOf(parsed)) {
+		t.Fatalf("expected DateOf to keep the date (a Saturday)")
+	}
+}
+
+func TestStatusAt(t *testing.T) {
+	calendar := New()
+	cases := []struct {
+		time       time.Time
+		market     string
+		earlyHours bool
+		afterHours bool
+	}{
+		{at(2024, 10, 15, 3, 0), polygon.MarketClosed, false, false},
+		{at(2024, 10, 15, 8, 0), polygon.MarketExtendedHours, true, false},
+		{at(2024, 10, 15, 9, 30), polygon.MarketOpen, false, false},
+		{at(2024, 10, 15, 17, 0), polygon.MarketExtendedHours, false, true},
+		{at(2024, 10, 15, 20, 0), polygon.MarketClosed, false, false},
+		{at(2024, 11, 29, 13, 30), polygon.MarketExtendedHours, false, true},
+		{at(2024, 11, 28, 12, 0), polygon.MarketClosed, false, false},
+	}
+	for _, c := range cases {
+		status := calendar.StatusAt(c.time)
+		if status.Market != c.market || status.EarlyHours != c.earlyHours || status.AfterHours != c.afterHours || status.IsOpen != (c.market == polygon.MarketOpen) {
+			t.Fatalf("%s: expected %s (early %t, after %t), got %+v", c.time, c.market, c.earlyHours, c.afterHours, status)
+		}
+		if status.Source != SourceCalendar {
+			t.Fatalf("expected a computed status")
+		}
+	}
+
+	thanksgiving := calendar.StatusAt(at(2024, 11, 28, 12, 0))
+	if thanksgiving.Holiday == nil || thanksgiving.Session != nil || !thanksgiving.NextSession.Date.Equal(date(2024, 11, 29)) {
+		t.Fatalf("expected Thanksgiving to be reported as a holiday, got %+v", thanksgiving)
+	}
+	if len(thanksgiving.UpcomingHolidays) == 0 || thanksgiving.UpcomingHolidays[0].Name != "Thanksgiving Day" {
+		t.Fatalf("expected upcoming holidays to start with Thanksgiving, got %+v", thanksgiving.UpcomingHolidays)
+	}
+}
+
+func newPolygonCalendar(t *testing.T) (*Calendar, *polygontest.Server) {
+	t.Helper()
+	fake := polygontest.NewServer()
+	t.Cleanup(fake.Close)
+	connection := polygon.GetPolygonConnection([]string{"test-key"},
+		polygon.WithBaseURL(fake.URL),
+		polygon.WithHTTPClient(fake.Client()),
+		polygon.WithRetryPolicy(polygon.NoPolygonRetries()),
+	)
+	calendar := NewWithPolygon(connection)
+	calendar.now = func() time.Time { return at(2024, 11, 28, 10, 0) }
+	return calendar, fake
+}
+
+func TestRefresh_AnnouncedHolidaysTakePrecedence(t *testing.T) {
+	calendar, fake := newPolygonCalendar(t)
+	// An unscheduled closure the built-in rules cannot know about
+	fake.Holidays = append(fake.Holidays, map[string]any{"exchange": "NYSE", "name": "Hurricane", "date": "2024-12-10", "status": "closed"})
+
+	if !calendar.IsTradingDay(date(2024, 12, 10)) {
+		t.Fatalf("expected December 10th to be a trading day before refreshing")
+	}
+	if err := calendar.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh error: %v", err)
+	}
+	if calendar.IsTradingDay(date(2024, 12, 10)) {
+		t.Fatalf("expected the announced closure to be applied")
+	}
+	session, _ := calendar.Session(date(2024, 12, 24))
+	if !session.EarlyClose || !session.Close.Equal(at(2024, 12, 24, 13, 0)) {
+		t.Fatalf("expected the announced early close, got %+v", session)
+	}
+
+	// Refreshed holidays are reused until they are stale
+	if err := calendar.RefreshIfStale(context.Background(), time.Hour); err != nil {
+		t.Fatalf("RefreshIfStale error: %v", err)
+	}
+	if count := fake.RequestCount("/v1/marketstatus/upcoming"); count != 1 {
+		t.Fatalf("expected holidays to be loaded once, got %d requests", count)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	calendar, fake := newPolygonCalendar(t)
+
+	status := calendar.Status(context.Background())
+	if status.Source != SourcePolygon || status.Market != polygon.MarketClosed || status.Holiday == nil {
+		t.Fatalf("expected Polygon's status on Thanksgiving, got %+v", status)
+	}
+
+	// Falls back to the calendar when Polygon fails
+	fake.FailNext("/v1/marketstatus/now", http.StatusInternalServerError, 1)
+	status = calendar.Status(context.Background())
+	if status.Source != SourceCalendar || status.Market != polygon.MarketClosed {
+		t.Fatalf("expected a computed status, got %+v", status)
+	}
+}
@@ -0,0 +1,141 @@
+package calendar
+
+// This file contains the built-in NYSE holiday rules, used for dates Polygon has not reported on
+// (Polygon only lists upcoming holidays) and whenever Polygon cannot be reached.
+// https://www.nyse.com/markets/hours-calendars
+
+import "time"
+
+// Days the exchange closed outside of its regular holiday rules
+var specialClosures = map[string]string{
+	"2001-09-11": "September 11 attacks",
+	"2001-09-12": "September 11 attacks",
+	"2001-09-13": "September 11 attacks",
+	"2001-09-14": "September 11 attacks",
+	"2004-06-11": "National Day of Mourning for Ronald Reagan",
+	"2007-01-02": "National Day of Mourning for Gerald Ford",
+	"2012-10-29": "Hurricane Sandy",
+	"2012-10-30": "Hurricane Sandy",
+	"2018-12-05": "National Day of Mourning for George H.W. Bush",
+	"2025-01-09": "National Day of Mourning for Jimmy Carter",
+}
+
+// Returns the NYSE holiday or early close on date (a midnight in the exchange's time zone), if there is one
+func nyseHoliday(date time.Time) (Day, bool) {
+	year, month, day := date.Date()
+	key := date.Format(dateLayout)
+
+	if name, ok := specialClosures[key]; ok {
+		return Day{Date: date, Name: name, Status: StatusClosed}, true
+	}
+
+	closed := func(name string) (Day, bool) {
+		return Day{Date: date, Name: name, Status: StatusClosed}, true
+	}
+	earlyClose := func(name string) (Day, bool) {
+		return Day{Date: date, Name: name, Status: StatusEarlyClose}, true
+	}
+
+	// New Year's Day on a Saturday is not observed, since the previous Friday ends a reporting year.
+	// Comparing with the observed date of this year's January 1st leaves December 31st open.
+	if sameDay(date, observed(time.Date(year, time.January, 1, 0, 0, 0, 0, date.Location()))) {
+		return closed("New Year's Day")
+	}
+	if year >= 1998 && sameDay(date, nthWeekday(year, time.January, time.Monday, 3, date.Location())) {
+		return closed("Martin Luther King, Jr. Day")
+	}
+	if sameDay(date, nthWeekday(year, time.February, time.Monday, 3, date.Location())) {
+		return closed("Washington's Birthday")
+	}
+	if sameDay(date, easter(year, date.Location()).AddDate(0, 0, -2)) {
+		return closed("Good Friday")
+	}
+	if sameDay(date, lastWeekday(year, time.May, time.Monday, date.Location())) {
+		return closed("Memorial Day")
+	}
+	if year >= 2022 && sameDay(date, observed(time.Date(year, time.June, 19, 0, 0, 0, 0, date.Location()))) {
+		return closed("Juneteenth National Independence Day")
+	}
+	independenceDay := time.Date(year, time.July, 4, 0, 0, 0, 0, date.Location())
+	if sameDay(date, observed(independenceDay)) {
+		return closed("Independence Day")
+	}
+	laborDay := nthWeekday(year, time.September, time.Monday, 1, date.Location())
+	if sameDay(date, laborDay) {
+		return closed("Labor Day")
+	}
+	thanksgiving := nthWeekday(year, time.November, time.Thursday, 4, date.Location())
+	if sameDay(date, thanksgiving) {
+		return closed("Thanksgiving Day")
+	}
+	christmas := time.Date(year, time.December, 25, 0, 0, 0, 0, date.Location())
+	if sameDay(date, observed(christmas)) {
+		return closed("Christmas Day")
+	}
+
+	// Early closes at 1pm
+	if month == time.July && day == 3 && isWeekday(date) && independenceDay.Weekday() != time.Saturday && independenceDay.Weekday() != time.Monday {
+		return earlyClose("Independence Day")
+	}
+	if sameDay(date, thanksgiving.AddDate(0, 0, 1)) {
+		return earlyClose("Thanksgiving Day")
+	}
+	if month == time.December && day == 24 && isWeekday(date) && christmas.Weekday() != time.Saturday {
+		return earlyClose("Christmas Day")
+	}
+
+	return Day{}, false
+}
+
+// Returns the weekday a holiday falling on date is observed on: the Friday before a Saturday
+// or the Monday after a Sunday
+func observed(date time.Time) time.Time {
+	switch date.Weekday() {
+	case time.Saturday:
+		return date.AddDate(0, 0, -1)
+	case time.Sunday:
+		return date.AddDate(0, 0, 1)
+	}
+	return date
+}
+
+// Returns the nth (1-based) weekday of month
+func nthWeekday(year int, month time.Month, weekday time.Weekday, n int, loc *time.Location) time.Time {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, loc)
+	offset := (int(weekday) - int(first.Weekday()) + 7) % 7
+	return first.AddDate(0, 0, offset+7*(n-1))
+}
+
+// Returns the last weekday of month
+func lastWeekday(year int, month time.Month, weekday time.Weekday, loc *time.Location) time.Time {
+	last := time.Date(year, month+1, 0, 0, 0, 0, 0, loc)
+	offset := (int(last.Weekday()) - int(weekday) + 7) % 7
+	return last.AddDate(0, 0, -offset)
+}
+
+// Returns Easter Sunday of year (anonymous Gregorian algorithm)
+func easter(year int, loc *time.Location) time.Time {
+	a := year % 19
+	b := year / 100
+	c := year % 100
+	d := b / 4
+	e := b % 4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i := c / 4
+	k := c % 4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, loc)
+}
+
+func isWeekday(date time.Time) bool {
+	return date.Weekday() != time.Saturday && date.Weekday() != time.Sunday
+}
+
+func sameDay(a, b time.Time) bool {
+	return a.Year() == b.Year() && a.YearDay() == b.YearDay()
+}
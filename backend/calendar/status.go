@@ -0,0 +1,178 @@
+package calendar
+
+import (
+	"context"
+	"errors"
+	"financial-helper/polygon"
+	"log"
+	"os"
+	"time"
+)
+
+var errLogger *log.Logger = log.New(os.Stderr, "ERROR: ", log.LstdFlags|log.Lshortfile)
+
+// How often RefreshIfStale reloads the holidays announced by Polygon
+const DefaultRefreshInterval = 24 * time.Hour
+
+// How far ahead MarketStatus.UpcomingHolidays looks
+const upcomingHolidaysWindow = 90 * 24 * time.Hour
+
+// Values of MarketStatus.Source
+const (
+	SourcePolygon  = "polygon"
+	SourceCalendar = "calendar"
+)
+
+// The Polygon endpoints a calendar reads, implemented by *polygon.PolygonConnection
+type marketStatusSource interface {
+	PolygonGetMarketStatusWithContext(ctx context.Context) (*polygon.PolygonGetMarketStatusResponse, error)
+	PolygonGetMarketHolidaysWithContext(ctx context.Context) ([]polygon.PolygonMarketHoliday, error)
+}
+
+// MarketStatus is the state of the market at a point in time
+type MarketStatus struct {
+	Market           string    `json:"market"` // polygon.MarketOpen, polygon.MarketClosed or polygon.MarketExtendedHours
+	IsOpen           bool      `json:"is_open"`
+	EarlyHours       bool      `json:"early_hours"`
+	AfterHours       bool      `json:"after_hours"`
+	Time             time.Time `json:"time"`
+	Source           string    `json:"source"`            // SourcePolygon if the live status was read from Polygon
+	Session          *Session  `json:"session,omitempty"` // Today's session, unset if the market is closed today
+	Holiday          *Day      `json:"holiday,omitempty"` // Today's holiday or early close
+	PreviousSession  Session   `json:"previous_session"`
+	NextSession      Session   `json:"next_session"`
+	UpcomingHolidays []Day     `json:"upcoming_holidays"`
+}
+
+// NewWithPolygon returns a calendar that also uses the holidays and live status reported by Polygon.
+// Holidays are loaded on the first call to RefreshIfStale or Status.
+func NewWithPolygon(polygonConnection *polygon.PolygonConnection) *Calendar {
+	calendar := New()
+	calendar.source = polygonConnection
+	return calendar
+}
+
+// Refresh loads the upcoming NYSE holidays and early closes announced by Polygon.
+// Calendars without Polygon have nothing to refresh.
+func (calendar *Calendar) Refresh(ctx context.Context) error {
+	if calendar.source == nil {
+		return nil
+	}
+	holidays, err := calendar.source.PolygonGetMarketHolidaysWithContext(ctx)
+	if err != nil && !polygon.IsPartialPolygonData(err) {
+		return errors.Join(errors.New("error refreshing market holidays"), err)
+	}
+
+	announced := map[string]Day{}
+	for _, holiday := range holidays {
+		if holiday.Exchange == nil || *holiday.Exchange != "NYSE" || holiday.Date == nil || holiday.Status == nil {
+			continue
+		}
+		date, err := time.ParseInLocation(dateLayout, *holiday.Date, Location)
+		if err != nil {
+			errLogger.Printf("Ignoring market holiday with invalid date %q", *holiday.Date)
+			continue
+		}
+		day := Day{Date: date, Status: *holiday.Status}
+		if holiday.Name != nil {
+			day.Name = *holiday.Name
+		}
+		if holiday.Open != nil && holiday.Close != nil {
+			open, errOpen := time.Parse(time.RFC3339, *holiday.Open)
+			close, errClose := time.Parse(time.RFC3339, *holiday.Close)
+			if errOpen == nil && errClose == nil {
+				day.Open, day.Close = open.In(Location), close.In(Location)
+			}
+		}
+		announced[*holiday.Date] = day
+	}
+
+	calendar.mu.Lock()
+	calendar.announced = announced
+	calendar.refreshedAt = calendar.now()
+	calendar.mu.Unlock()
+	return nil
+}
+
+// RefreshIfStale calls Refresh if the holidays were last loaded more than maxAge ago
+func (calendar *Calendar) RefreshIfStale(ctx context.Context, maxAge time.Duration) error {
+	calendar.mu.RLock()
+	fresh := !calendar.refreshedAt.IsZero() && calendar.now().Sub(calendar.refreshedAt) < maxAge
+	calendar.mu.RUnlock()
+	if fresh {
+		return nil
+	}
+	return calendar.Refresh(ctx)
+}
+
+// Status returns the current state of the market. The live status is read from Polygon when the calendar has it,
+// and computed from the calendar otherwise or if Polygon fails.
+func (calendar *Calendar) Status(ctx context.Context) MarketStatus {
+	if err := calendar.RefreshIfStale(ctx, DefaultRefreshInterval); err != nil {
+		errLogger.Printf("Using built-in market holidays: %v", err)
+	}
+
+	status := calendar.StatusAt(calendar.now())
+	if calendar.source == nil {
+		return status
+	}
+
+	live, err := calendar.source.PolygonGetMarketStatusWithContext(ctx)
+	if err != nil && !polygon.IsPartialPolygonData(err) {
+		errLogger.Printf("Computing market status from the calendar: %v", err)
+		return status
+	}
+	status.Source = SourcePolygon
+	status.Market = *live.Market
+	status.IsOpen = status.Market == polygon.MarketOpen
+	status.EarlyHours = live.EarlyHours != nil && *live.EarlyHours
+	status.AfterHours = live.AfterHours != nil && *live.AfterHours
+	return status
+}
+
+// StatusAt computes the state of the market at t from the calendar alone
+func (calendar *Calendar) StatusAt(t time.Time) MarketStatus {
+	status := MarketStatus{
+		Market:           polygon.MarketClosed,
+		Time:             t,
+		Source:           SourceCalendar,
+		PreviousSession:  calendar.PreviousSession(t),
+		NextSession:      calendar.NextSession(t),
+		UpcomingHolidays: calendar.holidaysBetween(t, t.Add(upcomingHolidaysWindow)),
+	}
+	if holiday, ok := calendar.Holiday(t); ok && isWeekday(holiday.Date) {
+		status.Holiday = &holiday
+	}
+
+	session, ok := calendar.Session(t)
+	if !ok {
+		return status
+	}
+	status.Session = &session
+	switch {
+	case !t.Before(session.Open) && t.Before(session.Close):
+		status.Market = polygon.MarketOpen
+		status.IsOpen = true
+	case !t.Before(session.Date.Add(PreMarketOpen)) && t.Before(session.Open):
+		status.Market = polygon.MarketExtendedHours
+		status.EarlyHours = true
+	case !t.Before(session.Close) && t.Before(session.Close.Add(AfterHoursClose-RegularClose)):
+		status.Market = polygon.MarketExtendedHours
+		status.AfterHours = true
+	}
+	return status
+}
+
+// Returns the holidays and early closes on weekdays between the dates of start and end, inclusive
+func (calendar *Calendar) holidaysBetween(start, end time.Time) []Day {
+	days := []Day{}
+	for date := dateOf(start); !date.After(dateOf(end)); date = date.AddDate(0, 0, 1) {
+		if !isWeekday(date) {
+			continue
+		}
+		if holiday, ok := calendar.Holiday(date); ok {
+			days = append(days, holiday)
+		}
+	}
+	return days
+}
@@ -11,8 +11,12 @@ import (
 )
 
 func LoadVars() (map[string]string, []string, error) {
+	// In demo mode, market data is read from the files of this directory instead of Polygon, so API keys are optional
+	marketDataDir := os.Getenv("MARKET_DATA_DIR")
+	demoMode := marketDataDir != ""
+
 	nytKey := os.Getenv("NYT_API_KEY")
-	if nytKey == "" {
+	if nytKey == "" && !demoMode {
 		return nil, nil, errors.New("new york times api key not found")
 	}
 
@@ -28,13 +32,16 @@ func LoadVars() (map[string]string, []string, error) {
 	for _, name := range polygonEnvNames {
 		v := os.Getenv(name)
 		if v == "" {
+			if demoMode {
+				continue
+			}
 			return nil, nil, errors.New(name + " not found")
 		}
 		polygonKeys = append(polygonKeys, v)
 	}
 
 	geminiKey := os.Getenv("GOOGLE_GEMINI_API_KEY")
-	if geminiKey == "" {
+	if geminiKey == "" && !demoMode {
 		return nil, nil, errors.New("gemini api key not found")
 	}
 
@@ -84,6 +91,7 @@ func LoadVars() (map[string]string, []string, error) {
 		"MONGO_PORT":                 mongoPortString,
 		"MONGO_HOST":                 mongoHost,
 		"THROTTLE_TIME":              throttleTimeString,
+		"MARKET_DATA_DIR":            marketDataDir,
 	}
 
 	return vars, polygonKeys, nil
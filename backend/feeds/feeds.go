@@ -0,0 +1,398 @@
+// Package feeds fetches and parses RSS and Atom news feeds, e.g. investor relations pages and the financial press.
+//
+// RSS 2.0, RSS 1.0 (RDF) and Atom 1.0 documents are all parsed into the same Feed type:
+//
+//	feed, err := feeds.Fetch(ctx, http.DefaultClient, "https://www.apple.com/newsroom/rss-feed.rss")
+//	for _, item := range feed.Items { ... }
+package feeds
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Feeds larger than this are rejected instead of being read into memory
+const maxFeedSize = 10 << 20
+
+// Feed is a parsed RSS or Atom feed
+type Feed struct {
+	Title string
+	Link  string // The site the feed belongs to
+	Items []FeedItem
+}
+
+// FeedItem is a single entry of a feed. Fields the feed doesn't provide are left empty.
+type FeedItem struct {
+	ID          string // The guid (RSS) or id (Atom), the link if the feed has neither
+	Title       string
+	Link        string // Canonical, see CanonicalURL
+	Description string // Plain text, any HTML markup is removed
+	Author      string
+	Published   time.Time // Zero if the feed has no date for the item
+	Categories  []string
+	ImageURL    string
+}
+
+// FeedStatusError is returned by Fetch when the server responds with a non-2XX status code
+type FeedStatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (err *FeedStatusError) Error() string {
+	return fmt.Sprintf("feed %s responded with status %d", err.URL, err.StatusCode)
+}
+
+// Fetch downloads and parses the feed at feedURL
+//
+// Input:
+//   - ctx: bounds the request
+//   - client: the client the request is sent with, e.g. http.DefaultClient
+//   - feedURL: the URL of the RSS or Atom document
+//
+// Output:
+//   - *Feed: the parsed feed
+//   - error: any error that occurred, a *FeedStatusError if the server did not respond with 2XX
+func Fetch(ctx context.Context, client *http.Client, feedURL string) (*Feed, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feedURL, nil)
+	if err != nil {
+		return nil, errors.Join(errors.New("error generating request for feed"), err)
+	}
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/xml;q=0.9, text/xml;q=0.8")
+	req.Header.Set("User-Agent", "StockSavvy feed reader")
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("error requesting feed %s", feedURL), err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		return nil, &FeedStatusError{URL: feedURL, StatusCode: res.StatusCode}
+	}
+
+	data, err := io.ReadAll(io.LimitReader(res.Body, maxFeedSize+1))
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("error reading feed %s", feedURL), err)
+	}
+	if len(data) > maxFeedSize {
+		return nil, fmt.Errorf("feed %s is larger than %d bytes", feedURL, maxFeedSize)
+	}
+
+	feed, err := Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("error parsing feed %s", feedURL), err)
+	}
+	return feed, nil
+}
+
+// The elements of RSS 2.0, RSS 1.0 and Atom documents that are read. Namespaced elements are matched
+// by local name, so dc:creator, media:content and content:encoded need no namespace declaration.
+type rawFeed struct {
+	XMLName xml.Name
+	// RSS 2.0 nests everything in a channel, RSS 1.0 puts items next to it
+	Channel *struct {
+		Title string    `xml:"title"`
+		Links []string  `xml:"link"` // Channels often add an empty atom:link to themselves
+		Items []rawItem `xml:"item"`
+	} `xml:"channel"`
+	Items []rawItem `xml:"item"`
+	// Atom
+	Title   string     `xml:"title"`
+	Links   []rawLink  `xml:"link"`
+	Entries []rawEntry `xml:"entry"`
+}
+
+type rawItem struct {
+	GUID        string   `xml:"guid"`
+	About       string   `xml:"about,attr"`
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Description string   `xml:"description"`
+	Content     string   `xml:"encoded"`
+	Author      string   `xml:"author"`
+	Creator     string   `xml:"creator"`
+	PubDate     string   `xml:"pubDate"`
+	Date        string   `xml:"date"`
+	Categories  []string `xml:"category"`
+	Enclosures  []struct {
+		URL  string `xml:"url,attr"`
+		Type string `xml:"type,attr"`
+	} `xml:"enclosure"`
+	Media []struct {
+		URL    string `xml:"url,attr"`
+		Medium string `xml:"medium,attr"`
+		Type   string `xml:"type,attr"`
+	} `xml:"content"`
+	Thumbnails []struct {
+		URL string `xml:"url,attr"`
+	} `xml:"thumbnail"`
+}
+
+type rawLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rawEntry struct {
+	ID      string    `xml:"id"`
+	Title   string    `xml:"title"`
+	Links   []rawLink `xml:"link"`
+	Summary string    `xml:"summary"`
+	Content string    `xml:"content"`
+	Authors []struct {
+		Name string `xml:"name"`
+	} `xml:"author"`
+	Published  string `xml:"published"`
+	Updated    string `xml:"updated"`
+	Categories []struct {
+		Term  string `xml:"term,attr"`
+		Label string `xml:"label,attr"`
+	} `xml:"category"`
+}
+
+// Parse reads an RSS 2.0, RSS 1.0 or Atom document
+//
+// Input:
+//   - r: the XML document
+//
+// Output:
+//   - *Feed: the parsed feed, whose items keep the order of the document
+//   - error: any error that occurred, e.g. if the document is not a feed
+func Parse(r io.Reader) (*Feed, error) {
+	decoder := xml.NewDecoder(r)
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+	decoder.CharsetReader = charsetReader
+
+	var raw rawFeed
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, errors.Join(errors.New("invalid feed XML"), err)
+	}
+
+	switch strings.ToLower(raw.XMLName.Local) {
+	case "rss", "rdf":
+		feed := &Feed{}
+		items := raw.Items
+		if raw.Channel != nil {
+			feed.Title = cleanText(raw.Channel.Title)
+			for _, link := range raw.Channel.Links {
+				if link = strings.TrimSpace(link); link != "" && feed.Link == "" {
+					feed.Link = link
+				}
+			}
+			items = append(raw.Channel.Items, items...)
+		}
+		for _, item := range items {
+			feed.Items = append(feed.Items, item.toFeedItem())
+		}
+		return feed, nil
+	case "feed":
+		feed := &Feed{Title: cleanText(raw.Title), Link: alternateLink(raw.Links)}
+		for _, entry := range raw.Entries {
+			feed.Items = append(feed.Items, entry.toFeedItem())
+		}
+		return feed, nil
+	default:
+		return nil, fmt.Errorf("unknown feed format <%s>", raw.XMLName.Local)
+	}
+}
+
+func (item rawItem) toFeedItem() FeedItem {
+	description := item.Description
+	if description == "" {
+		description = item.Content
+	}
+	author := item.Creator
+	if author == "" {
+		author = item.Author
+	}
+	published := item.PubDate
+	if published == "" {
+		published = item.Date
+	}
+
+	feedItem := FeedItem{
+		Title:       cleanText(item.Title),
+		Link:        CanonicalURL(item.Link),
+		Description: cleanText(description),
+		Author:      strings.TrimSpace(author),
+		Published:   parseFeedDate(published),
+	}
+	for _, category := range item.Categories {
+		if category = strings.TrimSpace(category); category != "" {
+			feedItem.Categories = append(feedItem.Categories, category)
+		}
+	}
+	for _, media := range item.Media {
+		if media.Medium == "image" || strings.HasPrefix(media.Type, "image/") {
+			feedItem.ImageURL = media.URL
+			break
+		}
+	}
+	if feedItem.ImageURL == "" && len(item.Thumbnails) > 0 {
+		feedItem.ImageURL = item.Thumbnails[0].URL
+	}
+	for _, enclosure := range item.Enclosures {
+		if feedItem.ImageURL == "" && strings.HasPrefix(enclosure.Type, "image/") {
+			feedItem.ImageURL = enclosure.URL
+		}
+	}
+
+	feedItem.ID = strings.TrimSpace(item.GUID)
+	if feedItem.ID == "" {
+		feedItem.ID = strings.TrimSpace(item.About)
+	}
+	if feedItem.ID == "" {
+		feedItem.ID = feedItem.Link
+	}
+	return feedItem
+}
+
+func (entry rawEntry) toFeedItem() FeedItem {
+	description := entry.Summary
+	if description == "" {
+		description = entry.Content
+	}
+	published := entry.Published
+	if published == "" {
+		published = entry.Updated
+	}
+
+	feedItem := FeedItem{
+		ID:          strings.TrimSpace(entry.ID),
+		Title:       cleanText(entry.Title),
+		Link:        CanonicalURL(alternateLink(entry.Links)),
+		Description: cleanText(description),
+		Published:   parseFeedDate(published),
+	}
+	authors := []string{}
+	for _, author := range entry.Authors {
+		if name := strings.TrimSpace(author.Name); name != "" {
+			authors = append(authors, name)
+		}
+	}
+	feedItem.Author = strings.Join(authors, ", ")
+	for _, category := range entry.Categories {
+		if category.Label != "" {
+			feedItem.Categories = append(feedItem.Categories, category.Label)
+		} else if category.Term != "" {
+			feedItem.Categories = append(feedItem.Categories, category.Term)
+		}
+	}
+	for _, link := range entry.Links {
+		if link.Rel == "enclosure" && strings.HasPrefix(link.Type, "image/") {
+			feedItem.ImageURL = link.Href
+			break
+		}
+	}
+	if feedItem.ID == "" {
+		feedItem.ID = feedItem.Link
+	}
+	return feedItem
+}
+
+// Returns the href of the rel="alternate" link (the default rel), or of the first link
+func alternateLink(links []rawLink) string {
+	for _, link := range links {
+		if link.Rel == "" || link.Rel == "alternate" {
+			return strings.TrimSpace(link.Href)
+		}
+	}
+	if len(links) > 0 {
+		return strings.TrimSpace(links[0].Href)
+	}
+	return ""
+}
+
+// The date formats seen in feeds, RFC 1123 variants for RSS and RFC 3339 for Atom and Dublin Core
+var feedDateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Mon, 02 Jan 2006 15:04 -0700",
+	"2 Jan 2006 15:04:05 -0700",
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// Parses the date of an item in UTC, or returns the zero time if the format is unknown
+func parseFeedDate(value string) time.Time {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}
+	}
+	for _, layout := range feedDateLayouts {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed.UTC()
+		}
+	}
+	return time.Time{}
+}
+
+var (
+	htmlTagPattern    = regexp.MustCompile(`(?s)<[^>]*>`)
+	whitespacePattern = regexp.MustCompile(`\s+`)
+)
+
+// Turns the HTML that feeds put in titles and descriptions into a single line of plain text
+func cleanText(value string) string {
+	value = htmlTagPattern.ReplaceAllString(value, " ")
+	value = html.UnescapeString(value)
+	return strings.TrimSpace(whitespacePattern.ReplaceAllString(value, " "))
+}
+
+// The query parameters that only track where a reader came from
+var trackingParameters = []string{"utm_source", "utm_medium", "utm_campaign", "utm_term", "utm_content", "cmpid", "smid", "ref", "mod"}
+
+// CanonicalURL normalizes an article URL so the same article is recognized across feeds and providers:
+// the scheme and host are lowercased, and the fragment and tracking parameters (utm_*, ...) are removed
+func CanonicalURL(rawURL string) string {
+	rawURL = strings.TrimSpace(rawURL)
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return rawURL
+	}
+	parsed.Scheme = strings.ToLower(parsed.Scheme)
+	parsed.Host = strings.ToLower(parsed.Host)
+	parsed.Fragment = ""
+
+	query := parsed.Query()
+	for _, parameter := range trackingParameters {
+		query.Del(parameter)
+	}
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+// Decodes the single byte charsets some older feeds still declare. Anything else is assumed to be UTF-8.
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "iso-8859-1", "latin1", "windows-1252", "cp1252":
+		data, err := io.ReadAll(input)
+		if err != nil {
+			return nil, err
+		}
+		decoded := make([]byte, 0, len(data))
+		for _, b := range data {
+			decoded = utf8.AppendRune(decoded, rune(b))
+		}
+		return bytes.NewReader(decoded), nil
+	default:
+		return input, nil
+	}
+}
@@ -0,0 +1,150 @@
+package feeds
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func parseFixture(t *testing.T, name string) *Feed {
+	t.Helper()
+	file, err := os.Open("testdata/" + name)
+	if err != nil {
+		t.Fatalf("failed to open fixture: %v", err)
+	}
+	defer file.Close()
+	feed, err := Parse(file)
+	if err != nil {
+		t.Fatalf("Parse(%s) error: %v", name, err)
+	}
+	return feed
+}
+
+func TestParse_RSS(t *testing.T) {
+	feed := parseFixture(t, "markets.rss")
+	if feed.Title != "Markets & Money" || feed.Link != "https://markets.example.com/" {
+		t.Fatalf("unexpected channel %q %q", feed.Title, feed.Link)
+	}
+	if len(feed.Items) != 3 {
+		t.Fatalf("expected 3 items, got %d", len(feed.Items))
+	}
+
+	first := feed.Items[0]
+	if first.ID != "markets-20241104-apple" || first.Author != "Jane Doe" {
+		t.Fatalf("unexpected guid %q or author %q", first.ID, first.Author)
+	}
+	if first.Link != "https://markets.example.com/2024/11/04/apple-supplier-warning" {
+		t.Fatalf("expected tracking parameters and fragment to be removed, got %q", first.Link)
+	}
+	if first.Description != "Shares of Apple ($AAPL) fell 2% in early trading." {
+		t.Fatalf("expected the HTML to be removed, got %q", first.Description)
+	}
+	if !first.Published.Equal(time.Date(2024, 11, 4, 14, 30, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected date %s", first.Published)
+	}
+	if first.ImageURL != "https://markets.example.com/images/apple.jpg" || !slices.Equal(first.Categories, []string{"Technology"}) {
+		t.Fatalf("unexpected image %q or categories %v", first.ImageURL, first.Categories)
+	}
+
+	if !feed.Items[1].Published.Equal(time.Date(2024, 11, 20, 21, 5, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected GMT date %s", feed.Items[1].Published)
+	}
+	// Without a guid, the link identifies the item
+	if third := feed.Items[2]; third.ID != third.Link || !third.Published.Equal(time.Date(2024, 11, 22, 17, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected id %q or date %s", third.ID, third.Published)
+	}
+}
+
+func TestParse_Atom(t *testing.T) {
+	feed := parseFixture(t, "newsroom.atom")
+	if feed.Title != "Company Newsroom" || feed.Link != "https://newsroom.example.com/" {
+		t.Fatalf("unexpected feed %q %q", feed.Title, feed.Link)
+	}
+	if len(feed.Items) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(feed.Items))
+	}
+
+	first := feed.Items[0]
+	if first.ID != "tag:newsroom.example.com,2024:release-1115" || first.Link != "https://newsroom.example.com/releases/2024/11/15/dividend" {
+		t.Fatalf("unexpected id %q or link %q", first.ID, first.Link)
+	}
+	if first.Description != "The board declared a dividend of $0.25 per share." || first.Author != "Investor Relations" {
+		t.Fatalf("unexpected summary %q or author %q", first.Description, first.Author)
+	}
+	if !first.Published.Equal(time.Date(2024, 11, 15, 21, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected the published date, got %s", first.Published)
+	}
+	if first.ImageURL != "https://newsroom.example.com/images/dividend.png" || !slices.Equal(first.Categories, []string{"Dividends"}) {
+		t.Fatalf("unexpected image %q or categories %v", first.ImageURL, first.Categories)
+	}
+
+	// An entry without a published date falls back to its updated date
+	if second := feed.Items[1]; second.Description != "A new campus in Austin." || !second.Published.Equal(time.Date(2024, 11, 1, 9, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected content %q or date %s", second.Description, second.Published)
+	}
+}
+
+func TestParse_NotAFeed(t *testing.T) {
+	if _, err := Parse(strings.NewReader(`<html><body>Not found</body></html>`)); err == nil {
+		t.Fatal("expected an error for an HTML page")
+	}
+	if _, err := Parse(strings.NewReader(`not xml`)); err == nil {
+		t.Fatal("expected an error for a document that is not XML")
+	}
+}
+
+func TestFetch(t *testing.T) {
+	server := httptest.NewServer(http.FileServer(http.Dir("testdata")))
+	defer server.Close()
+
+	feed, err := Fetch(context.Background(), server.Client(), server.URL+"/newsroom.atom")
+	if err != nil || len(feed.Items) != 2 {
+		t.Fatalf("expected the newsroom feed, got %v (%v)", feed, err)
+	}
+
+	_, err = Fetch(context.Background(), server.Client(), server.URL+"/missing.rss")
+	var statusErr *FeedStatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected a 404 status error, got %v", err)
+	}
+}
+
+func TestTickerDictionary(t *testing.T) {
+	feed := parseFixture(t, "markets.rss")
+	dictionary := NewTickerDictionary(map[string][]string{
+		"AAPL": {"Apple"},
+		"MSFT": {"Microsoft"},
+		"nvda": nil,
+		"ON":   nil,
+	})
+
+	expected := [][]string{
+		{"AAPL"},
+		{"MSFT", "NVDA"}, // By name, and by symbol after its exchange
+		nil,              // "apple" is not the company and "On" is not the ticker
+	}
+	for i, item := range feed.Items {
+		if symbols := dictionary.Tag(item); !slices.Equal(symbols, expected[i]) {
+			t.Errorf("item %d (%s): tagged %v, expected %v", i, item.Title, symbols, expected[i])
+		}
+	}
+}
+
+func TestCanonicalURL(t *testing.T) {
+	cases := map[string]string{
+		"HTTPS://Example.com/a?utm_source=x&id=3#top": "https://example.com/a?id=3",
+		"https://example.com/a?b=2&a=1":               "https://example.com/a?a=1&b=2",
+		"not a url":                                   "not a url",
+	}
+	for rawURL, expected := range cases {
+		if canonical := CanonicalURL(rawURL); canonical != expected {
+			t.Errorf("CanonicalURL(%q) = %q, expected %q", rawURL, canonical, expected)
+		}
+	}
+}
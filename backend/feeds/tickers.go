@@ -0,0 +1,73 @@
+package feeds
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// TickerDictionary tags feed items with the tickers they mention, from each ticker's symbol and company names.
+//
+// A symbol counts when it is written as a cashtag ($AAPL) or after an exchange (NASDAQ: AAPL), since a bare
+// symbol like "ON" or "ALL" is too ambiguous. Company names count as whole, case sensitive words.
+type TickerDictionary struct {
+	entries []dictionaryEntry
+}
+
+type dictionaryEntry struct {
+	symbol  string
+	pattern *regexp.Regexp
+}
+
+// NewTickerDictionary creates a dictionary
+//
+// Input:
+//   - names: the company names of every ticker, e.g. "AAPL": {"Apple"}. A ticker without names is only
+//     matched by symbol.
+//
+// Output:
+//   - *TickerDictionary: the dictionary
+func NewTickerDictionary(names map[string][]string) *TickerDictionary {
+	dictionary := &TickerDictionary{}
+	for symbol, companyNames := range names {
+		symbol = strings.ToUpper(strings.TrimSpace(symbol))
+		if symbol == "" {
+			continue
+		}
+
+		quotedSymbol := regexp.QuoteMeta(symbol)
+		alternatives := []string{
+			`\$` + quotedSymbol + `\b`,
+			`\b(?i:nasdaq|nyse|nyse american|nyse arca|amex|otc|cboe)\s*:\s*` + quotedSymbol + `\b`,
+		}
+		for _, name := range companyNames {
+			if name = strings.TrimSpace(name); len(name) >= 2 {
+				alternatives = append(alternatives, `\b`+regexp.QuoteMeta(name)+`\b`)
+			}
+		}
+		dictionary.entries = append(dictionary.entries, dictionaryEntry{
+			symbol:  symbol,
+			pattern: regexp.MustCompile(strings.Join(alternatives, "|")),
+		})
+	}
+	slices.SortFunc(dictionary.entries, func(a, b dictionaryEntry) int { return strings.Compare(a.symbol, b.symbol) })
+	return dictionary
+}
+
+// Len returns the number of tickers of the dictionary
+func (dictionary *TickerDictionary) Len() int {
+	return len(dictionary.entries)
+}
+
+// Tag returns the tickers mentioned in the title, description or categories of item, sorted, or nil if there are none
+func (dictionary *TickerDictionary) Tag(item FeedItem) []string {
+	text := strings.Join(append([]string{item.Title, item.Description}, item.Categories...), "\n")
+
+	var symbols []string
+	for _, entry := range dictionary.entries {
+		if entry.pattern.MatchString(text) {
+			symbols = append(symbols, entry.symbol)
+		}
+	}
+	return symbols
+}
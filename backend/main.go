@@ -1,43 +1,138 @@
 package main
 
 import (
+	"context"
 	"financial-helper/scraper"
 	"financial-helper/server"
 	"flag"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 )
 
 func main() {
-	runScraperFlag := flag.String("scrape", "", "Runs the scraper.")
+	runScraperFlag := flag.String("scrape", "", "Runs the scraper: aggs, grouped, news, nyt, feeds, dividends, splits or financials.")
 	flag.Parse()
 
+	// Ctrl-C (or docker stop) cancels the context, so scrapes stop cleanly after the current window
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	if *runScraperFlag != "" {
 		if *runScraperFlag == "aggs" {
-			runAggsScraper()
+			runAggsScraper(ctx)
+		} else if *runScraperFlag == "grouped" {
+			runGroupedDailyScraper(ctx)
 		} else if *runScraperFlag == "news" {
-			runNewsScraper()
+			runNewsScraper(ctx)
+		} else if *runScraperFlag == "nyt" {
+			runNYTScraper(ctx)
+		} else if *runScraperFlag == "feeds" {
+			runFeedsScraper(ctx)
+		} else if *runScraperFlag == "dividends" {
+			runDividendsScraper(ctx)
+		} else if *runScraperFlag == "splits" {
+			runSplitsScraper(ctx)
+		} else if *runScraperFlag == "financials" {
+			runFinancialsScraper(ctx)
+		} else {
+			log.Fatalf("Unknown scraper %q, expected aggs, grouped, news, nyt, feeds, dividends, splits or financials", *runScraperFlag)
 		}
 	} else {
 		runServer()
 	}
 }
 
-func runNewsScraper() {
+func runNewsScraper(ctx context.Context) {
+	scraper, err := scraper.New()
+	if err != nil {
+		log.Fatal("Failed to start scraper:", err)
+	}
+
+	if err := scraper.ScrapeTickersNewsFromJSON(ctx, "./scraper/article_instructions.json"); err != nil {
+		log.Println("News scrape stopped:", err)
+	}
+}
+
+// Scrapes the NYT archive month by month, keeping the articles about the tickers of the instructions
+func runNYTScraper(ctx context.Context) {
+	scraper, err := scraper.New()
+	if err != nil {
+		log.Fatal("Failed to start scraper:", err)
+	}
+
+	if err := scraper.ScrapeNYTArchiveFromJSON(ctx, "./scraper/nyt_instructions.json"); err != nil {
+		log.Println("NYT scrape stopped:", err)
+	}
+}
+
+// Scrapes the RSS and Atom feeds of the instructions, keeping the articles that mention a tracked ticker
+func runFeedsScraper(ctx context.Context) {
 	scraper, err := scraper.New()
 	if err != nil {
 		log.Fatal("Failed to start scraper:", err)
 	}
 
-	scraper.ScrapeTickersNewsFromJSON("./scraper/article_instructions.json")
+	if err := scraper.ScrapeFeedsFromJSON(ctx, "./scraper/feeds_instructions.json"); err != nil {
+		log.Println("Feeds scrape stopped:", err)
+	}
+}
+
+func runAggsScraper(ctx context.Context) {
+	scraper, err := scraper.New()
+	if err != nil {
+		log.Fatal("Failed to start scraper:", err)
+	}
+
+	if err := scraper.ScrapeTickersAggregatesFromJSON(ctx, "./scraper/aggs_instructions.json"); err != nil {
+		log.Println("Aggregates scrape stopped:", err)
+	}
 }
 
-func runAggsScraper() {
+// Scrapes the daily bars of the whole market, one request per trading day instead of one per ticker
+func runGroupedDailyScraper(ctx context.Context) {
 	scraper, err := scraper.New()
 	if err != nil {
 		log.Fatal("Failed to start scraper:", err)
 	}
 
-	scraper.ScrapeTickersAggregatesFromJSON("./scraper/aggs_instructions.json")
+	if err := scraper.ScrapeGroupedDailyFromJSON(ctx, "./scraper/grouped_instructions.json"); err != nil {
+		log.Println("Grouped daily scrape stopped:", err)
+	}
+}
+
+func runDividendsScraper(ctx context.Context) {
+	scraper, err := scraper.New()
+	if err != nil {
+		log.Fatal("Failed to start scraper:", err)
+	}
+
+	if err := scraper.ScrapeTickersDividendsFromJSON(ctx, "./scraper/dividends_instructions.json"); err != nil {
+		log.Println("Dividends scrape stopped:", err)
+	}
+}
+
+func runSplitsScraper(ctx context.Context) {
+	scraper, err := scraper.New()
+	if err != nil {
+		log.Fatal("Failed to start scraper:", err)
+	}
+
+	if err := scraper.ScrapeTickersSplitsFromJSON(ctx, "./scraper/splits_instructions.json"); err != nil {
+		log.Println("Splits scrape stopped:", err)
+	}
+}
+
+func runFinancialsScraper(ctx context.Context) {
+	scraper, err := scraper.New()
+	if err != nil {
+		log.Fatal("Failed to start scraper:", err)
+	}
+
+	if err := scraper.ScrapeTickersFinancialsFromJSON(ctx, "./scraper/financials_instructions.json"); err != nil {
+		log.Println("Financials scrape stopped:", err)
+	}
 }
 
 func runServer() {
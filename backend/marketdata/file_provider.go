@@ -0,0 +1,540 @@
+package marketdata
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"financial-helper/calendar"
+	"financial-helper/polygon"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	tickersFileName = "tickers.json"
+	aggsDirName     = "aggs"
+	newsFileName    = "news.jsonl"
+	// Articles per page when GetTickerNews is called without a limit, as for the Polygon news endpoint
+	defaultNewsLimit = 300
+	// Longest line of the news file, articles are a few KB at most
+	maxNewsLineSize = 1024 * 1024
+)
+
+var _ MarketDataProvider = (*FileProvider)(nil)
+
+var statusOK = "OK"
+
+// FileProvider reads market data from the files of a directory:
+//
+//	tickers.json       optional, an array of ticker details in the format of Polygon's ticker details endpoint
+//	aggs/<SYMBOL>.csv  the daily bars of a ticker, see readBars for the columns
+//	news.jsonl         one article per line, in the format of Polygon's news endpoint
+//
+// Files are read on every request, so they can be replaced while the backend runs. Tickers with bars but no details
+// are served with their symbol as name. Only daily and longer bars can be served, longer ones being merged from the
+// daily bars.
+type FileProvider struct {
+	dir string
+	now func() time.Time
+}
+
+// NewFileProvider returns a provider reading the files of dir
+func NewFileProvider(dir string) *FileProvider {
+	return &FileProvider{dir: dir, now: time.Now}
+}
+
+func (provider *FileProvider) Name() string {
+	return "files"
+}
+
+func (provider *FileProvider) GetTickerDetails(ctx context.Context, symbol string) (*polygon.PolygonGetTickerDetailsResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	tickers, err := provider.readTickers()
+	if err != nil {
+		return nil, err
+	}
+	for i := range tickers {
+		if tickers[i].Ticker != nil && strings.EqualFold(*tickers[i].Ticker, symbol) {
+			return &polygon.PolygonGetTickerDetailsResponse{Results: &tickers[i], Status: &statusOK}, nil
+		}
+	}
+	return nil, polygon.ErrNoResults
+}
+
+// SearchTickers matches the search term against the symbols and names of the tickers, ignoring case
+func (provider *FileProvider) SearchTickers(ctx context.Context, request polygon.PolygonTickerSearchRequest) (*polygon.PolygonGetTickerResponse, error) {
+	if request.Search == "" {
+		return nil, errors.New("search term is required")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	tickers, err := provider.readTickers()
+	if err != nil {
+		return nil, err
+	}
+
+	limit := request.Limit
+	if limit <= 0 {
+		limit = polygon.DefaultTickerSearchLimit
+	}
+	limit = min(limit, polygon.MaxTickerSearchLimit)
+
+	search := strings.ToLower(request.Search)
+	contains := func(value *string) bool {
+		return value != nil && strings.Contains(strings.ToLower(*value), search)
+	}
+	matchesFilter := func(value *string, filter string) bool {
+		return filter == "" || (value != nil && strings.EqualFold(*value, filter))
+	}
+
+	results := []polygon.PolygonTickerReference{}
+	for _, details := range tickers {
+		if len(results) == limit {
+			break
+		}
+		if !contains(details.Ticker) && !contains(details.Name) {
+			continue
+		}
+		if !matchesFilter(details.Market, request.Market) || !matchesFilter(details.Type, request.Type) || !matchesFilter(details.PrimaryExchange, request.Exchange) {
+			continue
+		}
+		results = append(results, polygon.PolygonTickerReference{
+			Ticker:          details.Ticker,
+			Name:            details.Name,
+			Market:          details.Market,
+			Locale:          details.Locale,
+			PrimaryExchange: details.PrimaryExchange,
+			Type:            details.Type,
+			Active:          details.Active,
+			CurrencyName:    details.CurrencyName,
+			Cik:             details.Cik,
+			CompositeFigi:   details.CompositeFigi,
+			ShareClassFigi:  details.ShareClassFigi,
+		})
+	}
+	if len(results) == 0 {
+		return nil, polygon.ErrNoResults
+	}
+
+	count := len(results)
+	return &polygon.PolygonGetTickerResponse{Results: &results, Status: &statusOK, Count: &count}, nil
+}
+
+// GetPreviousClose returns the latest bar dated before today in New York, so a demo directory whose bars stop in
+// the past serves its last bar
+func (provider *FileProvider) GetPreviousClose(ctx context.Context, symbol string) (*polygon.PolygonGetTickerAggregateResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	bars, err := provider.readBars(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	today := calendar.DateOf(provider.now().In(calendar.Location)).UnixMilli()
+	for i := len(bars) - 1; i >= 0; i-- {
+		bar := bars[i]
+		if *bar.Timestamp >= today {
+			continue
+		}
+		ticker := strings.ToUpper(symbol)
+		adjusted := true
+		count := 1
+		results := []polygon.PolygonPreviousCloseBar{{
+			Ticker:       &ticker,
+			Volume:       bar.Volume,
+			VWAP:         bar.VWAP,
+			Open:         bar.Open,
+			Close:        bar.Close,
+			High:         bar.High,
+			Low:          bar.Low,
+			Timestamp:    bar.Timestamp,
+			Transactions: bar.Transactions,
+		}}
+		return &polygon.PolygonGetTickerAggregateResponse{
+			Ticker:       &ticker,
+			QueryCount:   &count,
+			ResultsCount: &count,
+			Adjusted:     &adjusted,
+			Results:      &results,
+			Status:       &statusOK,
+			Count:        &count,
+		}, nil
+	}
+	return nil, polygon.ErrNoResults
+}
+
+func (provider *FileProvider) GetTickerHistory(ctx context.Context, symbol string, startDate time.Time, endDate time.Time, limit int) (*polygon.PolygonGetTickerHistoryResponse, error) {
+	return provider.GetTickerAggregates(ctx, polygon.PolygonAggregatesRequest{
+		Symbol:     symbol,
+		Multiplier: 1,
+		Timespan:   polygon.TimespanDay,
+		From:       startDate,
+		To:         endDate,
+		Limit:      limit,
+	})
+}
+
+// GetTickerAggregates serves the daily bars between the dates of request.From and request.To, merged into longer bars
+// if requested. The files hold a single series, so request.Unadjusted is ignored.
+func (provider *FileProvider) GetTickerAggregates(ctx context.Context, request polygon.PolygonAggregatesRequest) (*polygon.PolygonGetTickerHistoryResponse, error) {
+	request, err := request.Normalize()
+	if err != nil {
+		return nil, errors.Join(errors.New("invalid aggregates request"), err)
+	}
+	if request.Timespan.Intraday() {
+		return nil, fmt.Errorf("%s bars are not available from files, only daily and longer bars are", request.Timespan)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	bars, err := provider.readBars(request.Symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	// Like Polygon, the bounds are the dates of From and To in their own location
+	from := calendar.DateOf(request.From).UnixMilli()
+	to := calendar.DateOf(request.To).UnixMilli()
+	inRange := []polygon.PolygonAggregateBar{}
+	for _, bar := range bars {
+		if *bar.Timestamp >= from && *bar.Timestamp <= to {
+			inRange = append(inRange, bar)
+		}
+	}
+
+	results := resampleBars(inRange, request.Multiplier, request.Timespan)
+	if request.Sort == polygon.SortDescending {
+		for i, j := 0, len(results)-1; i < j; i, j = i+1, j-1 {
+			results[i], results[j] = results[j], results[i]
+		}
+	}
+	results = results[:min(len(results), request.Limit)]
+	if len(results) == 0 {
+		return nil, polygon.ErrNoResults
+	}
+
+	ticker := strings.ToUpper(request.Symbol)
+	adjusted := !request.Unadjusted
+	queryCount := len(inRange)
+	count := len(results)
+	return &polygon.PolygonGetTickerHistoryResponse{
+		Ticker:       &ticker,
+		QueryCount:   &queryCount,
+		ResultsCount: &count,
+		Adjusted:     &adjusted,
+		Results:      &results,
+		Status:       &statusOK,
+		Count:        &count,
+	}, nil
+}
+
+// GetTickerNews serves the articles of the news file listing symbol among their tickers. Pages are simulated, so
+// maxPages truncates the result like it does for Polygon.
+func (provider *FileProvider) GetTickerNews(ctx context.Context, symbol string, startDate time.Time, endDate time.Time, limit int, maxPages int) (*polygon.PolygonGetTickerNews, polygon.PolygonPageStats, error) {
+	if startDate.After(endDate) {
+		return nil, polygon.PolygonPageStats{}, errors.New("start date cannot be after end date")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, polygon.PolygonPageStats{}, err
+	}
+	articles, err := provider.readNews()
+	if err != nil {
+		return nil, polygon.PolygonPageStats{}, err
+	}
+
+	results := []polygon.PolygonTickerNewsResult{}
+	for _, article := range articles {
+		if article.PublishedUTC == nil || article.PublishedUTC.Before(startDate) || article.PublishedUTC.After(endDate) || article.Tickers == nil {
+			continue
+		}
+		for _, ticker := range *article.Tickers {
+			if strings.EqualFold(ticker, symbol) {
+				results = append(results, article)
+				break
+			}
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].PublishedUTC.After(*results[j].PublishedUTC) })
+
+	if limit <= 0 {
+		limit = defaultNewsLimit
+	}
+	stats := polygon.PolygonPageStats{Pages: max(1, (len(results)+limit-1)/limit), Results: len(results)}
+	if maxPages > 0 && stats.Pages > maxPages {
+		results = results[:maxPages*limit]
+		stats = polygon.PolygonPageStats{Pages: maxPages, Results: len(results), Truncated: true}
+	}
+	if len(results) == 0 {
+		return nil, stats, polygon.ErrNoResults
+	}
+
+	count := len(results)
+	return &polygon.PolygonGetTickerNews{Results: &results, Status: &statusOK, Count: &count}, stats, nil
+}
+
+// Returns the details of tickers.json, followed by minimal details for the tickers that only have bars
+func (provider *FileProvider) readTickers() ([]polygon.PolygonTickerDetails, error) {
+	tickers := []polygon.PolygonTickerDetails{}
+	path := filepath.Join(provider.dir, tickersFileName)
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, errors.Join(fmt.Errorf("failed to read %s", path), err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &tickers); err != nil {
+			return nil, errors.Join(fmt.Errorf("failed to parse %s", path), err)
+		}
+	}
+
+	known := map[string]bool{}
+	for _, details := range tickers {
+		if details.Ticker != nil {
+			known[strings.ToUpper(*details.Ticker)] = true
+		}
+	}
+
+	barFiles, err := filepath.Glob(filepath.Join(provider.dir, aggsDirName, "*.csv"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(barFiles)
+	for _, barFile := range barFiles {
+		symbol := strings.ToUpper(strings.TrimSuffix(filepath.Base(barFile), filepath.Ext(barFile)))
+		if known[symbol] {
+			continue
+		}
+		market, locale, active := "stocks", "us", true
+		tickers = append(tickers, polygon.PolygonTickerDetails{Ticker: &symbol, Name: &symbol, Market: &market, Locale: &locale, Active: &active})
+	}
+	return tickers, nil
+}
+
+// Reads the daily bars of a ticker from aggs/<SYMBOL>.csv, sorted by date.
+// The first row names the columns, in any order and case: date, open, high, low and close are required, while
+// volume, vwap and transactions are optional. Dates are YYYY-MM-DD in New York, or timestamps in milliseconds.
+func (provider *FileProvider) readBars(symbol string) ([]polygon.PolygonAggregateBar, error) {
+	// Symbols come from URLs, so they must not be able to leave the directory
+	if symbol == "" || strings.ContainsAny(symbol, `/\.`) {
+		return nil, polygon.ErrNoResults
+	}
+	path := filepath.Join(provider.dir, aggsDirName, strings.ToUpper(symbol)+".csv")
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, polygon.ErrNoResults
+	}
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("failed to open %s", path), err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("failed to read the header of %s", path), err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"date", "open", "high", "low", "close"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("%s has no %s column", path, required)
+		}
+	}
+
+	bars := []polygon.PolygonAggregateBar{}
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("failed to read %s", path), err)
+		}
+
+		timestamp, err := parseBarDate(strings.TrimSpace(record[columns["date"]]))
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("%s line %d has an invalid date", path, line), err)
+		}
+		bar := polygon.PolygonAggregateBar{Timestamp: &timestamp}
+		fields := map[string]**float64{"open": &bar.Open, "high": &bar.High, "low": &bar.Low, "close": &bar.Close, "volume": &bar.Volume, "vwap": &bar.VWAP}
+		for column, field := range fields {
+			index, ok := columns[column]
+			if !ok || strings.TrimSpace(record[index]) == "" {
+				continue
+			}
+			value, err := strconv.ParseFloat(strings.TrimSpace(record[index]), 64)
+			if err != nil {
+				return nil, errors.Join(fmt.Errorf("%s line %d has an invalid %s", path, line, column), err)
+			}
+			*field = &value
+		}
+		if index, ok := columns["transactions"]; ok && strings.TrimSpace(record[index]) != "" {
+			transactions, err := strconv.Atoi(strings.TrimSpace(record[index]))
+			if err != nil {
+				return nil, errors.Join(fmt.Errorf("%s line %d has invalid transactions", path, line), err)
+			}
+			bar.Transactions = &transactions
+		}
+		bars = append(bars, bar)
+	}
+
+	sort.SliceStable(bars, func(i, j int) bool { return *bars[i].Timestamp < *bars[j].Timestamp })
+	return bars, nil
+}
+
+// Returns the timestamp in milliseconds of a bar's date, which is midnight in New York like Polygon's daily bars
+func parseBarDate(value string) (int64, error) {
+	if millis, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return millis, nil
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return 0, err
+	}
+	return calendar.DateOf(date).UnixMilli(), nil
+}
+
+// Reads every article of news.jsonl, skipping blank lines
+func (provider *FileProvider) readNews() ([]polygon.PolygonTickerNewsResult, error) {
+	path := filepath.Join(provider.dir, newsFileName)
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("failed to open %s", path), err)
+	}
+	defer file.Close()
+
+	articles := []polygon.PolygonTickerNewsResult{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxNewsLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var article polygon.PolygonTickerNewsResult
+		if err := json.Unmarshal(scanner.Bytes(), &article); err != nil {
+			return nil, errors.Join(fmt.Errorf("%s line %d is not a valid article", path, line), err)
+		}
+		articles = append(articles, article)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Join(fmt.Errorf("failed to read %s", path), err)
+	}
+	return articles, nil
+}
+
+// Merges daily bars, sorted by date, into bars of multiplier timespans. Merged bars start on the first day of their
+// period (e.g. the 1st of the month) and their VWAP is weighted by volume. Periods of several timespans are counted
+// from the Unix epoch for days and weeks, and from year 0 for longer timespans.
+func resampleBars(bars []polygon.PolygonAggregateBar, multiplier int, timespan polygon.PolygonTimespan) []polygon.PolygonAggregateBar {
+	if timespan == polygon.TimespanDay && multiplier == 1 {
+		return bars
+	}
+
+	merged := []polygon.PolygonAggregateBar{}
+	var periods []int64
+	var weightedPrices, weightedVolumes []float64
+	copyValue := func(value *float64) *float64 {
+		if value == nil {
+			return nil
+		}
+		copied := *value
+		return &copied
+	}
+
+	for _, bar := range bars {
+		period, start := barPeriod(time.UnixMilli(*bar.Timestamp).In(calendar.Location), multiplier, timespan)
+		if len(merged) == 0 || periods[len(periods)-1] != period {
+			timestamp := start.UnixMilli()
+			merged = append(merged, polygon.PolygonAggregateBar{
+				Timestamp: &timestamp,
+				Open:      copyValue(bar.Open),
+				High:      copyValue(bar.High),
+				Low:       copyValue(bar.Low),
+				Close:     copyValue(bar.Close),
+			})
+			periods = append(periods, period)
+			weightedPrices = append(weightedPrices, 0)
+			weightedVolumes = append(weightedVolumes, 0)
+		}
+
+		current := &merged[len(merged)-1]
+		if bar.High != nil && (current.High == nil || *bar.High > *current.High) {
+			current.High = copyValue(bar.High)
+		}
+		if bar.Low != nil && (current.Low == nil || *bar.Low < *current.Low) {
+			current.Low = copyValue(bar.Low)
+		}
+		if bar.Close != nil {
+			current.Close = copyValue(bar.Close)
+		}
+		if bar.Volume != nil {
+			volume := *bar.Volume
+			if current.Volume != nil {
+				volume += *current.Volume
+			}
+			current.Volume = &volume
+			if bar.VWAP != nil {
+				weightedPrices[len(merged)-1] += *bar.VWAP * *bar.Volume
+				weightedVolumes[len(merged)-1] += *bar.Volume
+			}
+		}
+		if bar.Transactions != nil {
+			transactions := *bar.Transactions
+			if current.Transactions != nil {
+				transactions += *current.Transactions
+			}
+			current.Transactions = &transactions
+		}
+	}
+
+	for i := range merged {
+		if weightedVolumes[i] > 0 {
+			vwap := weightedPrices[i] / weightedVolumes[i]
+			merged[i].VWAP = &vwap
+		}
+	}
+	return merged
+}
+
+// Returns the index of the period of multiplier timespans containing date, and the first day of that period
+func barPeriod(date time.Time, multiplier int, timespan polygon.PolygonTimespan) (int64, time.Time) {
+	year, month, day := date.Date()
+	step := int64(multiplier)
+	days := time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Unix() / (24 * 60 * 60)
+
+	switch timespan {
+	case polygon.TimespanWeek:
+		// Weeks start on Sunday, and the epoch was a Thursday
+		period := (days + 4) / 7 / step
+		return period, time.Date(1970, time.January, 1+int(period*step*7-4), 0, 0, 0, 0, calendar.Location)
+	case polygon.TimespanMonth:
+		period := (int64(year)*12 + int64(month) - 1) / step
+		return period, time.Date(int(period*step/12), time.Month(period*step%12+1), 1, 0, 0, 0, 0, calendar.Location)
+	case polygon.TimespanQuarter:
+		period := (int64(year)*4 + (int64(month)-1)/3) / step
+		return period, time.Date(int(period*step/4), time.Month(period*step%4*3+1), 1, 0, 0, 0, 0, calendar.Location)
+	case polygon.TimespanYear:
+		period := int64(year) / step
+		return period, time.Date(int(period*step), time.January, 1, 0, 0, 0, 0, calendar.Location)
+	default:
+		period := days / step
+		return period, time.Date(1970, time.January, 1+int(period*step), 0, 0, 0, 0, calendar.Location)
+	}
+}
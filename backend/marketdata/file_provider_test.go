@@ -0,0 +1,176 @@
+package marketdata
+
+import (
+	"context"
+	"errors"
+	"financial-helper/calendar"
+	"financial-helper/polygon"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestProvider(t *testing.T) *FileProvider {
+	t.Helper()
+	provider := NewFileProvider("testdata")
+	provider.now = func() time.Time { return time.Date(2024, 11, 29, 12, 0, 0, 0, calendar.Location) }
+	return provider
+}
+
+func TestFileProviderTickerDetails(t *testing.T) {
+	provider := newTestProvider(t)
+
+	details, err := provider.GetTickerDetails(context.Background(), "aapl")
+	if err != nil {
+		t.Fatalf("GetTickerDetails error: %v", err)
+	}
+	if *details.Results.Name != "Apple Inc." || *details.Results.SicDescription != "ELECTRONIC COMPUTERS" {
+		t.Fatalf("expected the details of tickers.json, got %+v", details.Results)
+	}
+
+	// MSFT only has bars
+	details, err = provider.GetTickerDetails(context.Background(), "MSFT")
+	if err != nil || *details.Results.Name != "MSFT" {
+		t.Fatalf("expected minimal details for MSFT, got %+v (%v)", details, err)
+	}
+	if _, err := provider.GetTickerDetails(context.Background(), "TSLA"); !errors.Is(err, polygon.ErrNoResults) {
+		t.Fatalf("expected ErrNoResults for an unknown ticker, got %v", err)
+	}
+}
+
+func TestFileProviderSearchTickers(t *testing.T) {
+	provider := newTestProvider(t)
+
+	response, err := provider.SearchTickers(context.Background(), polygon.PolygonTickerSearchRequest{Search: "apple"})
+	if err != nil || len(*response.Results) != 1 || *(*response.Results)[0].Ticker != "AAPL" {
+		t.Fatalf("expected AAPL to match by name, got %+v (%v)", response, err)
+	}
+	if _, err := provider.SearchTickers(context.Background(), polygon.PolygonTickerSearchRequest{Search: "apple", Exchange: "XNYS"}); !errors.Is(err, polygon.ErrNoResults) {
+		t.Fatalf("expected the exchange filter to exclude AAPL, got %v", err)
+	}
+	response, err = provider.SearchTickers(context.Background(), polygon.PolygonTickerSearchRequest{Search: "ms"})
+	if err != nil || *(*response.Results)[0].Ticker != "MSFT" {
+		t.Fatalf("expected MSFT to match by symbol, got %+v (%v)", response, err)
+	}
+}
+
+func TestFileProviderPreviousClose(t *testing.T) {
+	provider := newTestProvider(t)
+
+	// Today's bar is not closed yet
+	response, err := provider.GetPreviousClose(context.Background(), "MSFT")
+	if err != nil {
+		t.Fatalf("GetPreviousClose error: %v", err)
+	}
+	bar := (*response.Results)[0]
+	if *bar.Close != 422.99 || *bar.Timestamp != time.Date(2024, 11, 27, 0, 0, 0, 0, calendar.Location).UnixMilli() {
+		t.Fatalf("expected the close of November 27th, got %v at %d", *bar.Close, *bar.Timestamp)
+	}
+	if *response.Count != 1 || *bar.Ticker != "MSFT" {
+		t.Fatalf("unexpected response %+v", response)
+	}
+}
+
+func TestFileProviderHistoryAndAggregates(t *testing.T) {
+	provider := newTestProvider(t)
+
+	// Dates parsed as UTC midnights select the same days as with Polygon
+	start, _ := time.Parse("2006-01-02", "2024-11-25")
+	end, _ := time.Parse("2006-01-02", "2024-11-29")
+	history, err := provider.GetTickerHistory(context.Background(), "AAPL", start, end, -1)
+	if err != nil {
+		t.Fatalf("GetTickerHistory error: %v", err)
+	}
+	if len(*history.Results) != 4 {
+		t.Fatalf("expected the 4 sessions of Thanksgiving week, got %d bars", len(*history.Results))
+	}
+
+	monthly, err := provider.GetTickerAggregates(context.Background(), polygon.PolygonAggregatesRequest{
+		Symbol:   "AAPL",
+		Timespan: polygon.TimespanMonth,
+		From:     time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC),
+		To:       time.Date(2024, 11, 30, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("GetTickerAggregates error: %v", err)
+	}
+	bars := *monthly.Results
+	if len(bars) != 2 || *bars[1].Timestamp != time.Date(2024, 11, 1, 0, 0, 0, 0, calendar.Location).UnixMilli() {
+		t.Fatalf("expected an October and a November bar, got %d bars", len(bars))
+	}
+	daily, _ := provider.GetTickerHistory(context.Background(), "AAPL", time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC), end, -1)
+	first, last := (*daily.Results)[0], (*daily.Results)[len(*daily.Results)-1]
+	if *bars[1].Open != *first.Open || *bars[1].Close != *last.Close {
+		t.Fatalf("expected November to open and close like its first and last sessions")
+	}
+	volume := 0.0
+	for _, bar := range *daily.Results {
+		volume += *bar.Volume
+		if *bar.High > *bars[1].High || *bar.Low < *bars[1].Low {
+			t.Fatalf("expected November's range to cover every session")
+		}
+	}
+	if *bars[1].Volume != volume || *bars[1].VWAP < *bars[1].Low || *bars[1].VWAP > *bars[1].High {
+		t.Fatalf("unexpected November volume %v and VWAP %v", *bars[1].Volume, *bars[1].VWAP)
+	}
+
+	weekly, err := provider.GetTickerAggregates(context.Background(), polygon.PolygonAggregatesRequest{Symbol: "AAPL", Timespan: polygon.TimespanWeek, From: start, To: end, Sort: polygon.SortDescending})
+	if err != nil || len(*weekly.Results) != 1 || *(*weekly.Results)[0].Timestamp != time.Date(2024, 11, 24, 0, 0, 0, 0, calendar.Location).UnixMilli() {
+		t.Fatalf("expected a single week starting on Sunday November 24th, got %+v (%v)", weekly, err)
+	}
+
+	if _, err := provider.GetTickerAggregates(context.Background(), polygon.PolygonAggregatesRequest{Symbol: "AAPL", Timespan: polygon.TimespanHour, From: start, To: end}); err == nil {
+		t.Fatalf("expected intraday bars to be unavailable")
+	}
+	if _, err := provider.GetTickerHistory(context.Background(), "../AAPL", start, end, -1); !errors.Is(err, polygon.ErrNoResults) {
+		t.Fatalf("expected a path in the symbol to find nothing, got %v", err)
+	}
+}
+
+func TestFileProviderNews(t *testing.T) {
+	provider := newTestProvider(t)
+	start := time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 11, 30, 0, 0, 0, 0, time.UTC)
+
+	news, stats, err := provider.GetTickerNews(context.Background(), "AAPL", start, end, 0, 0)
+	if err != nil {
+		t.Fatalf("GetTickerNews error: %v", err)
+	}
+	if len(*news.Results) != 3 || *(*news.Results)[0].ID != "file-0003" || stats.Truncated {
+		t.Fatalf("expected the 3 AAPL articles, most recent first, got %d (%+v)", len(*news.Results), stats)
+	}
+
+	news, stats, err = provider.GetTickerNews(context.Background(), "AAPL", start, end, 1, 2)
+	if err != nil || len(*news.Results) != 2 || !stats.Truncated || stats.Pages != 2 {
+		t.Fatalf("expected 2 pages of 1 article and a truncated result, got %+v (%v)", stats, err)
+	}
+
+	if _, _, err := provider.GetTickerNews(context.Background(), "MSFT", start, time.Date(2024, 11, 21, 0, 0, 0, 0, time.UTC), 0, 0); err != nil {
+		t.Fatalf("expected the MSFT article of November 20th, got %v", err)
+	}
+	if _, _, err := provider.GetTickerNews(context.Background(), "TSLA", start, end, 0, 0); !errors.Is(err, polygon.ErrNoResults) {
+		t.Fatalf("expected ErrNoResults, got %v", err)
+	}
+}
+
+func TestFileProviderInvalidFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "aggs"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "aggs", "AAPL.csv"), []byte("date,open,high,low\n2024-11-29,1,2,0.5\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "news.jsonl"), []byte("{\"id\": \"1\"}\nnot json\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	provider := NewFileProvider(dir)
+
+	if _, err := provider.GetTickerHistory(context.Background(), "AAPL", time.Time{}, time.Now(), -1); err == nil || errors.Is(err, polygon.ErrNoResults) {
+		t.Fatalf("expected an error about the missing close column, got %v", err)
+	}
+	if _, _, err := provider.GetTickerNews(context.Background(), "AAPL", time.Time{}, time.Now(), 0, 0); err == nil || errors.Is(err, polygon.ErrNoResults) {
+		t.Fatalf("expected an error about the invalid line, got %v", err)
+	}
+}
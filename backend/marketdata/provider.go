@@ -0,0 +1,73 @@
+// Package marketdata abstracts where the backend reads prices and news from.
+//
+// The server and the scrapers read through a MarketDataProvider: PolygonProvider queries the Polygon API, and
+// FileProvider reads a directory of CSV bars and JSONL news, so the backend can run as a demo or in tests without
+// API keys. Every provider answers with the polygon package's response types, so the code converting them to MongoDB
+// documents or API responses doesn't depend on where they came from.
+package marketdata
+
+import (
+	"context"
+	"financial-helper/polygon"
+	"time"
+)
+
+// MarketDataProvider is a source of tickers, prices and news.
+// Providers return polygon.ErrNoResults when they have nothing for a request, like the Polygon API does.
+type MarketDataProvider interface {
+	// Name identifies the provider in logs and errors
+	Name() string
+	// GetTickerDetails returns the company details of a ticker
+	GetTickerDetails(ctx context.Context, symbol string) (*polygon.PolygonGetTickerDetailsResponse, error)
+	// SearchTickers returns the tickers whose symbol or company name matches a search term
+	SearchTickers(ctx context.Context, request polygon.PolygonTickerSearchRequest) (*polygon.PolygonGetTickerResponse, error)
+	// GetPreviousClose returns the bar of the last session before today
+	GetPreviousClose(ctx context.Context, symbol string) (*polygon.PolygonGetTickerAggregateResponse, error)
+	// GetTickerHistory returns the daily bars of a ticker between two dates, limit <= 0 for every bar
+	GetTickerHistory(ctx context.Context, symbol string, startDate time.Time, endDate time.Time, limit int) (*polygon.PolygonGetTickerHistoryResponse, error)
+	// GetTickerAggregates returns the bars of any size selected by request
+	GetTickerAggregates(ctx context.Context, request polygon.PolygonAggregatesRequest) (*polygon.PolygonGetTickerHistoryResponse, error)
+	// GetTickerNews returns the articles about a ticker published in a time range, most recent first, reading up to
+	// maxPages pages of limit articles (maxPages <= 0 reads every page)
+	GetTickerNews(ctx context.Context, symbol string, startDate time.Time, endDate time.Time, limit int, maxPages int) (*polygon.PolygonGetTickerNews, polygon.PolygonPageStats, error)
+}
+
+var _ MarketDataProvider = (*PolygonProvider)(nil)
+
+// PolygonProvider reads market data from the Polygon API
+type PolygonProvider struct {
+	polygonConnection *polygon.PolygonConnection
+}
+
+// NewPolygonProvider returns a provider making its requests through polygonConnection
+func NewPolygonProvider(polygonConnection *polygon.PolygonConnection) *PolygonProvider {
+	return &PolygonProvider{polygonConnection: polygonConnection}
+}
+
+func (provider *PolygonProvider) Name() string {
+	return "polygon"
+}
+
+func (provider *PolygonProvider) GetTickerDetails(ctx context.Context, symbol string) (*polygon.PolygonGetTickerDetailsResponse, error) {
+	return provider.polygonConnection.PolygonGetTickerDetailsWithContext(ctx, symbol)
+}
+
+func (provider *PolygonProvider) SearchTickers(ctx context.Context, request polygon.PolygonTickerSearchRequest) (*polygon.PolygonGetTickerResponse, error) {
+	return provider.polygonConnection.PolygonSearchTickersWithContext(ctx, request)
+}
+
+func (provider *PolygonProvider) GetPreviousClose(ctx context.Context, symbol string) (*polygon.PolygonGetTickerAggregateResponse, error) {
+	return provider.polygonConnection.PolygonGetTickerDailyCloseWithContext(ctx, symbol)
+}
+
+func (provider *PolygonProvider) GetTickerHistory(ctx context.Context, symbol string, startDate time.Time, endDate time.Time, limit int) (*polygon.PolygonGetTickerHistoryResponse, error) {
+	return provider.polygonConnection.PolygonGetTickerHistoryWithContext(ctx, symbol, startDate, endDate, limit)
+}
+
+func (provider *PolygonProvider) GetTickerAggregates(ctx context.Context, request polygon.PolygonAggregatesRequest) (*polygon.PolygonGetTickerHistoryResponse, error) {
+	return provider.polygonConnection.PolygonGetTickerAggregatesWithContext(ctx, request)
+}
+
+func (provider *PolygonProvider) GetTickerNews(ctx context.Context, symbol string, startDate time.Time, endDate time.Time, limit int, maxPages int) (*polygon.PolygonGetTickerNews, polygon.PolygonPageStats, error) {
+	return provider.polygonConnection.PolygonGetTickerNewsPaginatedWithContext(ctx, symbol, startDate, endDate, limit, maxPages)
+}
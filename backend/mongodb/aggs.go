@@ -12,9 +12,15 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-type TickerDailyAggregate struct {
+// TickerAggregate is a single price bar stored in the "ticker_aggregates" collection.
+// Documents written before bars other than 1 day were supported have no multiplier or timespan,
+// and are treated as daily bars. Bars are adjusted for the splits known when they were fetched, unless they are
+// Unadjusted, which AdjustForSplits brings to the basis of any later date.
+type TickerAggregate struct {
 	ID           primitive.ObjectID `bson:"_id,omitempty"`
 	Ticker       string             `bson:"ticker,omitempty"`
+	Multiplier   int                `bson:"multiplier,omitempty"`
+	Timespan     string             `bson:"timespan,omitempty"`
 	Volume       float64            `bson:"volume,omitempty"`
 	VWAP         float64            `bson:"vwap,omitempty"`
 	Open         float64            `bson:"open,omitempty"`
@@ -24,11 +30,107 @@ type TickerDailyAggregate struct {
 	Timestamp    primitive.DateTime `bson:"timestamp,omitempty"`
 	Transactions int                `bson:"transactions,omitempty"`
 	OTC          bool               `bson:"otc,omitempty"`
+	Unadjusted   bool               `bson:"unadjusted,omitempty"` // Prices and volume as traded, before any split
 }
 
-// InsertAggregates inserts the provided daily aggregates into the "ticker_aggregates" collection of dbName.
+// TickerDailyAggregate is kept for code written when only daily bars were stored
+type TickerDailyAggregate = TickerAggregate
+
+// AggregateResolution is the size of a bar, e.g. 5 minute or 1 week bars
+type AggregateResolution struct {
+	Multiplier int
+	Timespan   string
+}
+
+// DailyResolution is the resolution of 1 day bars, the only one stored before resolutions were introduced
+var DailyResolution = AggregateResolution{Multiplier: 1, Timespan: "day"}
+
+// Resolution returns the size of the bar, DailyResolution for legacy documents without one
+func (aggregate TickerAggregate) Resolution() AggregateResolution {
+	return AggregateResolution{Multiplier: aggregate.Multiplier, Timespan: aggregate.Timespan}.normalize()
+}
+
+func (resolution AggregateResolution) normalize() AggregateResolution {
+	if resolution.Timespan == "" {
+		return DailyResolution
+	}
+	if resolution.Multiplier < 1 {
+		resolution.Multiplier = 1
+	}
+	return resolution
+}
+
+// String returns the resolution in Polygon's multiplier/timespan form, e.g. 5/minute
+func (resolution AggregateResolution) String() string {
+	return fmt.Sprintf("%d/%s", resolution.Multiplier, resolution.Timespan)
+}
+
+// Returns the filter matching documents of the given resolution, including legacy documents for daily bars, and
+// either only unadjusted or only adjusted bars
+func resolutionFilter(resolution AggregateResolution, unadjusted bool) bson.M {
+	resolution = resolution.normalize()
+	var filter bson.M
+	if resolution == DailyResolution {
+		filter = bson.M{"$or": bson.A{
+			bson.M{"multiplier": 1, "timespan": "day"},
+			bson.M{"timespan": bson.M{"$exists": false}},
+		}}
+	} else {
+		filter = bson.M{"multiplier": resolution.Multiplier, "timespan": resolution.Timespan}
+	}
+	if unadjusted {
+		filter["unadjusted"] = true
+	} else {
+		filter["unadjusted"] = bson.M{"$ne": true}
+	}
+	return filter
+}
+
+// Returns the key identifying the document a bar would be stored as. The adjusted and unadjusted bars of a
+// timestamp are different documents.
+func aggregateKey(ticker string, resolution AggregateResolution, unadjusted bool, timestamp primitive.DateTime) string {
+	key := fmt.Sprintf("%s_%s_%d", ticker, resolution.normalize(), int64(timestamp))
+	if unadjusted {
+		key += "_unadjusted"
+	}
+	return key
+}
+
+// AdjustForSplits returns an unadjusted bar adjusted for the splits executed after it until `until`: prices are
+// divided by their ratio and volume multiplied by it. Bars that are already adjusted are returned as they are.
+//
+// Input:
+//   - aggregate: the bar
+//   - splits: the splits of its ticker, at least those executed after the bar
+//   - until: the date the prices are expressed at, usually now
+//
+// Output:
+//   - TickerAggregate: the adjusted bar
+func AdjustForSplits(aggregate TickerAggregate, splits []Split, until time.Time) TickerAggregate {
+	if !aggregate.Unadjusted {
+		return aggregate
+	}
+	ratio := SplitRatioBetween(splits, aggregate.Timestamp.Time(), until)
+	aggregate.Open /= ratio
+	aggregate.High /= ratio
+	aggregate.Low /= ratio
+	aggregate.Close /= ratio
+	aggregate.VWAP /= ratio
+	aggregate.Volume *= ratio
+	aggregate.Unadjusted = false
+	return aggregate
+}
+
+// InsertAggregates is InsertAggregatesWithContext with a background context, so it times out after DefaultTimeout.
+func InsertAggregates(client *mongo.Client, dbName string, aggregates []TickerAggregate) (int, error) {
+	return InsertAggregatesWithContext(context.Background(), client, dbName, aggregates)
+}
+
+// InsertAggregatesWithContext inserts the provided aggregates into the "ticker_aggregates" collection of dbName.
+// Aggregates without a resolution are stored as daily bars. A bar is skipped if one with the same ticker,
+// resolution and timestamp is already stored.
 // It returns the number of successfully inserted documents and an error (if any).
-func InsertAggregates(client *mongo.Client, dbName string, aggregates []TickerDailyAggregate) (int, error) {
+func InsertAggregatesWithContext(ctx context.Context, client *mongo.Client, dbName string, aggregates []TickerAggregate) (int, error) {
 	if client == nil {
 		return 0, mongo.ErrClientDisconnected
 	}
@@ -36,14 +138,15 @@ func InsertAggregates(client *mongo.Client, dbName string, aggregates []TickerDa
 		return 0, nil
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	ctx, cancel := withDefaultTimeout(ctx)
 	defer cancel()
 
 	coll := client.Database(dbName).Collection("ticker_aggregates")
 
-	// Build a list of unique (ticker,timestamp) pairs from input and $or clauses for a single DB query.
+	// Build a list of unique (ticker,resolution,timestamp) keys from input and $or clauses for a single DB query.
+	// The query only matches on (ticker,timestamp), the resolution is compared once documents are loaded.
 	ors := make([]bson.M, 0, len(aggregates))
-	inputMap := make(map[string]TickerDailyAggregate, len(aggregates))
+	inputMap := make(map[string]TickerAggregate, len(aggregates))
 	for _, a := range aggregates {
 		if a.Ticker == "" {
 			continue
@@ -52,7 +155,9 @@ func InsertAggregates(client *mongo.Client, dbName string, aggregates []TickerDa
 			// skip invalid/missing timestamps for safety
 			continue
 		}
-		key := fmt.Sprintf("%s_%d", a.Ticker, int64(a.Timestamp))
+		resolution := a.Resolution()
+		a.Multiplier, a.Timespan = resolution.Multiplier, resolution.Timespan
+		key := aggregateKey(a.Ticker, resolution, a.Unadjusted, a.Timestamp)
 		if _, ok := inputMap[key]; ok {
 			continue // skip duplicates in the input slice
 		}
@@ -65,23 +170,19 @@ func InsertAggregates(client *mongo.Client, dbName string, aggregates []TickerDa
 	}
 
 	// Query the DB once to determine which pairs already exist.
-	cursor, err := coll.Find(ctx, bson.M{"$or": ors}, options.Find().SetProjection(bson.M{"ticker": 1, "timestamp": 1}))
+	cursor, err := coll.Find(ctx, bson.M{"$or": ors}, options.Find().SetProjection(bson.M{"ticker": 1, "timestamp": 1, "multiplier": 1, "timespan": 1, "unadjusted": 1}))
 	if err != nil {
 		return 0, err
 	}
 	defer cursor.Close(ctx)
 
 	existing := make(map[string]struct{}, len(ors))
-	var tmp struct {
-		Ticker    string             `bson:"ticker"`
-		Timestamp primitive.DateTime `bson:"timestamp"`
-	}
 	for cursor.Next(ctx) {
+		var tmp TickerAggregate
 		if err := cursor.Decode(&tmp); err != nil {
 			continue
 		}
-		k := fmt.Sprintf("%s_%d", tmp.Ticker, int64(tmp.Timestamp))
-		existing[k] = struct{}{}
+		existing[aggregateKey(tmp.Ticker, tmp.Resolution(), tmp.Unadjusted, tmp.Timestamp)] = struct{}{}
 	}
 	if err := cursor.Err(); err != nil {
 		return 0, err
@@ -113,12 +214,17 @@ func InsertAggregates(client *mongo.Client, dbName string, aggregates []TickerDa
 	return inserted, nil
 }
 
-// GetAggregatesByTicker returns aggregates for `ticker` sorted by timestamp ascending.
+// GetAggregatesByTicker is GetAggregatesByTickerWithContext with a background context, so it times out after DefaultTimeout.
+func GetAggregatesByTicker(client *mongo.Client, dbName, ticker string, limit, page, pageSize int) ([]TickerDailyAggregate, error) {
+	return GetAggregatesByTickerWithContext(context.Background(), client, dbName, ticker, limit, page, pageSize)
+}
+
+// GetAggregatesByTickerWithContext returns the adjusted daily aggregates for `ticker` sorted by timestamp ascending.
 // Pagination/windowing:
 // - If pageSize > 0: use pagination with 1-based page; skip = (page-1)*pageSize, limit = pageSize.
 // - Else if limit > 0: return up to `limit` documents (legacy behavior).
 // - Else: return all matching documents.
-func GetAggregatesByTicker(client *mongo.Client, dbName, ticker string, limit, page, pageSize int) ([]TickerDailyAggregate, error) {
+func GetAggregatesByTickerWithContext(ctx context.Context, client *mongo.Client, dbName, ticker string, limit, page, pageSize int) ([]TickerDailyAggregate, error) {
 	if client == nil {
 		return nil, mongo.ErrClientDisconnected
 	}
@@ -126,12 +232,13 @@ func GetAggregatesByTicker(client *mongo.Client, dbName, ticker string, limit, p
 		return nil, nil
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	ctx, cancel := withDefaultTimeout(ctx)
 	defer cancel()
 
 	coll := client.Database(dbName).Collection("ticker_aggregates")
 
-	filter := bson.M{"ticker": ticker}
+	filter := resolutionFilter(DailyResolution, false)
+	filter["ticker"] = ticker
 	findOpts := options.Find().SetSort(bson.D{{Key: "timestamp", Value: 1}})
 
 	// Apply pagination or limit
@@ -165,10 +272,43 @@ func GetAggregatesByTicker(client *mongo.Client, dbName, ticker string, limit, p
 	return out, nil
 }
 
-// GetAggregatesByTickerOverRange returns aggregates for `ticker` where timestamp is between
-// `start` and `end` (inclusive). If both start and end are zero, no timestamp filter is applied.
-// Pagination/windowing behavior mirrors GetAggregatesByTicker.
+// GetAggregatesByTickerOverRange is GetAggregatesByTickerOverRangeWithContext with a background context, so it times out after DefaultTimeout.
 func GetAggregatesByTickerOverRange(client *mongo.Client, dbName, ticker string, start, end time.Time, limit, page, pageSize int) ([]TickerDailyAggregate, error) {
+	return GetAggregatesByTickerOverRangeWithContext(context.Background(), client, dbName, ticker, start, end, limit, page, pageSize)
+}
+
+// GetAggregatesByTickerOverRangeWithContext returns the adjusted daily aggregates for `ticker` where timestamp is
+// between `start` and `end` (inclusive). See GetAggregatesByResolutionOverRangeWithContext for other bar sizes.
+func GetAggregatesByTickerOverRangeWithContext(ctx context.Context, client *mongo.Client, dbName, ticker string, start, end time.Time, limit, page, pageSize int) ([]TickerDailyAggregate, error) {
+	return GetAggregatesByResolutionOverRangeWithContext(ctx, client, dbName, ticker, DailyResolution, start, end, limit, page, pageSize)
+}
+
+// GetUnadjustedAggregatesByTickerOverRange is GetUnadjustedAggregatesByTickerOverRangeWithContext with a background context, so it times out after DefaultTimeout.
+func GetUnadjustedAggregatesByTickerOverRange(client *mongo.Client, dbName, ticker string, start, end time.Time) ([]TickerAggregate, error) {
+	return GetUnadjustedAggregatesByTickerOverRangeWithContext(context.Background(), client, dbName, ticker, start, end)
+}
+
+// GetUnadjustedAggregatesByTickerOverRangeWithContext returns the unadjusted daily aggregates for `ticker` where
+// timestamp is between `start` and `end` (inclusive), sorted by timestamp ascending. See AdjustForSplits.
+func GetUnadjustedAggregatesByTickerOverRangeWithContext(ctx context.Context, client *mongo.Client, dbName, ticker string, start, end time.Time) ([]TickerAggregate, error) {
+	return getAggregatesOverRange(ctx, client, dbName, ticker, DailyResolution, true, start, end, 0, 0, 0)
+}
+
+// GetAggregatesByResolutionOverRange is GetAggregatesByResolutionOverRangeWithContext with a background context, so it times out after DefaultTimeout.
+func GetAggregatesByResolutionOverRange(client *mongo.Client, dbName, ticker string, resolution AggregateResolution, start, end time.Time, limit, page, pageSize int) ([]TickerAggregate, error) {
+	return GetAggregatesByResolutionOverRangeWithContext(context.Background(), client, dbName, ticker, resolution, start, end, limit, page, pageSize)
+}
+
+// GetAggregatesByResolutionOverRangeWithContext returns the adjusted aggregates of the given resolution (e.g. 1/hour) for `ticker`
+// where timestamp is between `start` and `end` (inclusive). If both start and end are zero, no timestamp filter is applied.
+// Pagination/windowing behavior mirrors GetAggregatesByTicker.
+func GetAggregatesByResolutionOverRangeWithContext(ctx context.Context, client *mongo.Client, dbName, ticker string, resolution AggregateResolution, start, end time.Time, limit, page, pageSize int) ([]TickerAggregate, error) {
+	return getAggregatesOverRange(ctx, client, dbName, ticker, resolution, false, start, end, limit, page, pageSize)
+}
+
+// Returns the adjusted or unadjusted aggregates of the given resolution for `ticker` where timestamp is between
+// `start` and `end`, as described by GetAggregatesByResolutionOverRangeWithContext
+func getAggregatesOverRange(ctx context.Context, client *mongo.Client, dbName, ticker string, resolution AggregateResolution, unadjusted bool, start, end time.Time, limit, page, pageSize int) ([]TickerAggregate, error) {
 	if client == nil {
 		return nil, mongo.ErrClientDisconnected
 	}
@@ -176,12 +316,13 @@ func GetAggregatesByTickerOverRange(client *mongo.Client, dbName, ticker string,
 		return nil, nil
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	ctx, cancel := withDefaultTimeout(ctx)
 	defer cancel()
 
 	coll := client.Database(dbName).Collection("ticker_aggregates")
 
-	filter := bson.M{"ticker": ticker}
+	filter := resolutionFilter(resolution, unadjusted)
+	filter["ticker"] = ticker
 
 	// Add timestamp range filter only if start or end provided
 	if !start.IsZero() || !end.IsZero() {
@@ -228,20 +369,25 @@ func GetAggregatesByTickerOverRange(client *mongo.Client, dbName, ticker string,
 	return out, nil
 }
 
-// GetAggregatesOverRange returns aggregates for all tickers where timestamp is between
+// GetAggregatesOverRange is GetAggregatesOverRangeWithContext with a background context, so it times out after DefaultTimeout.
+func GetAggregatesOverRange(client *mongo.Client, dbName string, start, end time.Time, limit, page, pageSize int) ([]TickerDailyAggregate, error) {
+	return GetAggregatesOverRangeWithContext(context.Background(), client, dbName, start, end, limit, page, pageSize)
+}
+
+// GetAggregatesOverRangeWithContext returns the adjusted daily aggregates for all tickers where timestamp is between
 // `start` and `end` (inclusive). If both start and end are zero, no timestamp filter is applied.
 // Pagination/windowing behavior mirrors GetAggregatesByTicker.
-func GetAggregatesOverRange(client *mongo.Client, dbName string, start, end time.Time, limit, page, pageSize int) ([]TickerDailyAggregate, error) {
+func GetAggregatesOverRangeWithContext(ctx context.Context, client *mongo.Client, dbName string, start, end time.Time, limit, page, pageSize int) ([]TickerDailyAggregate, error) {
 	if client == nil {
 		return nil, mongo.ErrClientDisconnected
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	ctx, cancel := withDefaultTimeout(ctx)
 	defer cancel()
 
 	coll := client.Database(dbName).Collection("ticker_aggregates")
 
-	filter := bson.M{}
+	filter := resolutionFilter(DailyResolution, false)
 
 	// Add timestamp range filter only if start or end provided
 	if !start.IsZero() || !end.IsZero() {
@@ -288,17 +434,26 @@ func GetAggregatesOverRange(client *mongo.Client, dbName string, start, end time
 	return out, nil
 }
 
-// Convert a PolygonGetTickerHistoryResponse into a slice of TickerDailyAggregate.
+// Convert a PolygonGetTickerHistoryResponse of daily bars into a slice of TickerDailyAggregate.
 func PolygonHistoryToAggs(news polygon.PolygonGetTickerHistoryResponse) ([]TickerDailyAggregate, error) {
+	return PolygonAggregatesToTickerAggregates(news, DailyResolution)
+}
+
+// PolygonAggregatesToTickerAggregates converts a response of PolygonGetTickerAggregates into aggregates of the
+// resolution that was requested, since Polygon's response does not include it.
+func PolygonAggregatesToTickerAggregates(news polygon.PolygonGetTickerHistoryResponse, resolution AggregateResolution) ([]TickerAggregate, error) {
 	if news.Results == nil || len(*news.Results) == 0 {
 		return nil, nil
 	}
+	resolution = resolution.normalize()
 	results := *news.Results
-	out := make([]TickerDailyAggregate, 0, len(results))
+	out := make([]TickerAggregate, 0, len(results))
 
 	for _, r := range results {
-		var a TickerDailyAggregate
+		var a TickerAggregate
 		a.ID = primitive.NewObjectID()
+		a.Multiplier = resolution.Multiplier
+		a.Timespan = resolution.Timespan
 		if news.Ticker != nil {
 			a.Ticker = *news.Ticker
 		}
@@ -334,3 +489,55 @@ func PolygonHistoryToAggs(news polygon.PolygonGetTickerHistoryResponse) ([]Ticke
 
 	return out, nil
 }
+
+// PolygonGroupedDailyToAggs converts a response of PolygonGetGroupedDaily into daily aggregates, one per ticker.
+// Bars without a ticker can't be stored and are dropped.
+func PolygonGroupedDailyToAggs(grouped polygon.PolygonGetGroupedDailyResponse) ([]TickerAggregate, error) {
+	if grouped.Results == nil || len(*grouped.Results) == 0 {
+		return nil, nil
+	}
+	results := *grouped.Results
+	out := make([]TickerAggregate, 0, len(results))
+
+	for _, r := range results {
+		if r.Ticker == nil || *r.Ticker == "" {
+			continue
+		}
+		a := TickerAggregate{
+			ID:         primitive.NewObjectID(),
+			Ticker:     *r.Ticker,
+			Multiplier: DailyResolution.Multiplier,
+			Timespan:   DailyResolution.Timespan,
+		}
+		if r.Volume != nil {
+			a.Volume = *r.Volume
+		}
+		if r.VWAP != nil {
+			a.VWAP = *r.VWAP
+		}
+		if r.Open != nil {
+			a.Open = *r.Open
+		}
+		if r.Close != nil {
+			a.Close = *r.Close
+		}
+		if r.High != nil {
+			a.High = *r.High
+		}
+		if r.Low != nil {
+			a.Low = *r.Low
+		}
+		if r.Timestamp != nil {
+			a.Timestamp = primitive.NewDateTimeFromTime(time.UnixMilli(*r.Timestamp))
+		}
+		if r.Transactions != nil {
+			a.Transactions = *r.Transactions
+		}
+		if r.OTC != nil {
+			a.OTC = *r.OTC
+		}
+		out = append(out, a)
+	}
+
+	return out, nil
+}
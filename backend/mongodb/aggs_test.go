@@ -2,6 +2,8 @@ package mongodb
 
 import (
 	"context"
+	"financial-helper/polygon"
+	"financial-helper/polygon/polygontest"
 	"fmt"
 	"math/rand"
 	"testing"
@@ -395,3 +397,231 @@ func TestGetAggregatesOverRange_NoPaginationAndPagination(t *testing.T) {
 		t.Logf("cleanup error: %v", err)
 	}
 }
+
+// TestPolygonHistoryToAggs_Cassette converts a recorded Polygon aggregates response and checks every bar.
+func TestPolygonHistoryToAggs_Cassette(t *testing.T) {
+	history, err := newCassettePolygonConnection(t).PolygonGetTickerHistory(polygontest.CassetteSymbol, polygontest.CassetteStart, polygontest.CassetteEnd, polygontest.CassetteHistoryLimit)
+	if err != nil {
+		t.Fatalf("failed to replay history cassette: %v", err)
+	}
+
+	aggs, err := PolygonHistoryToAggs(*history)
+	if err != nil {
+		t.Fatalf("PolygonHistoryToAggs returned error: %v", err)
+	}
+	if len(aggs) != len(*history.Results) {
+		t.Fatalf("expected %d aggregates, got %d", len(*history.Results), len(aggs))
+	}
+
+	for i, agg := range aggs {
+		bar := (*history.Results)[i]
+		if agg.Ticker != polygontest.CassetteSymbol {
+			t.Fatalf("aggregate #%d has ticker %q", i, agg.Ticker)
+		}
+		if agg.Open != *bar.Open || agg.Close != *bar.Close || agg.High != *bar.High || agg.Low != *bar.Low || agg.Volume != *bar.Volume || agg.VWAP != *bar.VWAP {
+			t.Fatalf("aggregate #%d prices do not match the polygon bar", i)
+		}
+		if agg.Transactions != *bar.Transactions {
+			t.Fatalf("aggregate #%d has %d transactions, expected %d", i, agg.Transactions, *bar.Transactions)
+		}
+		if agg.Timestamp.Time().UnixMilli() != *bar.Timestamp {
+			t.Fatalf("aggregate #%d has timestamp %d, expected %d", i, agg.Timestamp.Time().UnixMilli(), *bar.Timestamp)
+		}
+		if agg.Timestamp.Time().Before(polygontest.CassetteStart) || agg.Timestamp.Time().After(polygontest.CassetteEnd) {
+			t.Fatalf("aggregate #%d is outside of the recorded range", i)
+		}
+	}
+}
+
+func TestPolygonAggregatesToTickerAggregates_Resolution(t *testing.T) {
+	history, err := newFakePolygonConnection(t).PolygonGetTickerHistory(polygontest.CassetteSymbol, polygontest.CassetteStart, polygontest.CassetteEnd, polygontest.CassetteHistoryLimit)
+	if err != nil {
+		t.Fatalf("failed to get the fake history: %v", err)
+	}
+
+	hourly := AggregateResolution{Multiplier: 4, Timespan: "hour"}
+	aggs, err := PolygonAggregatesToTickerAggregates(*history, hourly)
+	if err != nil {
+		t.Fatalf("PolygonAggregatesToTickerAggregates returned error: %v", err)
+	}
+	for i, agg := range aggs {
+		if agg.Resolution() != hourly {
+			t.Fatalf("aggregate #%d has resolution %s, expected %s", i, agg.Resolution(), hourly)
+		}
+	}
+
+	daily, err := PolygonHistoryToAggs(*history)
+	if err != nil {
+		t.Fatalf("PolygonHistoryToAggs returned error: %v", err)
+	}
+	if len(daily) == 0 || daily[0].Multiplier != 1 || daily[0].Timespan != "day" {
+		t.Fatalf("expected daily aggregates to be stored as 1/day")
+	}
+	if legacy := (TickerAggregate{}); legacy.Resolution() != DailyResolution {
+		t.Fatalf("expected aggregates without a resolution to be daily, got %s", legacy.Resolution())
+	}
+}
+
+// TestGetAggregatesByResolutionOverRange stores the same timestamps at two resolutions and checks that
+// neither is deduplicated against the other, and that each getter only sees its own resolution.
+func TestGetAggregatesByResolutionOverRange(t *testing.T) {
+	if testMongoClient == nil {
+		t.Skip("test mongo client not initialized")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	ticker := fmt.Sprintf("TEST-RES-%d", time.Now().UnixNano())
+	hourly := AggregateResolution{Multiplier: 1, Timespan: "hour"}
+	base := time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	var aggs []TickerAggregate
+	for i := 0; i < 3; i++ {
+		ts := primitive.NewDateTimeFromTime(base.Add(time.Duration(i) * 24 * time.Hour))
+		aggs = append(aggs,
+			TickerAggregate{Ticker: ticker, Timestamp: ts, Close: float64(i)},
+			TickerAggregate{Ticker: ticker, Multiplier: hourly.Multiplier, Timespan: hourly.Timespan, Timestamp: ts, Close: float64(i)},
+		)
+	}
+	defer func() {
+		if _, err := testMongoClient.Database(DB_NAME).Collection("ticker_aggregates").DeleteMany(ctx, bson.M{"ticker": ticker}); err != nil {
+			t.Logf("cleanup error: %v", err)
+		}
+	}()
+
+	inserted, err := InsertAggregates(testMongoClient, DB_NAME, aggs)
+	if err != nil {
+		t.Fatalf("InsertAggregates returned error: %v", err)
+	}
+	if inserted != len(aggs) {
+		t.Fatalf("expected %d inserted documents, got %d", len(aggs), inserted)
+	}
+	if inserted, err = InsertAggregates(testMongoClient, DB_NAME, aggs); err != nil || inserted != 0 {
+		t.Fatalf("expected duplicates to be skipped, inserted %d (err %v)", inserted, err)
+	}
+
+	end := base.Add(7 * 24 * time.Hour)
+	daily, err := GetAggregatesByTickerOverRange(testMongoClient, DB_NAME, ticker, base, end, 0, 0, 0)
+	if err != nil {
+		t.Fatalf("GetAggregatesByTickerOverRange returned error: %v", err)
+	}
+	hours, err := GetAggregatesByResolutionOverRange(testMongoClient, DB_NAME, ticker, hourly, base, end, 0, 0, 0)
+	if err != nil {
+		t.Fatalf("GetAggregatesByResolutionOverRange returned error: %v", err)
+	}
+	if len(daily) != 3 || len(hours) != 3 {
+		t.Fatalf("expected 3 daily and 3 hourly aggregates, got %d and %d", len(daily), len(hours))
+	}
+	for _, agg := range hours {
+		if agg.Resolution() != hourly {
+			t.Fatalf("hourly query returned a %s aggregate", agg.Resolution())
+		}
+	}
+}
+
+func TestPolygonGroupedDailyToAggs(t *testing.T) {
+	date := time.Date(2024, 10, 15, 0, 0, 0, 0, time.UTC)
+	grouped, err := newFakePolygonConnection(t).PolygonGetGroupedDaily(date, false)
+	if err != nil {
+		t.Fatalf("PolygonGetGroupedDaily error: %v", err)
+	}
+	// A bar Polygon couldn't attribute to a ticker is dropped
+	*grouped.Results = append(*grouped.Results, polygon.PolygonGroupedDailyBar{})
+
+	aggs, err := PolygonGroupedDailyToAggs(*grouped)
+	if err != nil {
+		t.Fatalf("PolygonGroupedDailyToAggs returned error: %v", err)
+	}
+	if len(aggs) != len(*grouped.Results)-1 {
+		t.Fatalf("expected %d aggregates, got %d", len(*grouped.Results)-1, len(aggs))
+	}
+	tickers := map[string]bool{}
+	for i, agg := range aggs {
+		tickers[agg.Ticker] = true
+		if agg.Resolution() != DailyResolution || agg.Close == 0 {
+			t.Fatalf("aggregate #%d is not a complete daily bar: %+v", i, agg)
+		}
+		if day := agg.Timestamp.Time().UTC().Format("2006-01-02"); day != "2024-10-15" {
+			t.Fatalf("aggregate #%d is dated %s", i, day)
+		}
+	}
+	if len(tickers) != len(aggs) {
+		t.Fatalf("expected one aggregate per ticker, got %v", tickers)
+	}
+}
+
+func TestAdjustForSplits(t *testing.T) {
+	splits := []Split{{ExecutionDate: primitive.NewDateTimeFromTime(time.Date(2024, 6, 10, 0, 0, 0, 0, time.UTC)), SplitFrom: 1, SplitTo: 10}}
+	// Daily bars start at midnight in New York, after the midnight UTC of split execution dates
+	before := TickerAggregate{Timestamp: primitive.NewDateTimeFromTime(time.Date(2024, 6, 7, 4, 0, 0, 0, time.UTC)), Open: 1200, High: 1250, Low: 1150, Close: 1210, VWAP: 1205, Volume: 100, Unadjusted: true}
+	after := before
+	after.Timestamp = primitive.NewDateTimeFromTime(time.Date(2024, 6, 10, 4, 0, 0, 0, time.UTC))
+
+	adjusted := AdjustForSplits(before, splits, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+	if adjusted.Unadjusted || adjusted.Open != 120 || adjusted.Close != 121 || adjusted.VWAP != 120.5 || adjusted.Volume != 1000 {
+		t.Fatalf("unexpected adjusted bar %+v", adjusted)
+	}
+	// Before the split, the bar is already on the basis of its time
+	if early := AdjustForSplits(before, splits, time.Date(2024, 6, 8, 0, 0, 0, 0, time.UTC)); early.Close != 1210 || early.Volume != 100 {
+		t.Fatalf("expected no adjustment before the split, got %+v", early)
+	}
+	if unchanged := AdjustForSplits(after, splits, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)); unchanged.Close != 1210 {
+		t.Fatalf("expected the bar of the execution date not to be adjusted, got %+v", unchanged)
+	}
+	adjustedBar := before
+	adjustedBar.Unadjusted = false
+	if kept := AdjustForSplits(adjustedBar, splits, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)); kept.Close != 1210 {
+		t.Fatalf("expected an adjusted bar to be kept, got %+v", kept)
+	}
+}
+
+// TestGetUnadjustedAggregatesByTickerOverRange stores the adjusted and unadjusted bars of the same sessions and checks
+// that neither is deduplicated against the other, and that each getter only sees its own.
+func TestGetUnadjustedAggregatesByTickerOverRange(t *testing.T) {
+	if testMongoClient == nil {
+		t.Skip("test mongo client not initialized")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	ticker := fmt.Sprintf("TEST-UNADJ-%d", time.Now().UnixNano())
+	base := time.Date(2024, 10, 1, 4, 0, 0, 0, time.UTC)
+
+	var aggs []TickerAggregate
+	for i := 0; i < 3; i++ {
+		ts := primitive.NewDateTimeFromTime(base.AddDate(0, 0, i))
+		aggs = append(aggs,
+			TickerAggregate{Ticker: ticker, Timestamp: ts, Close: 10},
+			TickerAggregate{Ticker: ticker, Timestamp: ts, Close: 40, Unadjusted: true},
+		)
+	}
+	defer func() {
+		if _, err := testMongoClient.Database(DB_NAME).Collection("ticker_aggregates").DeleteMany(ctx, bson.M{"ticker": ticker}); err != nil {
+			t.Logf("cleanup error: %v", err)
+		}
+	}()
+
+	if inserted, err := InsertAggregates(testMongoClient, DB_NAME, aggs); err != nil || inserted != len(aggs) {
+		t.Fatalf("expected %d inserted documents, got %d (err %v)", len(aggs), inserted, err)
+	}
+
+	end := base.AddDate(0, 0, 7)
+	adjusted, err := GetAggregatesByTickerOverRange(testMongoClient, DB_NAME, ticker, base, end, 0, 0, 0)
+	if err != nil {
+		t.Fatalf("GetAggregatesByTickerOverRange returned error: %v", err)
+	}
+	unadjusted, err := GetUnadjustedAggregatesByTickerOverRange(testMongoClient, DB_NAME, ticker, base, end)
+	if err != nil {
+		t.Fatalf("GetUnadjustedAggregatesByTickerOverRange returned error: %v", err)
+	}
+	if len(adjusted) != 3 || len(unadjusted) != 3 {
+		t.Fatalf("expected 3 adjusted and 3 unadjusted aggregates, got %d and %d", len(adjusted), len(unadjusted))
+	}
+	for i := range unadjusted {
+		if adjusted[i].Unadjusted || adjusted[i].Close != 10 || !unadjusted[i].Unadjusted || unadjusted[i].Close != 40 {
+			t.Fatalf("unexpected aggregates %+v and %+v", adjusted[i], unadjusted[i])
+		}
+	}
+}
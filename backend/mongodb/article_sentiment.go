@@ -0,0 +1,119 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// The length of the buckets of a sentiment series
+type SentimentBucketSize string
+
+const (
+	SentimentBucketDay  SentimentBucketSize = "day"
+	SentimentBucketWeek SentimentBucketSize = "week" // Weeks start on Monday
+)
+
+// The sentiment towards a ticker of the articles published in a bucket. Rated articles have an insight about the
+// ticker: positive ones score 1, negative ones -1 and any other 0.
+type SentimentBucket struct {
+	Start     time.Time `bson:"_id"` // Midnight of the first day of the bucket
+	Articles  int       `bson:"articles"`
+	Positive  int       `bson:"positive"`
+	Neutral   int       `bson:"neutral"`
+	Negative  int       `bson:"negative"`
+	MeanScore *float64  `bson:"mean_score"` // Nil if no article of the bucket is rated
+}
+
+// GetSentimentSeriesByTicker is GetSentimentSeriesByTickerWithContext with a background context, so it times out after DefaultTimeout.
+func GetSentimentSeriesByTicker(client *mongo.Client, dbName, ticker string, start, end time.Time, size SentimentBucketSize, location *time.Location) ([]SentimentBucket, error) {
+	return GetSentimentSeriesByTickerWithContext(context.Background(), client, dbName, ticker, start, end, size, location)
+}
+
+// GetSentimentSeriesByTickerWithContext groups the articles for `ticker` where published_at is in [start, end) into
+// day or week buckets of `location`, and rates the sentiment of each bucket from the articles' insights.
+// Buckets are sorted oldest first, and buckets without articles are left out.
+// location must be loaded by its IANA name (e.g. America/New_York) or be UTC, time.Local is not understood by MongoDB.
+func GetSentimentSeriesByTickerWithContext(ctx context.Context, client *mongo.Client, dbName, ticker string, start, end time.Time, size SentimentBucketSize, location *time.Location) ([]SentimentBucket, error) {
+	if size != SentimentBucketDay && size != SentimentBucketWeek {
+		return nil, errors.New("sentiment buckets must be days or weeks")
+	}
+	if client == nil {
+		return nil, mongo.ErrClientDisconnected
+	}
+	if ticker == "" {
+		return nil, nil
+	}
+	if location == nil {
+		location = time.UTC
+	}
+
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+
+	coll := client.Database(dbName).Collection("ticker_news")
+
+	// The insight about ticker, if any, decides the score of an article
+	insight := bson.M{"$first": bson.M{"$filter": bson.M{
+		"input": bson.M{"$ifNull": bson.A{"$insights", bson.A{}}},
+		"cond":  bson.M{"$eq": bson.A{"$$this.ticker", ticker}},
+	}}}
+	score := bson.M{"$switch": bson.M{
+		"branches": bson.A{
+			bson.M{"case": bson.M{"$eq": bson.A{bson.M{"$ifNull": bson.A{"$$insight.sentiment", ""}}, ""}}, "then": nil},
+			bson.M{"case": bson.M{"$eq": bson.A{"$$insight.sentiment", "positive"}}, "then": 1},
+			bson.M{"case": bson.M{"$eq": bson.A{"$$insight.sentiment", "negative"}}, "then": -1},
+		},
+		"default": 0,
+	}}
+	countScore := func(value int) bson.M {
+		return bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$score", value}}, 1, 0}}}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"tickers": ticker,
+			"published_at": bson.M{
+				"$gte": primitive.NewDateTimeFromTime(start),
+				"$lt":  primitive.NewDateTimeFromTime(end),
+			},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"bucket": bson.M{"$dateTrunc": bson.M{
+				"date":        "$published_at",
+				"unit":        string(size),
+				"timezone":    location.String(),
+				"startOfWeek": "monday",
+			}},
+			// Missing insights score null, which $avg skips
+			"score": bson.M{"$let": bson.M{"vars": bson.M{"insight": insight}, "in": score}},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":        "$bucket",
+			"articles":   bson.M{"$sum": 1},
+			"positive":   countScore(1),
+			"neutral":    countScore(0),
+			"negative":   countScore(-1),
+			"mean_score": bson.M{"$avg": "$score"},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+	}
+	cursor, err := coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	buckets := []SentimentBucket{}
+	if err := cursor.All(ctx, &buckets); err != nil {
+		return nil, err
+	}
+	for i := range buckets {
+		buckets[i].Start = buckets[i].Start.In(location)
+	}
+	return buckets, nil
+}
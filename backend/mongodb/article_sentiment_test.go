@@ -0,0 +1,94 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestGetSentimentSeriesByTicker(t *testing.T) {
+	if testMongoClient == nil {
+		t.Skip("test mongo client not initialized")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("LoadLocation error: %v", err)
+	}
+	prefix := fmt.Sprintf("test-sentiment-%d-", time.Now().UnixNano())
+	ticker := "SENT"
+
+	// Tuesday and Wednesday of the same week, the late Tuesday article is already Wednesday in UTC
+	tuesday := time.Date(2024, 10, 29, 10, 0, 0, 0, newYork)
+	lateTuesday := time.Date(2024, 10, 29, 22, 0, 0, 0, newYork)
+	wednesday := time.Date(2024, 10, 30, 10, 0, 0, 0, newYork)
+	article := func(id string, published time.Time, insights ...ArticleInsight) Article {
+		return Article{
+			ID:          primitive.NewObjectID(),
+			PolygonID:   prefix + id,
+			Title:       "Sentiment " + id,
+			PublishedAt: primitive.NewDateTimeFromTime(published),
+			Tickers:     []string{ticker, "OTHER"},
+			Insights:    insights,
+		}
+	}
+	articles := []Article{
+		article("1", tuesday, ArticleInsight{Ticker: ticker, Sentiment: "positive"}),
+		article("2", lateTuesday, ArticleInsight{Ticker: "OTHER", Sentiment: "negative"}, ArticleInsight{Ticker: ticker, Sentiment: "neutral"}),
+		article("3", wednesday, ArticleInsight{Ticker: ticker, Sentiment: "negative"}),
+		// Not rated for ticker, only counted as an article
+		article("4", wednesday, ArticleInsight{Ticker: "OTHER", Sentiment: "positive"}),
+	}
+	if _, err := InsertArticles(testMongoClient, DB_NAME, articles); err != nil {
+		t.Fatalf("InsertArticles returned error: %v", err)
+	}
+	defer func() {
+		coll := testMongoClient.Database(DB_NAME).Collection("ticker_news")
+		if _, err := coll.DeleteMany(ctx, bson.M{"polygon_id": bson.M{"$regex": "^" + prefix}}); err != nil {
+			t.Logf("cleanup error: %v", err)
+		}
+	}()
+
+	start := time.Date(2024, 10, 28, 0, 0, 0, 0, newYork)
+	end := time.Date(2024, 11, 1, 0, 0, 0, 0, newYork)
+	days, err := GetSentimentSeriesByTicker(testMongoClient, DB_NAME, ticker, start, end, SentimentBucketDay, newYork)
+	if err != nil {
+		t.Fatalf("GetSentimentSeriesByTicker returned error: %v", err)
+	}
+	if len(days) != 2 {
+		t.Fatalf("expected a bucket for Tuesday and Wednesday, got %+v", days)
+	}
+	if !days[0].Start.Equal(time.Date(2024, 10, 29, 0, 0, 0, 0, newYork)) || days[0].Articles != 2 || days[0].Positive != 1 || days[0].Neutral != 1 {
+		t.Fatalf("unexpected Tuesday bucket %+v", days[0])
+	}
+	if days[0].MeanScore == nil || *days[0].MeanScore != 0.5 {
+		t.Fatalf("expected a mean score of 0.5 on Tuesday, got %v", days[0].MeanScore)
+	}
+	if days[1].Articles != 2 || days[1].Negative != 1 || days[1].Positive != 0 || days[1].MeanScore == nil || *days[1].MeanScore != -1 {
+		t.Fatalf("unexpected Wednesday bucket %+v", days[1])
+	}
+
+	weeks, err := GetSentimentSeriesByTicker(testMongoClient, DB_NAME, ticker, start, end, SentimentBucketWeek, newYork)
+	if err != nil {
+		t.Fatalf("GetSentimentSeriesByTicker returned error: %v", err)
+	}
+	if len(weeks) != 1 || !weeks[0].Start.Equal(start) || weeks[0].Articles != 4 || weeks[0].Positive+weeks[0].Neutral+weeks[0].Negative != 3 {
+		t.Fatalf("expected a single week starting on Monday, got %+v", weeks)
+	}
+}
+
+func TestGetSentimentSeriesByTicker_InvalidBucket(t *testing.T) {
+	// Checked before the client, so no database is needed
+	if _, err := GetSentimentSeriesByTicker(nil, DB_NAME, "SENT", time.Time{}, time.Now(), "month", time.UTC); err == nil || errors.Is(err, mongo.ErrClientDisconnected) {
+		t.Fatalf("expected an error for monthly buckets, got %v", err)
+	}
+}
@@ -2,7 +2,10 @@ package mongodb
 
 import (
 	"context"
+	"financial-helper/feeds"
+	"financial-helper/nyt"
 	"financial-helper/polygon"
+	"slices"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -11,6 +14,13 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// The sources an article can come from, stored in its source field
+const (
+	ArticleSourcePolygon = "polygon"
+	ArticleSourceNYT     = "nyt"
+	ArticleSourceFeed    = "feed" // Any RSS or Atom feed, the publisher tells them apart
+)
+
 type ArticlePublisher struct {
 	Name        string `bson:"name,omitempty"`
 	HomepageURL string `bson:"homepage_url,omitempty"`
@@ -27,6 +37,8 @@ type ArticleInsight struct {
 type Article struct {
 	ID          primitive.ObjectID `bson:"_id,omitempty"`
 	PolygonID   string             `bson:"polygon_id,omitempty"`
+	Source      string             `bson:"source,omitempty"`    // ArticleSourcePolygon, ArticleSourceNYT or ArticleSourceFeed
+	SourceID    string             `bson:"source_id,omitempty"` // The id of the article at its source
 	Publisher   ArticlePublisher   `bson:"publisher,omitempty"`
 	Title       string             `bson:"title,omitempty"`
 	Author      string             `bson:"author,omitempty"`
@@ -39,9 +51,14 @@ type Article struct {
 	Insights    []ArticleInsight   `bson:"insights,omitempty"`
 }
 
-// InsertArticles inserts the provided articles into the "ticker_news" collection of dbName.
-// It returns the number of successfully inserted documents and an error (if any).
+// InsertArticles is InsertArticlesWithContext with a background context, so it times out after DefaultTimeout.
 func InsertArticles(client *mongo.Client, dbName string, articles []Article) (int, error) {
+	return InsertArticlesWithContext(context.Background(), client, dbName, articles)
+}
+
+// InsertArticlesWithContext inserts the provided articles into the "ticker_news" collection of dbName.
+// It returns the number of successfully inserted documents and an error (if any).
+func InsertArticlesWithContext(ctx context.Context, client *mongo.Client, dbName string, articles []Article) (int, error) {
 	if client == nil {
 		return 0, mongo.ErrClientDisconnected
 	}
@@ -49,7 +66,7 @@ func InsertArticles(client *mongo.Client, dbName string, articles []Article) (in
 		return 0, nil
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	ctx, cancel := withDefaultTimeout(ctx)
 	defer cancel()
 
 	coll := client.Database(dbName).Collection("ticker_news")
@@ -57,13 +74,24 @@ func InsertArticles(client *mongo.Client, dbName string, articles []Article) (in
 	models := make([]mongo.WriteModel, 0, len(articles))
 	for _, a := range articles {
 		// If polygon_id is present, use an upsert with $setOnInsert so we only insert when no document
-		// with the same polygon_id exists. If polygon_id is empty, fall back to a plain insert.
-		if a.PolygonID == "" {
-			models = append(models, mongo.NewInsertOneModel().SetDocument(a))
-		} else {
+		// with the same polygon_id exists. Feed articles often repeat stories stored from other sources,
+		// so they are deduplicated on article_url, and articles from other sources on source and source_id.
+		// If none is set, fall back to a plain insert.
+		switch {
+		case a.PolygonID != "":
 			filter := bson.M{"polygon_id": a.PolygonID}
 			update := bson.M{"$setOnInsert": a}
 			models = append(models, mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(update).SetUpsert(true))
+		case a.Source == ArticleSourceFeed && a.ArticleURL != "":
+			filter := bson.M{"article_url": a.ArticleURL}
+			update := bson.M{"$setOnInsert": a}
+			models = append(models, mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(update).SetUpsert(true))
+		case a.Source != "" && a.SourceID != "":
+			filter := bson.M{"source": a.Source, "source_id": a.SourceID}
+			update := bson.M{"$setOnInsert": a}
+			models = append(models, mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(update).SetUpsert(true))
+		default:
+			models = append(models, mongo.NewInsertOneModel().SetDocument(a))
 		}
 	}
 
@@ -80,13 +108,18 @@ func InsertArticles(client *mongo.Client, dbName string, articles []Article) (in
 	return int(res.InsertedCount + res.UpsertedCount), nil
 }
 
-// GetArticlesByTicker returns articles containing `ticker` in the `tickers` array,
+// GetArticlesByTicker is GetArticlesByTickerWithContext with a background context, so it times out after DefaultTimeout.
+func GetArticlesByTicker(client *mongo.Client, dbName, ticker string, limit, page, pageSize int) ([]Article, error) {
+	return GetArticlesByTickerWithContext(context.Background(), client, dbName, ticker, limit, page, pageSize)
+}
+
+// GetArticlesByTickerWithContext returns articles containing `ticker` in the `tickers` array,
 // sorted by `published_at` descending.
 // Pagination/windowing:
 // - If pageSize > 0: use pagination with 1-based page; skip = (page-1)*pageSize, limit = pageSize.
 // - Else if limit > 0: return up to `limit` documents.
 // - Else: return all matching documents.
-func GetArticlesByTicker(client *mongo.Client, dbName, ticker string, limit, page, pageSize int) ([]Article, error) {
+func GetArticlesByTickerWithContext(ctx context.Context, client *mongo.Client, dbName, ticker string, limit, page, pageSize int) ([]Article, error) {
 	if client == nil {
 		return nil, mongo.ErrClientDisconnected
 	}
@@ -94,7 +127,7 @@ func GetArticlesByTicker(client *mongo.Client, dbName, ticker string, limit, pag
 		return nil, nil
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	ctx, cancel := withDefaultTimeout(ctx)
 	defer cancel()
 
 	coll := client.Database(dbName).Collection("ticker_news")
@@ -133,10 +166,15 @@ func GetArticlesByTicker(client *mongo.Client, dbName, ticker string, limit, pag
 	return out, nil
 }
 
-// GetArticlesByTickerOverRange returns articles for `ticker` where published_at is in [start, end),
+// GetArticlesByTickerOverRange is GetArticlesByTickerOverRangeWithContext with a background context, so it times out after DefaultTimeout.
+func GetArticlesByTickerOverRange(client *mongo.Client, dbName, ticker string, start, end time.Time, limit, page, pageSize int) ([]Article, error) {
+	return GetArticlesByTickerOverRangeWithContext(context.Background(), client, dbName, ticker, start, end, limit, page, pageSize)
+}
+
+// GetArticlesByTickerOverRangeWithContext returns articles for `ticker` where published_at is in [start, end),
 // sorted by `published_at` descending.
 // Pagination/windowing behavior mirrors GetArticlesByTicker.
-func GetArticlesByTickerOverRange(client *mongo.Client, dbName, ticker string, start, end time.Time, limit, page, pageSize int) ([]Article, error) {
+func GetArticlesByTickerOverRangeWithContext(ctx context.Context, client *mongo.Client, dbName, ticker string, start, end time.Time, limit, page, pageSize int) ([]Article, error) {
 	if client == nil {
 		return nil, mongo.ErrClientDisconnected
 	}
@@ -150,7 +188,7 @@ func GetArticlesByTickerOverRange(client *mongo.Client, dbName, ticker string, s
 		end = time.Now().UTC()
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	ctx, cancel := withDefaultTimeout(ctx)
 	defer cancel()
 
 	coll := client.Database(dbName).Collection("ticker_news")
@@ -195,10 +233,61 @@ func GetArticlesByTickerOverRange(client *mongo.Client, dbName, ticker string, s
 	return out, nil
 }
 
-// GetArticlesOverRange returns articles with published_at in [start, end),
+// GetArticleRatingsByTickerOverRange is GetArticleRatingsByTickerOverRangeWithContext with a background context, so it times out after DefaultTimeout.
+func GetArticleRatingsByTickerOverRange(client *mongo.Client, dbName, ticker string, start, end time.Time) ([]Article, error) {
+	return GetArticleRatingsByTickerOverRangeWithContext(context.Background(), client, dbName, ticker, start, end)
+}
+
+// GetArticleRatingsByTickerOverRangeWithContext returns every article for `ticker` where published_at is in
+// [start, end), sorted by `published_at` descending, with only what rating its sentiment takes: its publication
+// time, the name of its publisher and its insight about `ticker`, if any. Unlike GetArticlesByTickerOverRange it
+// is meant for ranges with too many articles to read whole.
+func GetArticleRatingsByTickerOverRangeWithContext(ctx context.Context, client *mongo.Client, dbName, ticker string, start, end time.Time) ([]Article, error) {
+	if client == nil {
+		return nil, mongo.ErrClientDisconnected
+	}
+	if ticker == "" {
+		return nil, nil
+	}
+
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+
+	filter := bson.M{
+		"tickers": ticker,
+		"published_at": bson.M{
+			"$gte": primitive.NewDateTimeFromTime(start),
+			"$lt":  primitive.NewDateTimeFromTime(end),
+		},
+	}
+	projection := bson.M{
+		"published_at":   1,
+		"publisher.name": 1,
+		"insights":       bson.M{"$elemMatch": bson.M{"ticker": ticker}},
+	}
+	findOpts := options.Find().SetSort(bson.D{{Key: "published_at", Value: -1}}).SetProjection(projection)
+	cursor, err := client.Database(dbName).Collection("ticker_news").Find(ctx, filter, findOpts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	out := []Article{}
+	if err := cursor.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetArticlesOverRange is GetArticlesOverRangeWithContext with a background context, so it times out after DefaultTimeout.
+func GetArticlesOverRange(client *mongo.Client, dbName string, start, end time.Time, limit, page, pageSize int) ([]Article, error) {
+	return GetArticlesOverRangeWithContext(context.Background(), client, dbName, start, end, limit, page, pageSize)
+}
+
+// GetArticlesOverRangeWithContext returns articles with published_at in [start, end),
 // sorted by `published_at` descending.
 // Pagination/windowing behavior mirrors GetArticlesByTicker.
-func GetArticlesOverRange(client *mongo.Client, dbName string, start, end time.Time, limit, page, pageSize int) ([]Article, error) {
+func GetArticlesOverRangeWithContext(ctx context.Context, client *mongo.Client, dbName string, start, end time.Time, limit, page, pageSize int) ([]Article, error) {
 	if client == nil {
 		return nil, mongo.ErrClientDisconnected
 	}
@@ -209,7 +298,7 @@ func GetArticlesOverRange(client *mongo.Client, dbName string, start, end time.T
 		end = time.Now().UTC()
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	ctx, cancel := withDefaultTimeout(ctx)
 	defer cancel()
 
 	coll := client.Database(dbName).Collection("ticker_news")
@@ -265,9 +354,11 @@ func PolygonNewsToArticles(news polygon.PolygonGetTickerNews) ([]Article, error)
 	for _, r := range results {
 		var a Article
 		a.ID = primitive.NewObjectID()
+		a.Source = ArticleSourcePolygon
 
 		if r.ID != nil {
 			a.PolygonID = *r.ID
+			a.SourceID = *r.ID
 		}
 
 		if r.Publisher != nil {
@@ -332,3 +423,79 @@ func PolygonNewsToArticles(news polygon.PolygonGetTickerNews) ([]Article, error)
 
 	return out, nil
 }
+
+// NYTArticlesToArticles converts articles of the NYT archive into mongodb Articles, tagged with the tickers
+// matcher finds in them. Articles that are about none of the matcher's companies are dropped.
+func NYTArticlesToArticles(articles []nyt.NYTArticle, matcher *nyt.NYTCompanyMatcher) []Article {
+	out := make([]Article, 0)
+	for _, r := range articles {
+		tickers := matcher.Match(r)
+		if len(tickers) == 0 {
+			continue
+		}
+
+		a := Article{
+			ID:          primitive.NewObjectID(),
+			Source:      ArticleSourceNYT,
+			SourceID:    r.ID,
+			Publisher:   ArticlePublisher{Name: "The New York Times", HomepageURL: "https://www.nytimes.com/"},
+			Title:       r.Headline.Main,
+			Author:      r.Author(),
+			PublishedAt: primitive.NewDateTimeFromTime(r.PubDate.Time),
+			ArticleURL:  r.WebURL,
+			Tickers:     tickers,
+			ImageURL:    r.ImageURL(),
+			Description: r.Abstract,
+		}
+		if a.SourceID == "" {
+			a.SourceID = r.URI
+		}
+		for _, keyword := range r.Keywords {
+			a.Keywords = append(a.Keywords, keyword.Value)
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+// FeedItemsToArticles converts the items of an RSS or Atom feed into mongodb Articles.
+// Items are tagged with the tickers dictionary finds in them plus tickers, e.g. the company of an investor
+// relations feed. Items without a link or without any ticker are dropped, and items without a date are
+// dated now.
+func FeedItemsToArticles(publisher ArticlePublisher, items []feeds.FeedItem, dictionary *feeds.TickerDictionary, tickers []string) []Article {
+	out := make([]Article, 0)
+	for _, r := range items {
+		if r.Link == "" {
+			continue
+		}
+		tagged := append(append([]string{}, tickers...), dictionary.Tag(r)...)
+		slices.Sort(tagged)
+		tagged = slices.Compact(tagged)
+		if len(tagged) == 0 {
+			continue
+		}
+
+		published := r.Published
+		if published.IsZero() {
+			published = time.Now().UTC()
+		}
+		a := Article{
+			ID:          primitive.NewObjectID(),
+			Source:      ArticleSourceFeed,
+			SourceID:    r.ID,
+			Publisher:   publisher,
+			Title:       r.Title,
+			Author:      r.Author,
+			PublishedAt: primitive.NewDateTimeFromTime(published),
+			ArticleURL:  r.Link,
+			Tickers:     tagged,
+			ImageURL:    r.ImageURL,
+			Description: r.Description,
+		}
+		if len(r.Categories) > 0 {
+			a.Keywords = append([]string{}, r.Categories...)
+		}
+		out = append(out, a)
+	}
+	return out
+}
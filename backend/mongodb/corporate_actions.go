@@ -0,0 +1,276 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"financial-helper/polygon"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Dividend is a cash dividend stored in the "ticker_dividends" collection
+type Dividend struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty"`
+	PolygonID       string             `bson:"polygon_id,omitempty"`
+	Ticker          string             `bson:"ticker,omitempty"`
+	CashAmount      float64            `bson:"cash_amount,omitempty"` // Per share held on the ex-dividend date
+	Currency        string             `bson:"currency,omitempty"`
+	DeclarationDate primitive.DateTime `bson:"declaration_date,omitempty"`
+	ExDividendDate  primitive.DateTime `bson:"ex_dividend_date,omitempty"`
+	RecordDate      primitive.DateTime `bson:"record_date,omitempty"`
+	PayDate         primitive.DateTime `bson:"pay_date,omitempty"`
+	Frequency       int                `bson:"frequency,omitempty"`
+	DividendType    string             `bson:"dividend_type,omitempty"`
+}
+
+// Split is a stock split stored in the "ticker_splits" collection
+type Split struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty"`
+	PolygonID     string             `bson:"polygon_id,omitempty"`
+	Ticker        string             `bson:"ticker,omitempty"`
+	ExecutionDate primitive.DateTime `bson:"execution_date,omitempty"`
+	SplitFrom     float64            `bson:"split_from,omitempty"`
+	SplitTo       float64            `bson:"split_to,omitempty"`
+}
+
+// Ratio returns the number of shares held after the split for every share held before it, e.g. 4 for a 4-for-1 split
+func (split Split) Ratio() float64 {
+	if split.SplitFrom == 0 || split.SplitTo == 0 {
+		return 1
+	}
+	return split.SplitTo / split.SplitFrom
+}
+
+// SplitRatioBetween returns the factor a share count held at `after` must be multiplied by to be expressed in
+// shares held at `until`, i.e. the product of the ratios of the splits executed in (after, until].
+func SplitRatioBetween(splits []Split, after, until time.Time) float64 {
+	ratio := 1.0
+	for _, split := range splits {
+		executed := split.ExecutionDate.Time()
+		if executed.After(after) && !executed.After(until) {
+			ratio *= split.Ratio()
+		}
+	}
+	return ratio
+}
+
+// InsertDividends is InsertDividendsWithContext with a background context, so it times out after DefaultTimeout.
+func InsertDividends(client *mongo.Client, dbName string, dividends []Dividend) (int, error) {
+	return InsertDividendsWithContext(context.Background(), client, dbName, dividends)
+}
+
+// InsertDividendsWithContext inserts the provided dividends into the "ticker_dividends" collection of dbName.
+// Dividends whose polygon_id is already stored are skipped.
+// It returns the number of successfully inserted documents and an error (if any).
+func InsertDividendsWithContext(ctx context.Context, client *mongo.Client, dbName string, dividends []Dividend) (int, error) {
+	documents := make([]corporateActionDocument, 0, len(dividends))
+	for _, dividend := range dividends {
+		documents = append(documents, corporateActionDocument{polygonID: dividend.PolygonID, document: dividend})
+	}
+	return insertCorporateActions(ctx, client, dbName, "ticker_dividends", documents)
+}
+
+// InsertSplits is InsertSplitsWithContext with a background context, so it times out after DefaultTimeout.
+func InsertSplits(client *mongo.Client, dbName string, splits []Split) (int, error) {
+	return InsertSplitsWithContext(context.Background(), client, dbName, splits)
+}
+
+// InsertSplitsWithContext inserts the provided splits into the "ticker_splits" collection of dbName.
+// Splits whose polygon_id is already stored are skipped.
+// It returns the number of successfully inserted documents and an error (if any).
+func InsertSplitsWithContext(ctx context.Context, client *mongo.Client, dbName string, splits []Split) (int, error) {
+	documents := make([]corporateActionDocument, 0, len(splits))
+	for _, split := range splits {
+		documents = append(documents, corporateActionDocument{polygonID: split.PolygonID, document: split})
+	}
+	return insertCorporateActions(ctx, client, dbName, "ticker_splits", documents)
+}
+
+type corporateActionDocument struct {
+	polygonID string
+	document  any
+}
+
+// Inserts documents into collection, deduplicating on polygon_id like InsertArticles
+func insertCorporateActions(ctx context.Context, client *mongo.Client, dbName string, collection string, documents []corporateActionDocument) (int, error) {
+	if client == nil {
+		return 0, mongo.ErrClientDisconnected
+	}
+	if len(documents) == 0 {
+		return 0, nil
+	}
+
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+
+	coll := client.Database(dbName).Collection(collection)
+
+	models := make([]mongo.WriteModel, 0, len(documents))
+	for _, d := range documents {
+		if d.polygonID == "" {
+			models = append(models, mongo.NewInsertOneModel().SetDocument(d.document))
+		} else {
+			filter := bson.M{"polygon_id": d.polygonID}
+			update := bson.M{"$setOnInsert": d.document}
+			models = append(models, mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(update).SetUpsert(true))
+		}
+	}
+
+	res, err := coll.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		if res != nil {
+			return int(res.InsertedCount + res.UpsertedCount), err
+		}
+		return 0, err
+	}
+	return int(res.InsertedCount + res.UpsertedCount), nil
+}
+
+// GetDividendsByTickerOverRange is GetDividendsByTickerOverRangeWithContext with a background context, so it times out after DefaultTimeout.
+func GetDividendsByTickerOverRange(client *mongo.Client, dbName, ticker string, start, end time.Time) ([]Dividend, error) {
+	return GetDividendsByTickerOverRangeWithContext(context.Background(), client, dbName, ticker, start, end)
+}
+
+// GetDividendsByTickerOverRangeWithContext returns the dividends of `ticker` whose ex-dividend date is between
+// `start` and `end` (inclusive), sorted by ex-dividend date ascending. Zero times leave that side of the range open.
+func GetDividendsByTickerOverRangeWithContext(ctx context.Context, client *mongo.Client, dbName, ticker string, start, end time.Time) ([]Dividend, error) {
+	return findCorporateActions[Dividend](ctx, client, dbName, "ticker_dividends", "ex_dividend_date", ticker, start, end)
+}
+
+// GetSplitsByTickerOverRange is GetSplitsByTickerOverRangeWithContext with a background context, so it times out after DefaultTimeout.
+func GetSplitsByTickerOverRange(client *mongo.Client, dbName, ticker string, start, end time.Time) ([]Split, error) {
+	return GetSplitsByTickerOverRangeWithContext(context.Background(), client, dbName, ticker, start, end)
+}
+
+// GetSplitsByTickerOverRangeWithContext returns the splits of `ticker` executed between `start` and `end`
+// (inclusive), sorted by execution date ascending. Zero times leave that side of the range open.
+func GetSplitsByTickerOverRangeWithContext(ctx context.Context, client *mongo.Client, dbName, ticker string, start, end time.Time) ([]Split, error) {
+	return findCorporateActions[Split](ctx, client, dbName, "ticker_splits", "execution_date", ticker, start, end)
+}
+
+func findCorporateActions[T any](ctx context.Context, client *mongo.Client, dbName, collection, dateField, ticker string, start, end time.Time) ([]T, error) {
+	if client == nil {
+		return nil, mongo.ErrClientDisconnected
+	}
+	if ticker == "" {
+		return nil, nil
+	}
+
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+
+	coll := client.Database(dbName).Collection(collection)
+
+	filter := bson.M{"ticker": ticker}
+	if !start.IsZero() || !end.IsZero() {
+		rangeFilter := bson.M{}
+		if !start.IsZero() {
+			rangeFilter["$gte"] = primitive.NewDateTimeFromTime(start)
+		}
+		if !end.IsZero() {
+			rangeFilter["$lte"] = primitive.NewDateTimeFromTime(end)
+		}
+		filter[dateField] = rangeFilter
+	}
+
+	cursor, err := coll.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: dateField, Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	out := make([]T, 0)
+	if err := cursor.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Parses an optional YYYY-MM-DD date returned by Polygon
+func parsePolygonDate(value *string) (primitive.DateTime, error) {
+	if value == nil || *value == "" {
+		return 0, nil
+	}
+	date, err := time.Parse("2006-01-02", *value)
+	if err != nil {
+		return 0, err
+	}
+	return primitive.NewDateTimeFromTime(date), nil
+}
+
+// Convert the dividends returned by PolygonGetTickerDividends into a slice of Dividend.
+func PolygonDividendsToDividends(dividends []polygon.PolygonDividend) ([]Dividend, error) {
+	out := make([]Dividend, 0, len(dividends))
+	for _, d := range dividends {
+		var dividend Dividend
+		dividend.ID = primitive.NewObjectID()
+		if d.ID != nil {
+			dividend.PolygonID = *d.ID
+		}
+		if d.Ticker != nil {
+			dividend.Ticker = *d.Ticker
+		}
+		if d.CashAmount != nil {
+			dividend.CashAmount = *d.CashAmount
+		}
+		if d.Currency != nil {
+			dividend.Currency = *d.Currency
+		}
+		if d.Frequency != nil {
+			dividend.Frequency = *d.Frequency
+		}
+		if d.DividendType != nil {
+			dividend.DividendType = *d.DividendType
+		}
+
+		var err error
+		if dividend.DeclarationDate, err = parsePolygonDate(d.DeclarationDate); err != nil {
+			return nil, errors.Join(errors.New("invalid declaration_date"), err)
+		}
+		if dividend.ExDividendDate, err = parsePolygonDate(d.ExDividendDate); err != nil {
+			return nil, errors.Join(errors.New("invalid ex_dividend_date"), err)
+		}
+		if dividend.RecordDate, err = parsePolygonDate(d.RecordDate); err != nil {
+			return nil, errors.Join(errors.New("invalid record_date"), err)
+		}
+		if dividend.PayDate, err = parsePolygonDate(d.PayDate); err != nil {
+			return nil, errors.Join(errors.New("invalid pay_date"), err)
+		}
+
+		out = append(out, dividend)
+	}
+	return out, nil
+}
+
+// Convert the splits returned by PolygonGetTickerSplits into a slice of Split.
+func PolygonSplitsToSplits(splits []polygon.PolygonSplit) ([]Split, error) {
+	out := make([]Split, 0, len(splits))
+	for _, s := range splits {
+		var split Split
+		split.ID = primitive.NewObjectID()
+		if s.ID != nil {
+			split.PolygonID = *s.ID
+		}
+		if s.Ticker != nil {
+			split.Ticker = *s.Ticker
+		}
+		if s.SplitFrom != nil {
+			split.SplitFrom = *s.SplitFrom
+		}
+		if s.SplitTo != nil {
+			split.SplitTo = *s.SplitTo
+		}
+
+		var err error
+		if split.ExecutionDate, err = parsePolygonDate(s.ExecutionDate); err != nil {
+			return nil, errors.Join(errors.New("invalid execution_date"), err)
+		}
+
+		out = append(out, split)
+	}
+	return out, nil
+}
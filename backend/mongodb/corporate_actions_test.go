@@ -0,0 +1,143 @@
+package mongodb
+
+import (
+	"context"
+	"financial-helper/polygon"
+	"fmt"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPolygonDividendsToDividends(t *testing.T) {
+	polygonDividends, err := newFakePolygonConnection(t).PolygonGetTickerDividends("AAPL", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Time{})
+	if err != nil {
+		t.Fatalf("PolygonGetTickerDividends error: %v", err)
+	}
+
+	dividends, err := PolygonDividendsToDividends(polygonDividends)
+	if err != nil {
+		t.Fatalf("PolygonDividendsToDividends returned error: %v", err)
+	}
+	if len(dividends) != len(polygonDividends) || len(dividends) == 0 {
+		t.Fatalf("expected %d dividends, got %d", len(polygonDividends), len(dividends))
+	}
+	for i, dividend := range dividends {
+		if dividend.PolygonID != *polygonDividends[i].ID || dividend.CashAmount != *polygonDividends[i].CashAmount {
+			t.Fatalf("dividend #%d does not match the polygon dividend", i)
+		}
+		if got := dividend.ExDividendDate.Time().UTC().Format("2006-01-02"); got != *polygonDividends[i].ExDividendDate {
+			t.Fatalf("dividend #%d has ex-dividend date %s, expected %s", i, got, *polygonDividends[i].ExDividendDate)
+		}
+		if dividend.PayDate.Time().Before(dividend.ExDividendDate.Time()) {
+			t.Fatalf("dividend #%d is paid before its ex-dividend date", i)
+		}
+	}
+
+	invalid := "02/09/2024"
+	if _, err := PolygonDividendsToDividends([]polygon.PolygonDividend{{ExDividendDate: &invalid}}); err == nil {
+		t.Fatalf("expected an error for an invalid date")
+	}
+}
+
+func TestPolygonSplitsToSplits(t *testing.T) {
+	polygonSplits, err := newFakePolygonConnection(t).PolygonGetTickerSplits("AAPL", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("PolygonGetTickerSplits error: %v", err)
+	}
+
+	splits, err := PolygonSplitsToSplits(polygonSplits)
+	if err != nil {
+		t.Fatalf("PolygonSplitsToSplits returned error: %v", err)
+	}
+	if len(splits) != 2 || splits[0].Ratio() != 7 || splits[1].Ratio() != 4 {
+		t.Fatalf("expected the 7-for-1 and 4-for-1 splits, got %+v", splits)
+	}
+}
+
+func TestSplitRatioBetween(t *testing.T) {
+	day := func(year int, month time.Month, d int) time.Time {
+		return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+	}
+	splits := []Split{
+		{ExecutionDate: primitive.NewDateTimeFromTime(day(2014, 6, 9)), SplitFrom: 1, SplitTo: 7},
+		{ExecutionDate: primitive.NewDateTimeFromTime(day(2020, 8, 31)), SplitFrom: 1, SplitTo: 4},
+		{ExecutionDate: primitive.NewDateTimeFromTime(day(2021, 1, 1))}, // Missing ratio, ignored
+	}
+
+	cases := []struct {
+		after, until time.Time
+		expected     float64
+	}{
+		{day(2010, 1, 1), day(2024, 1, 1), 28},
+		{day(2014, 6, 9), day(2024, 1, 1), 4},   // The split executed on the day of the purchase is already included
+		{day(2014, 6, 8), day(2014, 6, 9), 7},   // The split counts on its execution date
+		{day(2020, 9, 1), day(2024, 1, 1), 1},   // Bought after every split
+		{day(2024, 1, 1), day(2010, 1, 1), 1},   // Empty range
+		{day(2014, 1, 1), day(2020, 8, 30), 7},  // Second split not executed yet
+		{day(2014, 1, 1), day(2020, 8, 31), 28}, // Second split executed that day
+	}
+	for i, c := range cases {
+		if ratio := SplitRatioBetween(splits, c.after, c.until); ratio != c.expected {
+			t.Fatalf("case #%d: expected ratio %v, got %v", i, c.expected, ratio)
+		}
+	}
+}
+
+func TestInsertAndGetCorporateActions(t *testing.T) {
+	if testMongoClient == nil {
+		t.Skip("test mongo client not initialized")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	ticker := fmt.Sprintf("TEST-CA-%d", time.Now().UnixNano())
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var dividends []Dividend
+	for i := 0; i < 4; i++ {
+		exDate := start.AddDate(0, 3*i, 0)
+		dividends = append(dividends, Dividend{
+			PolygonID:      fmt.Sprintf("%s-dividend-%d", ticker, i),
+			Ticker:         ticker,
+			CashAmount:     0.25,
+			ExDividendDate: primitive.NewDateTimeFromTime(exDate),
+			PayDate:        primitive.NewDateTimeFromTime(exDate.AddDate(0, 0, 7)),
+		})
+	}
+	splits := []Split{{PolygonID: ticker + "-split", Ticker: ticker, ExecutionDate: primitive.NewDateTimeFromTime(start.AddDate(0, 6, 0)), SplitFrom: 1, SplitTo: 4}}
+	defer func() {
+		for _, collection := range []string{"ticker_dividends", "ticker_splits"} {
+			if _, err := testMongoClient.Database(DB_NAME).Collection(collection).DeleteMany(ctx, bson.M{"ticker": ticker}); err != nil {
+				t.Logf("cleanup error: %v", err)
+			}
+		}
+	}()
+
+	if inserted, err := InsertDividends(testMongoClient, DB_NAME, dividends); err != nil || inserted != len(dividends) {
+		t.Fatalf("expected %d inserted dividends, got %d (err %v)", len(dividends), inserted, err)
+	}
+	if inserted, err := InsertDividends(testMongoClient, DB_NAME, dividends); err != nil || inserted != 0 {
+		t.Fatalf("expected duplicate dividends to be skipped, got %d (err %v)", inserted, err)
+	}
+	if inserted, err := InsertSplits(testMongoClient, DB_NAME, splits); err != nil || inserted != 1 {
+		t.Fatalf("expected 1 inserted split, got %d (err %v)", inserted, err)
+	}
+
+	firstHalf, err := GetDividendsByTickerOverRange(testMongoClient, DB_NAME, ticker, start, start.AddDate(0, 6, -1))
+	if err != nil {
+		t.Fatalf("GetDividendsByTickerOverRange returned error: %v", err)
+	}
+	if len(firstHalf) != 2 || firstHalf[0].ExDividendDate > firstHalf[1].ExDividendDate {
+		t.Fatalf("expected the first 2 dividends in order, got %d", len(firstHalf))
+	}
+	storedSplits, err := GetSplitsByTickerOverRange(testMongoClient, DB_NAME, ticker, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("GetSplitsByTickerOverRange returned error: %v", err)
+	}
+	if len(storedSplits) != 1 || storedSplits[0].Ratio() != 4 {
+		t.Fatalf("expected the stored 4-for-1 split, got %+v", storedSplits)
+	}
+}
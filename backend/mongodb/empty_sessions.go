@@ -0,0 +1,89 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EmptySession is a closed session for which Polygon has no daily bar of a ticker, e.g. because it was halted or
+// not listed yet, stored in the "ticker_empty_sessions" collection so the session isn't requested again
+type EmptySession struct {
+	ID     string             `bson:"_id"` // The ticker and the date, e.g. AAPL_2024-10-01
+	Ticker string             `bson:"ticker"`
+	Date   primitive.DateTime `bson:"date"` // The start of the session's daily bar, midnight in New York
+}
+
+// InsertEmptySessions is InsertEmptySessionsWithContext with a background context, so it times out after DefaultTimeout.
+func InsertEmptySessions(client *mongo.Client, dbName, ticker string, dates []time.Time) (int, error) {
+	return InsertEmptySessionsWithContext(context.Background(), client, dbName, ticker, dates)
+}
+
+// InsertEmptySessionsWithContext records that `ticker` has no daily bar on the sessions of `dates`, each given as the
+// start of its bar. Sessions already recorded are skipped.
+// It returns the number of newly recorded sessions and an error (if any).
+func InsertEmptySessionsWithContext(ctx context.Context, client *mongo.Client, dbName, ticker string, dates []time.Time) (int, error) {
+	if client == nil {
+		return 0, mongo.ErrClientDisconnected
+	}
+	if ticker == "" || len(dates) == 0 {
+		return 0, nil
+	}
+
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+
+	models := make([]mongo.WriteModel, 0, len(dates))
+	for _, date := range dates {
+		session := EmptySession{
+			ID:     fmt.Sprintf("%s_%s", ticker, date.Format("2006-01-02")),
+			Ticker: ticker,
+			Date:   primitive.NewDateTimeFromTime(date),
+		}
+		update := bson.M{"$setOnInsert": session}
+		models = append(models, mongo.NewUpdateOneModel().SetFilter(bson.M{"_id": session.ID}).SetUpdate(update).SetUpsert(true))
+	}
+
+	res, err := client.Database(dbName).Collection("ticker_empty_sessions").BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		if res != nil {
+			return int(res.UpsertedCount), err
+		}
+		return 0, err
+	}
+	return int(res.UpsertedCount), nil
+}
+
+// GetEmptySessionsOverRange is GetEmptySessionsOverRangeWithContext with a background context, so it times out after DefaultTimeout.
+func GetEmptySessionsOverRange(client *mongo.Client, dbName, ticker string, start, end time.Time) ([]EmptySession, error) {
+	return GetEmptySessionsOverRangeWithContext(context.Background(), client, dbName, ticker, start, end)
+}
+
+// GetEmptySessionsOverRangeWithContext returns the sessions recorded without a daily bar of `ticker` whose date is
+// between `start` and `end` (inclusive), sorted by date ascending.
+func GetEmptySessionsOverRangeWithContext(ctx context.Context, client *mongo.Client, dbName, ticker string, start, end time.Time) ([]EmptySession, error) {
+	if client == nil {
+		return nil, mongo.ErrClientDisconnected
+	}
+
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+
+	filter := bson.M{"ticker": ticker, "date": bson.M{"$gte": primitive.NewDateTimeFromTime(start), "$lte": primitive.NewDateTimeFromTime(end)}}
+	cursor, err := client.Database(dbName).Collection("ticker_empty_sessions").Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "date", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	sessions := []EmptySession{}
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
@@ -0,0 +1,44 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestEmptySessions(t *testing.T) {
+	if testMongoClient == nil {
+		t.Skip("test mongo client not initialized")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	ticker := fmt.Sprintf("TEST-EMPTY-%d", time.Now().UnixNano())
+	defer func() {
+		if _, err := testMongoClient.Database(DB_NAME).Collection("ticker_empty_sessions").DeleteMany(ctx, bson.M{"ticker": ticker}); err != nil {
+			t.Logf("cleanup error: %v", err)
+		}
+	}()
+
+	base := time.Date(2024, 10, 1, 4, 0, 0, 0, time.UTC)
+	dates := []time.Time{base, base.AddDate(0, 0, 1), base.AddDate(0, 0, 7)}
+	if inserted, err := InsertEmptySessions(testMongoClient, DB_NAME, ticker, dates); err != nil || inserted != 3 {
+		t.Fatalf("expected 3 recorded sessions, got %d (err %v)", inserted, err)
+	}
+	// Recording a session again is a no-op
+	if inserted, err := InsertEmptySessions(testMongoClient, DB_NAME, ticker, dates[:1]); err != nil || inserted != 0 {
+		t.Fatalf("expected the duplicate to be skipped, got %d (err %v)", inserted, err)
+	}
+
+	sessions, err := GetEmptySessionsOverRange(testMongoClient, DB_NAME, ticker, base, base.AddDate(0, 0, 2))
+	if err != nil {
+		t.Fatalf("GetEmptySessionsOverRange error: %v", err)
+	}
+	if len(sessions) != 2 || !sessions[0].Date.Time().Equal(base) || sessions[1].ID != ticker+"_2024-10-02" {
+		t.Fatalf("unexpected sessions %+v", sessions)
+	}
+}
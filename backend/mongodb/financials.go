@@ -0,0 +1,241 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"financial-helper/polygon"
+	"fmt"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// FinancialFiling is the statements of a single filing stored in the "ticker_financials" collection.
+// Statements map Polygon's line items (e.g. "revenues") to their values in USD, or USD per share for earnings per share.
+type FinancialFiling struct {
+	ID                  primitive.ObjectID `bson:"_id,omitempty"`
+	FilingKey           string             `bson:"filing_key,omitempty"` // Identifies the filing, see financialFilingKey
+	Ticker              string             `bson:"ticker,omitempty"`
+	CIK                 string             `bson:"cik,omitempty"`
+	CompanyName         string             `bson:"company_name,omitempty"`
+	Timeframe           string             `bson:"timeframe,omitempty"`     // quarterly, annual or ttm
+	FiscalPeriod        string             `bson:"fiscal_period,omitempty"` // Q1 to Q4, FY or TTM
+	FiscalYear          string             `bson:"fiscal_year,omitempty"`
+	StartDate           primitive.DateTime `bson:"start_date,omitempty"`
+	EndDate             primitive.DateTime `bson:"end_date,omitempty"`
+	FilingDate          primitive.DateTime `bson:"filing_date,omitempty"`
+	SourceFilingURL     string             `bson:"source_filing_url,omitempty"`
+	IncomeStatement     map[string]float64 `bson:"income_statement,omitempty"`
+	BalanceSheet        map[string]float64 `bson:"balance_sheet,omitempty"`
+	CashFlowStatement   map[string]float64 `bson:"cash_flow_statement,omitempty"`
+	ComprehensiveIncome map[string]float64 `bson:"comprehensive_income,omitempty"`
+}
+
+// FinancialRatios are derived from the statements of a single filing. Ratios whose denominator is missing or 0 are nil.
+type FinancialRatios struct {
+	GrossMargin     *float64 // Gross profit / revenues
+	OperatingMargin *float64 // Operating income / revenues
+	NetMargin       *float64 // Net income / revenues
+	DebtToEquity    *float64 // Total liabilities / equity
+	CurrentRatio    *float64 // Current assets / current liabilities
+	ReturnOnEquity  *float64 // Net income / equity, for the period of the filing (not annualized)
+}
+
+// Returns the key identifying the filing of ticker covering a period, so re-scraping a filing replaces it
+func financialFilingKey(ticker, timeframe string, start, end primitive.DateTime) string {
+	return fmt.Sprintf("%s_%s_%d_%d", ticker, timeframe, int64(start), int64(end))
+}
+
+// Ratios returns the ratios derived from the filing's statements
+func (filing FinancialFiling) Ratios() FinancialRatios {
+	ratio := func(numerator float64, numeratorOK bool, denominator float64, denominatorOK bool) *float64 {
+		if !numeratorOK || !denominatorOK || denominator == 0 {
+			return nil
+		}
+		value := numerator / denominator
+		return &value
+	}
+
+	revenues, revenuesOK := filing.IncomeStatement["revenues"]
+	grossProfit, grossProfitOK := filing.IncomeStatement["gross_profit"]
+	operatingIncome, operatingIncomeOK := filing.IncomeStatement["operating_income_loss"]
+	netIncome, netIncomeOK := filing.IncomeStatement["net_income_loss"]
+	liabilities, liabilitiesOK := filing.BalanceSheet["liabilities"]
+	equity, equityOK := filing.BalanceSheet["equity"]
+	currentAssets, currentAssetsOK := filing.BalanceSheet["current_assets"]
+	currentLiabilities, currentLiabilitiesOK := filing.BalanceSheet["current_liabilities"]
+
+	return FinancialRatios{
+		GrossMargin:     ratio(grossProfit, grossProfitOK, revenues, revenuesOK),
+		OperatingMargin: ratio(operatingIncome, operatingIncomeOK, revenues, revenuesOK),
+		NetMargin:       ratio(netIncome, netIncomeOK, revenues, revenuesOK),
+		DebtToEquity:    ratio(liabilities, liabilitiesOK, equity, equityOK),
+		CurrentRatio:    ratio(currentAssets, currentAssetsOK, currentLiabilities, currentLiabilitiesOK),
+		ReturnOnEquity:  ratio(netIncome, netIncomeOK, equity, equityOK),
+	}
+}
+
+// TrailingEPS returns the diluted earnings per share over the last twelve months covered by filings: the latest
+// annual or TTM filing if it is the most recent, otherwise the sum of the last four quarters. It returns false if
+// filings do not cover the last twelve months.
+func TrailingEPS(filings []FinancialFiling) (float64, bool) {
+	sorted := append([]FinancialFiling{}, filings...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].EndDate > sorted[j].EndDate })
+
+	quarters := []float64{}
+	for _, filing := range sorted {
+		eps, ok := filing.IncomeStatement["diluted_earnings_per_share"]
+		if !ok {
+			continue
+		}
+		switch filing.Timeframe {
+		case string(polygon.FinancialsAnnual), string(polygon.FinancialsTTM):
+			if len(quarters) == 0 {
+				return eps, true
+			}
+		case string(polygon.FinancialsQuarterly):
+			quarters = append(quarters, eps)
+		}
+		if len(quarters) == 4 {
+			return quarters[0] + quarters[1] + quarters[2] + quarters[3], true
+		}
+	}
+	return 0, false
+}
+
+// InsertFinancials is InsertFinancialsWithContext with a background context, so it times out after DefaultTimeout.
+func InsertFinancials(client *mongo.Client, dbName string, filings []FinancialFiling) (int, error) {
+	return InsertFinancialsWithContext(context.Background(), client, dbName, filings)
+}
+
+// InsertFinancialsWithContext stores filings in the "ticker_financials" collection of dbName. A filing already stored
+// for the same ticker, timeframe and period is replaced, since companies restate earlier filings.
+// It returns the number of filings that were not stored yet and an error (if any).
+func InsertFinancialsWithContext(ctx context.Context, client *mongo.Client, dbName string, filings []FinancialFiling) (int, error) {
+	if client == nil {
+		return 0, mongo.ErrClientDisconnected
+	}
+
+	models := make([]mongo.WriteModel, 0, len(filings))
+	for _, filing := range filings {
+		if filing.Ticker == "" || filing.EndDate == primitive.DateTime(0) {
+			continue
+		}
+		filing.FilingKey = financialFilingKey(filing.Ticker, filing.Timeframe, filing.StartDate, filing.EndDate)
+		// Keep the _id of the stored document
+		filing.ID = primitive.NilObjectID
+		models = append(models, mongo.NewReplaceOneModel().SetFilter(bson.M{"filing_key": filing.FilingKey}).SetReplacement(filing).SetUpsert(true))
+	}
+	if len(models) == 0 {
+		return 0, nil
+	}
+
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+
+	coll := client.Database(dbName).Collection("ticker_financials")
+	res, err := coll.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	if res == nil {
+		return 0, err
+	}
+	return int(res.UpsertedCount), err
+}
+
+// GetFinancialsByTicker is GetFinancialsByTickerWithContext with a background context, so it times out after DefaultTimeout.
+func GetFinancialsByTicker(client *mongo.Client, dbName, ticker, timeframe string, limit int) ([]FinancialFiling, error) {
+	return GetFinancialsByTickerWithContext(context.Background(), client, dbName, ticker, timeframe, limit)
+}
+
+// GetFinancialsByTickerWithContext returns the latest `limit` filings of `ticker` for a timeframe, most recent
+// period first. limit <= 0 returns every filing.
+func GetFinancialsByTickerWithContext(ctx context.Context, client *mongo.Client, dbName, ticker, timeframe string, limit int) ([]FinancialFiling, error) {
+	if client == nil {
+		return nil, mongo.ErrClientDisconnected
+	}
+
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+
+	coll := client.Database(dbName).Collection("ticker_financials")
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "end_date", Value: -1}})
+	if limit > 0 {
+		findOptions.SetLimit(int64(limit))
+	}
+	cursor, err := coll.Find(ctx, bson.M{"ticker": ticker, "timeframe": timeframe}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	filings := []FinancialFiling{}
+	if err := cursor.All(ctx, &filings); err != nil {
+		return nil, err
+	}
+	return filings, nil
+}
+
+// Convert filings returned by PolygonGetTickerFinancials for `ticker` into FinancialFiling.
+// Polygon lists every ticker of the company on a filing, so the ticker the filings were requested for is stored.
+func PolygonFinancialsToFilings(ticker string, polygonFilings []polygon.PolygonFinancialFiling) ([]FinancialFiling, error) {
+	filings := make([]FinancialFiling, 0, len(polygonFilings))
+	for i, r := range polygonFilings {
+		filing := FinancialFiling{ID: primitive.NewObjectID(), Ticker: ticker}
+		if r.CIK != nil {
+			filing.CIK = *r.CIK
+		}
+		if r.CompanyName != nil {
+			filing.CompanyName = *r.CompanyName
+		}
+		if r.Timeframe != nil {
+			filing.Timeframe = *r.Timeframe
+		}
+		if r.FiscalPeriod != nil {
+			filing.FiscalPeriod = *r.FiscalPeriod
+		}
+		if r.FiscalYear != nil {
+			filing.FiscalYear = *r.FiscalYear
+		}
+		if r.SourceFilingURL != nil {
+			filing.SourceFilingURL = *r.SourceFilingURL
+		}
+
+		var err error
+		if filing.StartDate, err = parsePolygonDate(r.StartDate); err != nil {
+			return nil, errors.Join(fmt.Errorf("filing #%d has an invalid start_date", i), err)
+		}
+		if filing.EndDate, err = parsePolygonDate(r.EndDate); err != nil {
+			return nil, errors.Join(fmt.Errorf("filing #%d has an invalid end_date", i), err)
+		}
+		if filing.FilingDate, err = parsePolygonDate(r.FilingDate); err != nil {
+			return nil, errors.Join(fmt.Errorf("filing #%d has an invalid filing_date", i), err)
+		}
+
+		if r.Financials != nil {
+			filing.IncomeStatement = statementValues(r.Financials.IncomeStatement)
+			filing.BalanceSheet = statementValues(r.Financials.BalanceSheet)
+			filing.CashFlowStatement = statementValues(r.Financials.CashFlowStatement)
+			filing.ComprehensiveIncome = statementValues(r.Financials.ComprehensiveIncome)
+		}
+		filing.FilingKey = financialFilingKey(filing.Ticker, filing.Timeframe, filing.StartDate, filing.EndDate)
+		filings = append(filings, filing)
+	}
+	return filings, nil
+}
+
+// Returns the values of the line items of a statement, dropping the items without one
+func statementValues(statement polygon.PolygonFinancialStatement) map[string]float64 {
+	if len(statement) == 0 {
+		return nil
+	}
+	values := make(map[string]float64, len(statement))
+	for item, dataPoint := range statement {
+		if dataPoint.Value != nil {
+			values[item] = *dataPoint.Value
+		}
+	}
+	return values
+}
@@ -0,0 +1,124 @@
+package mongodb
+
+import (
+	"context"
+	"financial-helper/polygon"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func getFakeFinancials(t *testing.T, symbol string, timeframe polygon.PolygonFinancialsTimeframe) []FinancialFiling {
+	t.Helper()
+	polygonFilings, err := newFakePolygonConnection(t).PolygonGetTickerFinancials(symbol, timeframe, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("PolygonGetTickerFinancials error: %v", err)
+	}
+	filings, err := PolygonFinancialsToFilings(symbol, polygonFilings)
+	if err != nil {
+		t.Fatalf("PolygonFinancialsToFilings returned error: %v", err)
+	}
+	return filings
+}
+
+func TestPolygonFinancialsToFilings(t *testing.T) {
+	filings := getFakeFinancials(t, "AAPL", polygon.FinancialsQuarterly)
+	if len(filings) != 4 {
+		t.Fatalf("expected 4 quarterly filings, got %d", len(filings))
+	}
+
+	last := filings[3]
+	if last.Ticker != "AAPL" || last.FiscalPeriod != "Q4" || last.FiscalYear != "2024" || last.Timeframe != "quarterly" {
+		t.Fatalf("unexpected filing: %+v", last)
+	}
+	if end := last.EndDate.Time().UTC().Format("2006-01-02"); end != "2024-09-28" {
+		t.Fatalf("expected the period to end on 2024-09-28, got %s", end)
+	}
+	if last.IncomeStatement["revenues"] != 94_930e6 || last.BalanceSheet["equity"] != 56_950e6 || last.CashFlowStatement["net_cash_flow_from_operating_activities"] != 26_811e6 {
+		t.Fatalf("statements do not match the fixture: %+v", last)
+	}
+	if last.FilingKey == "" || last.FilingKey == filings[2].FilingKey {
+		t.Fatalf("expected every filing to have its own key")
+	}
+}
+
+func TestFinancialFilingRatios(t *testing.T) {
+	annual := getFakeFinancials(t, "AAPL", polygon.FinancialsAnnual)[0]
+	ratios := annual.Ratios()
+
+	closeTo := func(name string, value *float64, expected float64) {
+		if value == nil || math.Abs(*value-expected) > 0.001 {
+			t.Fatalf("expected %s to be %.3f, got %v", name, expected, value)
+		}
+	}
+	closeTo("gross margin", ratios.GrossMargin, 0.462)
+	closeTo("operating margin", ratios.OperatingMargin, 0.315)
+	closeTo("net margin", ratios.NetMargin, 0.240)
+	closeTo("debt to equity", ratios.DebtToEquity, 5.409)
+	closeTo("current ratio", ratios.CurrentRatio, 0.867)
+
+	// Missing line items leave the ratios that need them unset
+	delete(annual.IncomeStatement, "revenues")
+	annual.BalanceSheet["equity"] = 0
+	ratios = annual.Ratios()
+	if ratios.GrossMargin != nil || ratios.DebtToEquity != nil || ratios.CurrentRatio == nil {
+		t.Fatalf("expected margins and debt to equity to be unset, got %+v", ratios)
+	}
+}
+
+func TestTrailingEPS(t *testing.T) {
+	quarters := getFakeFinancials(t, "AAPL", polygon.FinancialsQuarterly)
+	annual := getFakeFinancials(t, "AAPL", polygon.FinancialsAnnual)
+
+	if eps, ok := TrailingEPS(quarters); !ok || math.Abs(eps-6.08) > 1e-9 {
+		t.Fatalf("expected the last four quarters to sum to 6.08, got %f (%t)", eps, ok)
+	}
+	if eps, ok := TrailingEPS(annual); !ok || eps != 6.08 {
+		t.Fatalf("expected the annual EPS, got %f (%t)", eps, ok)
+	}
+	if _, ok := TrailingEPS(quarters[1:]); ok {
+		t.Fatalf("expected three quarters not to cover twelve months")
+	}
+}
+
+func TestInsertFinancials(t *testing.T) {
+	if testMongoClient == nil {
+		t.Skip("test mongo client not initialized")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	ticker := fmt.Sprintf("TEST-%d", time.Now().UnixNano())
+	filings := getFakeFinancials(t, "AAPL", polygon.FinancialsQuarterly)
+	for i := range filings {
+		filings[i].Ticker = ticker
+	}
+	defer func() {
+		if _, err := testMongoClient.Database(DB_NAME).Collection("ticker_financials").DeleteMany(ctx, bson.M{"ticker": ticker}); err != nil {
+			t.Logf("cleanup error: %v", err)
+		}
+	}()
+
+	inserted, err := InsertFinancials(testMongoClient, DB_NAME, filings)
+	if err != nil || inserted != len(filings) {
+		t.Fatalf("expected %d filings to be inserted, got %d (err %v)", len(filings), inserted, err)
+	}
+
+	// A restated filing replaces the stored one
+	filings[3].IncomeStatement["revenues"] = 1
+	if inserted, err := InsertFinancials(testMongoClient, DB_NAME, filings); err != nil || inserted != 0 {
+		t.Fatalf("expected filings to be replaced, got %d inserted (err %v)", inserted, err)
+	}
+
+	stored, err := GetFinancialsByTicker(testMongoClient, DB_NAME, ticker, "quarterly", 2)
+	if err != nil || len(stored) != 2 {
+		t.Fatalf("expected the latest 2 filings, got %d (err %v)", len(stored), err)
+	}
+	if stored[0].FiscalPeriod != "Q4" || stored[0].IncomeStatement["revenues"] != 1 {
+		t.Fatalf("expected the restated Q4 filing first, got %+v", stored[0])
+	}
+}
@@ -11,6 +11,18 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/readpref"
 )
 
+// Timeout applied to database calls whose context has no deadline of its own
+const DefaultTimeout = 15 * time.Second
+
+// Returns a child of ctx that expires after DefaultTimeout, unless ctx already has a deadline,
+// in which case the caller's deadline is kept
+func withDefaultTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, DefaultTimeout)
+}
+
 // GetMongoDBInstance connects to a local MongoDB instance on the given port using the provided
 // username and password. It uses authSource=admin and SCRAM-SHA-256 by default and returns a
 // connected *mongo.Client (or an error).
@@ -0,0 +1,37 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithDefaultTimeout(t *testing.T) {
+	ctx, cancel := withDefaultTimeout(context.Background())
+	defer cancel()
+	deadline, ok := ctx.Deadline()
+	if !ok || time.Until(deadline) > DefaultTimeout {
+		t.Fatalf("expected the default timeout to be applied, got deadline %v (set: %t)", deadline, ok)
+	}
+
+	parent, parentCancel := context.WithTimeout(context.Background(), time.Hour)
+	defer parentCancel()
+	ctx, cancel = withDefaultTimeout(parent)
+	defer cancel()
+	if deadline, _ := ctx.Deadline(); time.Until(deadline) < 59*time.Minute {
+		t.Fatalf("expected the caller's deadline to be kept, got %v", deadline)
+	}
+}
+
+func TestGetAggregatesByTickerWithContext_Cancelled(t *testing.T) {
+	if testMongoClient == nil {
+		t.Skip("test mongo client not initialized")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := GetAggregatesByTickerWithContext(ctx, testMongoClient, DB_NAME, "AAPL", 0, 0, 0); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
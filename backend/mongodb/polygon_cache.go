@@ -0,0 +1,119 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"financial-helper/polygon"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// Environment variable selecting the Polygon response cache: "bolt", "mongo" or empty to disable it
+	PolygonCacheEnv = "POLYGON_CACHE"
+	// Environment variable with the path of the bolt cache file
+	PolygonCacheFileEnv = "POLYGON_CACHE_FILE"
+	// Cache file used if PolygonCacheFileEnv is not set
+	DefaultPolygonCacheFile = "polygon_cache.db"
+)
+
+type polygonCacheEntry struct {
+	Key       string    `bson:"_id"`
+	Body      []byte    `bson:"body"`
+	ExpiresAt time.Time `bson:"expires_at"`
+}
+
+// PolygonResponseCache is a polygon.PolygonCache stored in the "polygon_cache" collection of a database,
+// so every server and scraper connected to it shares the same responses
+type PolygonResponseCache struct {
+	client *mongo.Client
+	dbName string
+}
+
+// NewPolygonResponseCache is NewPolygonResponseCacheWithContext with a background context, so it times out after DefaultTimeout.
+func NewPolygonResponseCache(client *mongo.Client, dbName string) (*PolygonResponseCache, error) {
+	return NewPolygonResponseCacheWithContext(context.Background(), client, dbName)
+}
+
+// NewPolygonResponseCacheWithContext creates a cache in the "polygon_cache" collection of dbName, and the TTL index
+// that lets MongoDB delete expired responses
+func NewPolygonResponseCacheWithContext(ctx context.Context, client *mongo.Client, dbName string) (*PolygonResponseCache, error) {
+	if client == nil {
+		return nil, mongo.ErrClientDisconnected
+	}
+
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+
+	index := mongo.IndexModel{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)}
+	if _, err := client.Database(dbName).Collection("polygon_cache").Indexes().CreateOne(ctx, index); err != nil {
+		return nil, errors.Join(errors.New("error creating the polygon cache TTL index"), err)
+	}
+	return &PolygonResponseCache{client: client, dbName: dbName}, nil
+}
+
+// Get returns the response stored for key and when it expires
+func (cache *PolygonResponseCache) Get(ctx context.Context, key string) ([]byte, time.Time, bool, error) {
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+
+	var entry polygonCacheEntry
+	err := cache.client.Database(cache.dbName).Collection("polygon_cache").FindOne(ctx, bson.M{"_id": key}).Decode(&entry)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, time.Time{}, false, nil
+	}
+	if err != nil {
+		return nil, time.Time{}, false, err
+	}
+	return entry.Body, entry.ExpiresAt, true, nil
+}
+
+// Set stores the response for key until expiresAt
+func (cache *PolygonResponseCache) Set(ctx context.Context, key string, body []byte, expiresAt time.Time) error {
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+
+	entry := polygonCacheEntry{Key: key, Body: body, ExpiresAt: expiresAt.UTC()}
+	_, err := cache.client.Database(cache.dbName).Collection("polygon_cache").ReplaceOne(ctx, bson.M{"_id": key}, entry, options.Replace().SetUpsert(true))
+	return err
+}
+
+// PolygonCacheFromEnv returns the Polygon response cache selected by POLYGON_CACHE
+//
+// Input:
+//   - client, dbName: the database used if POLYGON_CACHE is "mongo"
+//
+// Output:
+//   - polygon.PolygonCache: the cache, nil if POLYGON_CACHE is not set. A bolt file stays open (and locked)
+//     until the process exits.
+//   - error: any error that occurred, e.g. if POLYGON_CACHE is unknown
+func PolygonCacheFromEnv(client *mongo.Client, dbName string) (polygon.PolygonCache, error) {
+	switch backend := strings.ToLower(strings.TrimSpace(os.Getenv(PolygonCacheEnv))); backend {
+	case "":
+		return nil, nil
+	case "mongo":
+		cache, err := NewPolygonResponseCache(client, dbName)
+		if err != nil {
+			return nil, err
+		}
+		return cache, nil
+	case "bolt":
+		path := os.Getenv(PolygonCacheFileEnv)
+		if path == "" {
+			path = DefaultPolygonCacheFile
+		}
+		cache, err := polygon.NewBoltPolygonCache(path)
+		if err != nil {
+			return nil, err
+		}
+		return cache, nil
+	default:
+		return nil, fmt.Errorf("unknown %s %q, expected \"bolt\" or \"mongo\"", PolygonCacheEnv, backend)
+	}
+}
@@ -0,0 +1,365 @@
+package mongodb
+
+import (
+	"context"
+	"financial-helper/polygon"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TickerReference is a ticker stored in the "ticker_reference" collection, the local copy of Polygon's ticker list
+// that searches run against. Unlike TickerDetails, it only holds what Polygon's tickers endpoint returns.
+type TickerReference struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty"`
+	Ticker          string             `bson:"ticker,omitempty"`
+	Name            string             `bson:"name,omitempty"`
+	Market          string             `bson:"market,omitempty"`
+	Locale          string             `bson:"locale,omitempty"`
+	PrimaryExchange string             `bson:"primary_exchange,omitempty"`
+	Type            string             `bson:"type,omitempty"`
+	Active          bool               `bson:"active,omitempty"`
+	CurrencyName    string             `bson:"currency_name,omitempty"`
+	CIK             string             `bson:"cik,omitempty"`
+	UpdatedAt       primitive.DateTime `bson:"updated_at,omitempty"` // When the ticker was last synced from Polygon
+}
+
+// TickerSearchQuery is a search term and the filters results must match. Empty filters match every ticker.
+type TickerSearchQuery struct {
+	Query    string
+	Market   string
+	Type     string
+	Exchange string // Matched against the primary exchange
+}
+
+// TickerSearchMatch is a ticker matching a search, with its relevance (higher is better)
+type TickerSearchMatch struct {
+	TickerReference
+	Score int
+}
+
+// Scores of the ways a ticker can match a search term, see ScoreTickerMatch
+const (
+	scoreExactSymbol     = 100
+	scoreSymbolPrefix    = 90
+	scoreExactName       = 85
+	scoreNamePrefix      = 75
+	scoreNameWordPrefix  = 70
+	scoreSymbolSubstring = 55
+	scoreNameSubstring   = 50
+	scoreFuzzy           = 40
+)
+
+// Most candidates loaded from MongoDB by a search, before they are ranked
+const maxSearchCandidates = 500
+
+// UpsertTickerReferences is UpsertTickerReferencesWithContext with a background context, so it times out after DefaultTimeout.
+func UpsertTickerReferences(client *mongo.Client, dbName string, references []TickerReference) (int, error) {
+	return UpsertTickerReferencesWithContext(context.Background(), client, dbName, references)
+}
+
+// UpsertTickerReferencesWithContext stores references in the "ticker_reference" collection of dbName, updating the
+// document of the same ticker if there is one. UpdatedAt is set to the current time if it is not set.
+// It returns the number of tickers that were not stored yet.
+func UpsertTickerReferencesWithContext(ctx context.Context, client *mongo.Client, dbName string, references []TickerReference) (int, error) {
+	if client == nil {
+		return 0, mongo.ErrClientDisconnected
+	}
+
+	now := primitive.NewDateTimeFromTime(time.Now().UTC())
+	models := make([]mongo.WriteModel, 0, len(references))
+	for _, reference := range references {
+		if reference.Ticker == "" {
+			continue
+		}
+		if reference.UpdatedAt == primitive.DateTime(0) {
+			reference.UpdatedAt = now
+		}
+		// Keep the _id of the stored document
+		reference.ID = primitive.NilObjectID
+		models = append(models, mongo.NewReplaceOneModel().SetFilter(bson.M{"ticker": reference.Ticker}).SetReplacement(reference).SetUpsert(true))
+	}
+	if len(models) == 0 {
+		return 0, nil
+	}
+
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+
+	coll := client.Database(dbName).Collection("ticker_reference")
+	res, err := coll.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	if res == nil {
+		return 0, err
+	}
+	return int(res.UpsertedCount), err
+}
+
+// SearchTickerReferences is SearchTickerReferencesWithContext with a background context, so it times out after DefaultTimeout.
+func SearchTickerReferences(client *mongo.Client, dbName string, query TickerSearchQuery, limit int) ([]TickerSearchMatch, error) {
+	return SearchTickerReferencesWithContext(context.Background(), client, dbName, query, limit)
+}
+
+// SearchTickerReferencesWithContext returns up to `limit` tickers of the "ticker_reference" collection matching
+// query, best matches first (see RankTickerMatches).
+//
+// Candidates are loaded in two passes: tickers whose symbol starts with or whose name contains the search term,
+// then, if there are not enough of those, tickers that may only match with a typo. Typos are only tolerated after
+// the first letter of the symbol and the first two letters of a word of the name, which keeps the second pass small.
+func SearchTickerReferencesWithContext(ctx context.Context, client *mongo.Client, dbName string, query TickerSearchQuery, limit int) ([]TickerSearchMatch, error) {
+	if client == nil {
+		return nil, mongo.ErrClientDisconnected
+	}
+	term := strings.TrimSpace(query.Query)
+	if term == "" || limit <= 0 {
+		return []TickerSearchMatch{}, nil
+	}
+
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+
+	coll := client.Database(dbName).Collection("ticker_reference")
+
+	filters := bson.M{}
+	if query.Market != "" {
+		filters["market"] = query.Market
+	}
+	if query.Type != "" {
+		filters["type"] = query.Type
+	}
+	if query.Exchange != "" {
+		filters["primary_exchange"] = query.Exchange
+	}
+	withFilters := func(or bson.A) bson.M {
+		filter := bson.M{"$or": or}
+		for key, value := range filters {
+			filter[key] = value
+		}
+		return filter
+	}
+
+	quoted := regexp.QuoteMeta(term)
+	candidates, err := findTickerReferences(ctx, coll, withFilters(bson.A{
+		bson.M{"ticker": bson.M{"$regex": "^" + regexp.QuoteMeta(strings.ToUpper(term))}},
+		bson.M{"name": bson.M{"$regex": quoted, "$options": "i"}},
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	matches := RankTickerMatches(term, candidates, limit)
+	if len(matches) >= limit {
+		return matches, nil
+	}
+
+	// Second pass for typos, e.g. "APPL" or "microsfot"
+	more, err := findTickerReferences(ctx, coll, withFilters(fuzzyTickerFilters(term)))
+	if err != nil {
+		return nil, err
+	}
+
+	// Merge both passes, a ticker found by both is scored once
+	seen := make(map[string]bool, len(candidates))
+	for _, candidate := range candidates {
+		seen[candidate.Ticker] = true
+	}
+	for _, candidate := range more {
+		if !seen[candidate.Ticker] {
+			candidates = append(candidates, candidate)
+			seen[candidate.Ticker] = true
+		}
+	}
+	return RankTickerMatches(term, candidates, limit), nil
+}
+
+// Returns up to maxSearchCandidates tickers matching filter, shortest symbols first so exact and short matches
+// are not cut off
+func findTickerReferences(ctx context.Context, coll *mongo.Collection, filter bson.M) ([]TickerReference, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$addFields", Value: bson.M{"_ticker_length": bson.M{"$strLenCP": "$ticker"}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_ticker_length", Value: 1}, {Key: "ticker", Value: 1}}}},
+		{{Key: "$limit", Value: maxSearchCandidates}},
+		{{Key: "$project", Value: bson.M{"_ticker_length": 0}}},
+	}
+	cursor, err := coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	references := []TickerReference{}
+	if err := cursor.All(ctx, &references); err != nil {
+		return nil, err
+	}
+	return references, nil
+}
+
+// RankTickerMatches scores every reference against term with ScoreTickerMatch and returns up to `limit` of those
+// that match, best first. Ties go to the shorter, then alphabetically first, symbol.
+func RankTickerMatches(term string, references []TickerReference, limit int) []TickerSearchMatch {
+	matches := []TickerSearchMatch{}
+	for _, reference := range references {
+		if score := ScoreTickerMatch(term, reference); score > 0 {
+			matches = append(matches, TickerSearchMatch{TickerReference: reference, Score: score})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		if len(matches[i].Ticker) != len(matches[j].Ticker) {
+			return len(matches[i].Ticker) < len(matches[j].Ticker)
+		}
+		return matches[i].Ticker < matches[j].Ticker
+	})
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// ScoreTickerMatch returns how well reference matches a search term, 0 if it doesn't.
+// Symbol matches rank above name matches, and prefixes above substrings. A symbol or a word of the name that is
+// one typo away from the term (two for terms longer than 5 characters) is a fuzzy match.
+func ScoreTickerMatch(term string, reference TickerReference) int {
+	term = strings.TrimSpace(term)
+	if term == "" {
+		return 0
+	}
+	symbol := strings.ToUpper(reference.Ticker)
+	upperTerm := strings.ToUpper(term)
+	name := strings.ToLower(reference.Name)
+	lowerTerm := strings.ToLower(term)
+
+	switch {
+	case symbol == upperTerm:
+		return scoreExactSymbol
+	case strings.HasPrefix(symbol, upperTerm):
+		// AAP ranks AAPL above AAPLW
+		return max(scoreSymbolPrefix-(len(symbol)-len(upperTerm)), scoreExactName+1)
+	case name != "" && name == lowerTerm:
+		return scoreExactName
+	case name != "" && strings.HasPrefix(name, lowerTerm):
+		return scoreNamePrefix
+	}
+
+	for _, word := range nameWords(name) {
+		if strings.HasPrefix(word, lowerTerm) {
+			return scoreNameWordPrefix
+		}
+	}
+	if strings.Contains(symbol, upperTerm) {
+		return scoreSymbolSubstring
+	}
+	if name != "" && strings.Contains(name, lowerTerm) {
+		return scoreNameSubstring
+	}
+
+	maxEdits := 1
+	if len(term) > 5 {
+		maxEdits = 2
+	}
+	if len(term) < 3 {
+		return 0
+	}
+	best := maxEdits + 1
+	best = min(best, editDistance(upperTerm, symbol))
+	for _, word := range nameWords(name) {
+		best = min(best, editDistance(lowerTerm, word))
+	}
+	if best <= maxEdits {
+		return scoreFuzzy - 10*best
+	}
+	return 0
+}
+
+// Returns the filters of the fuzzy pass of a search for term: tickers starting with its first character, and names
+// with a word starting with the first two characters of its first word. Prefixes are taken in runes, so a term
+// starting with an accented letter doesn't cut it in half.
+func fuzzyTickerFilters(term string) bson.A {
+	runes := []rune(term)
+	filters := bson.A{bson.M{"ticker": bson.M{"$regex": "^" + regexp.QuoteMeta(strings.ToUpper(string(runes[:1])))}}}
+	if words := nameWords(term); len(words) > 0 {
+		if word := []rune(words[0]); len(word) >= 2 {
+			filters = append(filters, bson.M{"name": bson.M{"$regex": `\b` + regexp.QuoteMeta(string(word[:2])), "$options": "i"}})
+		}
+	}
+	return filters
+}
+
+// Splits a name into lowercase words, dropping punctuation
+func nameWords(name string) []string {
+	return strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
+
+// Convert the tickers of a PolygonGetTickerResponse into TickerReference. UpdatedAt is left unset.
+func PolygonTickersToTickerReferences(response polygon.PolygonGetTickerResponse) []TickerReference {
+	if response.Results == nil {
+		return nil
+	}
+	references := make([]TickerReference, 0, len(*response.Results))
+	for _, r := range *response.Results {
+		if r.Ticker == nil || *r.Ticker == "" {
+			continue
+		}
+		reference := TickerReference{Ticker: *r.Ticker}
+		if r.Name != nil {
+			reference.Name = *r.Name
+		}
+		if r.Market != nil {
+			reference.Market = *r.Market
+		}
+		if r.Locale != nil {
+			reference.Locale = *r.Locale
+		}
+		if r.PrimaryExchange != nil {
+			reference.PrimaryExchange = *r.PrimaryExchange
+		}
+		if r.Type != nil {
+			reference.Type = *r.Type
+		}
+		if r.Active != nil {
+			reference.Active = *r.Active
+		}
+		if r.CurrencyName != nil {
+			reference.CurrencyName = *r.CurrencyName
+		}
+		if r.Cik != nil {
+			reference.CIK = *r.Cik
+		}
+		references = append(references, reference)
+	}
+	return references
+}
@@ -0,0 +1,145 @@
+package mongodb
+
+import (
+	"context"
+	"financial-helper/polygon"
+	"fmt"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func getFakeTickerReferences(t *testing.T) []TickerReference {
+	t.Helper()
+	connection := newFakePolygonConnection(t)
+
+	// The fake matches tickers whose symbol or name contains the search term, together these cover every fixture
+	references := []TickerReference{}
+	seen := map[string]bool{}
+	for _, term := range []string{"a", "s"} {
+		response, err := connection.PolygonSearchTickers(polygon.PolygonTickerSearchRequest{Search: term, Limit: 100})
+		if err != nil {
+			t.Fatalf("PolygonSearchTickers error: %v", err)
+		}
+		for _, reference := range PolygonTickersToTickerReferences(*response) {
+			if !seen[reference.Ticker] {
+				references = append(references, reference)
+				seen[reference.Ticker] = true
+			}
+		}
+	}
+	return references
+}
+
+func TestScoreTickerMatch(t *testing.T) {
+	apple := TickerReference{Ticker: "AAPL", Name: "Apple Inc."}
+	microsoft := TickerReference{Ticker: "MSFT", Name: "Microsoft Corp"}
+	cases := []struct {
+		term      string
+		reference TickerReference
+		expected  int
+	}{
+		{"aapl", apple, scoreExactSymbol},
+		{"AAP", apple, scoreSymbolPrefix - 1},
+		{"apple inc.", apple, scoreExactName},
+		{"app", apple, scoreNamePrefix},
+		{"corp", microsoft, scoreNameWordPrefix},
+		{"SF", microsoft, scoreSymbolSubstring},
+		{"soft", microsoft, scoreNameSubstring},
+		{"AAPK", apple, scoreFuzzy - 10},
+		{"microsfot", microsoft, scoreFuzzy - 20},
+		{"google", apple, 0},
+		{"  ", apple, 0},
+	}
+	for _, c := range cases {
+		if score := ScoreTickerMatch(c.term, c.reference); score != c.expected {
+			t.Fatalf("%q against %s: expected score %d, got %d", c.term, c.reference.Ticker, c.expected, score)
+		}
+	}
+}
+
+func TestFuzzyTickerFilters(t *testing.T) {
+	// Both prefixes of an accented term are whole characters
+	filters := fuzzyTickerFilters("Électricité de France")
+	if len(filters) != 2 {
+		t.Fatalf("expected a ticker and a name filter, got %v", filters)
+	}
+	for i, expected := range []string{"^É", `\bél`} {
+		pattern := filters[i].(bson.M)
+		for _, value := range pattern {
+			regex := value.(bson.M)["$regex"].(string)
+			if regex != expected || !utf8.ValidString(regex) {
+				t.Fatalf("filter #%d: expected the regex %q, got %q", i, expected, regex)
+			}
+		}
+	}
+
+	if filters := fuzzyTickerFilters("é"); len(filters) != 1 || filters[0].(bson.M)["ticker"].(bson.M)["$regex"] != "^É" {
+		t.Fatalf("expected only a ticker filter for a single accented letter, got %v", filters)
+	}
+}
+
+func TestRankTickerMatches(t *testing.T) {
+	references := getFakeTickerReferences(t)
+	if len(references) != 5 {
+		t.Fatalf("expected the fixture tickers, got %d", len(references))
+	}
+
+	// AAPL's name starts with the term, APLE only contains it
+	matches := RankTickerMatches("apple", references, 10)
+	if len(matches) != 2 || matches[0].Ticker != "AAPL" || matches[1].Ticker != "APLE" {
+		t.Fatalf("expected AAPL then APLE, got %+v", matches)
+	}
+	if matches := RankTickerMatches("a", references, 1); len(matches) != 1 || matches[0].Ticker != "AAPL" {
+		t.Fatalf("expected the limit to keep the best match, got %+v", matches)
+	}
+}
+
+func TestSearchTickerReferences(t *testing.T) {
+	if testMongoClient == nil {
+		t.Skip("test mongo client not initialized")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	// Prefix the fixture tickers so the test neither sees nor overwrites real ones
+	prefix := fmt.Sprintf("T%d", time.Now().UnixNano()%1_000_000)
+	references := getFakeTickerReferences(t)
+	for i := range references {
+		references[i].Ticker = prefix + references[i].Ticker
+		references[i].Name = prefix + " " + references[i].Name
+	}
+	defer func() {
+		if _, err := testMongoClient.Database(DB_NAME).Collection("ticker_reference").DeleteMany(ctx, bson.M{"ticker": bson.M{"$regex": "^" + prefix}}); err != nil {
+			t.Logf("cleanup error: %v", err)
+		}
+	}()
+
+	inserted, err := UpsertTickerReferences(testMongoClient, DB_NAME, references)
+	if err != nil || inserted != len(references) {
+		t.Fatalf("expected %d new tickers, got %d (err %v)", len(references), inserted, err)
+	}
+	if inserted, err := UpsertTickerReferences(testMongoClient, DB_NAME, references); err != nil || inserted != 0 {
+		t.Fatalf("expected the second upsert to only update, got %d new tickers (err %v)", inserted, err)
+	}
+
+	matches, err := SearchTickerReferences(testMongoClient, DB_NAME, TickerSearchQuery{Query: prefix + "AAPL"}, 5)
+	if err != nil || len(matches) == 0 || matches[0].Ticker != prefix+"AAPL" {
+		t.Fatalf("expected the exact symbol first, got %+v (err %v)", matches, err)
+	}
+
+	// One typo away
+	matches, err = SearchTickerReferences(testMongoClient, DB_NAME, TickerSearchQuery{Query: prefix + "MSFY"}, 5)
+	if err != nil || len(matches) == 0 || matches[0].Ticker != prefix+"MSFT" {
+		t.Fatalf("expected a fuzzy match on MSFT, got %+v (err %v)", matches, err)
+	}
+
+	// Filters apply to every pass
+	matches, err = SearchTickerReferences(testMongoClient, DB_NAME, TickerSearchQuery{Query: prefix, Type: "ETF"}, 10)
+	if err != nil || len(matches) != 1 || matches[0].Ticker != prefix+"SPY" {
+		t.Fatalf("expected only the ETF, got %+v (err %v)", matches, err)
+	}
+}
@@ -0,0 +1,170 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"financial-helper/polygon"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type TickerBranding struct {
+	LogoURL string `bson:"logo_url,omitempty"`
+	IconURL string `bson:"icon_url,omitempty"`
+}
+
+// TickerDetails is the company information of a ticker stored in the "tickers" collection, one document per ticker
+type TickerDetails struct {
+	ID                          primitive.ObjectID `bson:"_id,omitempty"`
+	Ticker                      string             `bson:"ticker,omitempty"`
+	Name                        string             `bson:"name,omitempty"`
+	Market                      string             `bson:"market,omitempty"`
+	Locale                      string             `bson:"locale,omitempty"`
+	PrimaryExchange             string             `bson:"primary_exchange,omitempty"`
+	Type                        string             `bson:"type,omitempty"`
+	Active                      bool               `bson:"active,omitempty"`
+	CurrencyName                string             `bson:"currency_name,omitempty"`
+	CIK                         string             `bson:"cik,omitempty"`
+	SICCode                     string             `bson:"sic_code,omitempty"`
+	Industry                    string             `bson:"industry,omitempty"` // Polygon's SIC description
+	MarketCap                   float64            `bson:"market_cap,omitempty"`
+	ShareClassSharesOutstanding float64            `bson:"share_class_shares_outstanding,omitempty"`
+	WeightedSharesOutstanding   float64            `bson:"weighted_shares_outstanding,omitempty"`
+	TotalEmployees              int                `bson:"total_employees,omitempty"`
+	HomepageURL                 string             `bson:"homepage_url,omitempty"`
+	Description                 string             `bson:"description,omitempty"`
+	Branding                    TickerBranding     `bson:"branding,omitempty"`
+	ListDate                    primitive.DateTime `bson:"list_date,omitempty"`
+	UpdatedAt                   primitive.DateTime `bson:"updated_at,omitempty"` // When the details were last fetched from Polygon
+}
+
+// UpsertTickerDetails is UpsertTickerDetailsWithContext with a background context, so it times out after DefaultTimeout.
+func UpsertTickerDetails(client *mongo.Client, dbName string, details TickerDetails) error {
+	return UpsertTickerDetailsWithContext(context.Background(), client, dbName, details)
+}
+
+// UpsertTickerDetailsWithContext stores details in the "tickers" collection of dbName, replacing the document
+// of the same ticker if there is one. UpdatedAt is set to the current time if it is not set.
+func UpsertTickerDetailsWithContext(ctx context.Context, client *mongo.Client, dbName string, details TickerDetails) error {
+	if client == nil {
+		return mongo.ErrClientDisconnected
+	}
+	if details.Ticker == "" {
+		return errors.New("ticker details have no ticker")
+	}
+	if details.UpdatedAt == primitive.DateTime(0) {
+		details.UpdatedAt = primitive.NewDateTimeFromTime(time.Now().UTC())
+	}
+	// Keep the _id of the stored document
+	details.ID = primitive.NilObjectID
+
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+
+	coll := client.Database(dbName).Collection("tickers")
+	_, err := coll.ReplaceOne(ctx, bson.M{"ticker": details.Ticker}, details, options.Replace().SetUpsert(true))
+	return err
+}
+
+// GetTickerDetails is GetTickerDetailsWithContext with a background context, so it times out after DefaultTimeout.
+func GetTickerDetails(client *mongo.Client, dbName, ticker string) (*TickerDetails, error) {
+	return GetTickerDetailsWithContext(context.Background(), client, dbName, ticker)
+}
+
+// GetTickerDetailsWithContext returns the stored details of `ticker`, or mongo.ErrNoDocuments if there are none.
+func GetTickerDetailsWithContext(ctx context.Context, client *mongo.Client, dbName, ticker string) (*TickerDetails, error) {
+	if client == nil {
+		return nil, mongo.ErrClientDisconnected
+	}
+
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+
+	coll := client.Database(dbName).Collection("tickers")
+
+	var details TickerDetails
+	if err := coll.FindOne(ctx, bson.M{"ticker": ticker}).Decode(&details); err != nil {
+		return nil, err
+	}
+	return &details, nil
+}
+
+// Convert a PolygonGetTickerDetailsResponse into TickerDetails. UpdatedAt is left unset.
+func PolygonTickerDetailsToTickerDetails(response polygon.PolygonGetTickerDetailsResponse) (TickerDetails, error) {
+	var details TickerDetails
+	r := response.Results
+	if r == nil || r.Ticker == nil {
+		return details, errors.New("ticker details response has no results")
+	}
+
+	details.Ticker = *r.Ticker
+	if r.Name != nil {
+		details.Name = *r.Name
+	}
+	if r.Market != nil {
+		details.Market = *r.Market
+	}
+	if r.Locale != nil {
+		details.Locale = *r.Locale
+	}
+	if r.PrimaryExchange != nil {
+		details.PrimaryExchange = *r.PrimaryExchange
+	}
+	if r.Type != nil {
+		details.Type = *r.Type
+	}
+	if r.Active != nil {
+		details.Active = *r.Active
+	}
+	if r.CurrencyName != nil {
+		details.CurrencyName = *r.CurrencyName
+	}
+	if r.Cik != nil {
+		details.CIK = *r.Cik
+	}
+	if r.SicCode != nil {
+		details.SICCode = *r.SicCode
+	}
+	if r.SicDescription != nil {
+		details.Industry = *r.SicDescription
+	}
+	if r.MarketCap != nil {
+		details.MarketCap = *r.MarketCap
+	}
+	if r.ShareClassSharesOutstanding != nil {
+		details.ShareClassSharesOutstanding = *r.ShareClassSharesOutstanding
+	}
+	if r.WeightedSharesOutstanding != nil {
+		details.WeightedSharesOutstanding = *r.WeightedSharesOutstanding
+	}
+	if r.TotalEmployees != nil {
+		details.TotalEmployees = *r.TotalEmployees
+	}
+	if r.HomepageURL != nil {
+		details.HomepageURL = *r.HomepageURL
+	}
+	if r.Description != nil {
+		details.Description = *r.Description
+	}
+	if r.Branding != nil {
+		if r.Branding.LogoURL != nil {
+			details.Branding.LogoURL = *r.Branding.LogoURL
+		}
+		if r.Branding.IconURL != nil {
+			details.Branding.IconURL = *r.Branding.IconURL
+		}
+	}
+	if r.ListDate != nil && *r.ListDate != "" {
+		listDate, err := time.Parse("2006-01-02", *r.ListDate)
+		if err != nil {
+			return details, errors.Join(errors.New("invalid list_date"), err)
+		}
+		details.ListDate = primitive.NewDateTimeFromTime(listDate)
+	}
+
+	return details, nil
+}
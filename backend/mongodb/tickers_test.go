@@ -0,0 +1,102 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"financial-helper/polygon"
+	"fmt"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func getFakeTickerDetails(t *testing.T, symbol string) *polygon.PolygonGetTickerDetailsResponse {
+	t.Helper()
+	response, err := newFakePolygonConnection(t).PolygonGetTickerDetails(symbol)
+	if err != nil {
+		t.Fatalf("PolygonGetTickerDetails error: %v", err)
+	}
+	return response
+}
+
+func TestPolygonTickerDetailsToTickerDetails(t *testing.T) {
+	response := getFakeTickerDetails(t, "AAPL")
+
+	details, err := PolygonTickerDetailsToTickerDetails(*response)
+	if err != nil {
+		t.Fatalf("PolygonTickerDetailsToTickerDetails returned error: %v", err)
+	}
+	if details.Ticker != "AAPL" || details.Industry != *response.Results.SicDescription {
+		t.Fatalf("expected AAPL with industry %q, got %s with %q", *response.Results.SicDescription, details.Ticker, details.Industry)
+	}
+	if details.MarketCap != *response.Results.MarketCap || details.Description == "" || details.Branding.LogoURL == "" {
+		t.Fatalf("details are missing market cap, description or branding: %+v", details)
+	}
+	if listDate := details.ListDate.Time().UTC().Format("2006-01-02"); listDate != *response.Results.ListDate {
+		t.Fatalf("expected list date %s, got %s", *response.Results.ListDate, listDate)
+	}
+	if details.UpdatedAt != 0 {
+		t.Fatalf("expected UpdatedAt to be left unset")
+	}
+}
+
+func TestPolygonTickerDetailsToTickerDetails_PartialDetails(t *testing.T) {
+	// ETFs have no industry, market cap or branding
+	details, err := PolygonTickerDetailsToTickerDetails(*getFakeTickerDetails(t, "SPY"))
+	if err != nil {
+		t.Fatalf("PolygonTickerDetailsToTickerDetails returned error: %v", err)
+	}
+	if details.Ticker != "SPY" || details.Industry != "" || details.MarketCap != 0 {
+		t.Fatalf("unexpected details: %+v", details)
+	}
+
+	if _, err := PolygonTickerDetailsToTickerDetails(polygon.PolygonGetTickerDetailsResponse{}); err == nil {
+		t.Fatalf("expected an error for a response without results")
+	}
+}
+
+func TestUpsertTickerDetails(t *testing.T) {
+	if testMongoClient == nil {
+		t.Skip("test mongo client not initialized")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	details, err := PolygonTickerDetailsToTickerDetails(*getFakeTickerDetails(t, "AAPL"))
+	if err != nil {
+		t.Fatalf("PolygonTickerDetailsToTickerDetails returned error: %v", err)
+	}
+	details.Ticker = fmt.Sprintf("TEST-%d", time.Now().UnixNano())
+	defer func() {
+		if _, err := testMongoClient.Database(DB_NAME).Collection("tickers").DeleteMany(ctx, bson.M{"ticker": details.Ticker}); err != nil {
+			t.Logf("cleanup error: %v", err)
+		}
+	}()
+
+	if _, err := GetTickerDetails(testMongoClient, DB_NAME, details.Ticker); !errors.Is(err, mongo.ErrNoDocuments) {
+		t.Fatalf("expected mongo.ErrNoDocuments before upserting, got %v", err)
+	}
+
+	if err := UpsertTickerDetails(testMongoClient, DB_NAME, details); err != nil {
+		t.Fatalf("UpsertTickerDetails returned error: %v", err)
+	}
+	details.MarketCap = 1
+	if err := UpsertTickerDetails(testMongoClient, DB_NAME, details); err != nil {
+		t.Fatalf("second UpsertTickerDetails returned error: %v", err)
+	}
+
+	count, err := testMongoClient.Database(DB_NAME).Collection("tickers").CountDocuments(ctx, bson.M{"ticker": details.Ticker})
+	if err != nil || count != 1 {
+		t.Fatalf("expected a single document per ticker, got %d (err %v)", count, err)
+	}
+	stored, err := GetTickerDetails(testMongoClient, DB_NAME, details.Ticker)
+	if err != nil {
+		t.Fatalf("GetTickerDetails returned error: %v", err)
+	}
+	if stored.MarketCap != 1 || stored.Industry != details.Industry || stored.UpdatedAt == 0 {
+		t.Fatalf("stored details do not match the last upsert: %+v", stored)
+	}
+}
@@ -0,0 +1,252 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// The sides of a transaction, stored in its side field
+const (
+	TransactionBuy  = "buy"
+	TransactionSell = "sell"
+)
+
+// Transaction is a purchase or sale of shares of a user's portfolio, stored in the "transactions" collection
+type Transaction struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	UserID    string             `bson:"user_id"` // The hex id of the User owning the portfolio
+	Symbol    string             `bson:"symbol"`
+	Side      string             `bson:"side"`     // TransactionBuy or TransactionSell
+	Quantity  float64            `bson:"quantity"` // Shares at the time of the transaction, before any later split
+	Price     float64            `bson:"price"`    // Per share
+	Fees      float64            `bson:"fees,omitempty"`
+	Timestamp primitive.DateTime `bson:"timestamp"`
+	Note      string             `bson:"note,omitempty"`
+}
+
+// SignedQuantity returns the change in shares held caused by the transaction, negative for sales
+func (transaction Transaction) SignedQuantity() float64 {
+	if transaction.Side == TransactionSell {
+		return -transaction.Quantity
+	}
+	return transaction.Quantity
+}
+
+// ErrOversold is returned by ValidateTransactions when a sale exceeds the shares held at its time
+var ErrOversold = errors.New("sale exceeds the shares held")
+
+// Shares held below this are rounding errors of fractional shares
+const shareTolerance = 1e-9
+
+// ValidateTransactions checks that the transactions of a single symbol never sell more shares than are held.
+// The shares held before a split are multiplied by its ratio, so selling the shares received from a split is valid.
+//
+// Input:
+//   - transactions: the transactions of the symbol, in any order
+//   - splits: the splits of the symbol executed since its first transaction
+//
+// Output:
+//   - []float64: the shares held after each transaction once sorted with SortTransactions, in shares of its time
+//   - error: an error wrapping ErrOversold for the first oversold sale, if any
+func ValidateTransactions(transactions []Transaction, splits []Split) ([]float64, error) {
+	sorted := append([]Transaction{}, transactions...)
+	SortTransactions(sorted)
+
+	held := make([]float64, len(sorted))
+	shares := 0.0
+	for i, transaction := range sorted {
+		if i > 0 {
+			shares *= SplitRatioBetween(splits, sorted[i-1].Timestamp.Time(), transaction.Timestamp.Time())
+		}
+		shares += transaction.SignedQuantity()
+		if shares < -shareTolerance {
+			return nil, fmt.Errorf("%w: selling %g shares of %s on %s leaves %g", ErrOversold, transaction.Quantity, transaction.Symbol, transaction.Timestamp.Time().UTC().Format(time.DateOnly), shares)
+		}
+		held[i] = max(shares, 0)
+	}
+	return held, nil
+}
+
+// SortTransactions sorts transactions oldest first. Of the transactions made at the same time, buys come first so a
+// purchase and a sale recorded together are valid, and the others keep their order.
+func SortTransactions(transactions []Transaction) {
+	sort.SliceStable(transactions, func(i, j int) bool {
+		if transactions[i].Timestamp != transactions[j].Timestamp {
+			return transactions[i].Timestamp < transactions[j].Timestamp
+		}
+		return transactions[i].Side == TransactionBuy && transactions[j].Side == TransactionSell
+	})
+}
+
+// InsertTransaction is InsertTransactionWithContext with a background context, so it times out after DefaultTimeout.
+func InsertTransaction(client *mongo.Client, dbName string, transaction Transaction) (primitive.ObjectID, error) {
+	return InsertTransactionWithContext(context.Background(), client, dbName, transaction)
+}
+
+// InsertTransactionWithContext stores transaction in the "transactions" collection of dbName and returns its id.
+// A transaction without an id is given a new one.
+func InsertTransactionWithContext(ctx context.Context, client *mongo.Client, dbName string, transaction Transaction) (primitive.ObjectID, error) {
+	if client == nil {
+		return primitive.NilObjectID, mongo.ErrClientDisconnected
+	}
+	if transaction.ID.IsZero() {
+		transaction.ID = primitive.NewObjectID()
+	}
+
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+
+	coll := client.Database(dbName).Collection("transactions")
+	if _, err := coll.InsertOne(ctx, transaction); err != nil {
+		return primitive.NilObjectID, err
+	}
+	return transaction.ID, nil
+}
+
+// GetTransactions is GetTransactionsWithContext with a background context, so it times out after DefaultTimeout.
+func GetTransactions(client *mongo.Client, dbName, userID, symbol string) ([]Transaction, error) {
+	return GetTransactionsWithContext(context.Background(), client, dbName, userID, symbol)
+}
+
+// GetTransactionsWithContext returns the transactions of `userID` in `symbol`, or in every symbol if it is empty,
+// sorted with SortTransactions.
+func GetTransactionsWithContext(ctx context.Context, client *mongo.Client, dbName, userID, symbol string) ([]Transaction, error) {
+	if client == nil {
+		return nil, mongo.ErrClientDisconnected
+	}
+
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+
+	coll := client.Database(dbName).Collection("transactions")
+
+	filter := bson.M{"user_id": userID}
+	if symbol != "" {
+		filter["symbol"] = symbol
+	}
+	// Ids grow with insertion, which keeps transactions made at the same time in the order they were stored
+	findOpts := options.Find().SetSort(bson.D{{Key: "timestamp", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := coll.Find(ctx, filter, findOpts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	transactions := []Transaction{}
+	if err := cursor.All(ctx, &transactions); err != nil {
+		return nil, err
+	}
+	SortTransactions(transactions)
+	return transactions, nil
+}
+
+// GetTransaction is GetTransactionWithContext with a background context, so it times out after DefaultTimeout.
+func GetTransaction(client *mongo.Client, dbName, userID string, id primitive.ObjectID) (*Transaction, error) {
+	return GetTransactionWithContext(context.Background(), client, dbName, userID, id)
+}
+
+// GetTransactionWithContext returns the transaction of `userID` with the given id, or mongo.ErrNoDocuments if there
+// is none.
+func GetTransactionWithContext(ctx context.Context, client *mongo.Client, dbName, userID string, id primitive.ObjectID) (*Transaction, error) {
+	if client == nil {
+		return nil, mongo.ErrClientDisconnected
+	}
+
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+
+	coll := client.Database(dbName).Collection("transactions")
+
+	var transaction Transaction
+	if err := coll.FindOne(ctx, bson.M{"_id": id, "user_id": userID}).Decode(&transaction); err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
+
+// ReplaceTransaction is ReplaceTransactionWithContext with a background context, so it times out after DefaultTimeout.
+func ReplaceTransaction(client *mongo.Client, dbName string, transaction Transaction) error {
+	return ReplaceTransactionWithContext(context.Background(), client, dbName, transaction)
+}
+
+// ReplaceTransactionWithContext replaces the stored transaction with the id and user of transaction, or returns
+// mongo.ErrNoDocuments if there is none.
+func ReplaceTransactionWithContext(ctx context.Context, client *mongo.Client, dbName string, transaction Transaction) error {
+	if client == nil {
+		return mongo.ErrClientDisconnected
+	}
+
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+
+	coll := client.Database(dbName).Collection("transactions")
+	result, err := coll.ReplaceOne(ctx, bson.M{"_id": transaction.ID, "user_id": transaction.UserID}, transaction)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// DeleteTransaction is DeleteTransactionWithContext with a background context, so it times out after DefaultTimeout.
+func DeleteTransaction(client *mongo.Client, dbName, userID string, id primitive.ObjectID) error {
+	return DeleteTransactionWithContext(context.Background(), client, dbName, userID, id)
+}
+
+// DeleteTransactionWithContext deletes the transaction of `userID` with the given id, or returns
+// mongo.ErrNoDocuments if there is none.
+func DeleteTransactionWithContext(ctx context.Context, client *mongo.Client, dbName, userID string, id primitive.ObjectID) error {
+	if client == nil {
+		return mongo.ErrClientDisconnected
+	}
+
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+
+	coll := client.Database(dbName).Collection("transactions")
+	result, err := coll.DeleteOne(ctx, bson.M{"_id": id, "user_id": userID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// AssignUnownedTransactions is AssignUnownedTransactionsWithContext with a background context, so it times out after DefaultTimeout.
+func AssignUnownedTransactions(client *mongo.Client, dbName, userID string) (int, error) {
+	return AssignUnownedTransactionsWithContext(context.Background(), client, dbName, userID)
+}
+
+// AssignUnownedTransactionsWithContext gives the transactions stored without a user_id, from before users existed,
+// to `userID`. Transactions that already have an owner are left alone, so running it again is a no-op.
+// It returns the number of transactions assigned and an error (if any).
+func AssignUnownedTransactionsWithContext(ctx context.Context, client *mongo.Client, dbName, userID string) (int, error) {
+	if client == nil {
+		return 0, mongo.ErrClientDisconnected
+	}
+	if userID == "" {
+		return 0, errors.New("the owner of the transactions must not be empty")
+	}
+
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+
+	filter := bson.M{"$or": bson.A{bson.M{"user_id": bson.M{"$exists": false}}, bson.M{"user_id": ""}}}
+	result, err := client.Database(dbName).Collection("transactions").UpdateMany(ctx, filter, bson.M{"$set": bson.M{"user_id": userID}})
+	if err != nil {
+		return 0, err
+	}
+	return int(result.ModifiedCount), nil
+}
@@ -0,0 +1,154 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func testTransaction(side string, quantity float64, timestamp time.Time) Transaction {
+	return Transaction{Symbol: "TXN", Side: side, Quantity: quantity, Price: 10, Timestamp: primitive.NewDateTimeFromTime(timestamp)}
+}
+
+func TestValidateTransactions(t *testing.T) {
+	day := func(month, d int) time.Time { return time.Date(2024, time.Month(month), d, 15, 0, 0, 0, time.UTC) }
+	splits := []Split{{ExecutionDate: primitive.NewDateTimeFromTime(day(6, 1)), SplitFrom: 1, SplitTo: 4}}
+
+	// Stored out of order, the sale of the shares received from the split is valid
+	transactions := []Transaction{
+		testTransaction(TransactionSell, 6, day(7, 1)),
+		testTransaction(TransactionBuy, 2, day(1, 1)),
+		testTransaction(TransactionSell, 0.5, day(2, 1)),
+	}
+	held, err := ValidateTransactions(transactions, splits)
+	if err != nil {
+		t.Fatalf("ValidateTransactions error: %v", err)
+	}
+	if len(held) != 3 || held[0] != 2 || held[1] != 1.5 || held[2] != 0 {
+		t.Fatalf("unexpected shares held %v", held)
+	}
+
+	// Without the split, the last sale is oversold
+	if _, err := ValidateTransactions(transactions, nil); !errors.Is(err, ErrOversold) {
+		t.Fatalf("expected ErrOversold, got %v", err)
+	}
+	// A sale recorded at the same time as a purchase comes after it
+	sameTime := []Transaction{testTransaction(TransactionSell, 1, day(1, 1)), testTransaction(TransactionBuy, 1, day(1, 1))}
+	if _, err := ValidateTransactions(sameTime, nil); err != nil {
+		t.Fatalf("expected a purchase and sale at the same time to be valid, got %v", err)
+	}
+}
+
+func TestTransactionsCRUD(t *testing.T) {
+	if testMongoClient == nil {
+		t.Skip("test mongo client not initialized")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	symbol := fmt.Sprintf("TEST-TXN-%d", time.Now().UnixNano())
+	defer func() {
+		if _, err := testMongoClient.Database(DB_NAME).Collection("transactions").DeleteMany(ctx, bson.M{"symbol": symbol}); err != nil {
+			t.Logf("cleanup error: %v", err)
+		}
+	}()
+
+	start := time.Date(2024, 1, 2, 15, 0, 0, 0, time.UTC)
+	buy := testTransaction(TransactionBuy, 3, start)
+	buy.Symbol = symbol
+	buy.UserID = "test-user"
+	sell := testTransaction(TransactionSell, 1, start.AddDate(0, 1, 0))
+	sell.Symbol = symbol
+	sell.UserID = "test-user"
+	sell.Note = "Rebalancing"
+
+	// Inserted newest first, returned oldest first
+	sellID, err := InsertTransaction(testMongoClient, DB_NAME, sell)
+	if err != nil {
+		t.Fatalf("InsertTransaction error: %v", err)
+	}
+	buyID, err := InsertTransaction(testMongoClient, DB_NAME, buy)
+	if err != nil {
+		t.Fatalf("InsertTransaction error: %v", err)
+	}
+	stored, err := GetTransactions(testMongoClient, DB_NAME, "test-user", symbol)
+	if err != nil || len(stored) != 2 || stored[0].ID != buyID || stored[1].Note != "Rebalancing" {
+		t.Fatalf("unexpected transactions %+v (err %v)", stored, err)
+	}
+	// Other users don't see them
+	if others, err := GetTransactions(testMongoClient, DB_NAME, "other-user", symbol); err != nil || len(others) != 0 {
+		t.Fatalf("expected no transactions for another user, got %+v (err %v)", others, err)
+	}
+	if _, err := GetTransaction(testMongoClient, DB_NAME, "other-user", sellID); !errors.Is(err, mongo.ErrNoDocuments) {
+		t.Fatalf("expected mongo.ErrNoDocuments for another user's transaction, got %v", err)
+	}
+
+	stored[1].Quantity = 2
+	if err := ReplaceTransaction(testMongoClient, DB_NAME, stored[1]); err != nil {
+		t.Fatalf("ReplaceTransaction error: %v", err)
+	}
+	replaced, err := GetTransaction(testMongoClient, DB_NAME, "test-user", sellID)
+	if err != nil || replaced.Quantity != 2 {
+		t.Fatalf("expected the sale to be replaced, got %+v (err %v)", replaced, err)
+	}
+
+	if err := DeleteTransaction(testMongoClient, DB_NAME, "other-user", sellID); !errors.Is(err, mongo.ErrNoDocuments) {
+		t.Fatalf("expected another user not to delete the transaction, got %v", err)
+	}
+	if err := DeleteTransaction(testMongoClient, DB_NAME, "test-user", sellID); err != nil {
+		t.Fatalf("DeleteTransaction error: %v", err)
+	}
+	if err := DeleteTransaction(testMongoClient, DB_NAME, "test-user", sellID); !errors.Is(err, mongo.ErrNoDocuments) {
+		t.Fatalf("expected mongo.ErrNoDocuments for a deleted transaction, got %v", err)
+	}
+	if _, err := GetTransaction(testMongoClient, DB_NAME, "test-user", sellID); !errors.Is(err, mongo.ErrNoDocuments) {
+		t.Fatalf("expected mongo.ErrNoDocuments for a deleted transaction, got %v", err)
+	}
+}
+
+func TestAssignUnownedTransactions(t *testing.T) {
+	if testMongoClient == nil {
+		t.Skip("test mongo client not initialized")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	symbol := fmt.Sprintf("TEST-TXN-OWNER-%d", time.Now().UnixNano())
+	coll := testMongoClient.Database(DB_NAME).Collection("transactions")
+	defer func() {
+		if _, err := coll.DeleteMany(ctx, bson.M{"symbol": symbol}); err != nil {
+			t.Logf("cleanup error: %v", err)
+		}
+	}()
+
+	// Stored the way transactions were before users existed
+	legacy, err := coll.InsertOne(ctx, bson.M{"symbol": symbol, "side": TransactionBuy, "quantity": 1.0, "price": 10.0, "timestamp": primitive.NewDateTimeFromTime(time.Now())})
+	if err != nil {
+		t.Fatalf("insert error: %v", err)
+	}
+	owned := testTransaction(TransactionBuy, 2, time.Now())
+	owned.Symbol = symbol
+	owned.UserID = "other-user"
+	if _, err := InsertTransaction(testMongoClient, DB_NAME, owned); err != nil {
+		t.Fatalf("InsertTransaction error: %v", err)
+	}
+
+	if assigned, err := AssignUnownedTransactions(testMongoClient, DB_NAME, "test-user"); err != nil || assigned < 1 {
+		t.Fatalf("expected the legacy transaction to be assigned, got %d (err %v)", assigned, err)
+	}
+	claimed, err := GetTransactions(testMongoClient, DB_NAME, "test-user", symbol)
+	if err != nil || len(claimed) != 1 || claimed[0].ID != legacy.InsertedID {
+		t.Fatalf("expected only the legacy transaction to be assigned, got %+v (err %v)", claimed, err)
+	}
+	if others, err := GetTransactions(testMongoClient, DB_NAME, "other-user", symbol); err != nil || len(others) != 1 {
+		t.Fatalf("expected the owned transaction to keep its owner, got %+v (err %v)", others, err)
+	}
+}
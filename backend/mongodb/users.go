@@ -0,0 +1,112 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// User is an account of the API, stored in the "users" collection
+type User struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty"`
+	Username     string             `bson:"username"` // Lowercase, unique
+	PasswordHash string             `bson:"password_hash"`
+	CreatedAt    primitive.DateTime `bson:"created_at"`
+}
+
+// ErrDuplicateUser is returned by InsertUser when the username is taken
+var ErrDuplicateUser = errors.New("username is taken")
+
+// CreateUsersIndex is CreateUsersIndexWithContext with a background context, so it times out after DefaultTimeout.
+func CreateUsersIndex(client *mongo.Client, dbName string) error {
+	return CreateUsersIndexWithContext(context.Background(), client, dbName)
+}
+
+// CreateUsersIndexWithContext creates the unique index on the username of the "users" collection of dbName, which
+// lets InsertUser reject taken usernames even when two users register at once
+func CreateUsersIndexWithContext(ctx context.Context, client *mongo.Client, dbName string) error {
+	if client == nil {
+		return mongo.ErrClientDisconnected
+	}
+
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+
+	index := mongo.IndexModel{Keys: bson.D{{Key: "username", Value: 1}}, Options: options.Index().SetUnique(true)}
+	if _, err := client.Database(dbName).Collection("users").Indexes().CreateOne(ctx, index); err != nil {
+		return errors.Join(errors.New("error creating the users username index"), err)
+	}
+	return nil
+}
+
+// InsertUser is InsertUserWithContext with a background context, so it times out after DefaultTimeout.
+func InsertUser(client *mongo.Client, dbName string, user User) (primitive.ObjectID, error) {
+	return InsertUserWithContext(context.Background(), client, dbName, user)
+}
+
+// InsertUserWithContext stores user in the "users" collection of dbName and returns its id, or ErrDuplicateUser if
+// its username is taken. A user without an id is given a new one.
+func InsertUserWithContext(ctx context.Context, client *mongo.Client, dbName string, user User) (primitive.ObjectID, error) {
+	if client == nil {
+		return primitive.NilObjectID, mongo.ErrClientDisconnected
+	}
+	if user.ID.IsZero() {
+		user.ID = primitive.NewObjectID()
+	}
+	if user.CreatedAt == 0 {
+		user.CreatedAt = primitive.NewDateTimeFromTime(time.Now())
+	}
+
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+
+	coll := client.Database(dbName).Collection("users")
+	if _, err := coll.InsertOne(ctx, user); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return primitive.NilObjectID, ErrDuplicateUser
+		}
+		return primitive.NilObjectID, err
+	}
+	return user.ID, nil
+}
+
+// GetUserByUsername is GetUserByUsernameWithContext with a background context, so it times out after DefaultTimeout.
+func GetUserByUsername(client *mongo.Client, dbName, username string) (*User, error) {
+	return GetUserByUsernameWithContext(context.Background(), client, dbName, username)
+}
+
+// GetUserByUsernameWithContext returns the user named `username`, or mongo.ErrNoDocuments if there is none.
+func GetUserByUsernameWithContext(ctx context.Context, client *mongo.Client, dbName, username string) (*User, error) {
+	return getUserWithContext(ctx, client, dbName, bson.M{"username": username})
+}
+
+// GetUserByID is GetUserByIDWithContext with a background context, so it times out after DefaultTimeout.
+func GetUserByID(client *mongo.Client, dbName string, id primitive.ObjectID) (*User, error) {
+	return GetUserByIDWithContext(context.Background(), client, dbName, id)
+}
+
+// GetUserByIDWithContext returns the user with the given id, or mongo.ErrNoDocuments if there is none.
+func GetUserByIDWithContext(ctx context.Context, client *mongo.Client, dbName string, id primitive.ObjectID) (*User, error) {
+	return getUserWithContext(ctx, client, dbName, bson.M{"_id": id})
+}
+
+// Returns the user matching filter in the "users" collection of dbName
+func getUserWithContext(ctx context.Context, client *mongo.Client, dbName string, filter bson.M) (*User, error) {
+	if client == nil {
+		return nil, mongo.ErrClientDisconnected
+	}
+
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+
+	var user User
+	if err := client.Database(dbName).Collection("users").FindOne(ctx, filter).Decode(&user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
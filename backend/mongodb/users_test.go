@@ -0,0 +1,52 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestUsers(t *testing.T) {
+	if testMongoClient == nil {
+		t.Skip("test mongo client not initialized")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	if err := CreateUsersIndex(testMongoClient, DB_NAME); err != nil {
+		t.Fatalf("CreateUsersIndex error: %v", err)
+	}
+	username := fmt.Sprintf("test-user-%d", time.Now().UnixNano())
+	defer func() {
+		if _, err := testMongoClient.Database(DB_NAME).Collection("users").DeleteMany(ctx, bson.M{"username": username}); err != nil {
+			t.Logf("cleanup error: %v", err)
+		}
+	}()
+
+	id, err := InsertUser(testMongoClient, DB_NAME, User{Username: username, PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("InsertUser error: %v", err)
+	}
+	if _, err := InsertUser(testMongoClient, DB_NAME, User{Username: username, PasswordHash: "other"}); !errors.Is(err, ErrDuplicateUser) {
+		t.Fatalf("expected ErrDuplicateUser, got %v", err)
+	}
+
+	byName, err := GetUserByUsername(testMongoClient, DB_NAME, username)
+	if err != nil || byName.ID != id || byName.PasswordHash != "hash" || byName.CreatedAt == 0 {
+		t.Fatalf("unexpected user %+v (err %v)", byName, err)
+	}
+	byID, err := GetUserByID(testMongoClient, DB_NAME, id)
+	if err != nil || byID.Username != username {
+		t.Fatalf("unexpected user %+v (err %v)", byID, err)
+	}
+	if _, err := GetUserByID(testMongoClient, DB_NAME, primitive.NewObjectID()); !errors.Is(err, mongo.ErrNoDocuments) {
+		t.Fatalf("expected mongo.ErrNoDocuments for a missing user, got %v", err)
+	}
+}
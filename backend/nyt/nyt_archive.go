@@ -0,0 +1,152 @@
+package nyt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// The first month covered by the archive
+var firstArchiveMonth = time.Date(1851, time.September, 1, 0, 0, 0, 0, time.UTC)
+
+// The response of the Archive API, every article published in a month
+type NYTArchiveResponse struct {
+	Copyright string `json:"copyright"`
+	Response  struct {
+		Docs []NYTArticle `json:"docs"`
+		Meta struct {
+			Hits int `json:"hits"`
+		} `json:"meta"`
+	} `json:"response"`
+}
+
+// A single article of the archive. Older articles leave many fields empty, so they decode to zero values.
+type NYTArticle struct {
+	ID             string          `json:"_id"` // e.g. nyt://article/5f5a7e8c-...
+	URI            string          `json:"uri"`
+	WebURL         string          `json:"web_url"`
+	Abstract       string          `json:"abstract"`
+	Snippet        string          `json:"snippet"`
+	LeadParagraph  string          `json:"lead_paragraph"`
+	Source         string          `json:"source"`
+	Headline       NYTHeadline     `json:"headline"`
+	Keywords       []NYTKeyword    `json:"keywords"`
+	PubDate        NYTTime         `json:"pub_date"`
+	DocumentType   string          `json:"document_type"`
+	NewsDesk       string          `json:"news_desk"`
+	SectionName    string          `json:"section_name"`
+	SubsectionName string          `json:"subsection_name"`
+	Byline         NYTByline       `json:"byline"`
+	TypeOfMaterial string          `json:"type_of_material"`
+	WordCount      int             `json:"word_count"`
+	Multimedia     []NYTMultimedia `json:"multimedia"`
+}
+
+type NYTHeadline struct {
+	Main          string `json:"main"`
+	Kicker        string `json:"kicker"`
+	PrintHeadline string `json:"print_headline"`
+}
+
+// A keyword tagged by the NYT. Name is the kind of keyword (subject, persons, organizations, glocations, ...)
+type NYTKeyword struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	Rank  int    `json:"rank"`
+	Major string `json:"major"`
+}
+
+type NYTByline struct {
+	Original string `json:"original"` // e.g. "By Tripp Mickle"
+}
+
+// An image attached to an article. URL is relative to https://www.nytimes.com/
+type NYTMultimedia struct {
+	URL     string `json:"url"`
+	Type    string `json:"type"`
+	Subtype string `json:"subtype"`
+	Width   int    `json:"width"`
+	Height  int    `json:"height"`
+}
+
+// NYTTime decodes the dates of the archive, which use a numeric zone without a colon (2024-11-01T09:00:12+0000)
+type NYTTime struct {
+	time.Time
+}
+
+func (nytTime *NYTTime) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	if value == "" {
+		nytTime.Time = time.Time{}
+		return nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05-0700", time.RFC3339} {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			nytTime.Time = parsed.UTC()
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid nyt date %q", value)
+}
+
+func (nytTime NYTTime) MarshalJSON() ([]byte, error) {
+	if nytTime.IsZero() {
+		return json.Marshal("")
+	}
+	return json.Marshal(nytTime.UTC().Format("2006-01-02T15:04:05-0700"))
+}
+
+// Author returns the byline without its leading "By "
+func (article NYTArticle) Author() string {
+	return strings.TrimPrefix(strings.TrimSpace(article.Byline.Original), "By ")
+}
+
+// ImageURL returns the absolute URL of the article's largest image, or "" if it has none
+func (article NYTArticle) ImageURL() string {
+	best := NYTMultimedia{}
+	for _, media := range article.Multimedia {
+		if media.Type == "image" && media.Width*media.Height >= best.Width*best.Height {
+			best = media
+		}
+	}
+	if best.URL == "" || strings.HasPrefix(best.URL, "http") {
+		return best.URL
+	}
+	return "https://www.nytimes.com/" + strings.TrimPrefix(best.URL, "/")
+}
+
+// NYTGetArchive returns every article published by the NYT in a month
+//
+// Input:
+//   - year: the year of the month, from 1851
+//   - month: the month
+//
+// Output:
+//   - *NYTArchiveResponse: the response from the Archive API
+//   - error: any error that occurred, ErrDailyLimitReached if the connection's daily budget is spent
+func (nytConnection *NYTConnection) NYTGetArchive(year int, month time.Month) (*NYTArchiveResponse, error) {
+	return nytConnection.NYTGetArchiveWithContext(context.Background(), year, month)
+}
+
+// NYTGetArchiveWithContext is NYTGetArchive bounded by ctx
+func (nytConnection *NYTConnection) NYTGetArchiveWithContext(ctx context.Context, year int, month time.Month) (*NYTArchiveResponse, error) {
+	if month < time.January || month > time.December {
+		return nil, fmt.Errorf("invalid month %d", month)
+	}
+	if time.Date(year, month, 1, 0, 0, 0, 0, time.UTC).Before(firstArchiveMonth) {
+		return nil, fmt.Errorf("the archive starts in %s", firstArchiveMonth.Format("January 2006"))
+	}
+
+	url := fmt.Sprintf("%s/%d/%d.json", nytConnection.baseURL, year, int(month))
+	response, err := genericNYTGetRequest[NYTArchiveResponse](ctx, nytConnection, url)
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("error getting the nyt archive of %d/%d", year, int(month)), err)
+	}
+	return response, nil
+}
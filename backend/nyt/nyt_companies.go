@@ -0,0 +1,117 @@
+package nyt
+
+// This file tags archive articles with the tickers of the companies they are about.
+//
+// The archive has no tickers, so articles are matched by company name: an "organizations" keyword
+// naming the company is trusted on any desk, while a name that only appears in the headline or abstract
+// counts on the business and technology desks, so a recipe for apple pie is not tagged AAPL.
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// The desks and sections whose articles may be tagged from their headline or abstract alone
+var businessDesks = map[string]bool{
+	"business":       true,
+	"business day":   true,
+	"dealbook":       true,
+	"sundaybusiness": true,
+	"technology":     true,
+	"your money":     true,
+}
+
+// Share classes and listing details that ticker references append to company names
+var shareDescriptionPattern = regexp.MustCompile(`(?i)\s+(class [a-z]\b|common stock|ordinary shares|american depositary shares|depositary shares|\(the\)).*$`)
+
+// Legal suffixes dropped from the end of company names, e.g. "Apple Inc." and "Microsoft Corp"
+var legalSuffixes = []string{"incorporated", "corporation", "company", "limited", "holdings", "inc", "corp", "co", "ltd", "plc", "llc", "sa", "nv", "ag", "se"}
+
+// NYTCompanyMatcher finds the tickers an article is about from the names of their companies
+type NYTCompanyMatcher struct {
+	companies []nytCompany
+}
+
+type nytCompany struct {
+	symbol  string
+	name    string // Normalized, e.g. Apple for "Apple Inc."
+	pattern *regexp.Regexp
+}
+
+// NewNYTCompanyMatcher creates a matcher for a set of companies
+//
+// Input:
+//   - names: the company name of every ticker, e.g. "AAPL": "Apple Inc."
+//
+// Output:
+//   - *NYTCompanyMatcher: the matcher. Names that are empty once normalized are ignored.
+func NewNYTCompanyMatcher(names map[string]string) *NYTCompanyMatcher {
+	matcher := &NYTCompanyMatcher{}
+	for symbol, name := range names {
+		normalized := NormalizeCompanyName(name)
+		if len(normalized) < 2 {
+			continue
+		}
+
+		// Names listed in capitals (NVIDIA Corp) are usually written in title case by the NYT (Nvidia)
+		alternatives := []string{regexp.QuoteMeta(normalized)}
+		if len(normalized) > 4 && normalized == strings.ToUpper(normalized) {
+			alternatives = append(alternatives, regexp.QuoteMeta(normalized[:1]+strings.ToLower(normalized[1:])))
+		}
+		matcher.companies = append(matcher.companies, nytCompany{
+			symbol:  strings.ToUpper(symbol),
+			name:    normalized,
+			pattern: regexp.MustCompile(`\b(?:` + strings.Join(alternatives, "|") + `)\b`),
+		})
+	}
+	slices.SortFunc(matcher.companies, func(a, b nytCompany) int { return strings.Compare(a.symbol, b.symbol) })
+	return matcher
+}
+
+// Len returns the number of companies the matcher looks for
+func (matcher *NYTCompanyMatcher) Len() int {
+	return len(matcher.companies)
+}
+
+// Match returns the tickers of the companies article is about, sorted, or nil if there are none
+func (matcher *NYTCompanyMatcher) Match(article NYTArticle) []string {
+	organizations := []string{}
+	for _, keyword := range article.Keywords {
+		if keyword.Name == "organizations" {
+			organizations = append(organizations, NormalizeCompanyName(keyword.Value))
+		}
+	}
+	onBusinessDesk := businessDesks[strings.ToLower(article.NewsDesk)] || businessDesks[strings.ToLower(article.SectionName)]
+	text := article.Headline.Main + "\n" + article.Abstract
+
+	var symbols []string
+	for _, company := range matcher.companies {
+		tagged := slices.ContainsFunc(organizations, func(organization string) bool {
+			return strings.EqualFold(organization, company.name)
+		})
+		if tagged || (onBusinessDesk && company.pattern.MatchString(text)) {
+			symbols = append(symbols, company.symbol)
+		}
+	}
+	return symbols
+}
+
+// NormalizeCompanyName strips share classes, legal suffixes and punctuation from a company name,
+// e.g. "Alphabet Inc. Class A Common Stock" becomes "Alphabet"
+func NormalizeCompanyName(name string) string {
+	name = shareDescriptionPattern.ReplaceAllString(strings.TrimSpace(name), "")
+	name = strings.TrimPrefix(name, "The ")
+
+	for {
+		trimmed := strings.TrimRightFunc(name, func(r rune) bool {
+			return unicode.IsSpace(r) || r == ',' || r == '.' || r == '&'
+		})
+		lastSpace := strings.LastIndexFunc(trimmed, unicode.IsSpace)
+		if lastSpace < 0 || !slices.Contains(legalSuffixes, strings.ToLower(strings.TrimSuffix(trimmed[lastSpace+1:], "."))) {
+			return trimmed
+		}
+		name = trimmed[:lastSpace]
+	}
+}
@@ -0,0 +1,143 @@
+package nyt
+
+// This file contains the typed errors returned by requests to the NYT APIs.
+//
+// Every error can be inspected with errors.As, even after being wrapped with errors.Join:
+//
+//	var unauthorized *nyt.NYTUnauthorizedError
+//	if errors.As(err, &unauthorized) { ... }
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// ErrDailyLimitReached is returned instead of sending a request once the daily request budget is spent.
+// Waiting for it to free up could take hours, so the caller decides whether to stop or come back later.
+var ErrDailyLimitReached = errors.New("nyt daily request limit reached")
+
+// NYTRateLimitError is returned when the NYT responds with 429 Too Many Requests
+type NYTRateLimitError struct {
+	URL     string
+	Message string
+}
+
+func (err *NYTRateLimitError) Error() string {
+	return fmt.Sprintf("nyt rate limit reached for %s: %s", err.URL, err.Message)
+}
+
+// NYTUnauthorizedError is returned when the NYT responds with 401 or 403,
+// which means the API key is invalid or the Archive API is not enabled for its app
+type NYTUnauthorizedError struct {
+	URL        string
+	StatusCode int
+	Message    string
+}
+
+func (err *NYTUnauthorizedError) Error() string {
+	return fmt.Sprintf("nyt refused %s with status %d: %s", err.URL, err.StatusCode, err.Message)
+}
+
+// NYTRequestError is returned for any other non-2XX status code
+type NYTRequestError struct {
+	URL        string
+	StatusCode int
+	Message    string
+}
+
+func (err *NYTRequestError) Error() string {
+	return fmt.Sprintf("nyt rejected %s with status %d: %s", err.URL, err.StatusCode, err.Message)
+}
+
+// NYTTransportError is returned when the request could not be sent or the response could not be read
+type NYTTransportError struct {
+	URL string
+	Err error
+}
+
+func (err *NYTTransportError) Error() string {
+	return fmt.Sprintf("error sending/receiving request to %s: %v", err.URL, err.Err)
+}
+
+func (err *NYTTransportError) Unwrap() error {
+	return err.Err
+}
+
+// NYTDecodeError is returned when a successful response could not be decoded
+type NYTDecodeError struct {
+	URL string
+	Err error
+}
+
+func (err *NYTDecodeError) Error() string {
+	return fmt.Sprintf("error decoding response from %s: %v", err.URL, err.Err)
+}
+
+func (err *NYTDecodeError) Unwrap() error {
+	return err.Err
+}
+
+// IsFatalNYTError reports whether err means that no further request will succeed today,
+// so a long running job should stop instead of skipping to its next step
+func IsFatalNYTError(err error) bool {
+	var unauthorizedErr *NYTUnauthorizedError
+	return errors.Is(err, ErrDailyLimitReached) || errors.As(err, &unauthorizedErr)
+}
+
+// Converts a non-2XX response into the matching typed error.
+// The response body is read (but not closed) to extract the NYT's error message.
+func newNYTStatusError(res *http.Response, requestURL string) error {
+	scrubbedURL := scrubNYTURL(requestURL)
+	message := readNYTErrorMessage(res.Body)
+
+	switch {
+	case res.StatusCode == http.StatusTooManyRequests:
+		return &NYTRateLimitError{URL: scrubbedURL, Message: message}
+	case res.StatusCode == http.StatusUnauthorized || res.StatusCode == http.StatusForbidden:
+		return &NYTUnauthorizedError{URL: scrubbedURL, StatusCode: res.StatusCode, Message: message}
+	default:
+		return &NYTRequestError{URL: scrubbedURL, StatusCode: res.StatusCode, Message: message}
+	}
+}
+
+// Extracts the message of an error response. The NYT answers errors with an Apigee fault:
+// {"fault": {"faultstring": "Invalid ApiKey", "detail": {"errorcode": "oauth.v2.InvalidApiKey"}}}
+func readNYTErrorMessage(body io.Reader) string {
+	data, err := io.ReadAll(io.LimitReader(body, 4096))
+	if err != nil || len(data) == 0 {
+		return ""
+	}
+	var decoded struct {
+		Fault *struct {
+			FaultString string `json:"faultstring"`
+		} `json:"fault"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(data, &decoded); err == nil {
+		if decoded.Fault != nil && decoded.Fault.FaultString != "" {
+			return decoded.Fault.FaultString
+		}
+		if decoded.Message != "" {
+			return decoded.Message
+		}
+	}
+	return string(data)
+}
+
+// Removes the api-key parameter from a URL so it can be logged or returned in errors
+func scrubNYTURL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "<invalid url>"
+	}
+	query := parsed.Query()
+	if query.Has("api-key") {
+		query.Set("api-key", "REDACTED")
+		parsed.RawQuery = query.Encode()
+	}
+	return parsed.String()
+}
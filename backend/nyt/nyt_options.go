@@ -0,0 +1,79 @@
+package nyt
+
+import (
+	"net/http"
+	"time"
+)
+
+// The URL of the real Archive API, used unless WithBaseURL is given
+const DefaultBaseURL = "https://api.nytimes.com/svc/archive/v1"
+
+const (
+	// The NYT asks for 12 seconds between calls to stay under its limit of 5 requests per minute
+	DefaultMinInterval = 12 * time.Second
+	// Every key may send at most 500 requests per day
+	DefaultDailyLimit = 500
+	// An archive month is a single response of up to ~20MB, so attempts get more time than Polygon's
+	DefaultRequestTimeout = 2 * time.Minute
+)
+
+// Clock abstracts time so tests can control the rate limiting of a connection
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+type nytConnectionConfig struct {
+	baseURL        string
+	httpClient     *http.Client
+	clock          Clock
+	minInterval    time.Duration
+	dailyLimit     int
+	requestTimeout time.Duration
+}
+
+// NYTConnectionOption customizes a connection created by GetNYTConnection
+type NYTConnectionOption func(*nytConnectionConfig)
+
+// WithBaseURL sends requests to baseURL (e.g. a fake server) instead of the real Archive API
+func WithBaseURL(baseURL string) NYTConnectionOption {
+	return func(config *nytConnectionConfig) {
+		config.baseURL = baseURL
+	}
+}
+
+// WithHTTPClient sends requests with client instead of http.DefaultClient
+func WithHTTPClient(client *http.Client) NYTConnectionOption {
+	return func(config *nytConnectionConfig) {
+		config.httpClient = client
+	}
+}
+
+// WithClock makes the connection read the time and wait with clock instead of the system clock
+func WithClock(clock Clock) NYTConnectionOption {
+	return func(config *nytConnectionConfig) {
+		config.clock = clock
+	}
+}
+
+// WithRateLimit sets the minimum delay between two requests and the number of requests allowed
+// in any 24 hour window. Set dailyLimit <= 0 to disable the daily limit.
+func WithRateLimit(minInterval time.Duration, dailyLimit int) NYTConnectionOption {
+	return func(config *nytConnectionConfig) {
+		config.minInterval = minInterval
+		config.dailyLimit = dailyLimit
+	}
+}
+
+// WithRequestTimeout limits every attempt of a request to timeout (DefaultRequestTimeout by default).
+// Set timeout <= 0 to rely on the caller's context only.
+func WithRequestTimeout(timeout time.Duration) NYTConnectionOption {
+	return func(config *nytConnectionConfig) {
+		config.requestTimeout = timeout
+	}
+}
@@ -0,0 +1,257 @@
+// Package nyt wraps the New York Times APIs used by the backend
+// https://developer.nytimes.com/apis
+//
+// All calls to the NYT should be directed through this package, which enforces the limits of a key:
+// 5 requests per minute (12 seconds between calls) and 500 requests per day.
+package nyt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var errLogger *log.Logger = log.New(os.Stderr, "ERROR: ", log.LstdFlags|log.Lshortfile)
+
+// How long to wait before retrying a request the NYT rate limited, so the per minute window is over
+const rateLimitBackoff = time.Minute
+
+// Total number of attempts of a request that failed with a retryable error
+const maxAttempts = 2
+
+type NYTConnection struct {
+	apiKey     string
+	baseURL    string
+	httpClient *http.Client
+	clock      Clock
+	limiter    *nytRateLimiter
+	// Limit for a single attempt of a request, on top of the caller's context
+	requestTimeout time.Duration
+}
+
+// GetNYTConnection creates a connection that sends requests with apiKey
+//
+// Input:
+//   - apiKey: the NYT API key requests are made with
+//   - options: optional overrides (WithRateLimit, WithBaseURL, WithHTTPClient, WithClock, ...)
+//
+// Output:
+//   - *NYTConnection: the connection
+func GetNYTConnection(apiKey string, options ...NYTConnectionOption) *NYTConnection {
+	config := nytConnectionConfig{
+		baseURL:        DefaultBaseURL,
+		httpClient:     http.DefaultClient,
+		clock:          systemClock{},
+		minInterval:    DefaultMinInterval,
+		dailyLimit:     DefaultDailyLimit,
+		requestTimeout: DefaultRequestTimeout,
+	}
+	for _, option := range options {
+		option(&config)
+	}
+
+	return &NYTConnection{
+		apiKey:         apiKey,
+		baseURL:        strings.TrimSuffix(config.baseURL, "/"),
+		httpClient:     config.httpClient,
+		clock:          config.clock,
+		limiter:        &nytRateLimiter{minInterval: config.minInterval, dailyLimit: config.dailyLimit, now: config.clock.Now},
+		requestTimeout: config.requestTimeout,
+	}
+}
+
+// RemainingToday returns how many requests the connection may still send in the current 24 hour window,
+// or -1 if it has no daily limit
+func (nytConnection *NYTConnection) RemainingToday() int {
+	return nytConnection.limiter.remaining()
+}
+
+// Blocks for d according to the connection's clock, or until ctx is done
+func (nytConnection *NYTConnection) sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	select {
+	case <-nytConnection.clock.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends a GET request to the NYT and decodes the response into T
+//
+// The API key is attached by this function, and every attempt waits for the connection's rate limiter.
+// A request that is rate limited, fails with a 5XX status code or does not reach the NYT is retried once.
+//
+// Input:
+//   - ctx: cancelling it aborts the request in flight as well as any wait for the rate limiter
+//   - url: the url to send the request to, without an api-key parameter
+//
+// Output:
+//   - *T: the decoded response
+//   - error: any error that occurred. Errors are typed (NYTUnauthorizedError, ErrDailyLimitReached, ...)
+//     and can be inspected with errors.As
+func genericNYTGetRequest[T any](ctx context.Context, nytConnection *NYTConnection, url string) (*T, error) {
+	wait := func(d time.Duration) error {
+		return nytConnection.sleep(ctx, d)
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if attempt > 1 {
+			var rateLimitErr *NYTRateLimitError
+			if errors.As(lastErr, &rateLimitErr) {
+				errLogger.Printf("Retrying nyt request in %s after error: %v", rateLimitBackoff, lastErr)
+				if err := wait(rateLimitBackoff); err != nil {
+					return nil, errors.Join(err, lastErr)
+				}
+			}
+		}
+
+		if err := nytConnection.limiter.acquire(wait); err != nil {
+			return nil, errors.Join(err, lastErr)
+		}
+		keyedURL, err := withNYTKey(url, nytConnection.apiKey)
+		if err != nil {
+			return nil, err
+		}
+
+		response, err := sendNYTGetRequest[T](ctx, nytConnection.httpClient, nytConnection.requestTimeout, keyedURL)
+		if err == nil {
+			return response, nil
+		}
+		lastErr = err
+		// A cancelled caller must not be retried, even though the failure looks like a network error
+		if ctx.Err() != nil || !isRetryableNYTError(err) {
+			break
+		}
+	}
+
+	return nil, lastErr
+}
+
+// Sends a single GET request to the NYT and decodes the response
+func sendNYTGetRequest[T any](ctx context.Context, client *http.Client, timeout time.Duration, url string) (*T, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, errors.Join(errors.New("error generating request for the nyt"), err)
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, &NYTTransportError{URL: scrubNYTURL(url), Err: err}
+	}
+	defer res.Body.Close()
+
+	if !(res.StatusCode >= http.StatusOK && res.StatusCode < http.StatusMultipleChoices) {
+		return nil, newNYTStatusError(res, url)
+	}
+
+	var decodedBody T
+	if err := json.NewDecoder(res.Body).Decode(&decodedBody); err != nil {
+		return nil, &NYTDecodeError{URL: scrubNYTURL(url), Err: err}
+	}
+	return &decodedBody, nil
+}
+
+// Reports whether a request that failed with err may succeed if sent again
+func isRetryableNYTError(err error) bool {
+	var rateLimitErr *NYTRateLimitError
+	var transportErr *NYTTransportError
+	var requestErr *NYTRequestError
+	if errors.As(err, &requestErr) && requestErr.StatusCode >= http.StatusInternalServerError {
+		return true
+	}
+	return errors.As(err, &rateLimitErr) || errors.As(err, &transportErr)
+}
+
+// Returns rawURL with the api-key parameter set to key
+func withNYTKey(rawURL string, key string) (string, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "", errors.Join(errors.New("invalid nyt url"), err)
+	}
+	query := parsed.Query()
+	query.Set("api-key", key)
+	parsed.RawQuery = query.Encode()
+	return parsed.String(), nil
+}
+
+// Spaces requests out by minInterval and counts them over a rolling 24 hour window
+type nytRateLimiter struct {
+	mu          sync.Mutex
+	minInterval time.Duration
+	dailyLimit  int
+	sent        []time.Time // Requests sent in the last 24 hours, oldest first
+	now         func() time.Time
+}
+
+// Drops the requests that left the 24 hour window. Must be called with the limiter locked.
+func (limiter *nytRateLimiter) prune(now time.Time) {
+	cutoff := now.Add(-24 * time.Hour)
+	kept := 0
+	for kept < len(limiter.sent) && !limiter.sent[kept].After(cutoff) {
+		kept++
+	}
+	limiter.sent = limiter.sent[kept:]
+}
+
+// Records a request if one can be sent right now, otherwise returns how long until one can.
+// Fails with ErrDailyLimitReached when the daily budget is spent.
+func (limiter *nytRateLimiter) tryAcquire() (time.Duration, error) {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	now := limiter.now()
+	limiter.prune(now)
+	if limiter.dailyLimit > 0 && len(limiter.sent) >= limiter.dailyLimit {
+		return 0, ErrDailyLimitReached
+	}
+	if len(limiter.sent) > 0 && limiter.minInterval > 0 {
+		if wait := limiter.sent[len(limiter.sent)-1].Add(limiter.minInterval).Sub(now); wait > 0 {
+			return wait, nil
+		}
+	}
+	limiter.sent = append(limiter.sent, now)
+	return 0, nil
+}
+
+// Blocks until a request can be sent. wait is called with the time until then, and aborts if it fails.
+func (limiter *nytRateLimiter) acquire(wait func(time.Duration) error) error {
+	for {
+		delay, err := limiter.tryAcquire()
+		if err != nil {
+			return err
+		}
+		if delay <= 0 {
+			return nil
+		}
+		if err := wait(delay); err != nil {
+			return err
+		}
+	}
+}
+
+// Returns the number of requests left in the current 24 hour window, or -1 without a daily limit
+func (limiter *nytRateLimiter) remaining() int {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	if limiter.dailyLimit <= 0 {
+		return -1
+	}
+	limiter.prune(limiter.now())
+	return max(0, limiter.dailyLimit-len(limiter.sent))
+}
@@ -0,0 +1,181 @@
+package nyt
+
+import (
+	"errors"
+	"financial-helper/nyt/nyttest"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+var testNow = time.Date(2024, 12, 2, 15, 0, 0, 0, time.UTC)
+
+// Clock that never blocks and records every wait, so rate limiting can be tested instantly
+type testRecordingClock struct {
+	mu    sync.Mutex
+	now   time.Time
+	waits []time.Duration
+}
+
+func (clock *testRecordingClock) Now() time.Time {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	return clock.now
+}
+
+func (clock *testRecordingClock) After(d time.Duration) <-chan time.Time {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	clock.now = clock.now.Add(d)
+	clock.waits = append(clock.waits, d)
+	channel := make(chan time.Time, 1)
+	channel <- clock.now
+	return channel
+}
+
+func newTestConnection(t *testing.T, key string, options ...NYTConnectionOption) (*NYTConnection, *nyttest.Server) {
+	t.Helper()
+	fake := nyttest.NewServer()
+	t.Cleanup(fake.Close)
+	options = append([]NYTConnectionOption{WithBaseURL(fake.URL), WithHTTPClient(fake.Client()), WithRateLimit(0, 0)}, options...)
+	return GetNYTConnection(key, options...), fake
+}
+
+func TestNYTGetArchive(t *testing.T) {
+	connection, fake := newTestConnection(t, "test-key")
+
+	archive, err := connection.NYTGetArchive(2024, time.November)
+	if err != nil {
+		t.Fatalf("NYTGetArchive error: %v", err)
+	}
+	if len(archive.Response.Docs) != 5 || archive.Response.Meta.Hits != 5 {
+		t.Fatalf("expected 5 articles, got %d (%d hits)", len(archive.Response.Docs), archive.Response.Meta.Hits)
+	}
+	if requests := fake.Requests(); len(requests) != 1 || requests[0] != "/2024/11.json" {
+		t.Fatalf("unexpected requests %v", requests)
+	}
+
+	first := archive.Response.Docs[0]
+	if !first.PubDate.Equal(time.Date(2024, 11, 1, 0, 12, 41, 0, time.UTC)) {
+		t.Fatalf("unexpected pub_date %s", first.PubDate)
+	}
+	if first.Author() != "Tripp Mickle" {
+		t.Fatalf("unexpected author %q", first.Author())
+	}
+	if first.ImageURL() != "https://www.nytimes.com/images/2024/10/31/multimedia/31apple-earnings-superJumbo.jpg" {
+		t.Fatalf("expected the largest image, got %q", first.ImageURL())
+	}
+
+	empty, err := connection.NYTGetArchive(2024, time.December)
+	if err != nil || len(empty.Response.Docs) != 0 {
+		t.Fatalf("expected an empty month, got %v and %v", empty, err)
+	}
+	if _, err := connection.NYTGetArchive(1850, time.January); err == nil {
+		t.Fatal("expected an error for a month before the archive starts")
+	}
+}
+
+func TestNYTConnection_RateLimit(t *testing.T) {
+	clock := &testRecordingClock{now: testNow}
+	connection, _ := newTestConnection(t, "test-key", WithClock(clock), WithRateLimit(DefaultMinInterval, 3))
+
+	for month := time.September; month <= time.November; month++ {
+		if _, err := connection.NYTGetArchive(2024, month); err != nil {
+			t.Fatalf("request for month %d: %v", month, err)
+		}
+	}
+	if !slices.Equal(clock.waits, []time.Duration{DefaultMinInterval, DefaultMinInterval}) {
+		t.Fatalf("expected 12s between requests, waited %v", clock.waits)
+	}
+	if remaining := connection.RemainingToday(); remaining != 0 {
+		t.Fatalf("expected no request left today, got %d", remaining)
+	}
+
+	// The daily limit fails fast instead of waiting for hours
+	_, err := connection.NYTGetArchive(2024, time.December)
+	if !errors.Is(err, ErrDailyLimitReached) || !IsFatalNYTError(err) {
+		t.Fatalf("expected the daily limit to be reached, got %v", err)
+	}
+
+	clock.After(24 * time.Hour)
+	if _, err := connection.NYTGetArchive(2024, time.December); err != nil {
+		t.Fatalf("expected the daily limit to reset after 24 hours, got %v", err)
+	}
+}
+
+func TestNYTConnection_RetriesRateLimitedRequest(t *testing.T) {
+	clock := &testRecordingClock{now: testNow}
+	connection, fake := newTestConnection(t, "test-key", WithClock(clock))
+	fake.FailNext(http.StatusTooManyRequests, 1)
+
+	if _, err := connection.NYTGetArchive(2024, time.November); err != nil {
+		t.Fatalf("expected the retry to succeed, got %v", err)
+	}
+	if len(fake.Requests()) != 2 || !slices.Equal(clock.waits, []time.Duration{rateLimitBackoff}) {
+		t.Fatalf("expected one retry after %s, got %d requests and waits %v", rateLimitBackoff, len(fake.Requests()), clock.waits)
+	}
+}
+
+func TestNYTConnection_RejectedKey(t *testing.T) {
+	connection, fake := newTestConnection(t, "revoked-key")
+	fake.RejectKey("revoked-key")
+
+	_, err := connection.NYTGetArchive(2024, time.November)
+	var unauthorized *NYTUnauthorizedError
+	if !errors.As(err, &unauthorized) || !IsFatalNYTError(err) {
+		t.Fatalf("expected an unauthorized error, got %v", err)
+	}
+	if unauthorized.Message != "Invalid ApiKey" {
+		t.Fatalf("expected the fault message, got %q", unauthorized.Message)
+	}
+	if len(fake.Requests()) != 1 {
+		t.Fatalf("expected a rejected key not to be retried, got %d requests", len(fake.Requests()))
+	}
+}
+
+func TestNormalizeCompanyName(t *testing.T) {
+	cases := map[string]string{
+		"Apple Inc.":                         "Apple",
+		"Apple Inc":                          "Apple",
+		"Microsoft Corp":                     "Microsoft",
+		"Alphabet Inc. Class A Common Stock": "Alphabet",
+		"NVIDIA Corporation":                 "NVIDIA",
+		"Amazon.com, Inc.":                   "Amazon.com",
+		"The Coca-Cola Company":              "Coca-Cola",
+		"Procter & Gamble Co":                "Procter & Gamble",
+		"Johnson & Johnson":                  "Johnson & Johnson",
+	}
+	for name, expected := range cases {
+		if normalized := NormalizeCompanyName(name); normalized != expected {
+			t.Errorf("NormalizeCompanyName(%q) = %q, expected %q", name, normalized, expected)
+		}
+	}
+}
+
+func TestNYTCompanyMatcher(t *testing.T) {
+	connection, _ := newTestConnection(t, "test-key")
+	archive, err := connection.NYTGetArchive(2024, time.November)
+	if err != nil {
+		t.Fatalf("NYTGetArchive error: %v", err)
+	}
+
+	matcher := NewNYTCompanyMatcher(map[string]string{
+		"AAPL": "Apple Inc.",
+		"MSFT": "Microsoft Corp",
+		"nvda": "NVIDIA Corp",
+	})
+	expected := [][]string{
+		{"AAPL"}, // Organizations keyword
+		nil,      // A recipe mentioning apples is not about Apple
+		{"MSFT"}, // Organizations keyword, although the headline names no company
+		{"NVDA"}, // Headline on the business desk, written in title case
+		nil,
+	}
+	for i, article := range archive.Response.Docs {
+		if symbols := matcher.Match(article); !slices.Equal(symbols, expected[i]) {
+			t.Errorf("article %d (%s): matched %v, expected %v", i, article.Headline.Main, symbols, expected[i])
+		}
+	}
+}
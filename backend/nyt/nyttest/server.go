@@ -0,0 +1,136 @@
+// Package nyttest provides a fake NYT Archive API, so code that talks to the NYT can be tested
+// without network access or an API key.
+//
+//	fake := nyttest.NewServer()
+//	defer fake.Close()
+//	connection := nyt.GetNYTConnection("test-key", nyt.WithBaseURL(fake.URL), nyt.WithHTTPClient(fake.Client()), nyt.WithRateLimit(0, 0))
+package nyttest
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//go:embed fixtures/*.json
+var fixtures embed.FS
+
+// Server is a fake NYT Archive API backed by an httptest.Server
+type Server struct {
+	*httptest.Server
+
+	mu           sync.Mutex
+	requests     []string
+	failures     []int
+	rejectedKeys map[string]bool
+
+	// The articles of every month keyed by "year/month" (e.g. "2024/11"), exported so tests can change them.
+	// Months without an entry are served as an empty archive, like months the NYT has not published yet.
+	Archives map[string][]map[string]any
+}
+
+// NewServer starts a fake Archive API serving the embedded fixtures. Callers must Close it.
+func NewServer() *Server {
+	server := &Server{rejectedKeys: map[string]bool{}}
+
+	data, err := fixtures.ReadFile("fixtures/archive.json")
+	if err != nil {
+		panic(fmt.Sprintf("nyttest: missing fixture: %v", err))
+	}
+	if err := json.Unmarshal(data, &server.Archives); err != nil {
+		panic(fmt.Sprintf("nyttest: invalid fixture: %v", err))
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{year}/{month}", server.handleArchive)
+	server.Server = httptest.NewServer(server.middleware(mux))
+	return server
+}
+
+// FailNext makes the next `times` requests fail with status
+func (server *Server) FailNext(status int, times int) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	for i := 0; i < times; i++ {
+		server.failures = append(server.failures, status)
+	}
+}
+
+// RejectKey makes every request sent with key fail with 401 Unauthorized
+func (server *Server) RejectKey(key string) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.rejectedKeys[key] = true
+}
+
+// Requests returns the path of every request received so far
+func (server *Server) Requests() []string {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	return append([]string{}, server.requests...)
+}
+
+// Records requests and applies key rejections and queued failures before routing
+func (server *Server) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.URL.Query().Get("api-key")
+
+		server.mu.Lock()
+		server.requests = append(server.requests, r.URL.Path)
+		rejected := server.rejectedKeys[key]
+		failure := 0
+		if len(server.failures) > 0 {
+			failure = server.failures[0]
+			server.failures = server.failures[1:]
+		}
+		server.mu.Unlock()
+
+		switch {
+		case key == "" || rejected:
+			writeFault(w, http.StatusUnauthorized, "Invalid ApiKey")
+		case failure != 0:
+			writeFault(w, failure, "injected failure")
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+func (server *Server) handleArchive(w http.ResponseWriter, r *http.Request) {
+	year, yearErr := strconv.Atoi(r.PathValue("year"))
+	month, monthErr := strconv.Atoi(strings.TrimSuffix(r.PathValue("month"), ".json"))
+	if yearErr != nil || monthErr != nil || month < 1 || month > 12 {
+		writeFault(w, http.StatusBadRequest, "Invalid year or month")
+		return
+	}
+
+	server.mu.Lock()
+	docs := server.Archives[fmt.Sprintf("%d/%d", year, month)]
+	server.mu.Unlock()
+	if docs == nil {
+		docs = []map[string]any{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"copyright": "Copyright (c) 2024 The New York Times Company. All Rights Reserved.",
+		"response": map[string]any{
+			"docs": docs,
+			"meta": map[string]any{"hits": len(docs)},
+		},
+	})
+}
+
+func writeFault(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]any{"fault": map[string]any{"faultstring": message, "detail": map[string]any{"errorcode": "fake"}}})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
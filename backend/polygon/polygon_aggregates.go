@@ -0,0 +1,151 @@
+package polygon
+
+// This file contains the typed API for Polygon's custom bars endpoint
+// https://polygon.io/docs/stocks/get_v2_aggs_ticker__stocksticker__range__multiplier___timespan___from___to
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// PolygonTimespan is the unit of the window covered by a single aggregate bar
+type PolygonTimespan string
+
+const (
+	TimespanMinute  PolygonTimespan = "minute"
+	TimespanHour    PolygonTimespan = "hour"
+	TimespanDay     PolygonTimespan = "day"
+	TimespanWeek    PolygonTimespan = "week"
+	TimespanMonth   PolygonTimespan = "month"
+	TimespanQuarter PolygonTimespan = "quarter"
+	TimespanYear    PolygonTimespan = "year"
+)
+
+// Valid reports whether the timespan is one Polygon accepts
+func (timespan PolygonTimespan) Valid() bool {
+	switch timespan {
+	case TimespanMinute, TimespanHour, TimespanDay, TimespanWeek, TimespanMonth, TimespanQuarter, TimespanYear:
+		return true
+	}
+	return false
+}
+
+// Intraday reports whether bars of this timespan are shorter than a day
+func (timespan PolygonTimespan) Intraday() bool {
+	return timespan == TimespanMinute || timespan == TimespanHour
+}
+
+// PolygonSortOrder is the order bars are returned in, by timestamp
+type PolygonSortOrder string
+
+const (
+	SortAscending  PolygonSortOrder = "asc"
+	SortDescending PolygonSortOrder = "desc"
+)
+
+const (
+	// Number of bars returned when PolygonAggregatesRequest.Limit is not set
+	DefaultAggregatesLimit = 5000
+	// The largest limit Polygon accepts
+	MaxAggregatesLimit = 50000
+)
+
+// PolygonAggregatesRequest selects the bars returned by PolygonGetTickerAggregates
+type PolygonAggregatesRequest struct {
+	Symbol     string
+	Multiplier int             // Number of timespans per bar, 1 if not set
+	Timespan   PolygonTimespan // TimespanDay if not set
+	From       time.Time
+	To         time.Time
+	// Bars are adjusted for splits unless this is set
+	Unadjusted bool
+	Sort       PolygonSortOrder // SortAscending if not set
+	Limit      int              // DefaultAggregatesLimit if <= 0
+}
+
+// Normalize fills in the defaults of a request and checks that it can be sent
+func (request PolygonAggregatesRequest) Normalize() (PolygonAggregatesRequest, error) {
+	if request.Symbol == "" {
+		return request, errors.New("symbol is required")
+	}
+	if request.Multiplier == 0 {
+		request.Multiplier = 1
+	}
+	if request.Multiplier < 0 {
+		return request, fmt.Errorf("invalid multiplier %d", request.Multiplier)
+	}
+	if request.Timespan == "" {
+		request.Timespan = TimespanDay
+	}
+	if !request.Timespan.Valid() {
+		return request, fmt.Errorf("invalid timespan %q", request.Timespan)
+	}
+	if request.From.After(request.To) {
+		return request, errors.New("start date cannot be after end date")
+	}
+	if request.Sort == "" {
+		request.Sort = SortAscending
+	}
+	if request.Sort != SortAscending && request.Sort != SortDescending {
+		return request, fmt.Errorf("invalid sort order %q", request.Sort)
+	}
+	if request.Limit <= 0 {
+		request.Limit = DefaultAggregatesLimit
+	}
+	if request.Limit > MaxAggregatesLimit {
+		return request, fmt.Errorf("limit cannot be above %d", MaxAggregatesLimit)
+	}
+	return request, nil
+}
+
+// Builds the custom bars URL (without an API key) for a normalized request
+func (polygonConnection *PolygonConnection) aggregatesURL(request PolygonAggregatesRequest) string {
+	// Polygon takes dates for daily and longer bars, and millisecond timestamps to bound intraday bars precisely
+	formatBound := func(t time.Time) string {
+		if request.Timespan.Intraday() {
+			return strconv.FormatInt(t.UnixMilli(), 10)
+		}
+		return t.Format("2006-01-02")
+	}
+
+	query := url.Values{}
+	query.Set("adjusted", strconv.FormatBool(!request.Unadjusted))
+	query.Set("sort", string(request.Sort))
+	query.Set("limit", strconv.Itoa(request.Limit))
+	return fmt.Sprintf("%s/v2/aggs/ticker/%s/range/%d/%s/%s/%s?%s", polygonConnection.baseURL, url.PathEscape(request.Symbol), request.Multiplier, request.Timespan, formatBound(request.From), formatBound(request.To), query.Encode())
+}
+
+// PolygonGetTickerAggregates returns the ticker's price bars of any size (e.g. 5 minute, 1 hour or 1 week bars)
+// within the selected time range
+//
+// Input:
+//   - request: the symbol, bar size, time range and options of the request
+//
+// Output:
+//   - *PolygonGetTickerHistoryResponse: the response from the Polygon API
+//   - error: any error that occurred, ErrNoResults if there are no bars in the range
+func (polygonConnection *PolygonConnection) PolygonGetTickerAggregates(request PolygonAggregatesRequest) (*PolygonGetTickerHistoryResponse, error) {
+	return polygonConnection.PolygonGetTickerAggregatesWithContext(context.Background(), request)
+}
+
+// PolygonGetTickerAggregatesWithContext is PolygonGetTickerAggregates bounded by ctx
+func (polygonConnection *PolygonConnection) PolygonGetTickerAggregatesWithContext(ctx context.Context, request PolygonAggregatesRequest) (*PolygonGetTickerHistoryResponse, error) {
+	request, err := request.Normalize()
+	if err != nil {
+		return nil, errors.Join(errors.New("invalid aggregates request"), err)
+	}
+
+	response, err := GenericPolygonGetRequestWithContext[PolygonGetTickerHistoryResponse](ctx, polygonConnection, polygonConnection.aggregatesURL(request))
+	if err != nil && !IsPartialPolygonData(err) {
+		return nil, errors.Join(errors.New("error getting info from polygon"), err)
+	}
+	if response.Results == nil || len(*response.Results) == 0 || response.Count == nil || *response.Count == 0 {
+		return nil, ErrNoResults
+	}
+
+	return response, err
+}
@@ -0,0 +1,269 @@
+package polygon
+
+// This file contains the response cache of a PolygonConnection.
+//
+// GenericPolygonGetRequest looks responses up in the cache before taking a key from the pool, so a hit costs
+// neither request budget nor throttling. Responses are keyed by path and sorted query without the apiKey,
+// like cassettes, and kept for as long as the connection's PolygonCachePolicy allows for their endpoint.
+// Only successful responses are stored.
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+	_ "time/tzdata" // Sessions are in New York time, even in images without a zoneinfo database
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// PolygonCache stores raw Polygon responses until they expire
+type PolygonCache interface {
+	// Get returns the response stored for key and when it expires, or found = false if there is none
+	Get(ctx context.Context, key string) (body []byte, expiresAt time.Time, found bool, err error)
+	// Set stores the response for key until expiresAt
+	Set(ctx context.Context, key string, body []byte, expiresAt time.Time) error
+}
+
+// PolygonCachePolicy returns how long the response of a request may be served from the cache, 0 to never cache it
+type PolygonCachePolicy func(requestURL *url.URL, now time.Time) time.Duration
+
+// PolygonCacheStats counts the lookups of a connection's cache since it was created
+type PolygonCacheStats struct {
+	Enabled  bool  `json:"enabled"`
+	Hits     int64 `json:"hits"`
+	Misses   int64 `json:"misses"`
+	Stores   int64 `json:"stores"`
+	Uncached int64 `json:"uncached"` // Requests whose endpoint the policy never caches
+	Errors   int64 `json:"errors"`   // Failed reads or writes, which are treated as misses
+}
+
+// WithCache serves responses from cache for as long as policy allows, DefaultPolygonCachePolicy if policy is nil
+func WithCache(cache PolygonCache, policy PolygonCachePolicy) PolygonConnectionOption {
+	return func(config *polygonConnectionConfig) {
+		config.cache = cache
+		config.cachePolicy = policy
+	}
+}
+
+const (
+	// Tickers, details, corporate actions, financials and holidays change a few times a day at most
+	referenceCacheTTL = 24 * time.Hour
+	// News keeps coming in during the day
+	newsCacheTTL = 5 * time.Minute
+	// Market status and bars of the current session change by the minute
+	liveCacheTTL = time.Minute
+)
+
+var (
+	newYork = mustLoadLocation("America/New_York")
+
+	aggregatesPathPattern   = regexp.MustCompile(`^/v2/aggs/ticker/[^/]+/range/[^/]+/[^/]+/[^/]+/([^/]+)$`)
+	groupedDailyPathPattern = regexp.MustCompile(`^/v2/aggs/grouped/locale/us/market/stocks/(\d{4}-\d{2}-\d{2})$`)
+)
+
+// DefaultPolygonCachePolicy caches reference data for a day, previous closes until the next session opens,
+// news for 5 minutes, bars of past sessions for a day and anything about the current session for a minute
+func DefaultPolygonCachePolicy(requestURL *url.URL, now time.Time) time.Duration {
+	path := requestURL.Path
+	switch {
+	case strings.HasPrefix(path, "/v3/reference/tickers"), strings.HasPrefix(path, "/v3/reference/dividends"),
+		strings.HasPrefix(path, "/v3/reference/splits"), strings.HasPrefix(path, "/vX/reference/financials"),
+		path == "/v1/marketstatus/upcoming":
+		return referenceCacheTTL
+	case strings.HasPrefix(path, "/v2/aggs/ticker/") && strings.HasSuffix(path, "/prev"):
+		return nextSessionOpen(now).Sub(now)
+	case path == "/v2/reference/news":
+		return newsCacheTTL
+	case path == "/v1/marketstatus/now":
+		return liveCacheTTL
+	}
+
+	// Bars are final once their session has closed
+	today := now.In(newYork).Format("2006-01-02")
+	if match := aggregatesPathPattern.FindStringSubmatch(path); match != nil {
+		to := match[1]
+		// Intraday windows are bounded by millisecond timestamps instead of dates
+		if millis, err := strconv.ParseInt(to, 10, 64); err == nil {
+			to = time.UnixMilli(millis).In(newYork).Format("2006-01-02")
+		}
+		if to < today {
+			return referenceCacheTTL
+		}
+		return liveCacheTTL
+	}
+	if match := groupedDailyPathPattern.FindStringSubmatch(path); match != nil && match[1] < today {
+		return referenceCacheTTL
+	}
+	return 0
+}
+
+// Returns the next weekday at 9:30 in New York after now. Holidays are not known here, so a cached previous
+// close is refreshed on the morning of a holiday, which is harmless.
+func nextSessionOpen(now time.Time) time.Time {
+	local := now.In(newYork)
+	open := time.Date(local.Year(), local.Month(), local.Day(), 9, 30, 0, 0, newYork)
+	for !open.After(local) || open.Weekday() == time.Saturday || open.Weekday() == time.Sunday {
+		open = open.AddDate(0, 0, 1)
+	}
+	return open
+}
+
+func mustLoadLocation(name string) *time.Location {
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return location
+}
+
+// Counters of a connection's cache
+type polygonCacheCounters struct {
+	hits, misses, stores, uncached, errors atomic.Int64
+}
+
+// CacheStats returns the number of cache hits and misses of the connection since it was created
+func (polygonConnection *PolygonConnection) CacheStats() PolygonCacheStats {
+	counters := &polygonConnection.cacheCounters
+	return PolygonCacheStats{
+		Enabled:  polygonConnection.cache != nil,
+		Hits:     counters.hits.Load(),
+		Misses:   counters.misses.Load(),
+		Stores:   counters.stores.Load(),
+		Uncached: counters.uncached.Load(),
+		Errors:   counters.errors.Load(),
+	}
+}
+
+// Returns the cache key of a request and how long its response may be cached, or a zero TTL if the
+// connection has no cache or the request is never cached
+func (polygonConnection *PolygonConnection) cacheLookupKey(rawURL string) (string, time.Duration) {
+	if polygonConnection.cache == nil {
+		return "", 0
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "", 0
+	}
+	ttl := polygonConnection.cachePolicy(parsed, polygonConnection.clock.Now())
+	if ttl <= 0 {
+		polygonConnection.cacheCounters.uncached.Add(1)
+		return "", 0
+	}
+	return cassetteRequestKey(parsed), ttl
+}
+
+// Returns the cached response for key, if there is one that has not expired
+func (polygonConnection *PolygonConnection) cacheGet(ctx context.Context, key string) ([]byte, bool) {
+	body, expiresAt, found, err := polygonConnection.cache.Get(ctx, key)
+	if err != nil {
+		polygonConnection.cacheCounters.errors.Add(1)
+		errLogger.Printf("Error reading %s from the polygon cache: %v", key, err)
+	}
+	if err != nil || !found || !polygonConnection.clock.Now().Before(expiresAt) {
+		polygonConnection.cacheCounters.misses.Add(1)
+		return nil, false
+	}
+	polygonConnection.cacheCounters.hits.Add(1)
+	return body, true
+}
+
+// Stores a response for ttl. Failures are logged, the response is still returned to the caller.
+func (polygonConnection *PolygonConnection) cacheSet(ctx context.Context, key string, body []byte, ttl time.Duration) {
+	if err := polygonConnection.cache.Set(context.WithoutCancel(ctx), key, body, polygonConnection.clock.Now().Add(ttl)); err != nil {
+		polygonConnection.cacheCounters.errors.Add(1)
+		errLogger.Printf("Error writing %s to the polygon cache: %v", key, err)
+		return
+	}
+	polygonConnection.cacheCounters.stores.Add(1)
+}
+
+// The bucket of a bolt cache file responses are stored in
+var boltCacheBucket = []byte("polygon_responses")
+
+// BoltPolygonCache is a PolygonCache stored in a local bbolt file
+type BoltPolygonCache struct {
+	db *bolt.DB
+}
+
+// NewBoltPolygonCache opens (or creates) the cache file at path. Callers must Close it.
+//
+// Input:
+//   - path: the file the cache is stored in, e.g. polygon_cache.db
+//
+// Output:
+//   - *BoltPolygonCache: the cache
+//   - error: any error that occurred, e.g. if another process holds the file
+func NewBoltPolygonCache(path string) (*BoltPolygonCache, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, errors.Join(errors.New("error opening polygon cache file"), err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltCacheBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, errors.Join(errors.New("error creating polygon cache bucket"), err)
+	}
+	return &BoltPolygonCache{db: db}, nil
+}
+
+// Get returns the response stored for key and when it expires
+func (cache *BoltPolygonCache) Get(ctx context.Context, key string) ([]byte, time.Time, bool, error) {
+	var body []byte
+	var expiresAt time.Time
+	err := cache.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(boltCacheBucket).Get([]byte(key))
+		if len(value) < 8 {
+			return nil
+		}
+		// Values are the expiry in Unix nanoseconds followed by the body, and are only valid during the transaction
+		expiresAt = time.Unix(0, int64(binary.BigEndian.Uint64(value[:8])))
+		body = append([]byte{}, value[8:]...)
+		return nil
+	})
+	if err != nil {
+		return nil, time.Time{}, false, err
+	}
+	return body, expiresAt, body != nil, nil
+}
+
+// Set stores the response for key until expiresAt
+func (cache *BoltPolygonCache) Set(ctx context.Context, key string, body []byte, expiresAt time.Time) error {
+	value := make([]byte, 8, 8+len(body))
+	binary.BigEndian.PutUint64(value, uint64(expiresAt.UnixNano()))
+	value = append(value, body...)
+	return cache.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltCacheBucket).Put([]byte(key), value)
+	})
+}
+
+// Prune deletes the responses that expired before now and returns how many were deleted
+func (cache *BoltPolygonCache) Prune(now time.Time) (int, error) {
+	deleted := 0
+	err := cache.db.Update(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(boltCacheBucket).Cursor()
+		for key, value := cursor.First(); key != nil; key, value = cursor.Next() {
+			if len(value) < 8 || time.Unix(0, int64(binary.BigEndian.Uint64(value[:8]))).Before(now) {
+				if err := cursor.Delete(); err != nil {
+					return err
+				}
+				deleted++
+			}
+		}
+		return nil
+	})
+	return deleted, err
+}
+
+// Close closes the cache file
+func (cache *BoltPolygonCache) Close() error {
+	return cache.db.Close()
+}
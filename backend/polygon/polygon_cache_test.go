@@ -0,0 +1,136 @@
+package polygon
+
+import (
+	"financial-helper/polygon/polygontest"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newCachedTestConnection(t *testing.T, clock Clock) (*PolygonConnection, *polygontest.Server, *BoltPolygonCache) {
+	t.Helper()
+	fake := polygontest.NewServer()
+	t.Cleanup(fake.Close)
+	cache, err := NewBoltPolygonCache(filepath.Join(t.TempDir(), "cache.db"))
+	if err != nil {
+		t.Fatalf("NewBoltPolygonCache error: %v", err)
+	}
+	t.Cleanup(func() { cache.Close() })
+
+	connection := GetPolygonConnection([]string{"test-key"},
+		WithBaseURL(fake.URL),
+		WithHTTPClient(fake.Client()),
+		WithClock(clock),
+		WithKeyRateLimit(1000, time.Minute),
+		WithRetryPolicy(NoPolygonRetries()),
+		WithCache(cache, nil),
+	)
+	return connection, fake, cache
+}
+
+func TestPolygonConnection_CacheServesRepeatedRequests(t *testing.T) {
+	clock := &testRecordingClock{now: testNow}
+	connection, fake, _ := newCachedTestConnection(t, clock)
+
+	first, err := connection.PolygonGetTickerDetails(testTicker)
+	if err != nil {
+		t.Fatalf("PolygonGetTickerDetails error: %v", err)
+	}
+	second, err := connection.PolygonGetTickerDetails(testTicker)
+	if err != nil {
+		t.Fatalf("cached PolygonGetTickerDetails error: %v", err)
+	}
+	if *first.Results.Name != *second.Results.Name {
+		t.Fatalf("cached response differs: %q and %q", *first.Results.Name, *second.Results.Name)
+	}
+	if count := fake.RequestCount("/v3/reference/tickers/AAPL"); count != 1 {
+		t.Fatalf("expected the second request to be served from the cache, got %d requests", count)
+	}
+	if stats := connection.CacheStats(); !stats.Enabled || stats.Hits != 1 || stats.Misses != 1 || stats.Stores != 1 {
+		t.Fatalf("unexpected cache stats %+v", stats)
+	}
+
+	// Reference data is kept for a day
+	clock.After(25 * time.Hour)
+	if _, err := connection.PolygonGetTickerDetails(testTicker); err != nil {
+		t.Fatalf("PolygonGetTickerDetails error: %v", err)
+	}
+	if count := fake.RequestCount("/v3/reference/tickers/AAPL"); count != 2 {
+		t.Fatalf("expected the expired response to be requested again, got %d requests", count)
+	}
+}
+
+func TestPolygonConnection_CacheSkipsErrorsAndUncachedEndpoints(t *testing.T) {
+	clock := &testRecordingClock{now: testNow}
+	connection, fake, _ := newCachedTestConnection(t, clock)
+	fake.FailNext("/v3/reference/tickers/AAPL", http.StatusInternalServerError, 1)
+
+	if _, err := connection.PolygonGetTickerDetails(testTicker); err == nil {
+		t.Fatal("expected the injected failure")
+	}
+	if _, err := connection.PolygonGetTickerDetails(testTicker); err != nil {
+		t.Fatalf("expected the failure not to be cached, got %v", err)
+	}
+
+	// The grouped daily of the current session is never cached
+	for i := 0; i < 2; i++ {
+		_, _ = connection.PolygonGetGroupedDaily(testNow.In(newYork), false)
+	}
+	if count := fake.RequestCount("/v2/aggs/grouped"); count != 2 {
+		t.Fatalf("expected both grouped daily requests to reach Polygon, got %d", count)
+	}
+	if stats := connection.CacheStats(); stats.Stores != 1 || stats.Uncached != 2 {
+		t.Fatalf("unexpected cache stats %+v", stats)
+	}
+}
+
+func TestDefaultPolygonCachePolicy(t *testing.T) {
+	// Friday 2024-11-01 at 18:00 in New York
+	friday := time.Date(2024, 11, 1, 22, 0, 0, 0, time.UTC)
+	monday := time.Date(2024, 11, 4, 14, 30, 0, 0, time.UTC)
+	cases := map[string]time.Duration{
+		"/v3/reference/tickers/AAPL":                                      referenceCacheTTL,
+		"/v3/reference/tickers?search=apple":                              referenceCacheTTL,
+		"/vX/reference/financials?ticker=AAPL":                            referenceCacheTTL,
+		"/v2/aggs/ticker/AAPL/prev":                                       monday.Sub(friday),
+		"/v2/reference/news?ticker=AAPL":                                  newsCacheTTL,
+		"/v1/marketstatus/now":                                            liveCacheTTL,
+		"/v2/aggs/ticker/AAPL/range/1/day/2024-10-01/2024-10-31":          referenceCacheTTL,
+		"/v2/aggs/ticker/AAPL/range/1/day/2024-10-01/2024-11-01":          liveCacheTTL,
+		"/v2/aggs/ticker/AAPL/range/5/minute/1730462400000/1730491200000": liveCacheTTL,
+		"/v2/aggs/grouped/locale/us/market/stocks/2024-10-31":             referenceCacheTTL,
+		"/v2/aggs/grouped/locale/us/market/stocks/2024-11-01":             0,
+		"/v1/open-close/AAPL/2024-10-31":                                  0,
+	}
+	for path, expected := range cases {
+		requestURL, _ := url.Parse(path)
+		if ttl := DefaultPolygonCachePolicy(requestURL, friday); ttl != expected {
+			t.Errorf("DefaultPolygonCachePolicy(%s) = %s, expected %s", path, ttl, expected)
+		}
+	}
+}
+
+func TestBoltPolygonCache_Prune(t *testing.T) {
+	cache, err := NewBoltPolygonCache(filepath.Join(t.TempDir(), "cache.db"))
+	if err != nil {
+		t.Fatalf("NewBoltPolygonCache error: %v", err)
+	}
+	defer cache.Close()
+
+	ctx := t.Context()
+	_ = cache.Set(ctx, "expired", []byte(`{}`), testNow.Add(-time.Minute))
+	_ = cache.Set(ctx, "fresh", []byte(`{"status":"OK"}`), testNow.Add(time.Hour))
+
+	if deleted, err := cache.Prune(testNow); err != nil || deleted != 1 {
+		t.Fatalf("expected 1 expired response to be deleted, got %d (%v)", deleted, err)
+	}
+	if _, _, found, _ := cache.Get(ctx, "expired"); found {
+		t.Fatal("expected the expired response to be gone")
+	}
+	body, expiresAt, found, err := cache.Get(ctx, "fresh")
+	if err != nil || !found || string(body) != `{"status":"OK"}` || !expiresAt.Equal(testNow.Add(time.Hour)) {
+		t.Fatalf("unexpected fresh response %q until %s (found %t, %v)", body, expiresAt, found, err)
+	}
+}
@@ -0,0 +1,231 @@
+package polygon
+
+// This file contains the cassette layer of a PolygonConnection.
+//
+// In record mode every response received from Polygon is saved to a JSON file (a "cassette") before being decoded.
+// In replay mode responses are read back from those files and no request leaves the process, so real payloads
+// captured once can be used to regression test decoding forever. Cassettes are keyed by method, path and sorted
+// query, without the apiKey parameter, so they do not depend on the host or on the key used to record them.
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// PolygonCassetteMode selects whether responses are recorded to or replayed from cassettes
+type PolygonCassetteMode string
+
+const (
+	CassetteDisabled PolygonCassetteMode = ""
+	CassetteRecord   PolygonCassetteMode = "record"
+	CassetteReplay   PolygonCassetteMode = "replay"
+)
+
+const (
+	// Environment variable read by WithCassetteFromEnv to select the cassette mode
+	CassetteModeEnv = "POLYGON_CASSETTE_MODE"
+	// Environment variable read by WithCassetteFromEnv to select the cassette directory
+	CassetteDirEnv = "POLYGON_CASSETTE_DIR"
+	// Directory used by WithCassetteFromEnv if CassetteDirEnv is not set
+	DefaultCassetteDir = "testdata/cassettes"
+)
+
+// ErrCassetteMiss is returned in replay mode for requests that were never recorded
+var ErrCassetteMiss = errors.New("no cassette recorded for polygon request")
+
+var unsafeCassetteChars = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+
+// A single recorded response
+type polygonCassette struct {
+	Method     string            `json:"method"`
+	URL        string            `json:"url"` // Path and sorted query, without the apiKey
+	RecordedAt time.Time         `json:"recorded_at"`
+	StatusCode int               `json:"status_code"`
+	Header     map[string]string `json:"header,omitempty"`
+	Body       json.RawMessage   `json:"body,omitempty"`      // Set if the body is valid JSON, so cassettes stay readable
+	BodyText   string            `json:"body_text,omitempty"` // Set otherwise
+}
+
+// Headers kept in cassettes, the rest are dropped
+var cassetteHeaders = []string{"Content-Type", "Retry-After"}
+
+// WithCassette records responses to (CassetteRecord) or replays them from (CassetteReplay) the cassettes in dir
+func WithCassette(dir string, mode PolygonCassetteMode) PolygonConnectionOption {
+	return func(config *polygonConnectionConfig) {
+		config.cassetteDir = dir
+		config.cassetteMode = mode
+	}
+}
+
+// WithCassetteFromEnv enables cassettes if POLYGON_CASSETTE_MODE is "record" or "replay",
+// using the directory in POLYGON_CASSETTE_DIR (testdata/cassettes by default)
+func WithCassetteFromEnv() PolygonConnectionOption {
+	return func(config *polygonConnectionConfig) {
+		mode := PolygonCassetteMode(strings.ToLower(strings.TrimSpace(os.Getenv(CassetteModeEnv))))
+		if mode != CassetteRecord && mode != CassetteReplay {
+			if mode != CassetteDisabled {
+				errLogger.Printf("Ignoring unknown %s %q, expected %q or %q", CassetteModeEnv, mode, CassetteRecord, CassetteReplay)
+			}
+			return
+		}
+		dir := os.Getenv(CassetteDirEnv)
+		if dir == "" {
+			dir = DefaultCassetteDir
+		}
+		config.cassetteDir = dir
+		config.cassetteMode = mode
+	}
+}
+
+// Wraps client so its requests go through the cassettes in dir. The client itself is left untouched.
+func withCassetteTransport(client *http.Client, dir string, mode PolygonCassetteMode, now func() time.Time) *http.Client {
+	if mode == CassetteDisabled {
+		return client
+	}
+	next := client.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	wrapped := *client
+	wrapped.Transport = &polygonCassetteTransport{dir: dir, mode: mode, next: next, now: now}
+	return &wrapped
+}
+
+type polygonCassetteTransport struct {
+	dir  string
+	mode PolygonCassetteMode
+	next http.RoundTripper
+	now  func() time.Time
+}
+
+func (transport *polygonCassetteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := cassetteRequestKey(req.URL)
+	path := filepath.Join(transport.dir, cassetteFileName(req.Method, req.URL))
+
+	if transport.mode == CassetteReplay {
+		return replayCassette(req, path, key)
+	}
+
+	res, err := transport.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, errors.Join(errors.New("error reading polygon response to record"), err)
+	}
+	// The body is handed back untouched, only the copy on disk is scrubbed
+	res.Body = io.NopCloser(bytes.NewReader(body))
+
+	if apiKey := req.URL.Query().Get("apiKey"); apiKey != "" {
+		body = bytes.ReplaceAll(body, []byte(apiKey), []byte("****"))
+	}
+	cassette := polygonCassette{
+		Method:     req.Method,
+		URL:        key,
+		RecordedAt: transport.now().UTC(),
+		StatusCode: res.StatusCode,
+		Header:     map[string]string{},
+	}
+	for _, name := range cassetteHeaders {
+		if value := res.Header.Get(name); value != "" {
+			cassette.Header[name] = value
+		}
+	}
+	if json.Valid(body) {
+		cassette.Body = body
+	} else {
+		cassette.BodyText = string(body)
+	}
+	if err := writeCassette(path, cassette); err != nil {
+		// Recording is best effort, the caller still gets its response
+		errLogger.Printf("Could not record polygon cassette %s: %v", path, err)
+	}
+
+	return res, nil
+}
+
+func replayCassette(req *http.Request, path string, key string) (*http.Response, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s %s (%s)", ErrCassetteMiss, req.Method, key, path)
+	}
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("error reading polygon cassette %s", path), err)
+	}
+
+	var cassette polygonCassette
+	if err := json.Unmarshal(data, &cassette); err != nil {
+		return nil, errors.Join(fmt.Errorf("error parsing polygon cassette %s", path), err)
+	}
+
+	body := []byte(cassette.Body)
+	if len(body) == 0 {
+		body = []byte(cassette.BodyText)
+	}
+	header := http.Header{}
+	for name, value := range cassette.Header {
+		header.Set(name, value)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", cassette.StatusCode, http.StatusText(cassette.StatusCode)),
+		StatusCode:    cassette.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+func writeCassette(path string, cassette polygonCassette) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	// Keep & and < readable in URLs and article text
+	var data bytes.Buffer
+	encoder := json.NewEncoder(&data)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(cassette); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data.Bytes(), 0o644)
+}
+
+// Returns the path and sorted query of a request, without the apiKey
+func cassetteRequestKey(requestURL *url.URL) string {
+	query := requestURL.Query()
+	query.Del("apiKey")
+	key := requestURL.EscapedPath()
+	if encoded := query.Encode(); encoded != "" {
+		key += "?" + encoded
+	}
+	return key
+}
+
+// Returns a readable file name that is unique to the method, path and query of a request,
+// e.g. v2-aggs-ticker-AAPL-prev-3f2a9c01b7d4.json
+func cassetteFileName(method string, requestURL *url.URL) string {
+	hash := sha256.Sum256([]byte(method + " " + cassetteRequestKey(requestURL)))
+	name := strings.Trim(unsafeCassetteChars.ReplaceAllString(requestURL.Path, "-"), "-")
+	if len(name) > 80 {
+		name = name[:80]
+	}
+	return fmt.Sprintf("%s-%s.json", name, hex.EncodeToString(hash[:6]))
+}
@@ -0,0 +1,132 @@
+package polygon
+
+import (
+	"errors"
+	"financial-helper/polygon/polygontest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Returns a connection that replays the checked-in cassettes, or skips the test if none were recorded. To record them
+// against the real API, run
+//
+//	POLYGON_CASSETTE_MODE=record POLYGON_API_KEY=<key> go test ./polygon -run TestPolygonCassettes
+func newCassetteConnection(t *testing.T) *PolygonConnection {
+	t.Helper()
+	if os.Getenv(CassetteModeEnv) == string(CassetteRecord) {
+		key := os.Getenv("POLYGON_API_KEY")
+		if key == "" {
+			t.Skip("POLYGON_API_KEY is required to record cassettes")
+		}
+		return GetPolygonConnection([]string{key}, WithCassette(polygontest.CassetteDir(), CassetteRecord), WithThrottle(12*time.Second))
+	}
+	polygontest.SkipWithoutCassettes(t)
+	return GetPolygonConnection([]string{"replay-key"}, WithCassette(polygontest.CassetteDir(), CassetteReplay), WithRetryPolicy(NoPolygonRetries()))
+}
+
+func TestPolygonCassettes_Ticker(t *testing.T) {
+	resp, err := newCassetteConnection(t).PolygonGetTicker(polygontest.CassetteSymbol)
+	if err != nil {
+		t.Fatalf("PolygonGetTicker error: %v", err)
+	}
+	if resp.Results == nil || len(*resp.Results) != 1 {
+		t.Fatalf("expected exactly one ticker")
+	}
+	first := (*resp.Results)[0]
+	if first.Ticker == nil || *first.Ticker != polygontest.CassetteSymbol || first.Name == nil || *first.Name == "" {
+		t.Fatalf("unexpected ticker %s", PolygonResponseToString(first))
+	}
+}
+
+func TestPolygonCassettes_History(t *testing.T) {
+	resp, err := newCassetteConnection(t).PolygonGetTickerHistory(polygontest.CassetteSymbol, polygontest.CassetteStart, polygontest.CassetteEnd, polygontest.CassetteHistoryLimit)
+	if err != nil {
+		t.Fatalf("PolygonGetTickerHistory error: %v", err)
+	}
+	if resp.Results == nil || len(*resp.Results) == 0 {
+		t.Fatalf("expected historical results")
+	}
+	previous := int64(0)
+	for i, result := range *resp.Results {
+		if result.Timestamp == nil || result.Open == nil || result.Close == nil || result.High == nil || result.Low == nil || result.Volume == nil {
+			t.Fatalf("result #%d is missing fields: %s", i, PolygonResponseToString(result))
+		}
+		if *result.Timestamp <= previous {
+			t.Fatalf("results are not sorted ascending at #%d", i)
+		}
+		if *result.Low > *result.High {
+			t.Fatalf("result #%d has low %f above high %f", i, *result.Low, *result.High)
+		}
+		previous = *result.Timestamp
+	}
+}
+
+func TestPolygonCassettes_News(t *testing.T) {
+	resp, err := newCassetteConnection(t).PolygonGetTickerNews(polygontest.CassetteSymbol, polygontest.CassetteStart, polygontest.CassetteEnd, polygontest.CassetteNewsLimit)
+	if err != nil {
+		t.Fatalf("PolygonGetTickerNews error: %v", err)
+	}
+	if resp.Results == nil || len(*resp.Results) == 0 {
+		t.Fatalf("expected news results")
+	}
+	for i, result := range *resp.Results {
+		if result.ID == nil || result.PublishedUTC == nil || result.Tickers == nil {
+			t.Fatalf("result #%d is missing fields: %s", i, PolygonResponseToString(result))
+		}
+		if result.PublishedUTC.Before(polygontest.CassetteStart) || result.PublishedUTC.After(polygontest.CassetteEnd) {
+			t.Fatalf("result #%d was published on %s, outside of the recorded range", i, result.PublishedUTC)
+		}
+	}
+}
+
+func TestPolygonCassette_RecordAndReplay(t *testing.T) {
+	fake := polygontest.NewServer()
+	defer fake.Close()
+	dir := t.TempDir()
+
+	recorder := GetPolygonConnection([]string{"secret-key"},
+		WithBaseURL(fake.URL),
+		WithHTTPClient(fake.Client()),
+		WithCassette(dir, CassetteRecord),
+	)
+	recorded, err := recorder.PolygonGetTickerDailyClose("MSFT")
+	if err != nil {
+		t.Fatalf("record error: %v", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 1 {
+		t.Fatalf("expected 1 cassette, got %v", files)
+	}
+	data, _ := os.ReadFile(files[0])
+	if strings.Contains(string(data), "secret-key") || strings.Contains(string(data), fake.URL) {
+		t.Fatalf("cassette leaks the api key or host: %s", data)
+	}
+
+	// The replaying connection points at a closed port, so any request that is not replayed would fail
+	fake.Close()
+	replayer := GetPolygonConnection([]string{"other-key"},
+		WithBaseURL("http://127.0.0.1:1"),
+		WithCassette(dir, CassetteReplay),
+	)
+	replayed, err := replayer.PolygonGetTickerDailyClose("MSFT")
+	if err != nil {
+		t.Fatalf("replay error: %v", err)
+	}
+	if PolygonResponseToString(replayed) != PolygonResponseToString(recorded) {
+		t.Fatalf("replayed response differs from the recorded one")
+	}
+
+	// Requests that were never recorded fail immediately instead of being retried
+	start := time.Now()
+	_, err = replayer.PolygonGetTickerDailyClose("GOOGL")
+	if !errors.Is(err, ErrCassetteMiss) {
+		t.Fatalf("expected ErrCassetteMiss, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Fatalf("cassette miss should not be retried")
+	}
+}
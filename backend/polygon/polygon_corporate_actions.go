@@ -0,0 +1,125 @@
+package polygon
+
+// This file contains the wrappers for Polygon's corporate action endpoints
+// https://polygon.io/docs/stocks/get_v3_reference_dividends
+// https://polygon.io/docs/stocks/get_v3_reference_splits
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// The largest page size accepted by the dividends and splits endpoints
+const corporateActionsPageLimit = 1000
+
+type PolygonGetTickerDividendsResponse struct {
+	Results   *[]PolygonDividend `json:"results"`
+	Status    *string            `json:"status"`
+	RequestID *string            `json:"request_id"`
+	NextURL   *string            `json:"next_url"`
+}
+
+// A single cash dividend returned by the Polygon dividends endpoint. Dates are formatted as YYYY-MM-DD.
+type PolygonDividend struct {
+	ID              *string  `json:"id"`
+	Ticker          *string  `json:"ticker"`
+	CashAmount      *float64 `json:"cash_amount"` // Per share, not adjusted for later splits
+	Currency        *string  `json:"currency"`
+	DeclarationDate *string  `json:"declaration_date"`
+	ExDividendDate  *string  `json:"ex_dividend_date"` // Shares held before this date receive the dividend
+	RecordDate      *string  `json:"record_date"`
+	PayDate         *string  `json:"pay_date"`
+	Frequency       *int     `json:"frequency"` // Times per year, 0 for one-time dividends
+	DividendType    *string  `json:"dividend_type"`
+}
+
+type PolygonGetTickerSplitsResponse struct {
+	Results   *[]PolygonSplit `json:"results"`
+	Status    *string         `json:"status"`
+	RequestID *string         `json:"request_id"`
+	NextURL   *string         `json:"next_url"`
+}
+
+// A single stock split returned by the Polygon splits endpoint, e.g. SplitFrom 1 and SplitTo 4 for a 4-for-1 split
+type PolygonSplit struct {
+	ID            *string  `json:"id"`
+	Ticker        *string  `json:"ticker"`
+	ExecutionDate *string  `json:"execution_date"` // YYYY-MM-DD
+	SplitFrom     *float64 `json:"split_from"`
+	SplitTo       *float64 `json:"split_to"`
+}
+
+// Builds the URL of a corporate actions endpoint filtered on ticker and on dateField between startDate and endDate.
+// Zero dates leave that side of the range open.
+func (polygonConnection *PolygonConnection) corporateActionsURL(path string, symbol string, dateField string, startDate time.Time, endDate time.Time) string {
+	query := url.Values{}
+	query.Set("ticker", symbol)
+	query.Set("order", "asc")
+	query.Set("sort", dateField)
+	query.Set("limit", strconv.Itoa(corporateActionsPageLimit))
+	if !startDate.IsZero() {
+		query.Set(dateField+".gte", startDate.Format("2006-01-02"))
+	}
+	if !endDate.IsZero() {
+		query.Set(dateField+".lte", endDate.Format("2006-01-02"))
+	}
+	return fmt.Sprintf("%s%s?%s", polygonConnection.baseURL, path, query.Encode())
+}
+
+// PolygonGetTickerDividends returns every cash dividend of a ticker whose ex-dividend date is within the selected
+// range, oldest first. Every page of results is fetched.
+//
+// Input:
+//   - symbol: the symbol of the stock
+//   - startDate: the earliest ex-dividend date, zero for no lower bound
+//   - endDate: the latest ex-dividend date, zero for no upper bound
+//
+// Output:
+//   - []PolygonDividend: the dividends, empty if the ticker paid none in the range
+//   - error: any error that occurred
+func (polygonConnection *PolygonConnection) PolygonGetTickerDividends(symbol string, startDate time.Time, endDate time.Time) ([]PolygonDividend, error) {
+	return polygonConnection.PolygonGetTickerDividendsWithContext(context.Background(), symbol, startDate, endDate)
+}
+
+// PolygonGetTickerDividendsWithContext is PolygonGetTickerDividends bounded by ctx
+func (polygonConnection *PolygonConnection) PolygonGetTickerDividendsWithContext(ctx context.Context, symbol string, startDate time.Time, endDate time.Time) ([]PolygonDividend, error) {
+	if !startDate.IsZero() && !endDate.IsZero() && startDate.After(endDate) {
+		return nil, errors.New("start date cannot be after end date")
+	}
+
+	return getAllPages(ctx, polygonConnection, polygonConnection.corporateActionsURL("/v3/reference/dividends", symbol, "ex_dividend_date", startDate, endDate),
+		func(page *PolygonGetTickerDividendsResponse) *[]PolygonDividend { return page.Results },
+		func(page *PolygonGetTickerDividendsResponse) *string { return page.NextURL },
+	)
+}
+
+// PolygonGetTickerSplits returns every split of a ticker executed within the selected range, oldest first.
+// Every page of results is fetched.
+//
+// Input:
+//   - symbol: the symbol of the stock
+//   - startDate: the earliest execution date, zero for no lower bound
+//   - endDate: the latest execution date, zero for no upper bound
+//
+// Output:
+//   - []PolygonSplit: the splits, empty if the ticker did not split in the range
+//   - error: any error that occurred
+func (polygonConnection *PolygonConnection) PolygonGetTickerSplits(symbol string, startDate time.Time, endDate time.Time) ([]PolygonSplit, error) {
+	return polygonConnection.PolygonGetTickerSplitsWithContext(context.Background(), symbol, startDate, endDate)
+}
+
+// PolygonGetTickerSplitsWithContext is PolygonGetTickerSplits bounded by ctx
+func (polygonConnection *PolygonConnection) PolygonGetTickerSplitsWithContext(ctx context.Context, symbol string, startDate time.Time, endDate time.Time) ([]PolygonSplit, error) {
+	if !startDate.IsZero() && !endDate.IsZero() && startDate.After(endDate) {
+		return nil, errors.New("start date cannot be after end date")
+	}
+
+	return getAllPages(ctx, polygonConnection, polygonConnection.corporateActionsURL("/v3/reference/splits", symbol, "execution_date", startDate, endDate),
+		func(page *PolygonGetTickerSplitsResponse) *[]PolygonSplit { return page.Results },
+		func(page *PolygonGetTickerSplitsResponse) *string { return page.NextURL },
+	)
+}
@@ -0,0 +1,227 @@
+package polygon
+
+// This file contains the typed errors returned by requests to Polygon.
+//
+// Every error can be inspected with errors.As, even after being wrapped with errors.Join:
+//
+//	var rateLimited *polygon.PolygonRateLimitError
+//	if errors.As(err, &rateLimited) { ... }
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNoResults is returned when Polygon answered successfully but without any results
+var ErrNoResults = errors.New("no results found")
+
+// PolygonRateLimitError is returned when Polygon responds with 429 Too Many Requests
+type PolygonRateLimitError struct {
+	URL        string
+	RetryAfter time.Duration // Zero if Polygon did not send a Retry-After header
+	Message    string
+}
+
+func (err *PolygonRateLimitError) Error() string {
+	return fmt.Sprintf("polygon rate limit reached for %s (retry after %s): %s", err.URL, err.RetryAfter, err.Message)
+}
+
+// PolygonUnauthorizedError is returned when Polygon responds with 401 or 403,
+// which means the API key is invalid or the endpoint is not included in the key's plan
+type PolygonUnauthorizedError struct {
+	URL        string
+	StatusCode int
+	Message    string
+}
+
+func (err *PolygonUnauthorizedError) Error() string {
+	return fmt.Sprintf("polygon refused %s with status %d: %s", err.URL, err.StatusCode, err.Message)
+}
+
+// PolygonNotFoundError is returned when Polygon responds with 404
+type PolygonNotFoundError struct {
+	URL     string
+	Message string
+}
+
+func (err *PolygonNotFoundError) Error() string {
+	return fmt.Sprintf("polygon could not find %s: %s", err.URL, err.Message)
+}
+
+// PolygonServerError is returned when Polygon responds with a 5XX status code
+type PolygonServerError struct {
+	URL        string
+	StatusCode int
+	Message    string
+}
+
+func (err *PolygonServerError) Error() string {
+	return fmt.Sprintf("polygon server error for %s with status %d: %s", err.URL, err.StatusCode, err.Message)
+}
+
+// PolygonRequestError is returned for any other non-2XX status code (e.g. 400 Bad Request)
+type PolygonRequestError struct {
+	URL        string
+	StatusCode int
+	Message    string
+}
+
+func (err *PolygonRequestError) Error() string {
+	return fmt.Sprintf("polygon rejected %s with status %d: %s", err.URL, err.StatusCode, err.Message)
+}
+
+// PolygonTransportError is returned when the request could not be sent or the response could not be read
+type PolygonTransportError struct {
+	URL string
+	Err error
+}
+
+func (err *PolygonTransportError) Error() string {
+	return fmt.Sprintf("error sending/receiving request to %s: %v", err.URL, err.Err)
+}
+
+func (err *PolygonTransportError) Unwrap() error {
+	return err.Err
+}
+
+// PolygonDecodeError is returned when a successful response could not be decoded
+type PolygonDecodeError struct {
+	URL string
+	Err error
+}
+
+func (err *PolygonDecodeError) Error() string {
+	return fmt.Sprintf("error decoding response from %s: %v", err.URL, err.Err)
+}
+
+func (err *PolygonDecodeError) Unwrap() error {
+	return err.Err
+}
+
+// The number of missing fields listed by PolygonPartialDataError.Error, a grouped daily response can miss thousands
+const maxReportedMissingFields = 5
+
+// PolygonPartialDataError is returned along with a response from which Polygon omitted required fields.
+// The rows missing one were dropped from the response, so the rest of it can be used: callers that can
+// make do without them tolerate the error with IsPartialPolygonData.
+type PolygonPartialDataError struct {
+	URL     string
+	Missing []string // JSON paths of the missing fields, e.g. "results[3].c"
+	Dropped int      // Number of rows removed from the response
+}
+
+func (err *PolygonPartialDataError) Error() string {
+	missing := strings.Join(err.Missing[:min(len(err.Missing), maxReportedMissingFields)], ", ")
+	if len(err.Missing) > maxReportedMissingFields {
+		missing += fmt.Sprintf(" and %d more", len(err.Missing)-maxReportedMissingFields)
+	}
+	return fmt.Sprintf("polygon response for %s is missing required fields %s (%d rows dropped)", err.URL, missing, err.Dropped)
+}
+
+// IsPartialPolygonData reports whether err only means that incomplete rows were dropped from the response
+// returned with it, which is usable
+func IsPartialPolygonData(err error) bool {
+	var partialErr *PolygonPartialDataError
+	return errors.As(err, &partialErr)
+}
+
+// IsRetryablePolygonError reports whether a request that failed with err may succeed if sent again
+// (rate limits, server errors and network failures)
+func IsRetryablePolygonError(err error) bool {
+	if errors.Is(err, ErrCassetteMiss) {
+		return false
+	}
+	var rateLimitErr *PolygonRateLimitError
+	var serverErr *PolygonServerError
+	var transportErr *PolygonTransportError
+	return errors.As(err, &rateLimitErr) || errors.As(err, &serverErr) || errors.As(err, &transportErr)
+}
+
+// IsFatalPolygonError reports whether err means that no further request will succeed,
+// so a long running job should stop instead of skipping to its next step
+func IsFatalPolygonError(err error) bool {
+	var unauthorizedErr *PolygonUnauthorizedError
+	return errors.As(err, &unauthorizedErr)
+}
+
+// Converts a non-2XX response into the matching typed error.
+// The response body is read (but not closed) to extract Polygon's error message.
+// now is the time a Retry-After header holding an HTTP date is relative to.
+func newPolygonStatusError(res *http.Response, requestURL string, now time.Time) error {
+	scrubbedURL := scrubPolygonURL(requestURL)
+	message := readPolygonErrorMessage(res.Body)
+
+	switch {
+	case res.StatusCode == http.StatusTooManyRequests:
+		return &PolygonRateLimitError{URL: scrubbedURL, RetryAfter: parseRetryAfter(res.Header.Get("Retry-After"), now), Message: message}
+	case res.StatusCode == http.StatusUnauthorized || res.StatusCode == http.StatusForbidden:
+		return &PolygonUnauthorizedError{URL: scrubbedURL, StatusCode: res.StatusCode, Message: message}
+	case res.StatusCode == http.StatusNotFound:
+		return &PolygonNotFoundError{URL: scrubbedURL, Message: message}
+	case res.StatusCode >= http.StatusInternalServerError:
+		return &PolygonServerError{URL: scrubbedURL, StatusCode: res.StatusCode, Message: message}
+	default:
+		return &PolygonRequestError{URL: scrubbedURL, StatusCode: res.StatusCode, Message: message}
+	}
+}
+
+// Extracts the message from a Polygon error body, falling back to the raw body
+func readPolygonErrorMessage(body io.Reader) string {
+	raw, err := io.ReadAll(io.LimitReader(body, 4096))
+	if err != nil || len(raw) == 0 {
+		return ""
+	}
+	var decoded struct {
+		Error   string `json:"error"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(raw, &decoded); err == nil {
+		if decoded.Error != "" {
+			return decoded.Error
+		}
+		if decoded.Message != "" {
+			return decoded.Message
+		}
+	}
+	return string(raw)
+}
+
+// Parses a Retry-After header, which is either a number of seconds or an HTTP date
+func parseRetryAfter(header string, now time.Time) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(header); err == nil {
+		if wait := date.Sub(now); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
+
+// Removes the API key from a Polygon URL so it can be logged or returned in errors
+func scrubPolygonURL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "<unparseable url>"
+	}
+	query := parsed.Query()
+	if query.Has("apiKey") {
+		query.Del("apiKey")
+		parsed.RawQuery = query.Encode()
+	}
+	return parsed.String()
+}
@@ -0,0 +1,121 @@
+package polygon
+
+// This file contains the wrapper for Polygon's financials endpoint, which returns the income statement, balance sheet
+// and cash flow statement of a company's SEC filings
+// https://polygon.io/docs/stocks/get_vx_reference_financials
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// PolygonFinancialsTimeframe is the period covered by the statements of a filing
+type PolygonFinancialsTimeframe string
+
+const (
+	FinancialsQuarterly PolygonFinancialsTimeframe = "quarterly"
+	FinancialsAnnual    PolygonFinancialsTimeframe = "annual"
+	// Trailing twelve months, computed by Polygon from the last four quarters
+	FinancialsTTM PolygonFinancialsTimeframe = "ttm"
+)
+
+// Valid reports whether the timeframe is one Polygon accepts
+func (timeframe PolygonFinancialsTimeframe) Valid() bool {
+	return timeframe == FinancialsQuarterly || timeframe == FinancialsAnnual || timeframe == FinancialsTTM
+}
+
+// The largest page size accepted by the financials endpoint
+const financialsPageLimit = 100
+
+type PolygonGetTickerFinancialsResponse struct {
+	Results   *[]PolygonFinancialFiling `json:"results"`
+	Status    *string                   `json:"status"`
+	RequestID *string                   `json:"request_id"`
+	NextURL   *string                   `json:"next_url"`
+}
+
+// A single filing returned by the Polygon financials endpoint. Dates are formatted as YYYY-MM-DD.
+type PolygonFinancialFiling struct {
+	CIK             *string                     `json:"cik"`
+	CompanyName     *string                     `json:"company_name"`
+	Tickers         *[]string                   `json:"tickers"`
+	StartDate       *string                     `json:"start_date"` // First day of the period covered
+	EndDate         *string                     `json:"end_date"`   // Last day of the period covered
+	FilingDate      *string                     `json:"filing_date"`
+	FiscalPeriod    *string                     `json:"fiscal_period"` // Q1 to Q4, FY for annual filings or TTM
+	FiscalYear      *string                     `json:"fiscal_year"`
+	Timeframe       *string                     `json:"timeframe"`
+	SourceFilingURL *string                     `json:"source_filing_url"`
+	Financials      *PolygonFinancialStatements `json:"financials"`
+}
+
+// The statements of a filing. Polygon omits statements and line items the filing does not report.
+type PolygonFinancialStatements struct {
+	IncomeStatement     PolygonFinancialStatement `json:"income_statement"`
+	BalanceSheet        PolygonFinancialStatement `json:"balance_sheet"`
+	CashFlowStatement   PolygonFinancialStatement `json:"cash_flow_statement"`
+	ComprehensiveIncome PolygonFinancialStatement `json:"comprehensive_income"`
+}
+
+// PolygonFinancialStatement maps line items (e.g. "revenues" or "net_income_loss") to their values
+type PolygonFinancialStatement map[string]PolygonFinancialDataPoint
+
+type PolygonFinancialDataPoint struct {
+	Value *float64 `json:"value"`
+	Unit  *string  `json:"unit"` // e.g. "USD" or "USD / shares"
+	Label *string  `json:"label"`
+	Order *int     `json:"order"`
+}
+
+// Builds the financials URL (without an API key) filtered on ticker, timeframe and filing date.
+// Zero dates leave that side of the range open.
+func (polygonConnection *PolygonConnection) financialsURL(symbol string, timeframe PolygonFinancialsTimeframe, startDate time.Time, endDate time.Time) string {
+	query := url.Values{}
+	query.Set("ticker", symbol)
+	query.Set("timeframe", string(timeframe))
+	query.Set("order", "asc")
+	query.Set("sort", "filing_date")
+	query.Set("limit", strconv.Itoa(financialsPageLimit))
+	if !startDate.IsZero() {
+		query.Set("filing_date.gte", startDate.Format("2006-01-02"))
+	}
+	if !endDate.IsZero() {
+		query.Set("filing_date.lte", endDate.Format("2006-01-02"))
+	}
+	return fmt.Sprintf("%s/vX/reference/financials?%s", polygonConnection.baseURL, query.Encode())
+}
+
+// PolygonGetTickerFinancials returns every filing of a ticker for the selected timeframe that was filed within the
+// selected range, oldest first. Every page of results is fetched.
+//
+// Input:
+//   - symbol: the symbol of the stock
+//   - timeframe: FinancialsQuarterly, FinancialsAnnual or FinancialsTTM
+//   - startDate: the earliest filing date, zero for no lower bound
+//   - endDate: the latest filing date, zero for no upper bound
+//
+// Output:
+//   - []PolygonFinancialFiling: the filings, empty if the ticker filed none in the range
+//   - error: any error that occurred
+func (polygonConnection *PolygonConnection) PolygonGetTickerFinancials(symbol string, timeframe PolygonFinancialsTimeframe, startDate time.Time, endDate time.Time) ([]PolygonFinancialFiling, error) {
+	return polygonConnection.PolygonGetTickerFinancialsWithContext(context.Background(), symbol, timeframe, startDate, endDate)
+}
+
+// PolygonGetTickerFinancialsWithContext is PolygonGetTickerFinancials bounded by ctx
+func (polygonConnection *PolygonConnection) PolygonGetTickerFinancialsWithContext(ctx context.Context, symbol string, timeframe PolygonFinancialsTimeframe, startDate time.Time, endDate time.Time) ([]PolygonFinancialFiling, error) {
+	if !timeframe.Valid() {
+		return nil, fmt.Errorf("invalid financials timeframe %q", timeframe)
+	}
+	if !startDate.IsZero() && !endDate.IsZero() && startDate.After(endDate) {
+		return nil, errors.New("start date cannot be after end date")
+	}
+
+	return getAllPages(ctx, polygonConnection, polygonConnection.financialsURL(symbol, timeframe, startDate, endDate),
+		func(page *PolygonGetTickerFinancialsResponse) *[]PolygonFinancialFiling { return page.Results },
+		func(page *PolygonGetTickerFinancialsResponse) *string { return page.NextURL },
+	)
+}
@@ -0,0 +1,70 @@
+package polygon
+
+// This file contains the wrapper for Polygon's grouped daily endpoint, which returns the daily bar of every
+// ticker in the market for a single date
+// https://polygon.io/docs/stocks/get_v2_aggs_grouped_locale_us_market_stocks__date
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// PolygonGroupedDailyBar is the daily bar of a single ticker. Unlike the bars of PolygonGetTickerHistoryResponse,
+// it carries the ticker it belongs to.
+type PolygonGroupedDailyBar struct {
+	Ticker       *string  `json:"T"`
+	Volume       *float64 `json:"v"`
+	VWAP         *float64 `json:"vw"`
+	Open         *float64 `json:"o"`
+	Close        *float64 `json:"c"`
+	High         *float64 `json:"h"`
+	Low          *float64 `json:"l"`
+	Timestamp    *int64   `json:"t"`
+	Transactions *int     `json:"n"`
+	OTC          *bool    `json:"otc"`
+}
+
+type PolygonGetGroupedDailyResponse struct {
+	QueryCount   *int                      `json:"queryCount"`
+	ResultsCount *int                      `json:"resultsCount"`
+	Adjusted     *bool                     `json:"adjusted"`
+	Results      *[]PolygonGroupedDailyBar `json:"results"`
+	Status       *string                   `json:"status"`
+	RequestID    *string                   `json:"request_id"`
+}
+
+// PolygonGetGroupedDaily returns the split-adjusted daily bar of every US stock that traded on date,
+// in a single request
+//
+// Input:
+//   - date: the trading day, only its year, month and day are used
+//   - includeOTC: whether to include over-the-counter securities
+//
+// Output:
+//   - *PolygonGetGroupedDailyResponse: the response from the Polygon API
+//   - error: any error that occurred, ErrNoResults if the market was closed on date
+func (polygonConnection *PolygonConnection) PolygonGetGroupedDaily(date time.Time, includeOTC bool) (*PolygonGetGroupedDailyResponse, error) {
+	return polygonConnection.PolygonGetGroupedDailyWithContext(context.Background(), date, includeOTC)
+}
+
+// PolygonGetGroupedDailyWithContext is PolygonGetGroupedDaily bounded by ctx
+func (polygonConnection *PolygonConnection) PolygonGetGroupedDailyWithContext(ctx context.Context, date time.Time, includeOTC bool) (*PolygonGetGroupedDailyResponse, error) {
+	query := url.Values{}
+	query.Set("adjusted", "true")
+	query.Set("include_otc", strconv.FormatBool(includeOTC))
+	requestURL := fmt.Sprintf("%s/v2/aggs/grouped/locale/us/market/stocks/%s?%s", polygonConnection.baseURL, date.Format("2006-01-02"), query.Encode())
+
+	response, err := GenericPolygonGetRequestWithContext[PolygonGetGroupedDailyResponse](ctx, polygonConnection, requestURL)
+	if err != nil && !IsPartialPolygonData(err) {
+		return nil, errors.Join(errors.New("error getting info from polygon"), err)
+	}
+	if response.Results == nil || len(*response.Results) == 0 {
+		return nil, ErrNoResults
+	}
+
+	return response, err
+}
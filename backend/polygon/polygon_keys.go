@@ -0,0 +1,234 @@
+package polygon
+
+// This file contains the pool of Polygon API keys shared by every request made through a PolygonConnection.
+//
+// Every key has its own token bucket, so throughput grows with the number of keys instead of being
+// serialized by a single global sleep. Keys that Polygon rejects (401) or rate limits (429) are
+// benched for a cooldown and skipped until it has elapsed. A 403 only means that the plan of the key doesn't cover
+// the endpoint, so the key keeps serving the others.
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	// Free tier keys are limited to 5 requests per minute
+	DefaultKeyRequestsPerMinute = 5
+	// How long a key is benched after being rate limited or rejected
+	DefaultKeyCooldown = time.Minute
+	// Upper bound for the cooldown of a key that keeps being rejected
+	maxKeyCooldown = time.Hour
+)
+
+var errNoPolygonKeys = errors.New("no polygon api keys configured")
+
+// PolygonKeyHealth describes the state of a single key of the pool
+type PolygonKeyHealth struct {
+	Key                 string    `json:"key"` // Masked, only the last characters are kept
+	Available           bool      `json:"available"`
+	Tokens              float64   `json:"tokens"`
+	BenchedUntil        time.Time `json:"benched_until"`
+	Requests            int       `json:"requests"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+}
+
+type polygonKeyState struct {
+	key                 string
+	tokens              float64
+	lastRefill          time.Time
+	lastUsed            time.Time
+	benchedUntil        time.Time
+	requests            int
+	consecutiveFailures int
+}
+
+type polygonKeyPool struct {
+	mu                sync.Mutex
+	keys              []*polygonKeyState
+	next              int
+	requestsPerMinute float64
+	minInterval       time.Duration
+	cooldown          time.Duration
+	now               func() time.Time
+}
+
+// Creates a key pool where every key can serve requestsPerMinute requests per minute,
+// with at least minInterval between two requests using the same key
+func newPolygonKeyPool(keys []string, requestsPerMinute int, minInterval time.Duration, cooldown time.Duration, now func() time.Time) *polygonKeyPool {
+	if requestsPerMinute <= 0 {
+		requestsPerMinute = DefaultKeyRequestsPerMinute
+	}
+	if cooldown <= 0 {
+		cooldown = DefaultKeyCooldown
+	}
+	if now == nil {
+		now = time.Now
+	}
+
+	pool := &polygonKeyPool{
+		requestsPerMinute: float64(requestsPerMinute),
+		minInterval:       minInterval,
+		cooldown:          cooldown,
+		now:               now,
+	}
+	for _, key := range keys {
+		pool.keys = append(pool.keys, &polygonKeyState{
+			key:        key,
+			tokens:     float64(requestsPerMinute),
+			lastRefill: now(),
+		})
+	}
+	return pool
+}
+
+// Tops up the bucket of a key according to the time elapsed since its last refill.
+// Must be called with the pool locked.
+func (pool *polygonKeyPool) refill(state *polygonKeyState, now time.Time) {
+	elapsed := now.Sub(state.lastRefill)
+	if elapsed <= 0 {
+		return
+	}
+	state.tokens = math.Min(pool.requestsPerMinute, state.tokens+elapsed.Minutes()*pool.requestsPerMinute)
+	state.lastRefill = now
+}
+
+// Returns how long until the key can serve a request. Must be called with the pool locked.
+func (pool *polygonKeyPool) waitFor(state *polygonKeyState, now time.Time) time.Duration {
+	wait := time.Duration(0)
+	if state.benchedUntil.After(now) {
+		wait = state.benchedUntil.Sub(now)
+	}
+	if state.tokens < 1 {
+		missing := (1 - state.tokens) / pool.requestsPerMinute
+		wait = max(wait, time.Duration(missing*float64(time.Minute)))
+	}
+	if pool.minInterval > 0 && !state.lastUsed.IsZero() {
+		wait = max(wait, state.lastUsed.Add(pool.minInterval).Sub(now))
+	}
+	return wait
+}
+
+// Hands out the next key (round robin) that can serve a request right now.
+// If none can, it returns the time until the earliest one becomes available.
+func (pool *polygonKeyPool) tryAcquire() (string, time.Duration, error) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	if len(pool.keys) == 0 {
+		return "", 0, errNoPolygonKeys
+	}
+
+	now := pool.now()
+	shortestWait := time.Duration(math.MaxInt64)
+	for i := 0; i < len(pool.keys); i++ {
+		index := (pool.next + i) % len(pool.keys)
+		state := pool.keys[index]
+		pool.refill(state, now)
+
+		wait := pool.waitFor(state, now)
+		if wait <= 0 {
+			state.tokens--
+			state.lastUsed = now
+			state.requests++
+			pool.next = (index + 1) % len(pool.keys)
+			return state.key, 0, nil
+		}
+		shortestWait = min(shortestWait, wait)
+	}
+
+	return "", shortestWait, nil
+}
+
+// Blocks until a key can serve a request and returns it.
+// wait is called with the time until the next key is available, and aborts the acquisition if it fails.
+func (pool *polygonKeyPool) acquire(wait func(time.Duration) error) (string, error) {
+	for {
+		key, delay, err := pool.tryAcquire()
+		if err != nil {
+			return "", err
+		}
+		if key != "" {
+			return key, nil
+		}
+		if err := wait(delay); err != nil {
+			return "", err
+		}
+	}
+}
+
+// Records the outcome of a request made with key, benching the key if Polygon rejected or rate limited it.
+// A 403 Forbidden is returned to the caller without benching the key, since it is specific to the endpoint.
+func (pool *polygonKeyPool) report(key string, requestErr error) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	for _, state := range pool.keys {
+		if state.key != key {
+			continue
+		}
+
+		var rateLimitErr *PolygonRateLimitError
+		var unauthorizedErr *PolygonUnauthorizedError
+		switch {
+		case errors.As(requestErr, &rateLimitErr):
+			// Polygon disagrees with our bucket, so drain it and wait at least as long as asked
+			state.consecutiveFailures++
+			state.tokens = 0
+			state.benchedUntil = pool.now().Add(max(pool.cooldown, rateLimitErr.RetryAfter))
+		case errors.As(requestErr, &unauthorizedErr) && unauthorizedErr.StatusCode != http.StatusForbidden:
+			// The key may have been revoked, back off exponentially
+			state.consecutiveFailures++
+			cooldown := time.Duration(float64(pool.cooldown) * math.Pow(2, float64(state.consecutiveFailures-1)))
+			state.benchedUntil = pool.now().Add(min(cooldown, maxKeyCooldown))
+		case requestErr == nil:
+			state.consecutiveFailures = 0
+		}
+		return
+	}
+}
+
+// Returns a snapshot of the state of every key of the pool
+func (pool *polygonKeyPool) health() []PolygonKeyHealth {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	now := pool.now()
+	healths := make([]PolygonKeyHealth, 0, len(pool.keys))
+	for _, state := range pool.keys {
+		pool.refill(state, now)
+		healths = append(healths, PolygonKeyHealth{
+			Key:                 maskPolygonKey(state.key),
+			Available:           pool.waitFor(state, now) <= 0,
+			Tokens:              state.tokens,
+			BenchedUntil:        state.benchedUntil,
+			Requests:            state.requests,
+			ConsecutiveFailures: state.consecutiveFailures,
+		})
+	}
+	return healths
+}
+
+// Hides all but the last 4 characters of a key
+func maskPolygonKey(key string) string {
+	if len(key) <= 4 {
+		return "****"
+	}
+	return "****" + key[len(key)-4:]
+}
+
+// Sets (or replaces) the apiKey query parameter of a Polygon URL
+func withPolygonKey(rawURL string, key string) (string, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "", errors.Join(errors.New("error parsing polygon url"), err)
+	}
+	query := parsed.Query()
+	query.Set("apiKey", key)
+	parsed.RawQuery = query.Encode()
+	return parsed.String(), nil
+}
@@ -0,0 +1,160 @@
+package polygon
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// Clock whose time only moves when the test says so
+type testPoolClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (clock *testPoolClock) Now() time.Time {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	return clock.now
+}
+
+func (clock *testPoolClock) Advance(d time.Duration) {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	clock.now = clock.now.Add(d)
+}
+
+func sleepFor(d time.Duration) error {
+	time.Sleep(d)
+	return nil
+}
+
+func TestPolygonKeyPool_PerKeyBudget(t *testing.T) {
+	clock := &testPoolClock{now: time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC)}
+	pool := newPolygonKeyPool([]string{"key-a", "key-b"}, 2, 0, time.Minute, clock.Now)
+
+	// Two keys with a budget of 2 requests each can serve 4 requests immediately, alternating keys
+	expected := []string{"key-a", "key-b", "key-a", "key-b"}
+	for i, want := range expected {
+		key, wait, err := pool.tryAcquire()
+		if err != nil {
+			t.Fatalf("tryAcquire error: %v", err)
+		}
+		if key != want {
+			t.Fatalf("request %d: expected %s, got %q (wait %s)", i, want, key, wait)
+		}
+	}
+
+	// The fifth has to wait for half a minute, until one token has been refilled
+	key, wait, _ := pool.tryAcquire()
+	if key != "" {
+		t.Fatalf("expected every key to be exhausted, got %s", key)
+	}
+	if wait != 30*time.Second {
+		t.Fatalf("expected a 30s wait, got %s", wait)
+	}
+
+	clock.Advance(30 * time.Second)
+	if key, _, _ := pool.tryAcquire(); key == "" {
+		t.Fatalf("expected a key to be available after refill")
+	}
+}
+
+func TestPolygonKeyPool_BenchesRejectedKeys(t *testing.T) {
+	clock := &testPoolClock{now: time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC)}
+	pool := newPolygonKeyPool([]string{"key-a", "key-b"}, 100, 0, time.Minute, clock.Now)
+
+	pool.report("key-a", &PolygonRateLimitError{RetryAfter: 2 * time.Minute})
+	pool.report("key-b", &PolygonUnauthorizedError{StatusCode: 401})
+
+	if key, wait, _ := pool.tryAcquire(); key != "" || wait != time.Minute {
+		t.Fatalf("expected both keys to be benched (key-b for 1m), got %q wait %s", key, wait)
+	}
+
+	clock.Advance(time.Minute)
+	if key, _, _ := pool.tryAcquire(); key != "key-b" {
+		t.Fatalf("expected key-b to be back after its cooldown, got %q", key)
+	}
+
+	// A second rejection doubles the cooldown
+	pool.report("key-b", &PolygonUnauthorizedError{StatusCode: 401})
+	clock.Advance(time.Minute)
+	if key, _, _ := pool.tryAcquire(); key != "key-a" {
+		t.Fatalf("expected key-a (Retry-After elapsed) while key-b is still benched, got %q", key)
+	}
+
+	health := pool.health()
+	if health[1].Available || health[1].ConsecutiveFailures != 2 {
+		t.Fatalf("unexpected health for key-b: %+v", health[1])
+	}
+	if health[0].Key != "****ey-a" {
+		t.Fatalf("expected the key to be masked, got %s", health[0].Key)
+	}
+}
+
+func TestPolygonKeyPool_ForbiddenDoesNotBench(t *testing.T) {
+	clock := &testPoolClock{now: time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC)}
+	pool := newPolygonKeyPool([]string{"key-a"}, 100, 0, time.Minute, clock.Now)
+
+	// An endpoint the plan doesn't cover must not take the key offline for every other endpoint
+	pool.report("key-a", &PolygonUnauthorizedError{StatusCode: 403})
+	if key, _, _ := pool.tryAcquire(); key != "key-a" {
+		t.Fatalf("expected key-a to stay available after a 403, got %q", key)
+	}
+	if health := pool.health(); health[0].ConsecutiveFailures != 0 {
+		t.Fatalf("expected a 403 not to count as a failure of the key, got %+v", health[0])
+	}
+}
+
+func TestPolygonKeyPool_MinInterval(t *testing.T) {
+	clock := &testPoolClock{now: time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC)}
+	pool := newPolygonKeyPool([]string{"key-a"}, 100, 12*time.Second, time.Minute, clock.Now)
+
+	if key, _, _ := pool.tryAcquire(); key != "key-a" {
+		t.Fatalf("expected key-a")
+	}
+	if _, wait, _ := pool.tryAcquire(); wait != 12*time.Second {
+		t.Fatalf("expected to wait the throttle time, got %s", wait)
+	}
+}
+
+func TestPolygonKeyPool_Concurrent(t *testing.T) {
+	pool := newPolygonKeyPool([]string{"key-a", "key-b", "key-c"}, 1000, 0, time.Minute, time.Now)
+
+	var wg sync.WaitGroup
+	counts := make(chan string, 300)
+	for i := 0; i < 30; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				key, err := pool.acquire(sleepFor)
+				if err != nil {
+					t.Errorf("acquire error: %v", err)
+					return
+				}
+				pool.report(key, nil)
+				counts <- key
+			}
+		}()
+	}
+	wg.Wait()
+	close(counts)
+
+	perKey := map[string]int{}
+	for key := range counts {
+		perKey[key]++
+	}
+	for _, key := range []string{"key-a", "key-b", "key-c"} {
+		if perKey[key] != 100 {
+			t.Fatalf("expected requests to be spread evenly, got %v", perKey)
+		}
+	}
+}
+
+func TestPolygonKeyPool_Empty(t *testing.T) {
+	pool := newPolygonKeyPool(nil, 5, 0, time.Minute, time.Now)
+	if _, err := pool.acquire(sleepFor); err == nil {
+		t.Fatalf("expected an error for an empty pool")
+	}
+}
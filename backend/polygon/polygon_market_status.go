@@ -0,0 +1,95 @@
+package polygon
+
+// This file contains the wrappers for Polygon's market status endpoints
+// https://polygon.io/docs/stocks/get_v1_marketstatus_now
+// https://polygon.io/docs/stocks/get_v1_marketstatus_upcoming
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Values of PolygonGetMarketStatusResponse.Market and of the exchanges' statuses
+const (
+	MarketOpen          = "open"
+	MarketClosed        = "closed"
+	MarketExtendedHours = "extended-hours"
+)
+
+// Values of PolygonMarketHoliday.Status
+const (
+	HolidayClosed     = "closed"
+	HolidayEarlyClose = "early-close"
+)
+
+type PolygonGetMarketStatusResponse struct {
+	Market     *string `json:"market"` // MarketOpen, MarketClosed or MarketExtendedHours
+	ServerTime *string `json:"serverTime"`
+	EarlyHours *bool   `json:"earlyHours"`
+	AfterHours *bool   `json:"afterHours"`
+	Exchanges  *struct {
+		Nasdaq *string `json:"nasdaq"`
+		Nyse   *string `json:"nyse"`
+		Otc    *string `json:"otc"`
+	} `json:"exchanges"`
+	Currencies *struct {
+		Crypto *string `json:"crypto"`
+		Fx     *string `json:"fx"`
+	} `json:"currencies"`
+}
+
+// An upcoming holiday of a single exchange. Open and Close are only set for early closes.
+type PolygonMarketHoliday struct {
+	Exchange *string `json:"exchange"` // e.g. "NYSE" or "NASDAQ"
+	Name     *string `json:"name"`
+	Date     *string `json:"date"`   // YYYY-MM-DD
+	Status   *string `json:"status"` // HolidayClosed or HolidayEarlyClose
+	Open     *string `json:"open"`   // RFC 3339
+	Close    *string `json:"close"`  // RFC 3339
+}
+
+// PolygonGetMarketStatus returns the current trading status of the exchanges and currency markets
+//
+// Output:
+//   - *PolygonGetMarketStatusResponse: the response from the Polygon API
+//   - error: any error that occurred
+func (polygonConnection *PolygonConnection) PolygonGetMarketStatus() (*PolygonGetMarketStatusResponse, error) {
+	return polygonConnection.PolygonGetMarketStatusWithContext(context.Background())
+}
+
+// PolygonGetMarketStatusWithContext is PolygonGetMarketStatus bounded by ctx
+func (polygonConnection *PolygonConnection) PolygonGetMarketStatusWithContext(ctx context.Context) (*PolygonGetMarketStatusResponse, error) {
+	url := fmt.Sprintf("%s/v1/marketstatus/now", polygonConnection.baseURL)
+
+	response, err := GenericPolygonGetRequestWithContext[PolygonGetMarketStatusResponse](ctx, polygonConnection, url)
+	if err != nil && !IsPartialPolygonData(err) {
+		return nil, errors.Join(errors.New("error getting info from polygon"), err)
+	}
+	if response.Market == nil {
+		return nil, ErrNoResults
+	}
+
+	return response, err
+}
+
+// PolygonGetMarketHolidays returns the upcoming holidays and early closes of every exchange
+//
+// Output:
+//   - []PolygonMarketHoliday: the holidays, one entry per exchange and date
+//   - error: any error that occurred
+func (polygonConnection *PolygonConnection) PolygonGetMarketHolidays() ([]PolygonMarketHoliday, error) {
+	return polygonConnection.PolygonGetMarketHolidaysWithContext(context.Background())
+}
+
+// PolygonGetMarketHolidaysWithContext is PolygonGetMarketHolidays bounded by ctx
+func (polygonConnection *PolygonConnection) PolygonGetMarketHolidaysWithContext(ctx context.Context) ([]PolygonMarketHoliday, error) {
+	url := fmt.Sprintf("%s/v1/marketstatus/upcoming", polygonConnection.baseURL)
+
+	response, err := GenericPolygonGetRequestWithContext[[]PolygonMarketHoliday](ctx, polygonConnection, url)
+	if err != nil && !IsPartialPolygonData(err) {
+		return nil, errors.Join(errors.New("error getting info from polygon"), err)
+	}
+
+	return *response, err
+}
@@ -0,0 +1,93 @@
+package polygon
+
+import (
+	"net/http"
+	"time"
+)
+
+// The URL of the real Polygon API, used unless WithBaseURL is given
+const DefaultBaseURL = "https://api.polygon.io"
+
+// The default limit for a single attempt of a request
+const DefaultRequestTimeout = 30 * time.Second
+
+// Clock abstracts time so tests can control the throttling and retry waits of a connection
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+type polygonConnectionConfig struct {
+	baseURL              string
+	httpClient           *http.Client
+	clock                Clock
+	throttleTime         time.Duration
+	keyRequestsPerMinute int
+	keyCooldown          time.Duration
+	retryPolicy          PolygonRetryPolicy
+	requestTimeout       time.Duration
+	cassetteDir          string
+	cassetteMode         PolygonCassetteMode
+	cache                PolygonCache
+	cachePolicy          PolygonCachePolicy
+}
+
+// PolygonConnectionOption customizes a connection created by GetPolygonConnection
+type PolygonConnectionOption func(*polygonConnectionConfig)
+
+// WithBaseURL sends requests to baseURL (e.g. a fake server) instead of https://api.polygon.io
+func WithBaseURL(baseURL string) PolygonConnectionOption {
+	return func(config *polygonConnectionConfig) {
+		config.baseURL = baseURL
+	}
+}
+
+// WithHTTPClient sends requests with client instead of http.DefaultClient
+func WithHTTPClient(client *http.Client) PolygonConnectionOption {
+	return func(config *polygonConnectionConfig) {
+		config.httpClient = client
+	}
+}
+
+// WithClock makes the connection read the time and wait with clock instead of the system clock
+func WithClock(clock Clock) PolygonConnectionOption {
+	return func(config *polygonConnectionConfig) {
+		config.clock = clock
+	}
+}
+
+// WithThrottle sets the minimum delay between two requests made with the same key
+func WithThrottle(throttleTime time.Duration) PolygonConnectionOption {
+	return func(config *polygonConnectionConfig) {
+		config.throttleTime = throttleTime
+	}
+}
+
+// WithKeyRateLimit sets the request budget of every key, and how long a key is benched
+// after being rate limited or rejected
+func WithKeyRateLimit(requestsPerMinute int, cooldown time.Duration) PolygonConnectionOption {
+	return func(config *polygonConnectionConfig) {
+		config.keyRequestsPerMinute = requestsPerMinute
+		config.keyCooldown = cooldown
+	}
+}
+
+// WithRequestTimeout limits every attempt of a request to timeout (DefaultRequestTimeout by default).
+// Set timeout <= 0 to rely on the caller's context only.
+func WithRequestTimeout(timeout time.Duration) PolygonConnectionOption {
+	return func(config *polygonConnectionConfig) {
+		config.requestTimeout = timeout
+	}
+}
+
+// WithRetryPolicy replaces DefaultPolygonRetryPolicy
+func WithRetryPolicy(policy PolygonRetryPolicy) PolygonConnectionOption {
+	return func(config *polygonConnectionConfig) {
+		config.retryPolicy = policy
+	}
+}
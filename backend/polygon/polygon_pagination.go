@@ -0,0 +1,227 @@
+package polygon
+
+// This file contains helpers for walking Polygon's paginated endpoints.
+//
+// Polygon returns at most `limit` results per response and sets `next_url` when more results
+// are available. The next_url never contains an API key; GenericPolygonGetRequest attaches a fresh one to every page.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// PolygonPageStats reports how much of a paginated result set was fetched
+type PolygonPageStats struct {
+	Pages     int  // Number of pages fetched
+	Results   int  // Number of results across all fetched pages
+	Truncated bool // True if Polygon still had a next_url when the page cap was reached
+}
+
+// PolygonPageIterator walks a paginated Polygon endpoint one page at a time.
+//
+// Usage:
+//
+//	for iterator.Next() {
+//		page := iterator.Page()
+//	}
+//	if err := iterator.Err(); err != nil { ... }
+type PolygonPageIterator[T any] struct {
+	ctx               context.Context
+	polygonConnection *PolygonConnection
+	nextURL           string
+	maxPages          int
+	page              *T
+	err               error
+	stats             PolygonPageStats
+	partialErr        *PolygonPartialDataError
+	nextURLOf         func(*T) *string
+	countOf           func(*T) int
+}
+
+// Creates an iterator starting at firstURL (without an API key), whose requests are bounded by ctx.
+// maxPages <= 0 means the iterator walks until next_url is exhausted.
+func newPolygonPageIterator[T any](ctx context.Context, polygonConnection *PolygonConnection, firstURL string, maxPages int, nextURLOf func(*T) *string, countOf func(*T) int) *PolygonPageIterator[T] {
+	return &PolygonPageIterator[T]{
+		ctx:               ctx,
+		polygonConnection: polygonConnection,
+		nextURL:           firstURL,
+		maxPages:          maxPages,
+		nextURLOf:         nextURLOf,
+		countOf:           countOf,
+	}
+}
+
+// Next fetches the next page. It returns false once every page has been fetched,
+// the page cap has been reached or an error occurred (check Err).
+func (iterator *PolygonPageIterator[T]) Next() bool {
+	if iterator.err != nil || iterator.nextURL == "" {
+		return false
+	}
+	if iterator.maxPages > 0 && iterator.stats.Pages >= iterator.maxPages {
+		iterator.stats.Truncated = true
+		return false
+	}
+
+	page, err := GenericPolygonGetRequestWithContext[T](iterator.ctx, iterator.polygonConnection, iterator.nextURL)
+	if err != nil && !IsPartialPolygonData(err) {
+		iterator.err = errors.Join(fmt.Errorf("error getting page %d from polygon", iterator.stats.Pages+1), err)
+		return false
+	}
+	// The rest of an incomplete page is still returned, and so are the next pages
+	var partialErr *PolygonPartialDataError
+	if errors.As(err, &partialErr) {
+		if iterator.partialErr == nil {
+			iterator.partialErr = &PolygonPartialDataError{URL: partialErr.URL}
+		}
+		iterator.partialErr.Missing = append(iterator.partialErr.Missing, partialErr.Missing...)
+		iterator.partialErr.Dropped += partialErr.Dropped
+	}
+
+	iterator.page = page
+	iterator.stats.Pages++
+	iterator.stats.Results += iterator.countOf(page)
+
+	iterator.nextURL = ""
+	if next := iterator.nextURLOf(page); next != nil {
+		iterator.nextURL = *next
+	}
+
+	return true
+}
+
+// Page returns the page fetched by the last successful call to Next
+func (iterator *PolygonPageIterator[T]) Page() *T {
+	return iterator.page
+}
+
+// Err returns the error that stopped the iteration, if any
+func (iterator *PolygonPageIterator[T]) Err() error {
+	return iterator.err
+}
+
+// PartialErr returns a *PolygonPartialDataError listing the fields missing from the pages fetched so far,
+// nil if every page was complete. Paths are relative to their page, and the URL is the first incomplete page's.
+func (iterator *PolygonPageIterator[T]) PartialErr() error {
+	if iterator.partialErr == nil {
+		return nil
+	}
+	return iterator.partialErr
+}
+
+// Stats returns the number of pages and results fetched so far
+func (iterator *PolygonPageIterator[T]) Stats() PolygonPageStats {
+	return iterator.stats
+}
+
+// Fetches every page of an endpoint starting at firstURL and returns the merged results
+func getAllPages[T any, R any](ctx context.Context, polygonConnection *PolygonConnection, firstURL string, resultsOf func(*T) *[]R, nextURLOf func(*T) *string) ([]R, error) {
+	iterator := newPolygonPageIterator(ctx, polygonConnection, firstURL, 0, nextURLOf, func(page *T) int {
+		if results := resultsOf(page); results != nil {
+			return len(*results)
+		}
+		return 0
+	})
+
+	merged := []R{}
+	for iterator.Next() {
+		if results := resultsOf(iterator.Page()); results != nil {
+			merged = append(merged, *results...)
+		}
+	}
+	if err := iterator.Err(); err != nil {
+		return nil, errors.Join(errors.New("error getting info from polygon"), err)
+	}
+	return merged, iterator.PartialErr()
+}
+
+// PolygonIterateTickerNews returns an iterator over every page of news articles about a ticker
+// within the selected time range.
+//
+// Input:
+//   - symbol: the symbol of the stock
+//   - startDate: the earliest date to retrieve data from
+//   - endDate: the lastest date to retrieve data from
+//   - limit: the number of articles per page, set <= 0 for the default
+//   - maxPages: the maximum number of pages to fetch, set <= 0 to follow next_url until exhausted
+//
+// Output:
+//   - *PolygonPageIterator[PolygonGetTickerNews]: the page iterator
+//   - error: any error that occurred
+func (polygonConnection *PolygonConnection) PolygonIterateTickerNews(symbol string, startDate time.Time, endDate time.Time, limit int, maxPages int) (*PolygonPageIterator[PolygonGetTickerNews], error) {
+	return polygonConnection.PolygonIterateTickerNewsWithContext(context.Background(), symbol, startDate, endDate, limit, maxPages)
+}
+
+// PolygonIterateTickerNewsWithContext is PolygonIterateTickerNews bounded by ctx
+func (polygonConnection *PolygonConnection) PolygonIterateTickerNewsWithContext(ctx context.Context, symbol string, startDate time.Time, endDate time.Time, limit int, maxPages int) (*PolygonPageIterator[PolygonGetTickerNews], error) {
+	if startDate.After(endDate) {
+		return nil, errors.New("start date cannot be after end date")
+	}
+
+	return newPolygonPageIterator(ctx, polygonConnection, polygonConnection.tickerNewsURL(symbol, startDate, endDate, limit), maxPages,
+		func(page *PolygonGetTickerNews) *string { return page.NextURL },
+		func(page *PolygonGetTickerNews) int {
+			if page.Results == nil {
+				return 0
+			}
+			return len(*page.Results)
+		},
+	), nil
+}
+
+// PolygonGetTickerNewsPaginated returns the news articles about a ticker within the selected time range,
+// following next_url until every page has been fetched or maxPages is reached.
+// The results of every page are merged into a single response, whose NextURL is set if the result was truncated.
+//
+// Input:
+//   - symbol: the symbol of the stock
+//   - startDate: the earliest date to retrieve data from
+//   - endDate: the lastest date to retrieve data from
+//   - limit: the number of articles per page, set <= 0 for the default
+//   - maxPages: the maximum number of pages to fetch, set <= 0 to follow next_url until exhausted
+//
+// Output:
+//   - *PolygonGetTickerNews: the merged response from the Polygon API
+//   - PolygonPageStats: how many pages and articles were fetched, and whether the result was truncated
+//   - error: any error that occurred
+func (polygonConnection *PolygonConnection) PolygonGetTickerNewsPaginated(symbol string, startDate time.Time, endDate time.Time, limit int, maxPages int) (*PolygonGetTickerNews, PolygonPageStats, error) {
+	return polygonConnection.PolygonGetTickerNewsPaginatedWithContext(context.Background(), symbol, startDate, endDate, limit, maxPages)
+}
+
+// PolygonGetTickerNewsPaginatedWithContext is PolygonGetTickerNewsPaginated bounded by ctx
+func (polygonConnection *PolygonConnection) PolygonGetTickerNewsPaginatedWithContext(ctx context.Context, symbol string, startDate time.Time, endDate time.Time, limit int, maxPages int) (*PolygonGetTickerNews, PolygonPageStats, error) {
+	iterator, err := polygonConnection.PolygonIterateTickerNewsWithContext(ctx, symbol, startDate, endDate, limit, maxPages)
+	if err != nil {
+		return nil, PolygonPageStats{}, err
+	}
+
+	var merged *PolygonGetTickerNews
+	results := []PolygonTickerNewsResult{}
+	for iterator.Next() {
+		page := iterator.Page()
+		if merged == nil {
+			merged = page
+		}
+		if page.Results != nil {
+			results = append(results, *page.Results...)
+		}
+		merged.NextURL = page.NextURL
+	}
+	stats := iterator.Stats()
+	if err := iterator.Err(); err != nil {
+		return nil, stats, errors.Join(errors.New("error getting info from polygon"), err)
+	}
+	if merged == nil || len(results) == 0 {
+		return nil, stats, ErrNoResults
+	}
+
+	count := len(results)
+	merged.Results = &results
+	merged.Count = &count
+	if !stats.Truncated {
+		merged.NextURL = nil
+	}
+
+	return merged, stats, iterator.PartialErr()
+}
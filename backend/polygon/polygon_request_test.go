@@ -1,54 +1,41 @@
 package polygon
 
 import (
+	"context"
 	"errors"
-	"financial-helper/environment"
+	"financial-helper/polygon/polygontest"
+	"fmt"
 	"log"
+	"net/http"
+	"net/http/httptest"
 	"os"
-	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
 
 var testTicker string = "AAPL"
 
+// The fixtures served by the fake API end on 2024-10-31, so tests use it as "now"
+var testNow = time.Date(2024, 10, 31, 0, 0, 0, 0, time.UTC)
+
 var polygonConnection *PolygonConnection
-var testTeardown func()
-var testInitErr error
+var fakePolygon *polygontest.Server
 
 func TestMain(m *testing.M) {
-	// Initialize server once for all tests
-	polygonConnection, testTeardown, testInitErr = func() (*PolygonConnection, func(), error) {
-		if err := environment.LoadEnvironment(); err != nil {
-			return nil, nil, errors.Join(errors.New("failed to load environment for testing"), err)
-		}
-		vars, polygonKeys, err := environment.LoadVars()
-		if err != nil {
-			return nil, nil, errors.Join(errors.New("failed to load env variables"), err)
-		}
-		throttleTimeInt, _ := strconv.Atoi(vars["THROTTLE_TIME"])
-		server := GetPolygonConnection(polygonKeys, time.Duration(throttleTimeInt)*time.Second)
-		if err != nil {
-			return nil, nil, err
-		}
-		// Note: don't start the router unless you need it for integration tests
-		return server, func() {
-			log.Println("Tearing down test environment...")
-		}, nil
-	}()
-
-	if testInitErr != nil {
-		// Fail early; individual tests can still choose to skip if API key is missing.
-		log.Printf("failed to initialize test server: %v\n", testInitErr)
-		os.Exit(1)
-	}
+	// Every test runs against the fake API, so no keys or network access are needed
+	fakePolygon = polygontest.NewServer()
+	polygonConnection = GetPolygonConnection([]string{"test-key-1", "test-key-2"},
+		WithBaseURL(fakePolygon.URL),
+		WithHTTPClient(fakePolygon.Client()),
+		WithKeyRateLimit(1000, time.Minute),
+	)
 
 	code := m.Run()
 
-	if testTeardown != nil {
-		testTeardown()
-	}
+	fakePolygon.Close()
 	os.Exit(code)
 }
 
@@ -76,6 +63,42 @@ func TestPolygonGetTicker(t *testing.T) {
 	}
 }
 
+func TestPolygonGetTickerDetails(t *testing.T) {
+	if polygonConnection == nil {
+		t.Skip("test server not initialized")
+	}
+
+	resp, err := polygonConnection.PolygonGetTickerDetails(testTicker)
+	if err != nil {
+		t.Fatalf("PolygonGetTickerDetails error: %v", err)
+	}
+	details := resp.Results
+	if details.Ticker == nil || *details.Ticker != testTicker {
+		t.Fatalf("expected details of %s", testTicker)
+	}
+	if details.SicDescription == nil || *details.SicDescription == "" {
+		t.Fatalf("expected an industry (sic_description)")
+	}
+	if details.MarketCap == nil || *details.MarketCap <= 0 {
+		t.Fatalf("expected a positive market cap")
+	}
+	if details.Branding == nil || details.Branding.LogoURL == nil {
+		t.Fatalf("expected branding")
+	}
+}
+
+func TestPolygonGetTickerDetails_UnknownTicker(t *testing.T) {
+	if polygonConnection == nil {
+		t.Skip("test server not initialized")
+	}
+
+	_, err := polygonConnection.PolygonGetTickerDetails("NOPE")
+	var notFoundErr *PolygonNotFoundError
+	if !errors.As(err, &notFoundErr) {
+		t.Fatalf("expected a not found error, got %v", err)
+	}
+}
+
 func TestPolygonGetTickerDailyClose(t *testing.T) {
 	if polygonConnection == nil {
 		t.Skip("test server not initialized")
@@ -103,7 +126,7 @@ func TestPolygonGetTickerHistory(t *testing.T) {
 		t.Skip("test server not initialized")
 	}
 
-	end := time.Now().UTC().AddDate(0, 0, -3)
+	end := testNow.AddDate(0, 0, -3)
 	start := end.AddDate(0, 0, -7)
 	resp, err := polygonConnection.PolygonGetTickerHistory(testTicker, start, end, 5)
 	if err != nil {
@@ -128,7 +151,7 @@ func TestPolygonGetTickerNews(t *testing.T) {
 		t.Skip("test server not initialized")
 	}
 
-	end := time.Now().UTC().AddDate(0, 0, -5)
+	end := testNow.AddDate(0, 0, -5)
 	start := end.AddDate(0, 0, -10)
 	resp, err := polygonConnection.PolygonGetTickerNews(testTicker, start, end, 10)
 	if err != nil {
@@ -153,7 +176,7 @@ func TestPolygonGetTickerNews_SingleDay(t *testing.T) {
 		t.Skip("test server not initialized")
 	}
 
-	end := time.Now().UTC().AddDate(0, 0, -5)
+	end := testNow.AddDate(0, 0, -5)
 	start := end.AddDate(0, 0, -1)
 	resp, err := polygonConnection.PolygonGetTickerNews(testTicker, start, end, 10)
 	if err != nil {
@@ -178,7 +201,7 @@ func TestPolygonGetTickerNews_SingleWeek(t *testing.T) {
 		t.Skip("test server not initialized")
 	}
 
-	end := time.Now().UTC().AddDate(0, 0, -5)
+	end := testNow.AddDate(0, 0, -5)
 	start := end.AddDate(0, 0, -30)
 	resp, err := polygonConnection.PolygonGetTickerNews(testTicker, start, end, 500)
 	if err != nil {
@@ -197,3 +220,571 @@ func TestPolygonGetTickerNews_SingleWeek(t *testing.T) {
 	}
 	log.Println("Got response from PolygonGetTickerNews:", PolygonResponseToString(resp))
 }
+
+func TestPolygonGetTickerNewsPaginated(t *testing.T) {
+	if polygonConnection == nil {
+		t.Skip("test server not initialized")
+	}
+
+	end := testNow.AddDate(0, 0, -5)
+	start := end.AddDate(0, 0, -10)
+	resp, stats, err := polygonConnection.PolygonGetTickerNewsPaginated(testTicker, start, end, 5, 3)
+	if err != nil {
+		t.Fatalf("PolygonGetTickerNewsPaginated error: %v", err)
+	}
+	if resp == nil || resp.Results == nil || len(*resp.Results) == 0 {
+		t.Fatalf("expected news results")
+	}
+	if stats.Pages < 1 || stats.Pages > 3 {
+		t.Fatalf("expected between 1 and 3 pages, got %d", stats.Pages)
+	}
+	if stats.Results != len(*resp.Results) {
+		t.Fatalf("stats reported %d articles but response has %d", stats.Results, len(*resp.Results))
+	}
+	if stats.Truncated && resp.NextURL == nil {
+		t.Fatalf("truncated response should keep its next_url")
+	}
+	for i, result := range *resp.Results {
+		if !result.PublishedUTC.After(start) || !result.PublishedUTC.Before(end) {
+			t.Fatalf("Result #%d in PolygonGetTickerNewsPaginated was published on %s, which is not within range (%s - %s)", i, result.PublishedUTC.Format("2006-01-02T15:04:05Z"), start.Format("2006-01-02T15:04:05Z"), end.Format("2006-01-02T15:04:05Z"))
+		}
+	}
+}
+
+func TestPolygonIterateTickerNews(t *testing.T) {
+	if polygonConnection == nil {
+		t.Skip("test server not initialized")
+	}
+
+	end := testNow.AddDate(0, 0, -5)
+	start := end.AddDate(0, 0, -10)
+	iterator, err := polygonConnection.PolygonIterateTickerNews(testTicker, start, end, 5, 2)
+	if err != nil {
+		t.Fatalf("PolygonIterateTickerNews error: %v", err)
+	}
+	pages := 0
+	for iterator.Next() {
+		pages++
+		if iterator.Page() == nil {
+			t.Fatalf("expected a page on iteration %d", pages)
+		}
+	}
+	if err := iterator.Err(); err != nil {
+		t.Fatalf("iteration error: %v", err)
+	}
+	if pages != iterator.Stats().Pages {
+		t.Fatalf("iterated %d pages but stats reported %d", pages, iterator.Stats().Pages)
+	}
+	if pages > 2 {
+		t.Fatalf("expected at most 2 pages, got %d", pages)
+	}
+}
+
+func TestWithPolygonKey(t *testing.T) {
+	got, err := withPolygonKey("https://api.polygon.io/v2/reference/news?cursor=abc&apiKey=old", "new")
+	if err != nil {
+		t.Fatalf("withPolygonKey error: %v", err)
+	}
+	if !strings.Contains(got, "apiKey=new") || strings.Contains(got, "apiKey=old") || !strings.Contains(got, "cursor=abc") {
+		t.Fatalf("unexpected url %s", got)
+	}
+}
+
+func TestGenericPolygonGetRequest_TypedErrors(t *testing.T) {
+	cases := []struct {
+		status int
+		check  func(error) bool
+	}{
+		{http.StatusTooManyRequests, func(err error) bool {
+			var e *PolygonRateLimitError
+			return errors.As(err, &e) && e.RetryAfter == 2*time.Second
+		}},
+		{http.StatusUnauthorized, func(err error) bool {
+			var e *PolygonUnauthorizedError
+			return errors.As(err, &e) && IsFatalPolygonError(err)
+		}},
+		{http.StatusForbidden, func(err error) bool { var e *PolygonUnauthorizedError; return errors.As(err, &e) }},
+		{http.StatusNotFound, func(err error) bool {
+			var e *PolygonNotFoundError
+			return errors.As(err, &e) && !IsRetryablePolygonError(err)
+		}},
+		{http.StatusBadGateway, func(err error) bool {
+			var e *PolygonServerError
+			return errors.As(err, &e) && IsRetryablePolygonError(err)
+		}},
+		{http.StatusBadRequest, func(err error) bool { var e *PolygonRequestError; return errors.As(err, &e) }},
+	}
+
+	for _, tc := range cases {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "2")
+			w.WriteHeader(tc.status)
+			w.Write([]byte(`{"status":"ERROR","error":"test error"}`))
+		}))
+		connection := GetPolygonConnection([]string{"secret-key"}, WithRetryPolicy(NoPolygonRetries()))
+
+		_, err := GenericPolygonGetRequest[PolygonGetTickerResponse](connection, server.URL+"/v3/reference/tickers?apiKey=secret-key")
+		server.Close()
+		if err == nil {
+			t.Fatalf("status %d: expected an error", tc.status)
+		}
+		if !tc.check(err) {
+			t.Fatalf("status %d: unexpected error type %T: %v", tc.status, err, err)
+		}
+		if strings.Contains(err.Error(), "secret-key") {
+			t.Fatalf("status %d: error leaks the api key: %v", tc.status, err)
+		}
+	}
+}
+
+func TestGenericPolygonGetRequest_DecodeError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"results": [`))
+	}))
+	defer server.Close()
+	connection := GetPolygonConnection([]string{"key"})
+
+	_, err := GenericPolygonGetRequest[PolygonGetTickerResponse](connection, server.URL)
+	var decodeErr *PolygonDecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("expected a PolygonDecodeError, got %T: %v", err, err)
+	}
+}
+
+func TestGenericPolygonGetRequest_RetriesWithBackoff(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"status":"OK","count":1,"results":[{"ticker":"AAPL"}]}`))
+	}))
+	defer server.Close()
+	connection := GetPolygonConnection([]string{"key-1", "key-2"},
+		WithRetryPolicy(PolygonRetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond, Multiplier: 2}))
+
+	resp, err := GenericPolygonGetRequest[PolygonGetTickerResponse](connection, server.URL+"?apiKey=key-1")
+	if err != nil {
+		t.Fatalf("expected the third attempt to succeed, got %v", err)
+	}
+	if calls.Load() != 3 {
+		t.Fatalf("expected 3 attempts, got %d", calls.Load())
+	}
+	if resp.Results == nil || len(*resp.Results) != 1 {
+		t.Fatalf("expected one result")
+	}
+}
+
+func TestPolygonRetryPolicyBackoff(t *testing.T) {
+	policy := PolygonRetryPolicy{MaxAttempts: 5, InitialBackoff: time.Second, MaxBackoff: 10 * time.Second, Multiplier: 2}
+	if got := policy.backoff(1, 0); got != time.Second {
+		t.Fatalf("expected 1s for the first retry, got %s", got)
+	}
+	if got := policy.backoff(3, 0); got != 4*time.Second {
+		t.Fatalf("expected 4s for the third retry, got %s", got)
+	}
+	if got := policy.backoff(10, 0); got != 10*time.Second {
+		t.Fatalf("expected backoff to be capped at 10s, got %s", got)
+	}
+	if got := policy.backoff(1, 5*time.Second); got != 5*time.Second {
+		t.Fatalf("expected Retry-After to be honoured, got %s", got)
+	}
+	if got := policy.backoff(1, 30*time.Second); got != 30*time.Second {
+		t.Fatalf("expected Retry-After not to be capped, got %s", got)
+	}
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if got := policy.backoff(2, 0); got < time.Second || got > 3*time.Second {
+			t.Fatalf("jittered backoff %s out of range", got)
+		}
+	}
+}
+
+// Clock that never blocks and records every wait, so retries and throttling can be tested instantly
+type testRecordingClock struct {
+	mu    sync.Mutex
+	now   time.Time
+	waits []time.Duration
+}
+
+func (clock *testRecordingClock) Now() time.Time {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	return clock.now
+}
+
+func (clock *testRecordingClock) After(d time.Duration) <-chan time.Time {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	clock.now = clock.now.Add(d)
+	clock.waits = append(clock.waits, d)
+	channel := make(chan time.Time, 1)
+	channel <- clock.now
+	return channel
+}
+
+func TestPolygonConnection_RejectedKeyIsSkipped(t *testing.T) {
+	fake := polygontest.NewServer()
+	defer fake.Close()
+	fake.RejectKey("bad-key")
+
+	clock := &testRecordingClock{now: testNow}
+	connection := GetPolygonConnection([]string{"bad-key", "good-key"},
+		WithBaseURL(fake.URL),
+		WithHTTPClient(fake.Client()),
+		WithClock(clock),
+		WithRetryPolicy(NoPolygonRetries()),
+	)
+
+	// The first request is sent with the rejected key and fails...
+	_, err := connection.PolygonGetTicker(testTicker)
+	if !IsFatalPolygonError(err) {
+		t.Fatalf("expected an unauthorized error, got %v", err)
+	}
+	// ...after which the key is benched and every request goes through the other one
+	for i := 0; i < 3; i++ {
+		if _, err := connection.PolygonGetTicker(testTicker); err != nil {
+			t.Fatalf("request %d: expected the healthy key to be used, got %v", i, err)
+		}
+	}
+	if health := connection.KeyHealth(); health[0].Available || health[0].Requests != 1 || health[1].Requests != 3 {
+		t.Fatalf("unexpected key health %+v", health)
+	}
+}
+
+func TestPolygonConnection_RetriesUseClock(t *testing.T) {
+	fake := polygontest.NewServer()
+	defer fake.Close()
+	fake.FailNext("/v2/aggs/ticker/AAPL/prev", http.StatusTooManyRequests, 2)
+
+	clock := &testRecordingClock{now: testNow}
+	connection := GetPolygonConnection([]string{"test-key"},
+		WithBaseURL(fake.URL),
+		WithHTTPClient(fake.Client()),
+		WithClock(clock),
+		WithKeyRateLimit(1000, time.Second),
+	)
+
+	resp, err := connection.PolygonGetTickerDailyClose(testTicker)
+	if err != nil {
+		t.Fatalf("expected the third attempt to succeed, got %v", err)
+	}
+	if resp.Results == nil || len(*resp.Results) != 1 {
+		t.Fatalf("expected one result")
+	}
+	if count := fake.RequestCount("/v2/aggs/ticker/AAPL/prev"); count != 3 {
+		t.Fatalf("expected 3 requests, got %d", count)
+	}
+
+	// Both the retry backoff and the benched key were waited out on the fake clock
+	var waited time.Duration
+	for _, wait := range clock.waits {
+		waited += wait
+	}
+	if waited < 2*time.Second {
+		t.Fatalf("expected to wait out both Retry-After headers, waited %s (%v)", waited, clock.waits)
+	}
+}
+
+func TestPolygonConnection_RetryAfterDateUsesClock(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", testNow.Add(90*time.Second).Format(http.TimeFormat))
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+	connection := GetPolygonConnection([]string{"key"},
+		WithClock(&testRecordingClock{now: testNow}),
+		WithRetryPolicy(NoPolygonRetries()),
+	)
+
+	// The date is 90 seconds after the fake clock, and long past on the system clock
+	_, err := GenericPolygonGetRequest[PolygonGetTickerResponse](connection, server.URL)
+	var rateLimitErr *PolygonRateLimitError
+	if !errors.As(err, &rateLimitErr) {
+		t.Fatalf("expected a PolygonRateLimitError, got %T: %v", err, err)
+	}
+	if rateLimitErr.RetryAfter != 90*time.Second {
+		t.Fatalf("expected Retry-After to be relative to the connection's clock, got %s", rateLimitErr.RetryAfter)
+	}
+}
+
+func TestPolygonConnection_LongRetryAfterIsNotRetried(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+	clock := &testRecordingClock{now: testNow}
+	connection := GetPolygonConnection([]string{"key-1", "key-2"}, WithClock(clock))
+
+	// Retrying before the two minutes Polygon asked for would only be rate limited again
+	_, err := GenericPolygonGetRequest[PolygonGetTickerResponse](connection, server.URL)
+	var rateLimitErr *PolygonRateLimitError
+	if !errors.As(err, &rateLimitErr) || rateLimitErr.RetryAfter != 2*time.Minute {
+		t.Fatalf("expected a PolygonRateLimitError asking for 2m, got %T: %v", err, err)
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("expected a single attempt, got %d", calls.Load())
+	}
+}
+
+func TestGenericPolygonGetRequestWithContext_Cancelled(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+	connection := GetPolygonConnection([]string{"key"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := GenericPolygonGetRequestWithContext[PolygonGetTickerResponse](ctx, connection, server.URL)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the request to be aborted by the context, got %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("a cancelled request must not be retried, got %d attempts", calls.Load())
+	}
+}
+
+func TestPolygonConnection_CancelledWhileWaitingForKey(t *testing.T) {
+	// With a budget of 1 request per minute, the second request has to wait for a key
+	connection := GetPolygonConnection([]string{"test-key"},
+		WithBaseURL(fakePolygon.URL),
+		WithHTTPClient(fakePolygon.Client()),
+		WithKeyRateLimit(1, time.Minute),
+	)
+	if _, err := connection.PolygonGetTickerWithContext(context.Background(), testTicker); err != nil {
+		t.Fatalf("PolygonGetTickerWithContext error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := connection.PolygonGetTickerWithContext(ctx, testTicker)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the wait for a key to be aborted, got %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Fatalf("the wait for a key ignored the context")
+	}
+}
+
+func TestPolygonGetTickerAggregates_Weekly(t *testing.T) {
+	from := time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 10, 31, 0, 0, 0, 0, time.UTC)
+	weekly, err := polygonConnection.PolygonGetTickerAggregates(PolygonAggregatesRequest{Symbol: testTicker, Timespan: TimespanWeek, From: from, To: to})
+	if err != nil {
+		t.Fatalf("PolygonGetTickerAggregates error: %v", err)
+	}
+	daily, err := polygonConnection.PolygonGetTickerHistory(testTicker, from, to, 0)
+	if err != nil {
+		t.Fatalf("PolygonGetTickerHistory error: %v", err)
+	}
+
+	// October 2024 spans the weeks starting on Sep 29, Oct 6, 13, 20 and 27
+	if weekly.Results == nil || len(*weekly.Results) != 5 {
+		t.Fatalf("expected 5 weekly bars, got %s", PolygonResponseToString(weekly))
+	}
+	first := (*weekly.Results)[0]
+	if *first.Open != *(*daily.Results)[0].Open {
+		t.Fatalf("expected the first week to open at the first day's open")
+	}
+	var weeklyVolume, dailyVolume float64
+	for _, bar := range *weekly.Results {
+		weeklyVolume += *bar.Volume
+	}
+	for _, bar := range *daily.Results {
+		dailyVolume += *bar.Volume
+	}
+	if weeklyVolume != dailyVolume {
+		t.Fatalf("weekly volume %f does not add up to daily volume %f", weeklyVolume, dailyVolume)
+	}
+}
+
+func TestPolygonGetTickerAggregates_RequestURL(t *testing.T) {
+	fake := polygontest.NewServer()
+	defer fake.Close()
+	connection := GetPolygonConnection([]string{"test-key"}, WithBaseURL(fake.URL), WithHTTPClient(fake.Client()))
+
+	from := time.Date(2024, 10, 1, 13, 30, 0, 0, time.UTC)
+	to := from.Add(6 * time.Hour)
+	_, err := connection.PolygonGetTickerAggregates(PolygonAggregatesRequest{Symbol: testTicker, Multiplier: 5, Timespan: TimespanMinute, From: from, To: to, Unadjusted: true, Sort: SortDescending, Limit: 100})
+	if !errors.Is(err, ErrNoResults) {
+		t.Fatalf("expected ErrNoResults for intraday bars the fixtures do not have, got %v", err)
+	}
+
+	expected := fmt.Sprintf("/v2/aggs/ticker/AAPL/range/5/minute/%d/%d?adjusted=false&limit=100&sort=desc", from.UnixMilli(), to.UnixMilli())
+	if requests := fake.Requests(); len(requests) != 1 || requests[0] != expected {
+		t.Fatalf("expected request %s, got %v", expected, requests)
+	}
+}
+
+func TestPolygonGetTickerAggregates_InvalidRequest(t *testing.T) {
+	from := time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)
+	invalid := []PolygonAggregatesRequest{
+		{Timespan: TimespanDay, From: from, To: from},
+		{Symbol: testTicker, Timespan: "fortnight", From: from, To: from},
+		{Symbol: testTicker, Multiplier: -1, From: from, To: from},
+		{Symbol: testTicker, From: from, To: from.AddDate(0, 0, -1)},
+		{Symbol: testTicker, From: from, To: from, Sort: "random"},
+		{Symbol: testTicker, From: from, To: from, Limit: MaxAggregatesLimit + 1},
+	}
+	before := len(fakePolygon.Requests())
+	for i, request := range invalid {
+		if _, err := polygonConnection.PolygonGetTickerAggregates(request); err == nil {
+			t.Fatalf("request #%d: expected a validation error", i)
+		}
+	}
+	if after := len(fakePolygon.Requests()); after != before {
+		t.Fatalf("invalid requests should not reach polygon")
+	}
+}
+
+func TestPolygonGetTickerDividends(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC)
+	dividends, err := polygonConnection.PolygonGetTickerDividends(testTicker, start, end)
+	if err != nil {
+		t.Fatalf("PolygonGetTickerDividends error: %v", err)
+	}
+	if len(dividends) != 4 {
+		t.Fatalf("expected 4 dividends in 2024, got %d", len(dividends))
+	}
+	for i, dividend := range dividends {
+		if dividend.Ticker == nil || *dividend.Ticker != testTicker || dividend.CashAmount == nil || dividend.ExDividendDate == nil {
+			t.Fatalf("dividend #%d is incomplete", i)
+		}
+		if i > 0 && *dividend.ExDividendDate < *dividends[i-1].ExDividendDate {
+			t.Fatalf("expected dividends sorted by ex-dividend date")
+		}
+	}
+	last := fakePolygon.Requests()[len(fakePolygon.Requests())-1]
+	if !strings.Contains(last, "ex_dividend_date.gte=2024-01-01") || !strings.Contains(last, "ex_dividend_date.lte=2024-12-31") {
+		t.Fatalf("expected the range to be sent as ex-dividend dates, got %s", last)
+	}
+}
+
+func TestPolygonGetTickerSplits(t *testing.T) {
+	splits, err := polygonConnection.PolygonGetTickerSplits(testTicker, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("PolygonGetTickerSplits error: %v", err)
+	}
+	if len(splits) != 2 || *splits[0].ExecutionDate != "2014-06-09" || *splits[1].SplitTo != 4 {
+		t.Fatalf("expected the 7-for-1 and 4-for-1 splits, got %d splits", len(splits))
+	}
+
+	// No splits in range is not an error
+	splits, err = polygonConnection.PolygonGetTickerSplits(testTicker, time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), time.Time{})
+	if err != nil || len(splits) != 0 {
+		t.Fatalf("expected no splits after 2021, got %d (err %v)", len(splits), err)
+	}
+}
+
+func TestPolygonGetMarketStatus(t *testing.T) {
+	status, err := polygonConnection.PolygonGetMarketStatus()
+	if err != nil {
+		t.Fatalf("PolygonGetMarketStatus error: %v", err)
+	}
+	if *status.Market != MarketClosed || status.Exchanges == nil || *status.Exchanges.Nyse != MarketClosed {
+		t.Fatalf("expected the fixture's closed market, got %s", *status.Market)
+	}
+}
+
+func TestPolygonGetMarketHolidays(t *testing.T) {
+	holidays, err := polygonConnection.PolygonGetMarketHolidays()
+	if err != nil {
+		t.Fatalf("PolygonGetMarketHolidays error: %v", err)
+	}
+	earlyCloses := 0
+	for _, holiday := range holidays {
+		if holiday.Exchange == nil || holiday.Date == nil || holiday.Status == nil {
+			t.Fatalf("holiday is incomplete")
+		}
+		if *holiday.Status == HolidayEarlyClose {
+			earlyCloses++
+			if holiday.Open == nil || holiday.Close == nil {
+				t.Fatalf("expected the early close of %s to have trading hours", *holiday.Date)
+			}
+		}
+	}
+	if len(holidays) == 0 || earlyCloses == 0 {
+		t.Fatalf("expected holidays and early closes, got %d holidays", len(holidays))
+	}
+}
+
+func TestPolygonGetGroupedDaily(t *testing.T) {
+	grouped, err := polygonConnection.PolygonGetGroupedDaily(time.Date(2024, 10, 15, 0, 0, 0, 0, time.UTC), false)
+	if err != nil {
+		t.Fatalf("PolygonGetGroupedDaily error: %v", err)
+	}
+	tickers := map[string]bool{}
+	for _, bar := range *grouped.Results {
+		if bar.Ticker == nil || bar.Close == nil || bar.Timestamp == nil {
+			t.Fatalf("grouped bar is incomplete")
+		}
+		tickers[*bar.Ticker] = true
+	}
+	if !tickers[testTicker] || len(tickers) < 2 {
+		t.Fatalf("expected the bars of several tickers, got %v", tickers)
+	}
+
+	// Weekends have no bars
+	if _, err := polygonConnection.PolygonGetGroupedDaily(time.Date(2024, 10, 12, 0, 0, 0, 0, time.UTC), false); !errors.Is(err, ErrNoResults) {
+		t.Fatalf("expected ErrNoResults on a Saturday, got %v", err)
+	}
+}
+
+func TestPolygonSearchTickers(t *testing.T) {
+	response, err := polygonConnection.PolygonSearchTickers(PolygonTickerSearchRequest{Search: "apple", Exchange: "XNAS"})
+	if err != nil {
+		t.Fatalf("PolygonSearchTickers error: %v", err)
+	}
+	if len(*response.Results) != 1 || *(*response.Results)[0].Ticker != testTicker {
+		t.Fatalf("expected only %s on XNAS, got %d tickers", testTicker, len(*response.Results))
+	}
+	last := fakePolygon.Requests()[len(fakePolygon.Requests())-1]
+	if !strings.Contains(last, "search=apple") || !strings.Contains(last, "limit=20") || strings.Contains(last, "market=") {
+		t.Fatalf("unexpected request %s", last)
+	}
+
+	if _, err := polygonConnection.PolygonSearchTickers(PolygonTickerSearchRequest{Search: "no such company"}); !errors.Is(err, ErrNoResults) {
+		t.Fatalf("expected ErrNoResults, got %v", err)
+	}
+	if _, err := polygonConnection.PolygonSearchTickers(PolygonTickerSearchRequest{}); err == nil {
+		t.Fatalf("expected an error without a search term")
+	}
+}
+
+func TestPolygonGetTickerFinancials(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC)
+	filings, err := polygonConnection.PolygonGetTickerFinancials(testTicker, FinancialsQuarterly, start, end)
+	if err != nil {
+		t.Fatalf("PolygonGetTickerFinancials error: %v", err)
+	}
+	if len(filings) != 4 || *filings[0].FiscalPeriod != "Q1" || *filings[3].FiscalPeriod != "Q4" {
+		t.Fatalf("expected the 4 quarters of 2024 in filing order, got %d filings", len(filings))
+	}
+	last := fakePolygon.Requests()[len(fakePolygon.Requests())-1]
+	if !strings.Contains(last, "timeframe=quarterly") || !strings.Contains(last, "filing_date.lte=2024-12-31") {
+		t.Fatalf("unexpected request %s", last)
+	}
+	revenues := filings[3].Financials.IncomeStatement["revenues"]
+	if revenues.Value == nil || *revenues.Value != 94930e6 {
+		t.Fatalf("expected Q4 revenues of 94.93B, got %+v", revenues)
+	}
+
+	annual, err := polygonConnection.PolygonGetTickerFinancials(testTicker, FinancialsAnnual, start, end)
+	if err != nil || len(annual) != 1 || *annual[0].FiscalPeriod != "FY" {
+		t.Fatalf("expected a single annual filing, got %d filings (%v)", len(annual), err)
+	}
+	if _, err := polygonConnection.PolygonGetTickerFinancials(testTicker, "monthly", start, end); err == nil {
+		t.Fatalf("expected an error for an invalid timeframe")
+	}
+}
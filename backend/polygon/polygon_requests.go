@@ -10,6 +10,7 @@ package polygon
 // https://mholt.github.io/json-to-go/
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -24,111 +25,230 @@ import (
 var errLogger *log.Logger = log.New(os.Stderr, "ERROR: ", log.LstdFlags|log.Lshortfile)
 
 type PolygonConnection struct {
-	polygonKeys       []string
-	currentPolygonKey int
-	ThrottleTime      time.Duration
+	keyPool    *polygonKeyPool
+	baseURL    string
+	httpClient *http.Client
+	clock      Clock
+	// Limit for a single attempt of a request, on top of the caller's context
+	requestTimeout time.Duration
+	RetryPolicy    PolygonRetryPolicy
+	// Unset unless WithCache is given
+	cache         PolygonCache
+	cachePolicy   PolygonCachePolicy
+	cacheCounters polygonCacheCounters
 }
 
-func GetPolygonConnection(polygonKeys []string, throttleTime time.Duration) *PolygonConnection {
+// GetPolygonConnection creates a connection that spreads requests over polygonKeys
+//
+// Input:
+//   - polygonKeys: the API keys requests are made with
+//   - options: optional overrides (WithThrottle, WithBaseURL, WithHTTPClient, WithClock, WithCassette, ...)
+//
+// Output:
+//   - *PolygonConnection: the connection
+func GetPolygonConnection(polygonKeys []string, options ...PolygonConnectionOption) *PolygonConnection {
+	config := polygonConnectionConfig{
+		baseURL:              DefaultBaseURL,
+		httpClient:           http.DefaultClient,
+		clock:                systemClock{},
+		keyRequestsPerMinute: DefaultKeyRequestsPerMinute,
+		keyCooldown:          DefaultKeyCooldown,
+		retryPolicy:          DefaultPolygonRetryPolicy(),
+		requestTimeout:       DefaultRequestTimeout,
+	}
+	for _, option := range options {
+		option(&config)
+	}
+	if config.cache != nil && config.cachePolicy == nil {
+		config.cachePolicy = DefaultPolygonCachePolicy
+	}
+
 	return &PolygonConnection{
-		polygonKeys:       polygonKeys,
-		currentPolygonKey: 0,
-		ThrottleTime:      throttleTime,
+		keyPool:        newPolygonKeyPool(polygonKeys, config.keyRequestsPerMinute, config.throttleTime, config.keyCooldown, config.clock.Now),
+		baseURL:        strings.TrimSuffix(config.baseURL, "/"),
+		httpClient:     withCassetteTransport(config.httpClient, config.cassetteDir, config.cassetteMode, config.clock.Now),
+		clock:          config.clock,
+		requestTimeout: config.requestTimeout,
+		RetryPolicy:    config.retryPolicy,
+		cache:          config.cache,
+		cachePolicy:    config.cachePolicy,
 	}
 }
 
-func (polygonConnection *PolygonConnection) GetPolygonKey() string {
-	polygonConnection.currentPolygonKey = (polygonConnection.currentPolygonKey + 1) % len(polygonConnection.polygonKeys)
-	return polygonConnection.polygonKeys[polygonConnection.currentPolygonKey]
+// BaseURL returns the root of the API the connection sends requests to, e.g. https://api.polygon.io
+func (polygonConnection *PolygonConnection) BaseURL() string {
+	return polygonConnection.baseURL
+}
+
+// Blocks for d according to the connection's clock, or until ctx is done
+func (polygonConnection *PolygonConnection) sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	select {
+	case <-polygonConnection.clock.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// KeyHealth returns the state of every key of the connection's key pool
+func (polygonConnection *PolygonConnection) KeyHealth() []PolygonKeyHealth {
+	return polygonConnection.keyPool.health()
 }
 
 // Sends a customizable GET request to Polygon.io's API
 //
+// The API key is attached by this function: every attempt takes the next key of the pool that has
+// request budget left, and keys that get rate limited or rejected are benched for a cooldown.
+// Retryable failures (see IsRetryablePolygonError) are retried according to the connection's RetryPolicy.
+// If the connection has a cache (see WithCache), responses it holds are returned without sending a request.
+// Responses are validated against the schema of T (see polygon_validation.go), and rows missing a required
+// field are dropped.
+//
 // Input:
-//   - url: the url to send the request to
+//   - url: the url to send the request to, without an apiKey parameter
 //   - T: the type of the response
 //
 // Output:
 //   - *T: the response from the Polygon API
-//   - error: non-nil if an error occurred during the request or if the response was not 200
+//   - error: non-nil if an error occurred during the request or if the response was not 200.
+//     Errors are typed (PolygonRateLimitError, PolygonUnauthorizedError, ...) and can be inspected with errors.As.
+//     A *PolygonPartialDataError is returned along with the response if rows were dropped.
 func GenericPolygonGetRequest[T any](polygonConnection *PolygonConnection, url string) (*T, error) {
-	time.Sleep(polygonConnection.ThrottleTime)
+	return GenericPolygonGetRequestWithContext[T](context.Background(), polygonConnection, url)
+}
+
+// GenericPolygonGetRequestWithContext is GenericPolygonGetRequest bounded by ctx.
+// Cancelling ctx aborts the request in flight as well as any wait for a key or before a retry.
+// Every attempt is additionally limited to the connection's request timeout.
+func GenericPolygonGetRequestWithContext[T any](ctx context.Context, polygonConnection *PolygonConnection, url string) (*T, error) {
+	cacheKey, cacheTTL := polygonConnection.cacheLookupKey(url)
+	if cacheTTL > 0 {
+		if body, found := polygonConnection.cacheGet(ctx, cacheKey); found {
+			var cached T
+			if err := json.Unmarshal(body, &cached); err == nil {
+				return &cached, validatePolygonResponse(url, &cached)
+			}
+			errLogger.Printf("Ignoring undecodable cached response for %s", cacheKey)
+		}
+	}
+
+	response, err := sendPolygonGetRequestWithRetries[T](ctx, polygonConnection, url)
+	if err != nil {
+		return nil, err
+	}
+	// Incomplete responses are not cached, so they are requested again
+	if err := validatePolygonResponse(url, response); err != nil {
+		errLogger.Println(err)
+		return response, err
+	}
+	if cacheTTL > 0 {
+		if body, err := json.Marshal(response); err == nil {
+			polygonConnection.cacheSet(ctx, cacheKey, body, cacheTTL)
+		}
+	}
+	return response, nil
+}
+
+// Sends a request with the next available key, retrying according to the connection's RetryPolicy
+func sendPolygonGetRequestWithRetries[T any](ctx context.Context, polygonConnection *PolygonConnection, url string) (*T, error) {
+	attempts := polygonConnection.RetryPolicy.attempts()
+	wait := func(d time.Duration) error {
+		return polygonConnection.sleep(ctx, d)
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 {
+			backoff := polygonConnection.RetryPolicy.backoff(attempt-1, retryAfterOf(lastErr))
+			errLogger.Printf("Retrying polygon request (attempt %d/%d) in %s after error: %v", attempt, attempts, backoff, lastErr)
+			if err := wait(backoff); err != nil {
+				return nil, errors.Join(err, lastErr)
+			}
+		}
+
+		key, err := polygonConnection.keyPool.acquire(wait)
+		if err != nil {
+			return nil, err
+		}
+		keyedURL, err := withPolygonKey(url, key)
+		if err != nil {
+			return nil, err
+		}
+
+		response, err := sendPolygonGetRequest[T](ctx, polygonConnection.httpClient, polygonConnection.requestTimeout, keyedURL, polygonConnection.clock.Now)
+		polygonConnection.keyPool.report(key, err)
+		if err == nil {
+			return response, nil
+		}
+		lastErr = err
+		// A cancelled caller must not be retried, even though the failure looks like a network error
+		if ctx.Err() != nil || !polygonConnection.RetryPolicy.retries(err) {
+			break
+		}
+	}
+
+	return nil, lastErr
+}
+
+// Sends a single GET request to Polygon and decodes the response. now is the connection's clock, which an HTTP date
+// in a Retry-After header is compared to.
+func sendPolygonGetRequest[T any](ctx context.Context, client *http.Client, timeout time.Duration, url string, now func() time.Time) (*T, error) {
 	method := "GET"
 
-	client := &http.Client{}
-	req, err := http.NewRequest(method, url, nil)
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
 
 	if err != nil {
 		return nil, errors.Join(errors.New("error generating request for polygon.io"), err)
 	}
 	res, err := client.Do(req)
 	if err != nil {
-		return nil, errors.Join(errors.New("error sending/receiving request to polygon.io"), err)
+		return nil, &PolygonTransportError{URL: scrubPolygonURL(url), Err: err}
 	}
+	defer res.Body.Close()
+
 	// Check if the response is valid (status code 2XX)
 	if !(res.StatusCode >= http.StatusOK && res.StatusCode < http.StatusMultipleChoices) {
-		return nil, fmt.Errorf("error: %d", res.StatusCode)
+		return nil, newPolygonStatusError(res, url, now())
 	}
-	defer res.Body.Close()
 
 	// Unmarshall the decodedBody
 	var decodedBody T
 	if err = json.NewDecoder(res.Body).Decode(&decodedBody); err != nil {
-		return nil, errors.Join(errors.New("error decoding response"), err)
-	}
-
-	// TODO: Check if this function is necessary, or if it can be done in a better way. Maybe a less brute force way?
-	isNil, err := anyFieldIsNil(&decodedBody)
-	if err != nil {
-		return nil, errors.Join(errors.New("error checking if decoded response has nil fields"), err)
-	}
-	if isNil {
-		errLogger.Println("Response has some nil fields")
-		//return nil, errors.New("decoded response has nil fields")
+		return nil, &PolygonDecodeError{URL: scrubPolygonURL(url), Err: err}
 	}
 
 	return &decodedBody, nil
 }
 
-// Checks if any field in a struct is nil
-func anyFieldIsNil(v interface{}) (bool, error) {
-	val := reflect.ValueOf(v)
-	if val.Kind() == reflect.Ptr {
-		val = val.Elem()
-	} else {
-		return false, errors.New("value is not a pointer")
-	}
-	var nilFields []string
-	for i := 0; i < val.NumField(); i++ {
-		if val.Field(i).Kind() == reflect.Ptr && val.Field(i).IsNil() {
-			nilFields = append(nilFields, val.Type().Field(i).Name)
-		}
-	}
-	if len(nilFields) > 0 {
-		log.Printf("Nil fields: %v\n", nilFields)
-		return true, nil
-	}
-	return false, nil
+type PolygonGetTickerResponse struct {
+	Results   *[]PolygonTickerReference `json:"results"`
+	Status    *string                   `json:"status"`
+	RequestID *string                   `json:"request_id"`
+	Count     *int                      `json:"count"`
 }
 
-type PolygonGetTickerResponse struct {
-	Results *[]struct {
-		Ticker          *string    `json:"ticker"`
-		Name            *string    `json:"name"`
-		Market          *string    `json:"market"`
-		Locale          *string    `json:"locale"`
-		PrimaryExchange *string    `json:"primary_exchange"`
-		Type            *string    `json:"type"`
-		Active          *bool      `json:"active"`
-		CurrencyName    *string    `json:"currency_name"`
-		Cik             *string    `json:"cik"`
-		CompositeFigi   *string    `json:"composite_figi"`
-		ShareClassFigi  *string    `json:"share_class_figi"`
-		LastUpdatedUtc  *time.Time `json:"last_updated_utc"`
-	} `json:"results"`
-	Status    *string `json:"status"`
-	RequestID *string `json:"request_id"`
-	Count     *int    `json:"count"`
+// A single ticker returned by the Polygon tickers endpoint
+type PolygonTickerReference struct {
+	Ticker          *string    `json:"ticker"`
+	Name            *string    `json:"name"`
+	Market          *string    `json:"market"`
+	Locale          *string    `json:"locale"`
+	PrimaryExchange *string    `json:"primary_exchange"`
+	Type            *string    `json:"type"`
+	Active          *bool      `json:"active"`
+	CurrencyName    *string    `json:"currency_name"`
+	Cik             *string    `json:"cik"`
+	CompositeFigi   *string    `json:"composite_figi"`
+	ShareClassFigi  *string    `json:"share_class_figi"`
+	LastUpdatedUtc  *time.Time `json:"last_updated_utc"`
 }
 
 // PolygonGetTicker returns the ticker information for a given symbol
@@ -140,39 +260,116 @@ type PolygonGetTickerResponse struct {
 //   - *GetTickerResponse: the response from the Polygon API
 //   - error: any error that occurred
 func (polygonConnection *PolygonConnection) PolygonGetTicker(symbol string) (*PolygonGetTickerResponse, error) {
-	url := fmt.Sprintf("https://api.polygon.io/v3/reference/tickers?ticker=%s&active=true&limit=100&apiKey=%s", symbol, polygonConnection.GetPolygonKey())
+	return polygonConnection.PolygonGetTickerWithContext(context.Background(), symbol)
+}
 
-	response, err := GenericPolygonGetRequest[PolygonGetTickerResponse](polygonConnection, url)
-	if err != nil {
+// PolygonGetTickerWithContext is PolygonGetTicker bounded by ctx
+func (polygonConnection *PolygonConnection) PolygonGetTickerWithContext(ctx context.Context, symbol string) (*PolygonGetTickerResponse, error) {
+	url := fmt.Sprintf("%s/v3/reference/tickers?ticker=%s&active=true&limit=100", polygonConnection.baseURL, symbol)
+
+	response, err := GenericPolygonGetRequestWithContext[PolygonGetTickerResponse](ctx, polygonConnection, url)
+	if err != nil && !IsPartialPolygonData(err) {
 		return nil, errors.Join(errors.New("error getting info from polygon"), err)
 	}
 	if response.Results == nil || len(*response.Results) == 0 || response.Count == nil || *response.Count == 0 {
-		return nil, errors.New("no results found")
+		return nil, ErrNoResults
 	}
 
-	return response, nil
+	return response, err
+}
+
+type PolygonGetTickerDetailsResponse struct {
+	Results   *PolygonTickerDetails `json:"results"`
+	Status    *string               `json:"status"`
+	RequestID *string               `json:"request_id"`
+}
+
+// The details of a single ticker returned by the Polygon ticker details endpoint
+type PolygonTickerDetails struct {
+	Ticker          *string  `json:"ticker"`
+	Name            *string  `json:"name"`
+	Market          *string  `json:"market"`
+	Locale          *string  `json:"locale"`
+	PrimaryExchange *string  `json:"primary_exchange"`
+	Type            *string  `json:"type"`
+	Active          *bool    `json:"active"`
+	CurrencyName    *string  `json:"currency_name"`
+	Cik             *string  `json:"cik"`
+	CompositeFigi   *string  `json:"composite_figi"`
+	ShareClassFigi  *string  `json:"share_class_figi"`
+	MarketCap       *float64 `json:"market_cap"`
+	PhoneNumber     *string  `json:"phone_number"`
+	Address         *struct {
+		Address1   *string `json:"address1"`
+		City       *string `json:"city"`
+		State      *string `json:"state"`
+		PostalCode *string `json:"postal_code"`
+	} `json:"address"`
+	Description                 *string  `json:"description"`
+	SicCode                     *string  `json:"sic_code"`
+	SicDescription              *string  `json:"sic_description"` // The industry of the company, e.g. "ELECTRONIC COMPUTERS"
+	TickerRoot                  *string  `json:"ticker_root"`
+	HomepageURL                 *string  `json:"homepage_url"`
+	TotalEmployees              *int     `json:"total_employees"`
+	ListDate                    *string  `json:"list_date"` // YYYY-MM-DD
+	ShareClassSharesOutstanding *float64 `json:"share_class_shares_outstanding"`
+	WeightedSharesOutstanding   *float64 `json:"weighted_shares_outstanding"`
+	Branding                    *struct {
+		LogoURL *string `json:"logo_url"` // Requires an apiKey parameter to be downloaded
+		IconURL *string `json:"icon_url"` // Requires an apiKey parameter to be downloaded
+	} `json:"branding"`
+}
+
+// PolygonGetTickerDetails returns the company details of a ticker, such as its industry, market cap and description
+//
+// Input:
+//   - symbol: the symbol of the stock
+//
+// Output:
+//   - *PolygonGetTickerDetailsResponse: the response from the Polygon API
+//   - error: any error that occurred, a *PolygonNotFoundError if Polygon does not know the ticker
+func (polygonConnection *PolygonConnection) PolygonGetTickerDetails(symbol string) (*PolygonGetTickerDetailsResponse, error) {
+	return polygonConnection.PolygonGetTickerDetailsWithContext(context.Background(), symbol)
+}
+
+// PolygonGetTickerDetailsWithContext is PolygonGetTickerDetails bounded by ctx
+func (polygonConnection *PolygonConnection) PolygonGetTickerDetailsWithContext(ctx context.Context, symbol string) (*PolygonGetTickerDetailsResponse, error) {
+	url := fmt.Sprintf("%s/v3/reference/tickers/%s", polygonConnection.baseURL, symbol)
+
+	response, err := GenericPolygonGetRequestWithContext[PolygonGetTickerDetailsResponse](ctx, polygonConnection, url)
+	if err != nil && !IsPartialPolygonData(err) {
+		return nil, errors.Join(errors.New("error getting info from polygon"), err)
+	}
+	if response.Results == nil || response.Results.Ticker == nil {
+		return nil, ErrNoResults
+	}
+
+	return response, err
 }
 
 type PolygonGetTickerAggregateResponse struct {
-	Ticker       *string `json:"ticker"`
-	QueryCount   *int    `json:"queryCount"`
-	ResultsCount *int    `json:"resultsCount"`
-	Adjusted     *bool   `json:"adjusted"`
-	Results      *[]struct {
-		Ticker       *string  `json:"T"`
-		Volume       *float64 `json:"v"`
-		VWAP         *float64 `json:"vw"`
-		Open         *float64 `json:"o"`
-		Close        *float64 `json:"c"`
-		High         *float64 `json:"h"`
-		Low          *float64 `json:"l"`
-		Timestamp    *int64   `json:"t"`
-		Transactions *int     `json:"n"`
-		OTC          *bool    `json:"otc"` // This is omitted if false
-	} `json:"results"`
-	Status    *string `json:"status"`
-	RequestID *string `json:"request_id"`
-	Count     *int    `json:"count"`
+	Ticker       *string                    `json:"ticker"`
+	QueryCount   *int                       `json:"queryCount"`
+	ResultsCount *int                       `json:"resultsCount"`
+	Adjusted     *bool                      `json:"adjusted"`
+	Results      *[]PolygonPreviousCloseBar `json:"results"`
+	Status       *string                    `json:"status"`
+	RequestID    *string                    `json:"request_id"`
+	Count        *int                       `json:"count"`
+}
+
+// The bar of the previous session returned by the Polygon previous close endpoint
+type PolygonPreviousCloseBar struct {
+	Ticker       *string  `json:"T"`
+	Volume       *float64 `json:"v"`
+	VWAP         *float64 `json:"vw"`
+	Open         *float64 `json:"o"`
+	Close        *float64 `json:"c"`
+	High         *float64 `json:"h"`
+	Low          *float64 `json:"l"`
+	Timestamp    *int64   `json:"t"`
+	Transactions *int     `json:"n"`
+	OTC          *bool    `json:"otc"` // This is omitted if false
 }
 
 // PolygonGetTickerAggregate returns the ticker aggregate information for a given symbol for the last day
@@ -184,43 +381,52 @@ type PolygonGetTickerAggregateResponse struct {
 //   - *GetTickerAggregateResponse: the response from the Polygon API
 //   - error: any error that occurred
 func (polygonConnection *PolygonConnection) PolygonGetTickerDailyClose(symbol string) (*PolygonGetTickerAggregateResponse, error) {
-	url := fmt.Sprintf("https://api.polygon.io/v2/aggs/ticker/%s/prev?apiKey=%s", symbol, polygonConnection.GetPolygonKey())
-	response, err := GenericPolygonGetRequest[PolygonGetTickerAggregateResponse](polygonConnection, url)
-	if err != nil {
+	return polygonConnection.PolygonGetTickerDailyCloseWithContext(context.Background(), symbol)
+}
+
+// PolygonGetTickerDailyCloseWithContext is PolygonGetTickerDailyClose bounded by ctx
+func (polygonConnection *PolygonConnection) PolygonGetTickerDailyCloseWithContext(ctx context.Context, symbol string) (*PolygonGetTickerAggregateResponse, error) {
+	url := fmt.Sprintf("%s/v2/aggs/ticker/%s/prev", polygonConnection.baseURL, symbol)
+	response, err := GenericPolygonGetRequestWithContext[PolygonGetTickerAggregateResponse](ctx, polygonConnection, url)
+	if err != nil && !IsPartialPolygonData(err) {
 		return nil, errors.Join(errors.New("error getting info from polygon"), err)
 	}
 	if response.Results == nil || len(*response.Results) == 0 || response.Count == nil || *response.Count == 0 {
-		return nil, errors.New("no results found")
+		return nil, ErrNoResults
 	}
 	if len(*response.Results) != 1 {
 		return nil, errors.New("too many results found")
 	}
 
-	return response, nil
+	return response, err
 }
 
 type PolygonGetTickerHistoryResponse struct {
-	Ticker       *string `json:"ticker"`
-	QueryCount   *int    `json:"queryCount"`
-	ResultsCount *int    `json:"resultsCount"`
-	Adjusted     *bool   `json:"adjusted"`
-	Results      *[]struct {
-		Volume       *float64 `json:"v"`
-		VWAP         *float64 `json:"vw"`
-		Open         *float64 `json:"o"`
-		Close        *float64 `json:"c"`
-		High         *float64 `json:"h"`
-		Low          *float64 `json:"l"`
-		Timestamp    *int64   `json:"t"`
-		Transactions *int     `json:"n"`
-		OTC          *bool    `json:"otc"`
-	} `json:"results"`
-	Status    *string `json:"status"`
-	RequestID *string `json:"request_id"`
-	Count     *int    `json:"count"`
+	Ticker       *string                `json:"ticker"`
+	QueryCount   *int                   `json:"queryCount"`
+	ResultsCount *int                   `json:"resultsCount"`
+	Adjusted     *bool                  `json:"adjusted"`
+	Results      *[]PolygonAggregateBar `json:"results"`
+	Status       *string                `json:"status"`
+	RequestID    *string                `json:"request_id"`
+	Count        *int                   `json:"count"`
+}
+
+// A single bar returned by the Polygon custom bars endpoint. Timestamp is the start of the bar, in milliseconds.
+type PolygonAggregateBar struct {
+	Volume       *float64 `json:"v"`
+	VWAP         *float64 `json:"vw"`
+	Open         *float64 `json:"o"`
+	Close        *float64 `json:"c"`
+	High         *float64 `json:"h"`
+	Low          *float64 `json:"l"`
+	Timestamp    *int64   `json:"t"`
+	Transactions *int     `json:"n"`
+	OTC          *bool    `json:"otc"`
 }
 
-// PolygonGetTickerHistory returns the ticker's historical price data within the selected time range
+// PolygonGetTickerHistory returns the ticker's daily, split-adjusted price data within the selected time range.
+// See PolygonGetTickerAggregates for other bar sizes.
 //
 // Input:
 //   - symbol: the symbol of the stock
@@ -232,72 +438,89 @@ type PolygonGetTickerHistoryResponse struct {
 //   - *GetTickerAggregateResponse: the response from the Polygon API
 //   - error: any error that occurred
 func (polygonConnection *PolygonConnection) PolygonGetTickerHistory(symbol string, startDate time.Time, endDate time.Time, limit int) (*PolygonGetTickerHistoryResponse, error) {
-	if startDate.After(endDate) {
-		return nil, errors.New("start date cannot be after end date")
-	}
-
-	// Set default limit
-	responseLengthLimit := 5000
-	if limit > 0 {
-		responseLengthLimit = limit
-	}
-	url := fmt.Sprintf("https://api.polygon.io/v2/aggs/ticker/%s/range/1/day/%s/%s?adjusted=true&sort=asc&limit=%d&apiKey=%s", symbol, startDate.Format("2006-01-02"), endDate.Format("2006-01-02"), responseLengthLimit, polygonConnection.GetPolygonKey())
-	response, err := GenericPolygonGetRequest[PolygonGetTickerHistoryResponse](polygonConnection, url)
-	if err != nil {
-		return nil, errors.Join(fmt.Errorf("error getting info from polygon (response: %v)", response), err)
-	}
-	if response.Results == nil || len(*response.Results) == 0 || response.Count == nil || *response.Count == 0 {
-		return nil, errors.New("no results found")
-	}
+	return polygonConnection.PolygonGetTickerHistoryWithContext(context.Background(), symbol, startDate, endDate, limit)
+}
 
-	return response, nil
+// PolygonGetTickerHistoryWithContext is PolygonGetTickerHistory bounded by ctx
+func (polygonConnection *PolygonConnection) PolygonGetTickerHistoryWithContext(ctx context.Context, symbol string, startDate time.Time, endDate time.Time, limit int) (*PolygonGetTickerHistoryResponse, error) {
+	return polygonConnection.PolygonGetTickerAggregatesWithContext(ctx, PolygonAggregatesRequest{
+		Symbol:     symbol,
+		Multiplier: 1,
+		Timespan:   TimespanDay,
+		From:       startDate,
+		To:         endDate,
+		Limit:      limit,
+	})
 }
 
 type PolygonGetTickerNews struct {
-	Results *[]struct {
-		ID        *string `json:"id"`
-		Publisher *struct {
-			Name        *string `json:"name"`
-			HomepageURL *string `json:"homepage_url"`
-			LogoURL     *string `json:"logo_url"`
-			FaviconURL  *string `json:"favicon_url"`
-		} `json:"publisher"`
-		Title        *string    `json:"title"`
-		Author       *string    `json:"author"`
-		PublishedUTC *time.Time `json:"published_utc"`
-		ArticleURL   *string    `json:"article_url"`
-		Tickers      *[]string  `json:"tickers"`
-		ImageURL     *string    `json:"image_url"`
-		Description  *string    `json:"description"`
-		Keywords     *[]string  `json:"keywords"`
-		Insights     *[]struct {
-			Ticker             *string `json:"ticker"`
-			Sentiment          *string `json:"sentiment"`
-			SentimentReasoning *string `json:"sentiment_reasoning"`
-		} `json:"insights"`
-	} `json:"results"`
-	Status    *string `json:"status"`
-	RequestID *string `json:"request_id"`
-	Count     *int    `json:"count"`
-	NextURL   *string `json:"next_url"`
+	Results   *[]PolygonTickerNewsResult `json:"results"`
+	Status    *string                    `json:"status"`
+	RequestID *string                    `json:"request_id"`
+	Count     *int                       `json:"count"`
+	NextURL   *string                    `json:"next_url"`
+}
+
+// A single article returned by the Polygon news endpoint
+type PolygonTickerNewsResult struct {
+	ID        *string `json:"id"`
+	Publisher *struct {
+		Name        *string `json:"name"`
+		HomepageURL *string `json:"homepage_url"`
+		LogoURL     *string `json:"logo_url"`
+		FaviconURL  *string `json:"favicon_url"`
+	} `json:"publisher"`
+	Title        *string    `json:"title"`
+	Author       *string    `json:"author"`
+	PublishedUTC *time.Time `json:"published_utc"`
+	ArticleURL   *string    `json:"article_url"`
+	Tickers      *[]string  `json:"tickers"`
+	ImageURL     *string    `json:"image_url"`
+	Description  *string    `json:"description"`
+	Keywords     *[]string  `json:"keywords"`
+	Insights     *[]struct {
+		Ticker             *string `json:"ticker"`
+		Sentiment          *string `json:"sentiment"`
+		SentimentReasoning *string `json:"sentiment_reasoning"`
+	} `json:"insights"`
 }
 
-// PolygonGetTickerNews returns the ticker's historical price data within the selected time range
+// PolygonGetTickerNews returns the news articles published about a ticker within the selected time range.
+// Only the first page of results is returned; use PolygonGetTickerNewsPaginated to follow next_url.
 //
 // Input:
 //   - symbol: the symbol of the stock
 //   - startDate: the earliest date to retrieve data from
 //   - endDate: the lastest date to retrieve data from
-//   - limit: the limit of days to retrieve, set <= 0 for maximum
+//   - limit: the limit of articles to retrieve, set <= 0 for the default
 //
 // Output:
-//   - *GetTickerAggregateResponse: the response from the Polygon API
+//   - *PolygonGetTickerNews: the response from the Polygon API
 //   - error: any error that occurred
 func (polygonConnection *PolygonConnection) PolygonGetTickerNews(symbol string, startDate time.Time, endDate time.Time, limit int) (*PolygonGetTickerNews, error) {
+	return polygonConnection.PolygonGetTickerNewsWithContext(context.Background(), symbol, startDate, endDate, limit)
+}
+
+// PolygonGetTickerNewsWithContext is PolygonGetTickerNews bounded by ctx
+func (polygonConnection *PolygonConnection) PolygonGetTickerNewsWithContext(ctx context.Context, symbol string, startDate time.Time, endDate time.Time, limit int) (*PolygonGetTickerNews, error) {
 	if startDate.After(endDate) {
 		return nil, errors.New("start date cannot be after end date")
 	}
 
+	url := polygonConnection.tickerNewsURL(symbol, startDate, endDate, limit)
+	response, err := GenericPolygonGetRequestWithContext[PolygonGetTickerNews](ctx, polygonConnection, url)
+	if err != nil && !IsPartialPolygonData(err) {
+		return nil, errors.Join(errors.New("error getting info from polygon"), err)
+	}
+	if response.Results == nil || len(*response.Results) == 0 || response.Count == nil || *response.Count == 0 {
+		return nil, ErrNoResults
+	}
+
+	return response, err
+}
+
+// Builds the news endpoint URL (without an API key) for the given ticker and time range
+func (polygonConnection *PolygonConnection) tickerNewsURL(symbol string, startDate time.Time, endDate time.Time, limit int) string {
 	// Set default limit
 	responseLengthLimit := 300
 	if limit > 0 {
@@ -305,16 +528,7 @@ func (polygonConnection *PolygonConnection) PolygonGetTickerNews(symbol string,
 	}
 	order := "desc"
 	sort := "published_utc"
-	url := fmt.Sprintf("https://api.polygon.io/v2/reference/news?ticker=%s&order=%s&limit=%d&sort=%s&apiKey=%s&published_utc.gte=%s&published_utc.lte=%s", symbol, order, responseLengthLimit, sort, polygonConnection.GetPolygonKey(), startDate.Format("2006-01-02T15:04:05Z"), endDate.Format("2006-01-02T15:04:05Z"))
-	response, err := GenericPolygonGetRequest[PolygonGetTickerNews](polygonConnection, url)
-	if err != nil {
-		return nil, errors.Join(errors.New("error getting info from polygon"), err)
-	}
-	if response.Results == nil || len(*response.Results) == 0 || response.Count == nil || *response.Count == 0 {
-		return nil, errors.New("no results found")
-	}
-
-	return response, nil
+	return fmt.Sprintf("%s/v2/reference/news?ticker=%s&order=%s&limit=%d&sort=%s&published_utc.gte=%s&published_utc.lte=%s", polygonConnection.baseURL, symbol, order, responseLengthLimit, sort, startDate.Format("2006-01-02T15:04:05Z"), endDate.Format("2006-01-02T15:04:05Z"))
 }
 
 // PolygonResponseToString converts any of the Polygon response structs in this file into a readable string using reflection.
@@ -0,0 +1,82 @@
+package polygon
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// PolygonRetryPolicy controls how failed requests to Polygon are retried.
+// Only retryable errors (see IsRetryablePolygonError) are retried.
+type PolygonRetryPolicy struct {
+	MaxAttempts    int           // Total number of attempts, including the first one. Values < 1 are treated as 1
+	InitialBackoff time.Duration // Wait before the first retry
+	MaxBackoff     time.Duration // Upper bound for any single backoff. A longer Retry-After is not retried (see retries)
+	Multiplier     float64       // Growth factor of the wait between consecutive retries
+	Jitter         float64       // Fraction (0-1) of the wait that is randomized, to avoid synchronized retries
+}
+
+// DefaultPolygonRetryPolicy returns the retry policy used by new connections
+func DefaultPolygonRetryPolicy() PolygonRetryPolicy {
+	return PolygonRetryPolicy{
+		MaxAttempts:    4,
+		InitialBackoff: time.Second,
+		MaxBackoff:     time.Minute,
+		Multiplier:     2,
+		Jitter:         0.2,
+	}
+}
+
+// NoPolygonRetries returns a policy that sends every request exactly once
+func NoPolygonRetries() PolygonRetryPolicy {
+	return PolygonRetryPolicy{MaxAttempts: 1}
+}
+
+// Returns the number of attempts allowed by the policy
+func (policy PolygonRetryPolicy) attempts() int {
+	if policy.MaxAttempts < 1 {
+		return 1
+	}
+	return policy.MaxAttempts
+}
+
+// Returns how long to wait before retry number `retry` (1-based).
+// If Polygon sent a Retry-After header, it is honoured as a lower bound, even past MaxBackoff.
+func (policy PolygonRetryPolicy) backoff(retry int, retryAfter time.Duration) time.Duration {
+	multiplier := policy.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	wait := float64(policy.InitialBackoff) * math.Pow(multiplier, float64(retry-1))
+
+	if policy.Jitter > 0 {
+		jitter := math.Min(policy.Jitter, 1)
+		// Spread the wait uniformly over [wait*(1-jitter), wait*(1+jitter)]
+		wait = wait * (1 - jitter + 2*jitter*rand.Float64())
+	}
+
+	backoff := time.Duration(wait)
+	if policy.MaxBackoff > 0 && backoff > policy.MaxBackoff {
+		backoff = policy.MaxBackoff
+	}
+	return max(backoff, retryAfter)
+}
+
+// Reports whether a request that failed with err may be retried. Polygon asking to wait longer than MaxBackoff is
+// not, since retrying any sooner would be rate limited again, so the PolygonRateLimitError is returned instead.
+func (policy PolygonRetryPolicy) retries(err error) bool {
+	if !IsRetryablePolygonError(err) {
+		return false
+	}
+	return policy.MaxBackoff <= 0 || retryAfterOf(err) <= policy.MaxBackoff
+}
+
+// Returns the Retry-After duration carried by err, if any
+func retryAfterOf(err error) time.Duration {
+	var rateLimitErr *PolygonRateLimitError
+	if errors.As(err, &rateLimitErr) {
+		return rateLimitErr.RetryAfter
+	}
+	return 0
+}
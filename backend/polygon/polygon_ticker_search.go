@@ -0,0 +1,81 @@
+package polygon
+
+// This file contains the wrapper for searching Polygon's tickers endpoint by symbol or company name
+// https://polygon.io/docs/stocks/get_v3_reference_tickers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+const (
+	// Number of tickers returned when PolygonTickerSearchRequest.Limit is not set
+	DefaultTickerSearchLimit = 20
+	// The largest limit Polygon accepts
+	MaxTickerSearchLimit = 1000
+)
+
+// PolygonTickerSearchRequest selects the tickers returned by PolygonSearchTickers. Empty filters match every ticker.
+type PolygonTickerSearchRequest struct {
+	Search   string // Matched by Polygon against the ticker and the company name
+	Market   string // e.g. "stocks", "crypto" or "otc"
+	Type     string // e.g. "CS" for common stock or "ETF"
+	Exchange string // MIC code of the primary exchange, e.g. "XNAS"
+	Limit    int    // DefaultTickerSearchLimit if <= 0
+}
+
+// Builds the search URL (without an API key) of a request
+func (polygonConnection *PolygonConnection) tickerSearchURL(request PolygonTickerSearchRequest) string {
+	limit := request.Limit
+	if limit <= 0 {
+		limit = DefaultTickerSearchLimit
+	}
+	limit = min(limit, MaxTickerSearchLimit)
+
+	query := url.Values{}
+	query.Set("search", request.Search)
+	query.Set("active", "true")
+	query.Set("limit", strconv.Itoa(limit))
+	if request.Market != "" {
+		query.Set("market", request.Market)
+	}
+	if request.Type != "" {
+		query.Set("type", request.Type)
+	}
+	if request.Exchange != "" {
+		query.Set("exchange", request.Exchange)
+	}
+	return fmt.Sprintf("%s/v3/reference/tickers?%s", polygonConnection.baseURL, query.Encode())
+}
+
+// PolygonSearchTickers returns the active tickers whose symbol or company name matches a search term
+//
+// Input:
+//   - request: the search term, filters and limit of the search
+//
+// Output:
+//   - *PolygonGetTickerResponse: the response from the Polygon API
+//   - error: any error that occurred, ErrNoResults if no ticker matches
+func (polygonConnection *PolygonConnection) PolygonSearchTickers(request PolygonTickerSearchRequest) (*PolygonGetTickerResponse, error) {
+	return polygonConnection.PolygonSearchTickersWithContext(context.Background(), request)
+}
+
+// PolygonSearchTickersWithContext is PolygonSearchTickers bounded by ctx
+func (polygonConnection *PolygonConnection) PolygonSearchTickersWithContext(ctx context.Context, request PolygonTickerSearchRequest) (*PolygonGetTickerResponse, error) {
+	if request.Search == "" {
+		return nil, errors.New("search term is required")
+	}
+
+	response, err := GenericPolygonGetRequestWithContext[PolygonGetTickerResponse](ctx, polygonConnection, polygonConnection.tickerSearchURL(request))
+	if err != nil && !IsPartialPolygonData(err) {
+		return nil, errors.Join(errors.New("error getting info from polygon"), err)
+	}
+	if response.Results == nil || len(*response.Results) == 0 {
+		return nil, ErrNoResults
+	}
+
+	return response, err
+}
@@ -0,0 +1,222 @@
+package polygon
+
+// This file contains the schemas Polygon responses are validated against before being returned.
+//
+// Polygon omits the fields it has no value for, so every field of the response types is a pointer. Each response
+// type lists the fields its callers can't do without: a row missing one of them is dropped from the response, on its
+// own, and its path (e.g. results[3].c) is reported by a PolygonPartialDataError. Every other field is optional and
+// may be nil.
+
+import (
+	"fmt"
+)
+
+// A field of R its callers can't do without, named as in the JSON response
+type requiredField[R any] struct {
+	name    string
+	present func(row *R) bool
+}
+
+// Bars are useless without their prices, volume and start. VWAP, transactions and OTC are optional.
+var (
+	aggregateBarSchema = []requiredField[PolygonAggregateBar]{
+		{"o", func(bar *PolygonAggregateBar) bool { return bar.Open != nil }},
+		{"h", func(bar *PolygonAggregateBar) bool { return bar.High != nil }},
+		{"l", func(bar *PolygonAggregateBar) bool { return bar.Low != nil }},
+		{"c", func(bar *PolygonAggregateBar) bool { return bar.Close != nil }},
+		{"v", func(bar *PolygonAggregateBar) bool { return bar.Volume != nil }},
+		{"t", func(bar *PolygonAggregateBar) bool { return bar.Timestamp != nil }},
+	}
+	previousCloseBarSchema = []requiredField[PolygonPreviousCloseBar]{
+		{"o", func(bar *PolygonPreviousCloseBar) bool { return bar.Open != nil }},
+		{"h", func(bar *PolygonPreviousCloseBar) bool { return bar.High != nil }},
+		{"l", func(bar *PolygonPreviousCloseBar) bool { return bar.Low != nil }},
+		{"c", func(bar *PolygonPreviousCloseBar) bool { return bar.Close != nil }},
+		{"v", func(bar *PolygonPreviousCloseBar) bool { return bar.Volume != nil }},
+		{"t", func(bar *PolygonPreviousCloseBar) bool { return bar.Timestamp != nil }},
+	}
+	groupedDailyBarSchema = []requiredField[PolygonGroupedDailyBar]{
+		{"T", func(bar *PolygonGroupedDailyBar) bool { return bar.Ticker != nil && *bar.Ticker != "" }},
+		{"o", func(bar *PolygonGroupedDailyBar) bool { return bar.Open != nil }},
+		{"h", func(bar *PolygonGroupedDailyBar) bool { return bar.High != nil }},
+		{"l", func(bar *PolygonGroupedDailyBar) bool { return bar.Low != nil }},
+		{"c", func(bar *PolygonGroupedDailyBar) bool { return bar.Close != nil }},
+		{"v", func(bar *PolygonGroupedDailyBar) bool { return bar.Volume != nil }},
+		{"t", func(bar *PolygonGroupedDailyBar) bool { return bar.Timestamp != nil }},
+	}
+)
+
+var (
+	tickerReferenceSchema = []requiredField[PolygonTickerReference]{
+		{"ticker", func(ticker *PolygonTickerReference) bool { return ticker.Ticker != nil && *ticker.Ticker != "" }},
+	}
+	tickerDetailsSchema = []requiredField[PolygonTickerDetails]{
+		{"ticker", func(details *PolygonTickerDetails) bool { return details.Ticker != nil && *details.Ticker != "" }},
+	}
+	// Articles are stored by id, and shown with their title, link and date
+	newsArticleSchema = []requiredField[PolygonTickerNewsResult]{
+		{"id", func(article *PolygonTickerNewsResult) bool { return article.ID != nil }},
+		{"title", func(article *PolygonTickerNewsResult) bool { return article.Title != nil }},
+		{"published_utc", func(article *PolygonTickerNewsResult) bool { return article.PublishedUTC != nil }},
+		{"article_url", func(article *PolygonTickerNewsResult) bool { return article.ArticleURL != nil }},
+	}
+)
+
+var (
+	// The declaration, record and pay dates are unknown for some dividends
+	dividendSchema = []requiredField[PolygonDividend]{
+		{"ticker", func(dividend *PolygonDividend) bool { return dividend.Ticker != nil }},
+		{"cash_amount", func(dividend *PolygonDividend) bool { return dividend.CashAmount != nil }},
+		{"ex_dividend_date", func(dividend *PolygonDividend) bool { return dividend.ExDividendDate != nil }},
+	}
+	splitSchema = []requiredField[PolygonSplit]{
+		{"ticker", func(split *PolygonSplit) bool { return split.Ticker != nil }},
+		{"execution_date", func(split *PolygonSplit) bool { return split.ExecutionDate != nil }},
+		{"split_from", func(split *PolygonSplit) bool { return split.SplitFrom != nil && *split.SplitFrom > 0 }},
+		{"split_to", func(split *PolygonSplit) bool { return split.SplitTo != nil && *split.SplitTo > 0 }},
+	}
+	// Filings are identified by their timeframe and period. Trailing twelve months filings have no filing date.
+	financialFilingSchema = []requiredField[PolygonFinancialFiling]{
+		{"timeframe", func(filing *PolygonFinancialFiling) bool { return filing.Timeframe != nil }},
+		{"start_date", func(filing *PolygonFinancialFiling) bool { return filing.StartDate != nil }},
+		{"end_date", func(filing *PolygonFinancialFiling) bool { return filing.EndDate != nil }},
+	}
+)
+
+var (
+	marketStatusSchema = []requiredField[PolygonGetMarketStatusResponse]{
+		{"market", func(status *PolygonGetMarketStatusResponse) bool { return status.Market != nil }},
+	}
+	marketHolidaySchema = []requiredField[PolygonMarketHoliday]{
+		{"exchange", func(holiday *PolygonMarketHoliday) bool { return holiday.Exchange != nil }},
+		{"date", func(holiday *PolygonMarketHoliday) bool { return holiday.Date != nil }},
+		{"status", func(holiday *PolygonMarketHoliday) bool { return holiday.Status != nil }},
+	}
+)
+
+// The fields found missing while validating a response
+type polygonValidation struct {
+	missing []string
+	dropped int
+}
+
+// Response types with required fields
+type polygonValidatable interface {
+	// Drops the rows missing a required field and records what was missing
+	validate(validation *polygonValidation)
+}
+
+// Validates a decoded response, which may be any of the response types of this package, in place
+//
+// Input:
+//   - url: the url the response was requested from, reported in the error
+//   - response: a pointer to the decoded response
+//
+// Output:
+//   - error: a *PolygonPartialDataError if required fields were missing, nil if the response is complete
+func validatePolygonResponse(url string, response any) error {
+	var validation polygonValidation
+	switch response := response.(type) {
+	case polygonValidatable:
+		response.validate(&validation)
+	case *[]PolygonMarketHoliday:
+		dropIncompleteRows(&validation, "", response, marketHolidaySchema)
+	}
+
+	if len(validation.missing) == 0 {
+		return nil
+	}
+	return &PolygonPartialDataError{URL: scrubPolygonURL(url), Missing: validation.missing, Dropped: validation.dropped}
+}
+
+// Records the required fields row is missing under path, and reports whether it has all of them
+func hasRequiredFields[R any](validation *polygonValidation, path string, row *R, schema []requiredField[R]) bool {
+	complete := true
+	for _, field := range schema {
+		if field.present(row) {
+			continue
+		}
+		complete = false
+		if path == "" {
+			validation.missing = append(validation.missing, field.name)
+		} else {
+			validation.missing = append(validation.missing, path+"."+field.name)
+		}
+	}
+	return complete
+}
+
+// Removes the rows missing a required field from *rows, keeping the others in order.
+// Rows are reported by their index in the response, e.g. results[3].
+func dropIncompleteRows[R any](validation *polygonValidation, path string, rows *[]R, schema []requiredField[R]) {
+	if rows == nil {
+		return
+	}
+	kept := (*rows)[:0]
+	for i := range *rows {
+		if hasRequiredFields(validation, fmt.Sprintf("%s[%d]", path, i), &(*rows)[i], schema) {
+			kept = append(kept, (*rows)[i])
+		} else {
+			validation.dropped++
+		}
+	}
+	*rows = kept
+}
+
+// Keeps a count sent by Polygon in line with the rows left after validation
+func recount[R any](count *int, rows *[]R) {
+	if count != nil && rows != nil {
+		*count = len(*rows)
+	}
+}
+
+func (response *PolygonGetTickerResponse) validate(validation *polygonValidation) {
+	dropIncompleteRows(validation, "results", response.Results, tickerReferenceSchema)
+	recount(response.Count, response.Results)
+}
+
+func (response *PolygonGetTickerDetailsResponse) validate(validation *polygonValidation) {
+	if response.Results != nil && !hasRequiredFields(validation, "results", response.Results, tickerDetailsSchema) {
+		response.Results = nil
+		validation.dropped++
+	}
+}
+
+func (response *PolygonGetTickerAggregateResponse) validate(validation *polygonValidation) {
+	dropIncompleteRows(validation, "results", response.Results, previousCloseBarSchema)
+	recount(response.Count, response.Results)
+	recount(response.ResultsCount, response.Results)
+}
+
+func (response *PolygonGetTickerHistoryResponse) validate(validation *polygonValidation) {
+	dropIncompleteRows(validation, "results", response.Results, aggregateBarSchema)
+	recount(response.Count, response.Results)
+	recount(response.ResultsCount, response.Results)
+}
+
+func (response *PolygonGetGroupedDailyResponse) validate(validation *polygonValidation) {
+	dropIncompleteRows(validation, "results", response.Results, groupedDailyBarSchema)
+	recount(response.ResultsCount, response.Results)
+}
+
+func (response *PolygonGetTickerNews) validate(validation *polygonValidation) {
+	dropIncompleteRows(validation, "results", response.Results, newsArticleSchema)
+	recount(response.Count, response.Results)
+}
+
+func (response *PolygonGetTickerDividendsResponse) validate(validation *polygonValidation) {
+	dropIncompleteRows(validation, "results", response.Results, dividendSchema)
+}
+
+func (response *PolygonGetTickerSplitsResponse) validate(validation *polygonValidation) {
+	dropIncompleteRows(validation, "results", response.Results, splitSchema)
+}
+
+func (response *PolygonGetTickerFinancialsResponse) validate(validation *polygonValidation) {
+	dropIncompleteRows(validation, "results", response.Results, financialFilingSchema)
+}
+
+// A market status without a market is reported, there is no row to drop
+func (response *PolygonGetMarketStatusResponse) validate(validation *polygonValidation) {
+	hasRequiredFields(validation, "", response, marketStatusSchema)
+}
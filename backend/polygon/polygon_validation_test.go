@@ -0,0 +1,136 @@
+package polygon
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Serves body on every path and counts the requests
+func newStaticServer(t *testing.T, body string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func TestPolygonGetTickerAggregates_DropsIncompleteBars(t *testing.T) {
+	server, _ := newStaticServer(t, `{"status":"OK","ticker":"AAPL","resultsCount":3,"count":3,"results":[
+		{"o":1,"h":2,"l":0.5,"c":1.5,"v":100,"t":1727755200000},
+		{"o":1,"h":2,"l":0.5,"v":100,"t":1727841600000},
+		{"o":1,"h":2,"l":0.5,"c":1.5,"v":100,"t":1727928000000}
+	]}`)
+	connection := GetPolygonConnection([]string{"key"}, WithBaseURL(server.URL))
+
+	response, err := connection.PolygonGetTickerAggregates(PolygonAggregatesRequest{Symbol: testTicker, Multiplier: 1, Timespan: TimespanDay, From: testNow.AddDate(0, 0, -30), To: testNow})
+	var partialErr *PolygonPartialDataError
+	if !errors.As(err, &partialErr) || !IsPartialPolygonData(err) {
+		t.Fatalf("expected a PolygonPartialDataError, got %T: %v", err, err)
+	}
+	if !slices.Equal(partialErr.Missing, []string{"results[1].c"}) || partialErr.Dropped != 1 {
+		t.Fatalf("unexpected missing fields %v (%d dropped)", partialErr.Missing, partialErr.Dropped)
+	}
+	if response == nil || len(*response.Results) != 2 || *response.Count != 2 {
+		t.Fatalf("expected the 2 complete bars to be returned, got %+v", response)
+	}
+	for _, bar := range *response.Results {
+		if bar.Close == nil {
+			t.Fatal("expected the bar without a close to be dropped")
+		}
+	}
+}
+
+func TestPolygonGetTickerDetails_MissingTicker(t *testing.T) {
+	server, _ := newStaticServer(t, `{"status":"OK","results":{"name":"Apple Inc."}}`)
+	connection := GetPolygonConnection([]string{"key"}, WithBaseURL(server.URL))
+
+	response, err := GenericPolygonGetRequest[PolygonGetTickerDetailsResponse](connection, server.URL+"/v3/reference/tickers/AAPL")
+	if !IsPartialPolygonData(err) || response.Results != nil {
+		t.Fatalf("expected the details to be dropped, got %+v (%v)", response, err)
+	}
+	// Nothing usable is left, so the endpoint has no results
+	if _, err := connection.PolygonGetTickerDetails(testTicker); !errors.Is(err, ErrNoResults) || IsPartialPolygonData(err) {
+		t.Fatalf("expected ErrNoResults, got %v", err)
+	}
+}
+
+func TestPolygonGetMarketHolidays_DropsIncompleteHolidays(t *testing.T) {
+	server, _ := newStaticServer(t, `[{"exchange":"NYSE","name":"Christmas","date":"2024-12-25","status":"closed"},{"exchange":"NYSE","name":"Mystery"}]`)
+	connection := GetPolygonConnection([]string{"key"}, WithBaseURL(server.URL))
+
+	holidays, err := connection.PolygonGetMarketHolidays()
+	var partialErr *PolygonPartialDataError
+	if !errors.As(err, &partialErr) || !slices.Equal(partialErr.Missing, []string{"[1].date", "[1].status"}) {
+		t.Fatalf("expected the date and status of the second holiday to be reported, got %v", err)
+	}
+	if len(holidays) != 1 || *holidays[0].Name != "Christmas" {
+		t.Fatalf("expected only Christmas, got %+v", holidays)
+	}
+}
+
+func TestGenericPolygonGetRequest_DoesNotCachePartialData(t *testing.T) {
+	server, calls := newStaticServer(t, `{"status":"OK","count":2,"results":[{"ticker":"AAPL"},{"name":"No ticker"}]}`)
+	cache, err := NewBoltPolygonCache(filepath.Join(t.TempDir(), "cache.db"))
+	if err != nil {
+		t.Fatalf("NewBoltPolygonCache error: %v", err)
+	}
+	defer cache.Close()
+	connection := GetPolygonConnection([]string{"key"}, WithBaseURL(server.URL), WithCache(cache, nil))
+
+	for i := 0; i < 2; i++ {
+		response, err := connection.PolygonGetTicker(testTicker)
+		if !IsPartialPolygonData(err) || len(*response.Results) != 1 {
+			t.Fatalf("expected one ticker and a partial data error, got %+v (%v)", response, err)
+		}
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("expected the incomplete response to be requested again, got %d requests", calls.Load())
+	}
+}
+
+func TestPolygonGetTickerNewsPaginated_KeepsPagesAfterIncompleteOne(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("cursor") == "" {
+			w.Write([]byte(`{"status":"OK","count":2,"next_url":"` + server.URL + `/v2/reference/news?cursor=2","results":[
+				{"id":"1","title":"First","published_utc":"2024-10-30T12:00:00Z","article_url":"https://example.com/1"},
+				{"id":"2","published_utc":"2024-10-30T11:00:00Z","article_url":"https://example.com/2"}
+			]}`))
+			return
+		}
+		w.Write([]byte(`{"status":"OK","count":1,"results":[
+			{"id":"3","title":"Third","published_utc":"2024-10-29T12:00:00Z","article_url":"https://example.com/3"}
+		]}`))
+	}))
+	defer server.Close()
+	connection := GetPolygonConnection([]string{"key"}, WithBaseURL(server.URL))
+
+	news, stats, err := connection.PolygonGetTickerNewsPaginated(testTicker, testNow.Add(-7*24*time.Hour), testNow, 2, 0)
+	var partialErr *PolygonPartialDataError
+	if !errors.As(err, &partialErr) || !slices.Equal(partialErr.Missing, []string{"results[1].title"}) {
+		t.Fatalf("expected the missing title to be reported, got %v", err)
+	}
+	if stats.Pages != 2 || len(*news.Results) != 2 || *news.Count != 2 {
+		t.Fatalf("expected the complete articles of both pages, got %d pages and %+v", stats.Pages, news)
+	}
+}
+
+func TestPolygonPartialDataError_Error(t *testing.T) {
+	err := &PolygonPartialDataError{URL: "https://api.polygon.io/v2/aggs/grouped", Dropped: 7}
+	for i := 0; i < 7; i++ {
+		err.Missing = append(err.Missing, "results[0].c")
+	}
+	if message := err.Error(); !strings.Contains(message, "and 2 more") || !strings.Contains(message, "7 rows dropped") {
+		t.Fatalf("unexpected message %q", message)
+	}
+}
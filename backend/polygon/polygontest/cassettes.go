@@ -0,0 +1,36 @@
+package polygontest
+
+import (
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// The requests the checked-in cassettes are recorded for. Tests that replay the cassettes
+// must send exactly these requests, or the replay will miss.
+const (
+	CassetteSymbol       = "AAPL"
+	CassetteHistoryLimit = 50
+	CassetteNewsLimit    = 10
+)
+
+var (
+	CassetteStart = time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)
+	CassetteEnd   = time.Date(2024, 10, 8, 23, 59, 59, 0, time.UTC)
+)
+
+// CassetteDir returns the absolute path of the checked-in cassettes, so they can be replayed from any package's tests
+func CassetteDir() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "cassettes")
+}
+
+// SkipWithoutCassettes skips the test if no cassette was recorded yet. Cassettes must be recorded from the real API,
+// see newCassetteConnection in the polygon tests, since a recording of the fake server adds nothing over its fixtures.
+func SkipWithoutCassettes(t testing.TB) {
+	t.Helper()
+	if files, _ := filepath.Glob(filepath.Join(CassetteDir(), "*.json")); len(files) == 0 {
+		t.Skip("no polygon cassettes recorded yet")
+	}
+}
@@ -0,0 +1,533 @@
+// Package polygontest provides a fake Polygon API, so code that talks to Polygon can be tested
+// without network access or API keys.
+//
+// The fake serves the canned fixtures in the fixtures directory and implements the parts of the
+// real API the backend relies on: query filters, limits, sort order and next_url pagination.
+//
+//	fake := polygontest.NewServer()
+//	defer fake.Close()
+//	connection := polygon.GetPolygonConnection([]string{"test-key"}, polygon.WithBaseURL(fake.URL), polygon.WithHTTPClient(fake.Client()))
+package polygontest
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//go:embed fixtures/*.json
+var fixtures embed.FS
+
+// Bar is a single aggregate bar as returned by Polygon
+type Bar struct {
+	Volume       float64 `json:"v"`
+	VWAP         float64 `json:"vw"`
+	Open         float64 `json:"o"`
+	Close        float64 `json:"c"`
+	High         float64 `json:"h"`
+	Low          float64 `json:"l"`
+	Timestamp    int64   `json:"t"`
+	Transactions int     `json:"n"`
+}
+
+// Server is a fake Polygon API backed by an httptest.Server
+type Server struct {
+	*httptest.Server
+
+	mu           sync.Mutex
+	requests     []string
+	failures     map[string][]int
+	rejectedKeys map[string]bool
+
+	// Fixtures, exported so tests can add or remove data before sending requests
+	Aggregates    map[string][]Bar
+	Tickers       []map[string]any
+	TickerDetails map[string]map[string]any
+	News          []map[string]any
+	Dividends     []map[string]any
+	Splits        []map[string]any
+	Financials    []map[string]any
+	MarketStatus  map[string]any
+	Holidays      []map[string]any
+}
+
+// NewServer starts a fake Polygon API serving the embedded fixtures. Callers must Close it.
+func NewServer() *Server {
+	server := &Server{
+		failures:     map[string][]int{},
+		rejectedKeys: map[string]bool{},
+	}
+	mustLoadFixture("fixtures/aggs.json", &server.Aggregates)
+	mustLoadFixture("fixtures/tickers.json", &server.Tickers)
+	mustLoadFixture("fixtures/ticker_details.json", &server.TickerDetails)
+	mustLoadFixture("fixtures/news.json", &server.News)
+	mustLoadFixture("fixtures/dividends.json", &server.Dividends)
+	mustLoadFixture("fixtures/splits.json", &server.Splits)
+	mustLoadFixture("fixtures/financials.json", &server.Financials)
+	mustLoadFixture("fixtures/market_status.json", &server.MarketStatus)
+	mustLoadFixture("fixtures/market_holidays.json", &server.Holidays)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v3/reference/tickers", server.handleTickers)
+	mux.HandleFunc("GET /v3/reference/tickers/{symbol}", server.handleTickerDetails)
+	mux.HandleFunc("GET /v2/aggs/ticker/{symbol}/prev", server.handlePreviousClose)
+	mux.HandleFunc("GET /v2/aggs/ticker/{symbol}/range/{multiplier}/{timespan}/{from}/{to}", server.handleAggregates)
+	mux.HandleFunc("GET /v2/aggs/grouped/locale/us/market/stocks/{date}", server.handleGroupedDaily)
+	mux.HandleFunc("GET /v2/reference/news", server.handleNews)
+	mux.HandleFunc("GET /v1/marketstatus/now", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, server.MarketStatus)
+	})
+	mux.HandleFunc("GET /v1/marketstatus/upcoming", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, server.Holidays)
+	})
+	mux.HandleFunc("GET /v3/reference/dividends", func(w http.ResponseWriter, r *http.Request) {
+		server.handleCorporateActions(w, r, server.Dividends, "ex_dividend_date")
+	})
+	mux.HandleFunc("GET /v3/reference/splits", func(w http.ResponseWriter, r *http.Request) {
+		server.handleCorporateActions(w, r, server.Splits, "execution_date")
+	})
+
+	mux.HandleFunc("GET /vX/reference/financials", server.handleFinancials)
+
+	server.Server = httptest.NewServer(server.middleware(mux))
+	return server
+}
+
+func mustLoadFixture(name string, target any) {
+	data, err := fixtures.ReadFile(name)
+	if err != nil {
+		panic(fmt.Sprintf("polygontest: missing fixture %s: %v", name, err))
+	}
+	if err := json.Unmarshal(data, target); err != nil {
+		panic(fmt.Sprintf("polygontest: invalid fixture %s: %v", name, err))
+	}
+}
+
+// FailNext makes the next `times` requests whose path starts with pathPrefix fail with status
+func (server *Server) FailNext(pathPrefix string, status int, times int) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	for i := 0; i < times; i++ {
+		server.failures[pathPrefix] = append(server.failures[pathPrefix], status)
+	}
+}
+
+// RejectKey makes every request sent with key fail with 401 Unauthorized
+func (server *Server) RejectKey(key string) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.rejectedKeys[key] = true
+}
+
+// Requests returns every request received so far, as path?query with the apiKey removed
+func (server *Server) Requests() []string {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	return append([]string{}, server.requests...)
+}
+
+// RequestCount returns the number of requests received so far whose path starts with pathPrefix
+func (server *Server) RequestCount(pathPrefix string) int {
+	count := 0
+	for _, request := range server.Requests() {
+		if strings.HasPrefix(request, pathPrefix) {
+			count++
+		}
+	}
+	return count
+}
+
+// Records requests and applies key rejections and queued failures before routing
+func (server *Server) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		key := query.Get("apiKey")
+		query.Del("apiKey")
+		logged := r.URL.Path
+		if encoded := query.Encode(); encoded != "" {
+			logged += "?" + encoded
+		}
+
+		server.mu.Lock()
+		server.requests = append(server.requests, logged)
+		rejected := server.rejectedKeys[key]
+		failure := 0
+		for prefix, statuses := range server.failures {
+			if strings.HasPrefix(r.URL.Path, prefix) && len(statuses) > 0 {
+				failure = statuses[0]
+				server.failures[prefix] = statuses[1:]
+				break
+			}
+		}
+		server.mu.Unlock()
+
+		switch {
+		case key == "":
+			writeError(w, http.StatusUnauthorized, "API Key was not provided")
+		case rejected:
+			writeError(w, http.StatusUnauthorized, "Unknown API Key")
+		case failure != 0:
+			if failure == http.StatusTooManyRequests {
+				w.Header().Set("Retry-After", "1")
+			}
+			writeError(w, failure, "injected failure")
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]any{"status": "ERROR", "request_id": "fake", "error": message})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// Returns the page of items selected by the limit and cursor parameters, and the next_url if more remain
+func (server *Server) paginate(r *http.Request, total int, defaultLimit int, maxLimit int) (int, int, string) {
+	query := r.URL.Query()
+	limit := defaultLimit
+	if parsed, err := strconv.Atoi(query.Get("limit")); err == nil && parsed > 0 {
+		limit = min(parsed, maxLimit)
+	}
+	offset := 0
+	if parsed, err := strconv.Atoi(query.Get("cursor")); err == nil && parsed > 0 {
+		offset = parsed
+	}
+	offset = min(offset, total)
+	end := min(offset+limit, total)
+
+	nextURL := ""
+	if end < total {
+		query.Del("apiKey")
+		query.Set("cursor", strconv.Itoa(end))
+		nextURL = server.URL + r.URL.Path + "?" + query.Encode()
+	}
+	return offset, end, nextURL
+}
+
+func (server *Server) handleTickers(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	matches := []map[string]any{}
+	for _, ticker := range server.Tickers {
+		if symbol := query.Get("ticker"); symbol != "" && ticker["ticker"] != symbol {
+			continue
+		}
+		if active := query.Get("active"); active != "" && fmt.Sprint(ticker["active"]) != active {
+			continue
+		}
+		if search := strings.ToLower(query.Get("search")); search != "" &&
+			!strings.Contains(strings.ToLower(fmt.Sprint(ticker["ticker"])), search) && !strings.Contains(strings.ToLower(fmt.Sprint(ticker["name"])), search) {
+			continue
+		}
+		if market := query.Get("market"); market != "" && ticker["market"] != market {
+			continue
+		}
+		if tickerType := query.Get("type"); tickerType != "" && ticker["type"] != tickerType {
+			continue
+		}
+		if exchange := query.Get("exchange"); exchange != "" && ticker["primary_exchange"] != exchange {
+			continue
+		}
+		matches = append(matches, ticker)
+	}
+
+	offset, end, nextURL := server.paginate(r, len(matches), 100, 1000)
+	body := map[string]any{"status": "OK", "request_id": "fake", "count": end - offset, "results": matches[offset:end]}
+	if nextURL != "" {
+		body["next_url"] = nextURL
+	}
+	writeJSON(w, http.StatusOK, body)
+}
+
+func (server *Server) handleTickerDetails(w http.ResponseWriter, r *http.Request) {
+	details, ok := server.TickerDetails[r.PathValue("symbol")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Ticker not found.")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"status": "OK", "request_id": "fake", "results": details})
+}
+
+func (server *Server) handlePreviousClose(w http.ResponseWriter, r *http.Request) {
+	symbol := r.PathValue("symbol")
+	bars := server.Aggregates[symbol]
+	if len(bars) == 0 {
+		writeJSON(w, http.StatusOK, map[string]any{"ticker": symbol, "queryCount": 0, "resultsCount": 0, "adjusted": true, "status": "OK", "request_id": "fake", "count": 0})
+		return
+	}
+
+	last := bars[len(bars)-1]
+	result := map[string]any{"T": symbol, "v": last.Volume, "vw": last.VWAP, "o": last.Open, "c": last.Close, "h": last.High, "l": last.Low, "t": last.Timestamp, "n": last.Transactions}
+	writeJSON(w, http.StatusOK, map[string]any{"ticker": symbol, "queryCount": 1, "resultsCount": 1, "adjusted": true, "results": []any{result}, "status": "OK", "request_id": "fake", "count": 1})
+}
+
+func (server *Server) handleAggregates(w http.ResponseWriter, r *http.Request) {
+	symbol := r.PathValue("symbol")
+	timespan := r.PathValue("timespan")
+	from, errFrom := parseAggregatesBound(r.PathValue("from"), false)
+	to, errTo := parseAggregatesBound(r.PathValue("to"), true)
+	multiplier, errMultiplier := strconv.Atoi(r.PathValue("multiplier"))
+	if errFrom != nil || errTo != nil {
+		writeError(w, http.StatusBadRequest, "Could not parse the from/to dates")
+		return
+	}
+	if errMultiplier != nil || multiplier < 1 {
+		writeError(w, http.StatusBadRequest, "Invalid multiplier")
+		return
+	}
+
+	bars := []Bar{}
+	switch timespan {
+	case "minute", "hour":
+		// The fixtures only hold daily bars, so there is no intraday data to serve
+	case "day", "week", "month", "quarter", "year":
+		for _, bar := range server.Aggregates[symbol] {
+			timestamp := time.UnixMilli(bar.Timestamp).UTC()
+			if !timestamp.Before(from) && !timestamp.After(to) {
+				bars = append(bars, bar)
+			}
+		}
+		bars = resampleBars(bars, timespan, multiplier)
+	default:
+		writeError(w, http.StatusBadRequest, "Invalid timespan")
+		return
+	}
+
+	if r.URL.Query().Get("sort") == "desc" {
+		sort.Slice(bars, func(i, j int) bool { return bars[i].Timestamp > bars[j].Timestamp })
+	}
+	if limit, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && limit > 0 && limit < len(bars) {
+		bars = bars[:limit]
+	}
+
+	body := map[string]any{"ticker": symbol, "queryCount": len(bars), "resultsCount": len(bars), "adjusted": r.URL.Query().Get("adjusted") != "false", "status": "OK", "request_id": "fake", "count": len(bars)}
+	if len(bars) > 0 {
+		body["results"] = bars
+	}
+	writeJSON(w, http.StatusOK, body)
+}
+
+// Serves the daily bar of every ticker in Aggregates on the requested date
+func (server *Server) handleGroupedDaily(w http.ResponseWriter, r *http.Request) {
+	date, err := time.Parse("2006-01-02", r.PathValue("date"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Could not parse the date")
+		return
+	}
+
+	symbols := make([]string, 0, len(server.Aggregates))
+	for symbol := range server.Aggregates {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+
+	results := []any{}
+	for _, symbol := range symbols {
+		for _, bar := range server.Aggregates[symbol] {
+			timestamp := time.UnixMilli(bar.Timestamp).UTC()
+			if !timestamp.Before(date) && timestamp.Before(date.AddDate(0, 0, 1)) {
+				results = append(results, map[string]any{"T": symbol, "v": bar.Volume, "vw": bar.VWAP, "o": bar.Open, "c": bar.Close, "h": bar.High, "l": bar.Low, "t": bar.Timestamp, "n": bar.Transactions})
+			}
+		}
+	}
+
+	body := map[string]any{"queryCount": len(results), "resultsCount": len(results), "adjusted": r.URL.Query().Get("adjusted") != "false", "status": "OK", "request_id": "fake"}
+	if len(results) > 0 {
+		body["results"] = results
+	}
+	writeJSON(w, http.StatusOK, body)
+}
+
+// Parses a from/to path parameter, which Polygon accepts as a date or a millisecond timestamp.
+// Dates used as an upper bound cover the whole day.
+func parseAggregatesBound(value string, upper bool) (time.Time, error) {
+	if millis, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.UnixMilli(millis).UTC(), nil
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if upper {
+		return date.AddDate(0, 0, 1).Add(-time.Millisecond), nil
+	}
+	return date, nil
+}
+
+// Merges ascending daily bars into bars of `multiplier` timespans, each stamped with the start of its first timespan
+func resampleBars(bars []Bar, timespan string, multiplier int) []Bar {
+	if timespan == "day" && multiplier == 1 {
+		return bars
+	}
+
+	// Index of the calendar bucket a bar falls in, counted from a fixed origin so buckets line up across requests
+	bucketOf := func(t time.Time) (int, time.Time) {
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		switch timespan {
+		case "week":
+			start := day.AddDate(0, 0, -int(day.Weekday()))
+			return int(start.Unix() / (7 * 24 * 3600)), start
+		case "month":
+			return t.Year()*12 + int(t.Month()) - 1, time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+		case "quarter":
+			quarter := (int(t.Month()) - 1) / 3
+			return t.Year()*4 + quarter, time.Date(t.Year(), time.Month(quarter*3+1), 1, 0, 0, 0, 0, time.UTC)
+		case "year":
+			return t.Year(), time.Date(t.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+		default:
+			return int(day.Unix() / (24 * 3600)), day
+		}
+	}
+
+	resampled := []Bar{}
+	currentGroup := 0
+	var notional float64
+	for i, bar := range bars {
+		// Keep the hour of the source bars (midnight New York time)
+		timestamp := time.UnixMilli(bar.Timestamp).UTC()
+		bucket, bucketStart := bucketOf(timestamp)
+		group := bucket / multiplier
+		if i == 0 || group != currentGroup {
+			if len(resampled) > 0 && resampled[len(resampled)-1].Volume > 0 {
+				resampled[len(resampled)-1].VWAP = notional / resampled[len(resampled)-1].Volume
+			}
+			offset := timestamp.Sub(time.Date(timestamp.Year(), timestamp.Month(), timestamp.Day(), 0, 0, 0, 0, time.UTC))
+			resampled = append(resampled, Bar{Open: bar.Open, High: bar.High, Low: bar.Low, Timestamp: bucketStart.Add(offset).UnixMilli()})
+			currentGroup = group
+			notional = 0
+		}
+		last := &resampled[len(resampled)-1]
+		last.High = max(last.High, bar.High)
+		last.Low = min(last.Low, bar.Low)
+		last.Close = bar.Close
+		last.Volume += bar.Volume
+		last.Transactions += bar.Transactions
+		notional += bar.VWAP * bar.Volume
+	}
+	if len(resampled) > 0 && resampled[len(resampled)-1].Volume > 0 {
+		resampled[len(resampled)-1].VWAP = notional / resampled[len(resampled)-1].Volume
+	}
+	return resampled
+}
+
+func (server *Server) handleNews(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	from, _ := parseOptionalTime(query.Get("published_utc.gte"))
+	to, _ := parseOptionalTime(query.Get("published_utc.lte"))
+
+	matches := []map[string]any{}
+	for _, article := range server.News {
+		if symbol := query.Get("ticker"); symbol != "" && !containsString(article["tickers"], symbol) {
+			continue
+		}
+		published, err := time.Parse(time.RFC3339, fmt.Sprint(article["published_utc"]))
+		if err != nil {
+			continue
+		}
+		if (!from.IsZero() && published.Before(from)) || (!to.IsZero() && published.After(to)) {
+			continue
+		}
+		matches = append(matches, article)
+	}
+
+	descending := query.Get("order") != "asc"
+	sort.SliceStable(matches, func(i, j int) bool {
+		if descending {
+			return fmt.Sprint(matches[i]["published_utc"]) > fmt.Sprint(matches[j]["published_utc"])
+		}
+		return fmt.Sprint(matches[i]["published_utc"]) < fmt.Sprint(matches[j]["published_utc"])
+	})
+
+	offset, end, nextURL := server.paginate(r, len(matches), 10, 1000)
+	body := map[string]any{"status": "OK", "request_id": "fake", "count": end - offset, "results": matches[offset:end]}
+	if nextURL != "" {
+		body["next_url"] = nextURL
+	}
+	writeJSON(w, http.StatusOK, body)
+}
+
+// Serves dividends or splits filtered on ticker and on the YYYY-MM-DD dateField, sorted by dateField
+func (server *Server) handleCorporateActions(w http.ResponseWriter, r *http.Request, actions []map[string]any, dateField string) {
+	query := r.URL.Query()
+	matches := []map[string]any{}
+	for _, action := range actions {
+		// Filings list every ticker of the company instead of a single one
+		if symbol := query.Get("ticker"); symbol != "" && action["ticker"] != symbol && !containsString(action["tickers"], symbol) {
+			continue
+		}
+		// Dates share a fixed width format, so they compare as strings
+		date := fmt.Sprint(action[dateField])
+		if from := query.Get(dateField + ".gte"); from != "" && date < from {
+			continue
+		}
+		if to := query.Get(dateField + ".lte"); to != "" && date > to {
+			continue
+		}
+		matches = append(matches, action)
+	}
+
+	descending := query.Get("order") != "asc"
+	sort.SliceStable(matches, func(i, j int) bool {
+		if descending {
+			return fmt.Sprint(matches[i][dateField]) > fmt.Sprint(matches[j][dateField])
+		}
+		return fmt.Sprint(matches[i][dateField]) < fmt.Sprint(matches[j][dateField])
+	})
+
+	offset, end, nextURL := server.paginate(r, len(matches), 10, 1000)
+	body := map[string]any{"status": "OK", "request_id": "fake", "results": matches[offset:end]}
+	if nextURL != "" {
+		body["next_url"] = nextURL
+	}
+	writeJSON(w, http.StatusOK, body)
+}
+
+// Serves the filings of a ticker and timeframe, filtered and sorted on filing date
+func (server *Server) handleFinancials(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filings := []map[string]any{}
+	for _, filing := range server.Financials {
+		if timeframe := query.Get("timeframe"); timeframe != "" && filing["timeframe"] != timeframe {
+			continue
+		}
+		filings = append(filings, filing)
+	}
+
+	// The ticker and date filters and the pagination are the same as the corporate actions endpoints'
+	server.handleCorporateActions(w, r, filings, "filing_date")
+}
+
+func parseOptionalTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+func containsString(values any, target string) bool {
+	list, ok := values.([]any)
+	if !ok {
+		return false
+	}
+	for _, value := range list {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
@@ -1,9 +1,12 @@
 package scraper
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"financial-helper/calendar"
 	"financial-helper/mongodb"
+	"financial-helper/polygon"
 	"fmt"
 	"math"
 	"os"
@@ -15,11 +18,15 @@ import (
 type ScrapeTickerAggregatesOptions struct {
 	collectionWindow *time.Duration
 	collectionLimit  *int
+	multiplier       *int
+	timespan         *polygon.PolygonTimespan
 }
 
 type aggregatesOptionsJSON struct {
-	CollectionWindow *int `json:"collection_window"`
-	CollectionLimit  *int `json:"collection_limit"`
+	CollectionWindow *int    `json:"collection_window"`
+	CollectionLimit  *int    `json:"collection_limit"`
+	Multiplier       *int    `json:"multiplier"` // Bar size, e.g. 5 with a "minute" timespan for 5 minute bars
+	Timespan         *string `json:"timespan"`   // minute, hour, day, week, month, quarter or year
 }
 type aggregatesInstructionsJSON struct {
 	Tickers   []string               `json:"tickers"`
@@ -29,7 +36,7 @@ type aggregatesInstructionsJSON struct {
 }
 
 // Reads scraping instructions from file and runs a scrape if instructions are valid
-func (scraper *Scraper) ScrapeTickersAggregatesFromJSON(path string) error {
+func (scraper *Scraper) ScrapeTickersAggregatesFromJSON(ctx context.Context, path string) error {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return errors.Join(errors.New("failed to read instructions file"), err)
@@ -63,12 +70,26 @@ func (scraper *Scraper) ScrapeTickersAggregatesFromJSON(path string) error {
 			l := *inst.Options.CollectionLimit
 			opts.collectionLimit = &l
 		}
+		if inst.Options.Multiplier != nil {
+			if *inst.Options.Multiplier < 1 {
+				return fmt.Errorf("invalid multiplier %d", *inst.Options.Multiplier)
+			}
+			m := *inst.Options.Multiplier
+			opts.multiplier = &m
+		}
+		if inst.Options.Timespan != nil {
+			ts := polygon.PolygonTimespan(*inst.Options.Timespan)
+			if !ts.Valid() {
+				return fmt.Errorf("invalid timespan %q", *inst.Options.Timespan)
+			}
+			opts.timespan = &ts
+		}
 	}
 
-	return scraper.ScrapeTickersAggregates(inst.Tickers, start, end, opts)
+	return scraper.ScrapeTickersAggregates(ctx, inst.Tickers, start, end, opts)
 }
 
-func (scraper *Scraper) ScrapeTickersAggregates(symbols []string, start, end time.Time, options ScrapeTickerAggregatesOptions) error {
+func (scraper *Scraper) ScrapeTickersAggregates(ctx context.Context, symbols []string, start, end time.Time, options ScrapeTickerAggregatesOptions) error {
 	if len(symbols) == 0 {
 		return nil
 	}
@@ -103,7 +124,7 @@ func (scraper *Scraper) ScrapeTickersAggregates(symbols []string, start, end tim
 		}())
 
 		tickerStart := time.Now()
-		numInserted, numSkipped, err := scraper.ScrapeTickerAggregates(symbol, start, end, &options)
+		numInserted, numSkipped, err := scraper.ScrapeTickerAggregates(ctx, symbol, start, end, &options)
 		duration := time.Since(tickerStart)
 
 		// update stats
@@ -163,7 +184,7 @@ func (scraper *Scraper) ScrapeTickersAggregates(symbols []string, start, end tim
 	return nil
 }
 
-func (scraper *Scraper) ScrapeTickerAggregates(symbol string, start, end time.Time, options *ScrapeTickerAggregatesOptions) (int, int, error) {
+func (scraper *Scraper) ScrapeTickerAggregates(ctx context.Context, symbol string, start, end time.Time, options *ScrapeTickerAggregatesOptions) (int, int, error) {
 	// Validate input
 	if start.After(end) {
 		return 0, 0, errors.New("start time must be before end time")
@@ -172,6 +193,8 @@ func (scraper *Scraper) ScrapeTickerAggregates(symbol string, start, end time.Ti
 	// Set optional values & defaults
 	collectionWindow := time.Hour * 24 * 7
 	collectionLimit := 500
+	multiplier := 1
+	timespan := polygon.TimespanDay
 	if options != nil {
 		if options.collectionWindow != nil {
 			collectionWindow = *options.collectionWindow
@@ -179,7 +202,14 @@ func (scraper *Scraper) ScrapeTickerAggregates(symbol string, start, end time.Ti
 		if options.collectionLimit != nil {
 			collectionLimit = *options.collectionLimit
 		}
+		if options.multiplier != nil {
+			multiplier = *options.multiplier
+		}
+		if options.timespan != nil {
+			timespan = *options.timespan
+		}
 	}
+	resolution := mongodb.AggregateResolution{Multiplier: multiplier, Timespan: string(timespan)}
 
 	// Calculate number of steps (windows) for the progress bar based on days
 	totalDays := int(math.Ceil(end.Sub(start).Hours() / 24.0))
@@ -202,6 +232,7 @@ func (scraper *Scraper) ScrapeTickerAggregates(symbol string, start, end time.Ti
 	skippedTotal := 0
 	stepDone := 0
 	iterationStart := time.Now()
+	var fatalErr error
 
 	for currentStart := start; !currentStart.After(end); {
 		// Compute window end so that the window covers `windowDays` days inclusive.
@@ -236,27 +267,46 @@ func (scraper *Scraper) ScrapeTickerAggregates(symbol string, start, end time.Ti
 				return "??"
 			}()))
 
-			history, err := scraper.polygonClient.PolygonGetTickerHistory(symbol, currentStart, currentEnd, collectionLimit)
-			if err != nil {
-				// retry once
-				history, err = scraper.polygonClient.PolygonGetTickerHistory(symbol, currentStart, currentEnd, collectionLimit)
-				if err != nil {
-					errLogger.Printf("Error receiving aggregates for %s from %s to %s : %s", symbol, currentStart.Format("2006-01-02T15:04:05Z"), currentEnd.Format("2006-01-02T15:04:05Z"), err.Error())
+			// Weekends and holidays have no bars, so a window without a session is not worth a request
+			if !scraper.marketCalendar.HasTradingDay(calendar.DateOf(currentStart), calendar.DateOf(currentEnd)) {
+				return
+			}
+
+			// Retryable errors are already retried by the polygon connection
+			// Intraday bars are bounded by timestamp, so extend the last day of the window to its end
+			requestEnd := currentEnd
+			if timespan.Intraday() {
+				requestEnd = currentEnd.Add(24*time.Hour - time.Millisecond)
+			}
+			history, err := scraper.marketData.GetTickerAggregates(ctx, polygon.PolygonAggregatesRequest{
+				Symbol:     symbol,
+				Multiplier: multiplier,
+				Timespan:   timespan,
+				From:       currentStart,
+				To:         requestEnd,
+				Limit:      collectionLimit,
+			})
+			if err != nil && !polygon.IsPartialPolygonData(err) {
+				if polygon.IsFatalPolygonError(err) || ctx.Err() != nil {
+					fatalErr = err
 					return
 				}
+				errLogger.Printf("Error receiving aggregates for %s from %s to %s : %s", symbol, currentStart.Format("2006-01-02T15:04:05Z"), currentEnd.Format("2006-01-02T15:04:05Z"), err.Error())
+				return
 			}
 
-			mongoAggs, err := mongodb.PolygonHistoryToAggs(*history)
+			mongoAggs, err := mongodb.PolygonAggregatesToTickerAggregates(*history, resolution)
 			if err != nil {
 				// retry once
-				mongoAggs, err = mongodb.PolygonHistoryToAggs(*history)
+				mongoAggs, err = mongodb.PolygonAggregatesToTickerAggregates(*history, resolution)
 				if err != nil {
 					errLogger.Printf("Error converting to MongoDB aggregate types for %s from %s to %s : %s", symbol, currentStart.Format("2006-01-02T15:04:05Z"), currentEnd.Format("2006-01-02T15:04:05Z"), err.Error())
 					return
 				}
 			}
 
-			numInsertedAggs, err := mongodb.InsertAggregates(scraper.mongoClient, scraper.tickerDBName, mongoAggs)
+			// Writes are not cancelled with ctx, so an interrupted scrape never leaves a window half inserted
+			numInsertedAggs, err := mongodb.InsertAggregatesWithContext(context.WithoutCancel(ctx), scraper.mongoClient, scraper.tickerDBName, mongoAggs)
 			if err != nil {
 				errLogger.Printf("Error inserting aggregates to MongoDB for %s from %s to %s : %s", symbol, currentStart.Format("2006-01-02T15:04:05Z"), currentEnd.Format("2006-01-02T15:04:05Z"), err.Error())
 				return
@@ -268,6 +318,16 @@ func (scraper *Scraper) ScrapeTickerAggregates(symbol string, start, end time.Ti
 			skippedTotal += len(mongoAggs) - numInsertedAggs
 		}()
 
+		// Errors like an invalid or plan-restricted API key affect every window, so stop the run.
+		// The same goes for a cancelled context (e.g. Ctrl-C), which is checked once the current window is stored.
+		if fatalErr == nil && ctx.Err() != nil {
+			fatalErr = ctx.Err()
+		}
+		if fatalErr != nil {
+			_ = bar.Exit()
+			return insertedTotal, skippedTotal, errors.Join(fmt.Errorf("stopping scrape of %s at %s", symbol, currentStart.Format("2006-01-02T15:04:05Z")), fatalErr)
+		}
+
 		// advance progress and window regardless of success or failure
 		_ = bar.Add(1)
 		stepDone++
@@ -0,0 +1,156 @@
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"financial-helper/mongodb"
+	"financial-helper/polygon"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Dividends and splits are rare, so a single request covers the whole range of a ticker and no windowing is needed
+type corporateActionsInstructionsJSON struct {
+	Tickers   []string `json:"tickers"`
+	StartTime string   `json:"start_time"`
+	EndTime   string   `json:"end_time"`
+}
+
+// Reads the tickers and range of a dividends or splits scrape from file
+func readCorporateActionsInstructions(path string) ([]string, time.Time, time.Time, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, time.Time{}, time.Time{}, errors.Join(errors.New("failed to read instructions file"), err)
+	}
+
+	var inst corporateActionsInstructionsJSON
+	if err := json.Unmarshal(data, &inst); err != nil {
+		return nil, time.Time{}, time.Time{}, errors.Join(errors.New("failed to parse instructions JSON"), err)
+	}
+
+	if len(inst.Tickers) == 0 {
+		return nil, time.Time{}, time.Time{}, errors.New("no tickers provided in JSON")
+	}
+
+	start, err := time.Parse("2006-01-02", inst.StartTime)
+	if err != nil {
+		return nil, time.Time{}, time.Time{}, errors.Join(errors.New("invalid start_time"), err)
+	}
+	end, err := time.Parse("2006-01-02", inst.EndTime)
+	if err != nil {
+		return nil, time.Time{}, time.Time{}, errors.Join(errors.New("invalid end_time"), err)
+	}
+	if start.After(end) {
+		return nil, time.Time{}, time.Time{}, errors.New("start_time must be before end_time")
+	}
+
+	return inst.Tickers, start, end, nil
+}
+
+// Reads scraping instructions from file and runs a dividends scrape if instructions are valid
+func (scraper *Scraper) ScrapeTickersDividendsFromJSON(ctx context.Context, path string) error {
+	symbols, start, end, err := readCorporateActionsInstructions(path)
+	if err != nil {
+		return err
+	}
+	return scraper.scrapeEachTicker(ctx, "dividends", symbols, func(symbol string) (int, int, error) {
+		return scraper.ScrapeTickerDividends(ctx, symbol, start, end)
+	})
+}
+
+// Reads scraping instructions from file and runs a splits scrape if instructions are valid
+func (scraper *Scraper) ScrapeTickersSplitsFromJSON(ctx context.Context, path string) error {
+	symbols, start, end, err := readCorporateActionsInstructions(path)
+	if err != nil {
+		return err
+	}
+	return scraper.scrapeEachTicker(ctx, "splits", symbols, func(symbol string) (int, int, error) {
+		return scraper.ScrapeTickerSplits(ctx, symbol, start, end)
+	})
+}
+
+// Runs scrapeTicker for every symbol, printing progress. It suits scrapes needing a single request per ticker.
+// Errors of a single ticker are logged and skipped, unless they would affect every ticker
+// (see polygon.IsFatalPolygonError and errNoPolygon) or ctx is cancelled.
+func (scraper *Scraper) scrapeEachTicker(ctx context.Context, kind string, symbols []string, scrapeTicker func(symbol string) (int, int, error)) error {
+	startAll := time.Now()
+	totalInserted := 0
+	totalSkipped := 0
+
+	for i, symbol := range symbols {
+		if err := ctx.Err(); err != nil {
+			return errors.Join(fmt.Errorf("stopping %s scrape before %s", kind, symbol), err)
+		}
+
+		numInserted, numSkipped, err := scrapeTicker(symbol)
+		if err != nil {
+			if polygon.IsFatalPolygonError(err) || errors.Is(err, errNoPolygon) || ctx.Err() != nil {
+				return errors.Join(fmt.Errorf("stopping %s scrape at %s", kind, symbol), err)
+			}
+			errLogger.Printf("Error scraping %s of %s : %s", kind, symbol, err.Error())
+		}
+		totalInserted += numInserted
+		totalSkipped += numSkipped
+
+		fmt.Printf("%s (%d/%d): %s inserted=%d skipped=%d elapsed:%s\n", kind, i+1, len(symbols), symbol, numInserted, numSkipped, formatDuration(time.Since(startAll)))
+	}
+
+	fmt.Printf("\nRESULTS:\ntotal_time=%s\ntickers_processed=%d\ninserted_%s=%d\nskipped_%s=%d\n",
+		formatDuration(time.Since(startAll)), len(symbols), kind, totalInserted, kind, totalSkipped)
+	return nil
+}
+
+// ScrapeTickerDividends stores the dividends of symbol whose ex-dividend date is between start and end.
+// It returns the number of inserted dividends, and of dividends skipped because they were already stored.
+func (scraper *Scraper) ScrapeTickerDividends(ctx context.Context, symbol string, start, end time.Time) (int, int, error) {
+	if scraper.polygonClient == nil {
+		return 0, 0, errNoPolygon
+	}
+	polygonDividends, err := scraper.polygonClient.PolygonGetTickerDividendsWithContext(ctx, symbol, start, end)
+	if err != nil && !polygon.IsPartialPolygonData(err) {
+		return 0, 0, err
+	}
+	if len(polygonDividends) == 0 {
+		return 0, 0, nil
+	}
+
+	dividends, err := mongodb.PolygonDividendsToDividends(polygonDividends)
+	if err != nil {
+		return 0, 0, errors.Join(errors.New("error converting to MongoDB dividend types"), err)
+	}
+
+	// Like the other scrapes, writes are not cancelled with ctx
+	numInserted, err := mongodb.InsertDividendsWithContext(context.WithoutCancel(ctx), scraper.mongoClient, scraper.tickerDBName, dividends)
+	if err != nil {
+		return numInserted, 0, errors.Join(errors.New("error inserting dividends to MongoDB"), err)
+	}
+	return numInserted, len(dividends) - numInserted, nil
+}
+
+// ScrapeTickerSplits stores the splits of symbol executed between start and end.
+// It returns the number of inserted splits, and of splits skipped because they were already stored.
+func (scraper *Scraper) ScrapeTickerSplits(ctx context.Context, symbol string, start, end time.Time) (int, int, error) {
+	if scraper.polygonClient == nil {
+		return 0, 0, errNoPolygon
+	}
+	polygonSplits, err := scraper.polygonClient.PolygonGetTickerSplitsWithContext(ctx, symbol, start, end)
+	if err != nil && !polygon.IsPartialPolygonData(err) {
+		return 0, 0, err
+	}
+	if len(polygonSplits) == 0 {
+		return 0, 0, nil
+	}
+
+	splits, err := mongodb.PolygonSplitsToSplits(polygonSplits)
+	if err != nil {
+		return 0, 0, errors.Join(errors.New("error converting to MongoDB split types"), err)
+	}
+
+	numInserted, err := mongodb.InsertSplitsWithContext(context.WithoutCancel(ctx), scraper.mongoClient, scraper.tickerDBName, splits)
+	if err != nil {
+		return numInserted, 0, errors.Join(errors.New("error inserting splits to MongoDB"), err)
+	}
+	return numInserted, len(splits) - numInserted, nil
+}
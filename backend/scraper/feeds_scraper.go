@@ -0,0 +1,134 @@
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"financial-helper/feeds"
+	"financial-helper/mongodb"
+	"financial-helper/nyt"
+	"financial-helper/polygon"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// The limit for downloading a single feed
+const feedRequestTimeout = 30 * time.Second
+
+// FeedSource is a feed to scrape
+type FeedSource struct {
+	URL     string   `json:"url"`
+	Name    string   `json:"name"`    // Stored as the publisher of the articles, the feed's title if empty
+	Tickers []string `json:"tickers"` // Tagged on every article, e.g. the company of an investor relations feed
+}
+
+type feedsInstructionsJSON struct {
+	Feeds []FeedSource `json:"feeds"`
+	// Company names by ticker used to tag articles. Tickers without names get their company name
+	// from the market data provider.
+	Dictionary map[string][]string `json:"dictionary"`
+	StartTime  string              `json:"start_time"` // Optional, older articles are skipped
+}
+
+// Reads scraping instructions from file and scrapes every feed if instructions are valid
+func (scraper *Scraper) ScrapeFeedsFromJSON(ctx context.Context, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return errors.Join(errors.New("failed to read instructions file"), err)
+	}
+
+	var inst feedsInstructionsJSON
+	if err := json.Unmarshal(data, &inst); err != nil {
+		return errors.Join(errors.New("failed to parse instructions JSON"), err)
+	}
+
+	if len(inst.Feeds) == 0 {
+		return errors.New("no feeds provided in JSON")
+	}
+	sources := map[string]FeedSource{}
+	urls := []string{}
+	for _, source := range inst.Feeds {
+		if source.URL == "" {
+			return errors.New("every feed needs a url")
+		}
+		sources[source.URL] = source
+		urls = append(urls, source.URL)
+	}
+
+	since := time.Time{}
+	if inst.StartTime != "" {
+		since, err = time.Parse("2006-01-02", inst.StartTime)
+		if err != nil {
+			return errors.Join(errors.New("invalid start_time"), err)
+		}
+	}
+
+	dictionary := scraper.tickerDictionary(ctx, inst.Dictionary)
+	return scraper.scrapeEachTicker(ctx, "feed articles", urls, func(url string) (int, int, error) {
+		return scraper.ScrapeFeed(ctx, sources[url], dictionary, since)
+	})
+}
+
+// Builds the dictionary of names, looking up the company name of the tickers that have none
+func (scraper *Scraper) tickerDictionary(ctx context.Context, names map[string][]string) *feeds.TickerDictionary {
+	complete := map[string][]string{}
+	for symbol, companyNames := range names {
+		symbol = strings.ToUpper(symbol)
+		if len(companyNames) == 0 {
+			details, err := scraper.marketData.GetTickerDetails(ctx, symbol)
+			if (err == nil || polygon.IsPartialPolygonData(err)) && details.Results != nil && details.Results.Name != nil {
+				companyNames = []string{nyt.NormalizeCompanyName(*details.Results.Name)}
+			} else {
+				errLogger.Printf("Couldn't find the company name of %s, it is only matched by symbol: %v", symbol, err)
+			}
+		}
+		complete[symbol] = companyNames
+	}
+	return feeds.NewTickerDictionary(complete)
+}
+
+// ScrapeFeed stores the articles of a feed published since `since` (all of them if it is zero) that mention
+// a ticker of dictionary or are about source.Tickers. Articles whose URL is already stored, from the feed or
+// any other source, are skipped.
+// It returns the number of inserted articles, and of articles skipped because they were already stored.
+func (scraper *Scraper) ScrapeFeed(ctx context.Context, source FeedSource, dictionary *feeds.TickerDictionary, since time.Time) (int, int, error) {
+	feed, err := feeds.Fetch(ctx, scraper.feedClient, source.URL)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	items := []feeds.FeedItem{}
+	for _, item := range feed.Items {
+		if item.Published.IsZero() || !item.Published.Before(since) {
+			items = append(items, item)
+		}
+	}
+
+	publisher := mongodb.ArticlePublisher{Name: source.Name, HomepageURL: feed.Link}
+	if publisher.Name == "" {
+		publisher.Name = feed.Title
+	}
+	tickers := make([]string, 0, len(source.Tickers))
+	for _, ticker := range source.Tickers {
+		tickers = append(tickers, strings.ToUpper(ticker))
+	}
+
+	articles := mongodb.FeedItemsToArticles(publisher, items, dictionary, tickers)
+	if len(articles) == 0 {
+		return 0, 0, nil
+	}
+
+	numInserted, err := mongodb.InsertArticlesWithContext(context.WithoutCancel(ctx), scraper.mongoClient, scraper.tickerDBName, articles)
+	if err != nil {
+		return numInserted, 0, errors.Join(fmt.Errorf("error inserting articles of %s to MongoDB", source.URL), err)
+	}
+	return numInserted, len(articles) - numInserted, nil
+}
+
+// Returns the client feeds are downloaded with
+func newFeedClient() *http.Client {
+	return &http.Client{Timeout: feedRequestTimeout}
+}
@@ -0,0 +1,90 @@
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"financial-helper/mongodb"
+	"financial-helper/polygon"
+	"fmt"
+	"os"
+	"time"
+)
+
+type financialsOptionsJSON struct {
+	Timeframe *string `json:"timeframe"` // quarterly (default), annual or ttm
+}
+type financialsInstructionsJSON struct {
+	Tickers   []string               `json:"tickers"`
+	StartTime string                 `json:"start_time"` // Filing dates, not the periods the filings cover
+	EndTime   string                 `json:"end_time"`
+	Options   *financialsOptionsJSON `json:"options"`
+}
+
+// Reads scraping instructions from file and runs a financials scrape if instructions are valid
+func (scraper *Scraper) ScrapeTickersFinancialsFromJSON(ctx context.Context, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return errors.Join(errors.New("failed to read instructions file"), err)
+	}
+
+	var inst financialsInstructionsJSON
+	if err := json.Unmarshal(data, &inst); err != nil {
+		return errors.Join(errors.New("failed to parse instructions JSON"), err)
+	}
+
+	if len(inst.Tickers) == 0 {
+		return errors.New("no tickers provided in JSON")
+	}
+
+	start, err := time.Parse("2006-01-02", inst.StartTime)
+	if err != nil {
+		return errors.Join(errors.New("invalid start_time"), err)
+	}
+	end, err := time.Parse("2006-01-02", inst.EndTime)
+	if err != nil {
+		return errors.Join(errors.New("invalid end_time"), err)
+	}
+	if start.After(end) {
+		return errors.New("start_time must be before end_time")
+	}
+
+	timeframe := polygon.FinancialsQuarterly
+	if inst.Options != nil && inst.Options.Timeframe != nil {
+		timeframe = polygon.PolygonFinancialsTimeframe(*inst.Options.Timeframe)
+		if !timeframe.Valid() {
+			return fmt.Errorf("invalid timeframe %q", *inst.Options.Timeframe)
+		}
+	}
+
+	return scraper.scrapeEachTicker(ctx, "financials", inst.Tickers, func(symbol string) (int, int, error) {
+		return scraper.ScrapeTickerFinancials(ctx, symbol, timeframe, start, end)
+	})
+}
+
+// ScrapeTickerFinancials stores the filings of symbol for a timeframe that were filed between start and end.
+// It returns the number of inserted filings, and of filings that were already stored (which are replaced, in case
+// they were restated).
+func (scraper *Scraper) ScrapeTickerFinancials(ctx context.Context, symbol string, timeframe polygon.PolygonFinancialsTimeframe, start, end time.Time) (int, int, error) {
+	if scraper.polygonClient == nil {
+		return 0, 0, errNoPolygon
+	}
+	polygonFilings, err := scraper.polygonClient.PolygonGetTickerFinancialsWithContext(ctx, symbol, timeframe, start, end)
+	if err != nil && !polygon.IsPartialPolygonData(err) {
+		return 0, 0, err
+	}
+	if len(polygonFilings) == 0 {
+		return 0, 0, nil
+	}
+
+	filings, err := mongodb.PolygonFinancialsToFilings(symbol, polygonFilings)
+	if err != nil {
+		return 0, 0, errors.Join(errors.New("error converting to MongoDB financials types"), err)
+	}
+
+	numInserted, err := mongodb.InsertFinancialsWithContext(context.WithoutCancel(ctx), scraper.mongoClient, scraper.tickerDBName, filings)
+	if err != nil {
+		return numInserted, 0, errors.Join(errors.New("error inserting financials to MongoDB"), err)
+	}
+	return numInserted, len(filings) - numInserted, nil
+}
@@ -0,0 +1,143 @@
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"financial-helper/calendar"
+	"financial-helper/mongodb"
+	"financial-helper/polygon"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/schollz/progressbar/v3"
+)
+
+// The grouped daily endpoint returns every ticker at once, so instructions only hold a date range
+type ScrapeGroupedDailyOptions struct {
+	includeOTC *bool
+}
+
+type groupedDailyOptionsJSON struct {
+	IncludeOTC *bool `json:"include_otc"`
+}
+type groupedDailyInstructionsJSON struct {
+	StartTime string                   `json:"start_time"`
+	EndTime   string                   `json:"end_time"`
+	Options   *groupedDailyOptionsJSON `json:"options"`
+}
+
+// Reads scraping instructions from file and runs a grouped daily scrape if instructions are valid
+func (scraper *Scraper) ScrapeGroupedDailyFromJSON(ctx context.Context, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return errors.Join(errors.New("failed to read instructions file"), err)
+	}
+
+	var inst groupedDailyInstructionsJSON
+	if err := json.Unmarshal(data, &inst); err != nil {
+		return errors.Join(errors.New("failed to parse instructions JSON"), err)
+	}
+
+	start, err := time.Parse("2006-01-02", inst.StartTime)
+	if err != nil {
+		return errors.Join(errors.New("invalid start_time"), err)
+	}
+	end, err := time.Parse("2006-01-02", inst.EndTime)
+	if err != nil {
+		return errors.Join(errors.New("invalid end_time"), err)
+	}
+
+	var opts ScrapeGroupedDailyOptions
+	if inst.Options != nil && inst.Options.IncludeOTC != nil {
+		otc := *inst.Options.IncludeOTC
+		opts.includeOTC = &otc
+	}
+
+	startAll := time.Now()
+	inserted, skipped, err := scraper.ScrapeGroupedDaily(ctx, start, end, &opts)
+	fmt.Printf("\nRESULTS:\ntotal_time=%s\ninserted_aggregates=%d\nskipped_aggregates=%d\n", formatDuration(time.Since(startAll)), inserted, skipped)
+	return err
+}
+
+// ScrapeGroupedDaily stores the daily bar of every US stock for each trading day between the dates of start and end,
+// with one request per day. Days without a session are not requested.
+// It returns the number of inserted aggregates, and of aggregates skipped because they were already stored.
+func (scraper *Scraper) ScrapeGroupedDaily(ctx context.Context, start, end time.Time, options *ScrapeGroupedDailyOptions) (int, int, error) {
+	if start.After(end) {
+		return 0, 0, errors.New("start time must be before end time")
+	}
+	if scraper.polygonClient == nil {
+		return 0, 0, errNoPolygon
+	}
+
+	includeOTC := false
+	if options != nil && options.includeOTC != nil {
+		includeOTC = *options.includeOTC
+	}
+
+	sessions := scraper.marketCalendar.SessionsBetween(calendar.DateOf(start), calendar.DateOf(end))
+	if len(sessions) == 0 {
+		return 0, 0, nil
+	}
+
+	bar := progressbar.NewOptions(len(sessions),
+		progressbar.OptionSetDescription(fmt.Sprintf("grouped daily %s", sessions[0].Date.Format("2006-01-02"))),
+		progressbar.OptionShowCount(),
+		progressbar.OptionSetWidth(40),
+		progressbar.OptionSetPredictTime(false),
+	)
+
+	insertedTotal := 0
+	skippedTotal := 0
+	for _, session := range sessions {
+		day := session.Date.Format("2006-01-02")
+		bar.Describe(fmt.Sprintf("grouped daily %s", day))
+
+		numInserted, numSkipped, err := scraper.scrapeGroupedDay(ctx, session.Date, includeOTC)
+		insertedTotal += numInserted
+		skippedTotal += numSkipped
+		if err != nil {
+			// Like the per-ticker scrapes, only errors affecting every day (or a cancelled context) stop the run
+			if polygon.IsFatalPolygonError(err) || ctx.Err() != nil {
+				_ = bar.Exit()
+				return insertedTotal, skippedTotal, errors.Join(fmt.Errorf("stopping grouped daily scrape at %s", day), err)
+			}
+			errLogger.Printf("Error scraping grouped daily bars of %s : %s", day, err.Error())
+		}
+		if err := ctx.Err(); err != nil {
+			_ = bar.Exit()
+			return insertedTotal, skippedTotal, errors.Join(fmt.Errorf("stopping grouped daily scrape after %s", day), err)
+		}
+
+		_ = bar.Add(1)
+	}
+
+	_ = bar.Finish()
+	return insertedTotal, skippedTotal, nil
+}
+
+// Stores the daily bars of a single day, returning the number of inserted and skipped aggregates
+func (scraper *Scraper) scrapeGroupedDay(ctx context.Context, date time.Time, includeOTC bool) (int, int, error) {
+	grouped, err := scraper.polygonClient.PolygonGetGroupedDailyWithContext(ctx, date, includeOTC)
+	if errors.Is(err, polygon.ErrNoResults) {
+		// An unscheduled closure the calendar didn't know about
+		return 0, 0, nil
+	}
+	if err != nil && !polygon.IsPartialPolygonData(err) {
+		return 0, 0, err
+	}
+
+	aggs, err := mongodb.PolygonGroupedDailyToAggs(*grouped)
+	if err != nil {
+		return 0, 0, errors.Join(errors.New("error converting to MongoDB aggregate types"), err)
+	}
+
+	// A day holds thousands of bars, so it is written even if ctx is cancelled meanwhile
+	numInserted, err := mongodb.InsertAggregatesWithContext(context.WithoutCancel(ctx), scraper.mongoClient, scraper.tickerDBName, aggs)
+	if err != nil {
+		return numInserted, 0, errors.Join(errors.New("error inserting aggregates to MongoDB"), err)
+	}
+	return numInserted, len(aggs) - numInserted, nil
+}
@@ -0,0 +1,164 @@
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"financial-helper/mongodb"
+	"financial-helper/nyt"
+	"financial-helper/polygon"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/schollz/progressbar/v3"
+)
+
+// Returned by ScrapeNYTArchive when the scraper has no NYT connection
+var errNoNYT = errors.New("this scrape needs an NYT connection, but NYT_API_KEY is not set")
+
+type nytInstructionsJSON struct {
+	Tickers   []string          `json:"tickers"`
+	Companies map[string]string `json:"companies"` // Optional company names by ticker, instead of looking them up
+	StartTime string            `json:"start_time"`
+	EndTime   string            `json:"end_time"`
+}
+
+// Reads scraping instructions from file and runs an NYT archive scrape if instructions are valid
+func (scraper *Scraper) ScrapeNYTArchiveFromJSON(ctx context.Context, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return errors.Join(errors.New("failed to read instructions file"), err)
+	}
+
+	var inst nytInstructionsJSON
+	if err := json.Unmarshal(data, &inst); err != nil {
+		return errors.Join(errors.New("failed to parse instructions JSON"), err)
+	}
+
+	if len(inst.Tickers) == 0 {
+		return errors.New("no tickers provided in JSON")
+	}
+
+	start, err := time.Parse("2006-01-02", inst.StartTime)
+	if err != nil {
+		return errors.Join(errors.New("invalid start_time"), err)
+	}
+	end, err := time.Parse("2006-01-02", inst.EndTime)
+	if err != nil {
+		return errors.Join(errors.New("invalid end_time"), err)
+	}
+	if start.After(end) {
+		return errors.New("start_time must be before end_time")
+	}
+	if scraper.nytClient == nil {
+		return errNoNYT
+	}
+
+	matcher, err := scraper.companyMatcher(ctx, inst.Tickers, inst.Companies)
+	if err != nil {
+		return err
+	}
+
+	startAll := time.Now()
+	// end is a date, so the whole day is included
+	inserted, skipped, err := scraper.ScrapeNYTArchive(ctx, matcher, start, end.AddDate(0, 0, 1))
+	fmt.Printf("\nRESULTS:\ntotal_time=%s\ntickers_tracked=%d\ninserted_nyt=%d\nskipped_nyt=%d\n",
+		formatDuration(time.Since(startAll)), matcher.Len(), inserted, skipped)
+	return err
+}
+
+// Builds a matcher for the companies of symbols. Names come from overrides, or from the market data
+// provider's ticker details; tickers whose name can't be found are left out.
+func (scraper *Scraper) companyMatcher(ctx context.Context, symbols []string, overrides map[string]string) (*nyt.NYTCompanyMatcher, error) {
+	names := map[string]string{}
+	for _, symbol := range symbols {
+		symbol = strings.ToUpper(symbol)
+		if name, ok := overrides[symbol]; ok {
+			names[symbol] = name
+			continue
+		}
+
+		details, err := scraper.marketData.GetTickerDetails(ctx, symbol)
+		if (err != nil && !polygon.IsPartialPolygonData(err)) || details.Results == nil || details.Results.Name == nil {
+			errLogger.Printf("Couldn't find the company name of %s, add it to \"companies\" to track it: %v", symbol, err)
+			continue
+		}
+		names[symbol] = *details.Results.Name
+	}
+
+	matcher := nyt.NewNYTCompanyMatcher(names)
+	if matcher.Len() == 0 {
+		return nil, errors.New("no company names found for the tickers")
+	}
+	return matcher, nil
+}
+
+// ScrapeNYTArchive stores the NYT articles published between start and end that are about a company of matcher.
+// The archive is requested one month at a time, so a scrape of a year sends 12 requests of the 500 allowed per day.
+// It returns the number of inserted articles, and of articles skipped because they were already stored.
+func (scraper *Scraper) ScrapeNYTArchive(ctx context.Context, matcher *nyt.NYTCompanyMatcher, start, end time.Time) (int, int, error) {
+	if scraper.nytClient == nil {
+		return 0, 0, errNoNYT
+	}
+	if !start.Before(end) {
+		return 0, 0, errors.New("start must be before end")
+	}
+
+	months := []time.Time{}
+	for month := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.UTC); month.Before(end); month = month.AddDate(0, 1, 0) {
+		months = append(months, month)
+	}
+
+	bar := progressbar.NewOptions(len(months),
+		progressbar.OptionSetDescription(fmt.Sprintf("nyt %s", months[0].Format("2006-01"))),
+		progressbar.OptionShowCount(),
+		progressbar.OptionSetWidth(40),
+		progressbar.OptionSetPredictTime(false),
+	)
+
+	insertedTotal := 0
+	skippedTotal := 0
+	for _, month := range months {
+		bar.Describe(fmt.Sprintf("nyt %s", month.Format("2006-01")))
+
+		archive, err := scraper.nytClient.NYTGetArchiveWithContext(ctx, month.Year(), month.Month())
+		if err != nil {
+			// An invalid key or a spent daily budget affects every month, so stop the run
+			if nyt.IsFatalNYTError(err) || ctx.Err() != nil {
+				_ = bar.Exit()
+				return insertedTotal, skippedTotal, errors.Join(fmt.Errorf("stopping nyt scrape at %s", month.Format("2006-01")), err)
+			}
+			errLogger.Printf("Error getting the nyt archive of %s : %s", month.Format("2006-01"), err.Error())
+			_ = bar.Add(1)
+			continue
+		}
+
+		inRange := []nyt.NYTArticle{}
+		for _, article := range archive.Response.Docs {
+			if !article.PubDate.Before(start) && article.PubDate.Before(end) {
+				inRange = append(inRange, article)
+			}
+		}
+
+		articles := mongodb.NYTArticlesToArticles(inRange, matcher)
+		if len(articles) > 0 {
+			numInserted, err := mongodb.InsertArticlesWithContext(context.WithoutCancel(ctx), scraper.mongoClient, scraper.tickerDBName, articles)
+			if err != nil {
+				errLogger.Printf("Error inserting nyt articles of %s : %d/%d inserted: %s", month.Format("2006-01"), numInserted, len(articles), err.Error())
+			}
+			insertedTotal += numInserted
+			skippedTotal += len(articles) - numInserted
+		}
+
+		if err := ctx.Err(); err != nil {
+			_ = bar.Exit()
+			return insertedTotal, skippedTotal, errors.Join(fmt.Errorf("stopping nyt scrape after %s", month.Format("2006-01")), err)
+		}
+		_ = bar.Add(1)
+	}
+
+	_ = bar.Finish()
+	return insertedTotal, skippedTotal, nil
+}
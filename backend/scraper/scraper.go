@@ -1,14 +1,19 @@
 package scraper
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"financial-helper/calendar"
 	"financial-helper/environment"
+	"financial-helper/marketdata"
 	"financial-helper/mongodb"
+	"financial-helper/nyt"
 	"financial-helper/polygon"
 	"fmt"
 	"log"
 	"math"
+	"net/http"
 	"os"
 	"strconv"
 	"time"
@@ -20,9 +25,15 @@ import (
 var errLogger *log.Logger = log.New(os.Stderr, "ERROR: ", log.LstdFlags|log.Lshortfile)
 
 type Scraper struct {
-	mongoClient   *mongo.Client
-	polygonClient *polygon.PolygonConnection
+	mongoClient *mongo.Client
+	// Aggregates and news are read through marketData, the other scrapes need Polygon
+	marketData    marketdata.MarketDataProvider
+	polygonClient *polygon.PolygonConnection // Unset when market data is read from files
+	nytClient     *nyt.NYTConnection         // Unset without an NYT_API_KEY
+	feedClient    *http.Client
 	tickerDBName  string
+	// Used to skip windows without a trading session
+	marketCalendar *calendar.Calendar
 }
 
 func New() (*Scraper, error) {
@@ -42,27 +53,78 @@ func New() (*Scraper, error) {
 		return nil, errors.Join(errors.New("failed to initialize mongodb connection"), err)
 	}
 
+	var nytConnection *nyt.NYTConnection
+	if vars["NYT_API_KEY"] != "" {
+		nytConnection = nyt.GetNYTConnection(vars["NYT_API_KEY"])
+	}
+
+	if vars["MARKET_DATA_DIR"] != "" {
+		scraper := NewWithProvider(mongoClient, marketdata.NewFileProvider(vars["MARKET_DATA_DIR"]), os.Getenv("MONGO_INITDB_DATABASE"))
+		scraper.nytClient = nytConnection
+		return scraper, nil
+	}
+
 	// Initialize Polygon connection
 	throttleTimeInt, _ := strconv.Atoi(vars["THROTTLE_TIME"]) // Don't need to check that this works because LoadVars() already did
-	polygonConnection := polygon.GetPolygonConnection(polygonKeys, time.Duration(throttleTimeInt)*time.Second)
+	polygonOptions := []polygon.PolygonConnectionOption{polygon.WithThrottle(time.Duration(throttleTimeInt) * time.Second), polygon.WithCassetteFromEnv()}
+	cache, err := mongodb.PolygonCacheFromEnv(mongoClient, os.Getenv("MONGO_INITDB_DATABASE"))
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to open the polygon response cache"), err)
+	}
+	if cache != nil {
+		polygonOptions = append(polygonOptions, polygon.WithCache(cache, nil))
+	}
+	polygonConnection := polygon.GetPolygonConnection(polygonKeys, polygonOptions...)
 
-	scraper := &Scraper{
-		mongoClient:   mongoClient,
-		polygonClient: polygonConnection,
-		tickerDBName:  os.Getenv("MONGO_INITDB_DATABASE"),
+	scraper := NewWithClients(mongoClient, polygonConnection, os.Getenv("MONGO_INITDB_DATABASE"))
+	scraper.nytClient = nytConnection
+
+	// Announced closures are best effort, the built-in holiday rules cover the scrape if Polygon can't be reached
+	scraper.marketCalendar = calendar.NewWithPolygon(polygonConnection)
+	if err := scraper.marketCalendar.Refresh(context.Background()); err != nil {
+		errLogger.Printf("Couldn't load upcoming market holidays, using built-in holiday rules: %s", err.Error())
 	}
 
 	return scraper, nil
 }
 
+// NewWithClients creates a scraper from existing connections, e.g. a Polygon connection to a fake API in tests
+func NewWithClients(mongoClient *mongo.Client, polygonConnection *polygon.PolygonConnection, tickerDBName string) *Scraper {
+	return &Scraper{
+		mongoClient:    mongoClient,
+		marketData:     marketdata.NewPolygonProvider(polygonConnection),
+		polygonClient:  polygonConnection,
+		feedClient:     newFeedClient(),
+		tickerDBName:   tickerDBName,
+		marketCalendar: calendar.New(),
+	}
+}
+
+// NewWithProvider creates a scraper reading aggregates and news from provider, e.g. a marketdata.FileProvider.
+// Scrapes of Polygon-only endpoints (grouped daily, dividends, splits and financials) fail with errNoPolygon.
+func NewWithProvider(mongoClient *mongo.Client, provider marketdata.MarketDataProvider, tickerDBName string) *Scraper {
+	return &Scraper{
+		mongoClient:    mongoClient,
+		marketData:     provider,
+		feedClient:     newFeedClient(),
+		tickerDBName:   tickerDBName,
+		marketCalendar: calendar.New(),
+	}
+}
+
+// Returned by the scrapes that need a Polygon connection when the scraper has none
+var errNoPolygon = errors.New("this scrape needs a Polygon connection, but market data is read from files")
+
 type ScrapeTickerNewsOptions struct {
-	collectionWindow *time.Duration
-	collectionLimit  *int
+	collectionWindow   *time.Duration
+	collectionLimit    *int
+	collectionMaxPages *int
 }
 
 type newsOptionsJSON struct {
-	CollectionWindow *int `json:"collection_window"`
-	CollectionLimit  *int `json:"collection_limit"`
+	CollectionWindow   *int `json:"collection_window"`
+	CollectionLimit    *int `json:"collection_limit"`
+	CollectionMaxPages *int `json:"collection_max_pages"`
 }
 type newsInstructionsJSON struct {
 	Tickers   []string         `json:"tickers"`
@@ -72,7 +134,7 @@ type newsInstructionsJSON struct {
 }
 
 // Reads scraping instructions from file and runs a scrape if instructions are valid
-func (scraper *Scraper) ScrapeTickersNewsFromJSON(path string) error {
+func (scraper *Scraper) ScrapeTickersNewsFromJSON(ctx context.Context, path string) error {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return errors.Join(errors.New("failed to read instructions file"), err)
@@ -106,12 +168,16 @@ func (scraper *Scraper) ScrapeTickersNewsFromJSON(path string) error {
 			l := *inst.Options.CollectionLimit
 			opts.collectionLimit = &l
 		}
+		if inst.Options.CollectionMaxPages != nil {
+			p := *inst.Options.CollectionMaxPages
+			opts.collectionMaxPages = &p
+		}
 	}
 
-	return scraper.ScrapeTickersNews(inst.Tickers, start, end, opts)
+	return scraper.ScrapeTickersNews(ctx, inst.Tickers, start, end, opts)
 }
 
-func (scraper *Scraper) ScrapeTickersNews(symbols []string, start, end time.Time, options ScrapeTickerNewsOptions) error {
+func (scraper *Scraper) ScrapeTickersNews(ctx context.Context, symbols []string, start, end time.Time, options ScrapeTickerNewsOptions) error {
 	if len(symbols) == 0 {
 		return nil
 	}
@@ -146,7 +212,7 @@ func (scraper *Scraper) ScrapeTickersNews(symbols []string, start, end time.Time
 		}())
 
 		tickerStart := time.Now()
-		numInserted, numSkipped, err := scraper.ScrapeTickerNews(symbol, start, end, &options)
+		numInserted, numSkipped, err := scraper.ScrapeTickerNews(ctx, symbol, start, end, &options)
 		duration := time.Since(tickerStart)
 
 		// update stats
@@ -206,7 +272,7 @@ func (scraper *Scraper) ScrapeTickersNews(symbols []string, start, end time.Time
 	return nil
 }
 
-func (scraper *Scraper) ScrapeTickerNews(symbol string, start, end time.Time, options *ScrapeTickerNewsOptions) (int, int, error) {
+func (scraper *Scraper) ScrapeTickerNews(ctx context.Context, symbol string, start, end time.Time, options *ScrapeTickerNewsOptions) (int, int, error) {
 	// Validate input
 	if start.After(end) {
 		return 0, 0, errors.New("start time must be before end time")
@@ -215,6 +281,7 @@ func (scraper *Scraper) ScrapeTickerNews(symbol string, start, end time.Time, op
 	// Set optional values & defaults
 	collectionWindow := time.Hour * 24 * 7
 	collectionLimit := 500
+	collectionMaxPages := 0 // follow next_url until exhausted
 	if options != nil {
 		if options.collectionWindow != nil {
 			collectionWindow = *options.collectionWindow
@@ -222,6 +289,9 @@ func (scraper *Scraper) ScrapeTickerNews(symbol string, start, end time.Time, op
 		if options.collectionLimit != nil {
 			collectionLimit = *options.collectionLimit
 		}
+		if options.collectionMaxPages != nil {
+			collectionMaxPages = *options.collectionMaxPages
+		}
 	}
 
 	// Calculate number of steps for the progress bar
@@ -244,6 +314,7 @@ func (scraper *Scraper) ScrapeTickerNews(symbol string, start, end time.Time, op
 	skippedTotal := 0
 	stepDone := 0
 	iterationStart := time.Now()
+	var fatalErr error
 	for currentStart := start; currentStart.Before(end); {
 		currentEnd := currentStart.Add(collectionWindow)
 		if currentEnd.After(end) {
@@ -270,14 +341,18 @@ func (scraper *Scraper) ScrapeTickerNews(symbol string, start, end time.Time, op
 				return "??"
 			}()))
 
-			news, err := scraper.polygonClient.PolygonGetTickerNews(symbol, currentStart, currentEnd, collectionLimit)
-			if err != nil {
-				// retry once
-				news, err = scraper.polygonClient.PolygonGetTickerNews(symbol, currentStart, currentEnd, collectionLimit)
-				if err != nil {
-					errLogger.Printf("Error receiving ticker data for %s from %s to %s : %s", symbol, currentStart.Format("2006-01-02T15:04:05Z"), currentEnd.Format("2006-01-02T15:04:05Z"), err.Error())
+			// Retryable errors are already retried by the polygon connection
+			news, stats, err := scraper.marketData.GetTickerNews(ctx, symbol, currentStart, currentEnd, collectionLimit, collectionMaxPages)
+			if err != nil && !polygon.IsPartialPolygonData(err) {
+				if polygon.IsFatalPolygonError(err) || ctx.Err() != nil {
+					fatalErr = err
 					return
 				}
+				errLogger.Printf("Error receiving ticker data for %s from %s to %s : %s", symbol, currentStart.Format("2006-01-02T15:04:05Z"), currentEnd.Format("2006-01-02T15:04:05Z"), err.Error())
+				return
+			}
+			if stats.Truncated {
+				errLogger.Printf("News for %s from %s to %s was truncated after %d pages (%d articles); increase collection_max_pages or shrink collection_window", symbol, currentStart.Format("2006-01-02T15:04:05Z"), currentEnd.Format("2006-01-02T15:04:05Z"), stats.Pages, stats.Results)
 			}
 
 			mongoNews, err := mongodb.PolygonNewsToArticles(*news)
@@ -290,7 +365,8 @@ func (scraper *Scraper) ScrapeTickerNews(symbol string, start, end time.Time, op
 				}
 			}
 
-			numInsertedArticles, err := mongodb.InsertArticles(scraper.mongoClient, scraper.tickerDBName, mongoNews)
+			// Writes are not cancelled with ctx, so an interrupted scrape never leaves a window half inserted
+			numInsertedArticles, err := mongodb.InsertArticlesWithContext(context.WithoutCancel(ctx), scraper.mongoClient, scraper.tickerDBName, mongoNews)
 			if err != nil {
 				errLogger.Printf("Error inserting news to MongoDB for %s from %s to %s : %s", symbol, currentStart.Format("2006-01-02T15:04:05Z"), currentEnd.Format("2006-01-02T15:04:05Z"), err.Error())
 				return
@@ -303,6 +379,16 @@ func (scraper *Scraper) ScrapeTickerNews(symbol string, start, end time.Time, op
 			skippedTotal += len(mongoNews) - numInsertedArticles
 		}()
 
+		// Errors like an invalid or plan-restricted API key affect every window, so stop the run.
+		// The same goes for a cancelled context (e.g. Ctrl-C), which is checked once the current window is stored.
+		if fatalErr == nil && ctx.Err() != nil {
+			fatalErr = ctx.Err()
+		}
+		if fatalErr != nil {
+			_ = bar.Exit()
+			return insertedTotal, skippedTotal, errors.Join(fmt.Errorf("stopping scrape of %s at %s", symbol, currentStart.Format("2006-01-02T15:04:05Z")), fatalErr)
+		}
+
 		// advance progress and window regardless of success or failure
 		_ = bar.Add(1)
 		stepDone++
@@ -0,0 +1,406 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"financial-helper/feeds"
+	"financial-helper/marketdata"
+	"financial-helper/nyt"
+	"financial-helper/nyt/nyttest"
+	"financial-helper/polygon"
+	"financial-helper/polygon/polygontest"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Builds a scraper whose Polygon connection points at a fake API. There is no MongoDB connection,
+// so tests must only exercise paths that stop before inserting.
+func newTestScraper(t *testing.T, keys ...string) (*Scraper, *polygontest.Server) {
+	t.Helper()
+	fake := polygontest.NewServer()
+	t.Cleanup(fake.Close)
+	connection := polygon.GetPolygonConnection(keys,
+		polygon.WithBaseURL(fake.URL),
+		polygon.WithHTTPClient(fake.Client()),
+		polygon.WithKeyRateLimit(1000, time.Minute),
+		polygon.WithRetryPolicy(polygon.NoPolygonRetries()),
+	)
+	return NewWithClients(nil, connection, "test_stock_savvy"), fake
+}
+
+func TestScrapeTickerNews_StopsOnRejectedKey(t *testing.T) {
+	scraper, fake := newTestScraper(t, "revoked-key")
+	fake.RejectKey("revoked-key")
+
+	start := time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 28)
+	_, _, err := scraper.ScrapeTickerNews(context.Background(), "AAPL", start, end, nil)
+	if err == nil || !polygon.IsFatalPolygonError(err) {
+		t.Fatalf("expected the scrape to stop with an unauthorized error, got %v", err)
+	}
+	// Four weekly windows, but only the first one should have been requested
+	if count := fake.RequestCount("/v2/reference/news"); count != 1 {
+		t.Fatalf("expected the scrape to stop after 1 request, got %d", count)
+	}
+}
+
+func TestScrapeTickerNews_SkipsEmptyWindows(t *testing.T) {
+	scraper, fake := newTestScraper(t, "test-key")
+
+	// The fixtures have no news about SPY, so every window is skipped without touching MongoDB
+	start := time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 21)
+	inserted, skipped, err := scraper.ScrapeTickerNews(context.Background(), "SPY", start, end, nil)
+	if err != nil {
+		t.Fatalf("ScrapeTickerNews error: %v", err)
+	}
+	if inserted != 0 || skipped != 0 {
+		t.Fatalf("expected nothing to be inserted, got %d inserted and %d skipped", inserted, skipped)
+	}
+	if count := fake.RequestCount("/v2/reference/news"); count != 3 {
+		t.Fatalf("expected one request per weekly window, got %d", count)
+	}
+}
+
+func TestScrapeTickerAggregates_StopsOnRejectedKey(t *testing.T) {
+	scraper, fake := newTestScraper(t, "revoked-key")
+	fake.RejectKey("revoked-key")
+
+	start := time.Date(2024, 9, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 10, 31, 0, 0, 0, 0, time.UTC)
+	_, _, err := scraper.ScrapeTickerAggregates(context.Background(), "AAPL", start, end, nil)
+	if err == nil || !polygon.IsFatalPolygonError(err) {
+		t.Fatalf("expected the scrape to stop with an unauthorized error, got %v", err)
+	}
+	if count := fake.RequestCount("/v2/aggs/ticker/AAPL/range"); count != 1 {
+		t.Fatalf("expected the scrape to stop after 1 request, got %d", count)
+	}
+}
+
+func TestScrapeTickerAggregates_IntradayWindowsCoverWholeDays(t *testing.T) {
+	scraper, fake := newTestScraper(t, "test-key")
+
+	// The fake has no intraday bars, so every window is skipped without touching MongoDB
+	start := time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 1)
+	window := 24 * time.Hour
+	multiplier := 5
+	timespan := polygon.TimespanMinute
+	options := &ScrapeTickerAggregatesOptions{collectionWindow: &window, multiplier: &multiplier, timespan: &timespan}
+	if _, _, err := scraper.ScrapeTickerAggregates(context.Background(), "AAPL", start, end, options); err != nil {
+		t.Fatalf("ScrapeTickerAggregates error: %v", err)
+	}
+
+	requests := fake.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("expected one request per daily window, got %v", requests)
+	}
+	dayEnd := start.Add(24*time.Hour - time.Millisecond)
+	expected := fmt.Sprintf("/v2/aggs/ticker/AAPL/range/5/minute/%d/%d?", start.UnixMilli(), dayEnd.UnixMilli())
+	if !strings.HasPrefix(requests[0], expected) {
+		t.Fatalf("expected the first window to be requested as %s..., got %s", expected, requests[0])
+	}
+}
+
+func TestScrapeTickerAggregates_SkipsWindowsWithoutSessions(t *testing.T) {
+	scraper, fake := newTestScraper(t, "test-key")
+
+	// Daily windows over Thanksgiving weekend: only Friday the 29th (an early close) trades
+	start := time.Date(2024, 11, 28, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC)
+	window := 24 * time.Hour
+	if _, _, err := scraper.ScrapeTickerAggregates(context.Background(), "SPY", start, end, &ScrapeTickerAggregatesOptions{collectionWindow: &window}); err != nil {
+		t.Fatalf("ScrapeTickerAggregates error: %v", err)
+	}
+
+	requests := fake.Requests()
+	if len(requests) != 1 || !strings.Contains(requests[0], "/2024-11-29/2024-11-29?") {
+		t.Fatalf("expected a single request for November 29th, got %v", requests)
+	}
+}
+
+func TestScrapeTickersAggregatesFromJSON_InvalidResolution(t *testing.T) {
+	scraper, fake := newTestScraper(t, "test-key")
+
+	cases := map[string]string{
+		"invalid timespan":   `{"tickers": ["AAPL"], "start_time": "2024-10-01", "end_time": "2024-10-31", "options": {"timespan": "fortnight"}}`,
+		"invalid multiplier": `{"tickers": ["AAPL"], "start_time": "2024-10-01", "end_time": "2024-10-31", "options": {"multiplier": 0}}`,
+	}
+	for expected, instructions := range cases {
+		path := filepath.Join(t.TempDir(), "instructions.json")
+		if err := os.WriteFile(path, []byte(instructions), 0o644); err != nil {
+			t.Fatalf("failed to write instructions: %v", err)
+		}
+		err := scraper.ScrapeTickersAggregatesFromJSON(context.Background(), path)
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Fatalf("expected an error mentioning %q, got %v", expected, err)
+		}
+	}
+	if len(fake.Requests()) != 0 {
+		t.Fatalf("invalid instructions should not reach polygon, got %v", fake.Requests())
+	}
+}
+
+func TestScrapeTickersNewsFromJSON_InvalidInstructions(t *testing.T) {
+	scraper, fake := newTestScraper(t, "test-key")
+
+	cases := map[string]string{
+		"no tickers":         `{"tickers": [], "start_time": "2024-10-01", "end_time": "2024-10-31"}`,
+		"invalid start_time": `{"tickers": ["AAPL"], "start_time": "October 1st", "end_time": "2024-10-31"}`,
+		"parse":              `{"tickers": ["AAPL"`,
+	}
+	for expected, instructions := range cases {
+		path := filepath.Join(t.TempDir(), "instructions.json")
+		if err := os.WriteFile(path, []byte(instructions), 0o644); err != nil {
+			t.Fatalf("failed to write instructions: %v", err)
+		}
+		err := scraper.ScrapeTickersNewsFromJSON(context.Background(), path)
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Fatalf("expected an error mentioning %q, got %v", expected, err)
+		}
+	}
+	if len(fake.Requests()) != 0 {
+		t.Fatalf("invalid instructions should not reach polygon, got %v", fake.Requests())
+	}
+}
+
+func TestScrapeTickerNews_StopsWhenCancelled(t *testing.T) {
+	scraper, fake := newTestScraper(t, "test-key")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)
+	_, _, err := scraper.ScrapeTickerNews(ctx, "AAPL", start, start.AddDate(0, 0, 28), nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the scrape to stop with context.Canceled, got %v", err)
+	}
+	if count := len(fake.Requests()); count != 0 {
+		t.Fatalf("expected no request to be sent after cancellation, got %d", count)
+	}
+}
+
+func TestScrapeTickersSplitsFromJSON(t *testing.T) {
+	scraper, fake := newTestScraper(t, "test-key")
+
+	// None of the tickers split in the range, so nothing reaches MongoDB
+	path := filepath.Join(t.TempDir(), "instructions.json")
+	instructions := `{"tickers": ["AAPL", "MSFT", "SPY"], "start_time": "2024-01-01", "end_time": "2024-10-31"}`
+	if err := os.WriteFile(path, []byte(instructions), 0o644); err != nil {
+		t.Fatalf("failed to write instructions: %v", err)
+	}
+	if err := scraper.ScrapeTickersSplitsFromJSON(context.Background(), path); err != nil {
+		t.Fatalf("ScrapeTickersSplitsFromJSON error: %v", err)
+	}
+	if count := fake.RequestCount("/v3/reference/splits"); count != 3 {
+		t.Fatalf("expected one request per ticker, got %d", count)
+	}
+
+	inverted := `{"tickers": ["AAPL"], "start_time": "2024-10-31", "end_time": "2024-01-01"}`
+	if err := os.WriteFile(path, []byte(inverted), 0o644); err != nil {
+		t.Fatalf("failed to write instructions: %v", err)
+	}
+	if err := scraper.ScrapeTickersDividendsFromJSON(context.Background(), path); err == nil || !strings.Contains(err.Error(), "start_time") {
+		t.Fatalf("expected an error about the range, got %v", err)
+	}
+}
+
+func TestScrapeTickersDividends_StopsOnRejectedKey(t *testing.T) {
+	scraper, fake := newTestScraper(t, "revoked-key")
+	fake.RejectKey("revoked-key")
+
+	path := filepath.Join(t.TempDir(), "instructions.json")
+	instructions := `{"tickers": ["AAPL", "MSFT"], "start_time": "2024-01-01", "end_time": "2024-10-31"}`
+	if err := os.WriteFile(path, []byte(instructions), 0o644); err != nil {
+		t.Fatalf("failed to write instructions: %v", err)
+	}
+	err := scraper.ScrapeTickersDividendsFromJSON(context.Background(), path)
+	if err == nil || !polygon.IsFatalPolygonError(err) {
+		t.Fatalf("expected the scrape to stop with an unauthorized error, got %v", err)
+	}
+	if count := fake.RequestCount("/v3/reference/dividends"); count != 1 {
+		t.Fatalf("expected the scrape to stop after 1 request, got %d", count)
+	}
+}
+
+func TestScrapeGroupedDaily_OneRequestPerSession(t *testing.T) {
+	scraper, fake := newTestScraper(t, "test-key")
+
+	// Two weeks without holidays: 10 sessions, weekends are not requested.
+	// Inserting fails without MongoDB, which is logged without stopping the scrape.
+	start := time.Date(2024, 10, 7, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 10, 20, 0, 0, 0, 0, time.UTC)
+	if _, _, err := scraper.ScrapeGroupedDaily(context.Background(), start, end, nil); err != nil {
+		t.Fatalf("ScrapeGroupedDaily error: %v", err)
+	}
+
+	requests := fake.Requests()
+	if len(requests) != 10 {
+		t.Fatalf("expected one request per session, got %v", requests)
+	}
+	if !strings.HasPrefix(requests[0], "/v2/aggs/grouped/locale/us/market/stocks/2024-10-07?") || !strings.Contains(requests[0], "include_otc=false") {
+		t.Fatalf("unexpected first request %s", requests[0])
+	}
+	if !strings.HasPrefix(requests[9], "/v2/aggs/grouped/locale/us/market/stocks/2024-10-18?") {
+		t.Fatalf("unexpected last request %s", requests[9])
+	}
+}
+
+func TestScrapeGroupedDailyFromJSON_StopsOnRejectedKey(t *testing.T) {
+	scraper, fake := newTestScraper(t, "revoked-key")
+	fake.RejectKey("revoked-key")
+
+	path := filepath.Join(t.TempDir(), "instructions.json")
+	instructions := `{"start_time": "2024-10-01", "end_time": "2024-10-31", "options": {"include_otc": true}}`
+	if err := os.WriteFile(path, []byte(instructions), 0o644); err != nil {
+		t.Fatalf("failed to write instructions: %v", err)
+	}
+	err := scraper.ScrapeGroupedDailyFromJSON(context.Background(), path)
+	if err == nil || !polygon.IsFatalPolygonError(err) {
+		t.Fatalf("expected the scrape to stop with an unauthorized error, got %v", err)
+	}
+	if count := fake.RequestCount("/v2/aggs/grouped"); count != 1 {
+		t.Fatalf("expected the scrape to stop after 1 request, got %d", count)
+	}
+}
+
+func TestScrapeTickersFinancialsFromJSON(t *testing.T) {
+	scraper, fake := newTestScraper(t, "test-key")
+
+	// Filings are found, so every ticker fails at MongoDB, which is logged without stopping the scrape
+	path := filepath.Join(t.TempDir(), "instructions.json")
+	instructions := `{"tickers": ["AAPL", "MSFT"], "start_time": "2024-01-01", "end_time": "2024-12-31", "options": {"timeframe": "annual"}}`
+	if err := os.WriteFile(path, []byte(instructions), 0o644); err != nil {
+		t.Fatalf("failed to write instructions: %v", err)
+	}
+	if err := scraper.ScrapeTickersFinancialsFromJSON(context.Background(), path); err != nil {
+		t.Fatalf("ScrapeTickersFinancialsFromJSON error: %v", err)
+	}
+	requests := fake.Requests()
+	if len(requests) != 2 || !strings.Contains(requests[0], "timeframe=annual") || !strings.Contains(requests[0], "filing_date.gte=2024-01-01") {
+		t.Fatalf("expected one annual request per ticker, got %v", requests)
+	}
+
+	invalid := `{"tickers": ["AAPL"], "start_time": "2024-01-01", "end_time": "2024-12-31", "options": {"timeframe": "monthly"}}`
+	if err := os.WriteFile(path, []byte(invalid), 0o644); err != nil {
+		t.Fatalf("failed to write instructions: %v", err)
+	}
+	if err := scraper.ScrapeTickersFinancialsFromJSON(context.Background(), path); err == nil || !strings.Contains(err.Error(), "timeframe") {
+		t.Fatalf("expected an error about the timeframe, got %v", err)
+	}
+}
+
+func TestNewWithProvider(t *testing.T) {
+	scraper := NewWithProvider(nil, marketdata.NewFileProvider("../marketdata/testdata"), "test_stock_savvy")
+
+	// The files have no news about TSLA, so every window is skipped without touching MongoDB
+	start := time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC)
+	inserted, skipped, err := scraper.ScrapeTickerNews(context.Background(), "TSLA", start, start.AddDate(0, 0, 14), nil)
+	if err != nil || inserted != 0 || skipped != 0 {
+		t.Fatalf("expected an empty news scrape, got %d inserted, %d skipped (%v)", inserted, skipped, err)
+	}
+
+	// Corporate actions only come from Polygon, so the scrape stops at the first ticker
+	path := filepath.Join(t.TempDir(), "instructions.json")
+	if err := os.WriteFile(path, []byte(`{"tickers": ["AAPL", "MSFT"], "start_time": "2024-01-01", "end_time": "2024-12-31"}`), 0o644); err != nil {
+		t.Fatalf("failed to write instructions: %v", err)
+	}
+	if err := scraper.ScrapeTickersSplitsFromJSON(context.Background(), path); !errors.Is(err, errNoPolygon) {
+		t.Fatalf("expected the splits scrape to need Polygon, got %v", err)
+	}
+	if _, _, err := scraper.ScrapeGroupedDaily(context.Background(), start, start.AddDate(0, 0, 7), nil); !errors.Is(err, errNoPolygon) {
+		t.Fatalf("expected the grouped daily scrape to need Polygon, got %v", err)
+	}
+}
+
+// Points the scraper's NYT connection at a fake Archive API without rate limits
+func withTestNYT(t *testing.T, scraper *Scraper, key string) *nyttest.Server {
+	t.Helper()
+	fake := nyttest.NewServer()
+	t.Cleanup(fake.Close)
+	scraper.nytClient = nyt.GetNYTConnection(key, nyt.WithBaseURL(fake.URL), nyt.WithHTTPClient(fake.Client()), nyt.WithRateLimit(0, 0))
+	return fake
+}
+
+func TestScrapeNYTArchiveFromJSON(t *testing.T) {
+	scraper, polygonFake := newTestScraper(t, "test-key")
+
+	path := filepath.Join(t.TempDir(), "instructions.json")
+	instructions := `{"tickers": ["AAPL", "ZZZZ"], "companies": {"ZZZZ": "Nonexistent Widgets Inc"}, "start_time": "2024-10-15", "end_time": "2024-11-30"}`
+	if err := os.WriteFile(path, []byte(instructions), 0o644); err != nil {
+		t.Fatalf("failed to write instructions: %v", err)
+	}
+	if err := scraper.ScrapeNYTArchiveFromJSON(context.Background(), path); !errors.Is(err, errNoNYT) {
+		t.Fatalf("expected the scrape to need an NYT connection, got %v", err)
+	}
+
+	// The Apple article is found, so its month fails at MongoDB, which is logged without stopping the scrape
+	nytFake := withTestNYT(t, scraper, "test-key")
+	if err := scraper.ScrapeNYTArchiveFromJSON(context.Background(), path); err != nil {
+		t.Fatalf("ScrapeNYTArchiveFromJSON error: %v", err)
+	}
+	if requests := nytFake.Requests(); len(requests) != 2 || requests[0] != "/2024/10.json" || requests[1] != "/2024/11.json" {
+		t.Fatalf("expected one request per month, got %v", requests)
+	}
+	// Only the ticker without a name in the instructions is looked up
+	if count := polygonFake.RequestCount("/v3/reference/tickers/"); count != 1 {
+		t.Fatalf("expected a single ticker details request, got %d", count)
+	}
+}
+
+func TestScrapeNYTArchive_StopsOnRejectedKey(t *testing.T) {
+	scraper, _ := newTestScraper(t, "test-key")
+	fake := withTestNYT(t, scraper, "revoked-key")
+	fake.RejectKey("revoked-key")
+
+	matcher := nyt.NewNYTCompanyMatcher(map[string]string{"AAPL": "Apple Inc."})
+	start := time.Date(2024, 9, 1, 0, 0, 0, 0, time.UTC)
+	_, _, err := scraper.ScrapeNYTArchive(context.Background(), matcher, start, start.AddDate(0, 3, 0))
+	if err == nil || !nyt.IsFatalNYTError(err) {
+		t.Fatalf("expected the scrape to stop with an unauthorized error, got %v", err)
+	}
+	if requests := fake.Requests(); len(requests) != 1 {
+		t.Fatalf("expected the scrape to stop after 1 request, got %v", requests)
+	}
+}
+
+func TestScrapeFeedsFromJSON(t *testing.T) {
+	scraper, polygonFake := newTestScraper(t, "test-key")
+	requested := []string{}
+	files := http.FileServer(http.Dir("../feeds/testdata"))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		files.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	// The first feed has tagged articles, so it fails at MongoDB, and the second one is missing.
+	// Neither stops the scrape.
+	path := filepath.Join(t.TempDir(), "instructions.json")
+	instructions := fmt.Sprintf(`{"feeds": [{"url": "%[1]s/markets.rss"}, {"url": "%[1]s/missing.rss", "tickers": ["AAPL"]}], "dictionary": {"AAPL": [], "NVDA": ["Nvidia"]}}`, server.URL)
+	if err := os.WriteFile(path, []byte(instructions), 0o644); err != nil {
+		t.Fatalf("failed to write instructions: %v", err)
+	}
+	if err := scraper.ScrapeFeedsFromJSON(context.Background(), path); err != nil {
+		t.Fatalf("ScrapeFeedsFromJSON error: %v", err)
+	}
+	if len(requested) != 2 {
+		t.Fatalf("expected both feeds to be requested, got %v", requested)
+	}
+	// Only the ticker without names is looked up
+	if count := polygonFake.RequestCount("/v3/reference/tickers/"); count != 1 {
+		t.Fatalf("expected a single ticker details request, got %d", count)
+	}
+
+	// Every item is older than since, so nothing reaches MongoDB
+	dictionary := feeds.NewTickerDictionary(map[string][]string{"AAPL": {"Apple"}})
+	inserted, skipped, err := scraper.ScrapeFeed(context.Background(), FeedSource{URL: server.URL + "/markets.rss"}, dictionary, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil || inserted != 0 || skipped != 0 {
+		t.Fatalf("expected an empty scrape, got %d inserted, %d skipped (%v)", inserted, skipped, err)
+	}
+}
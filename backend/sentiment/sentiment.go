@@ -0,0 +1,153 @@
+// Package sentiment summarizes the sentiment of news articles towards a company.
+//
+// Every rated article is an Observation scoring 1 if positive, -1 if negative and 0 if neutral. Summarize weighs
+// them by recency and publisher and returns their weighted mean, standard deviation and a confidence interval for
+// the mean, along with the unweighted count of every class.
+package sentiment
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// The sentiment labels of Polygon's article insights
+const (
+	Positive = "positive"
+	Neutral  = "neutral"
+	Negative = "negative"
+)
+
+// ErrNoObservations is returned by Summarize when no article is rated, in which case there is no sentiment to report
+var ErrNoObservations = errors.New("no rated articles")
+
+// DefaultConfidence is the confidence level of the interval when Options.Confidence is unset
+const DefaultConfidence = 0.95
+
+// The rating of a single article
+type Observation struct {
+	Score       float64 // 1, 0 or -1, see Score
+	PublishedAt time.Time
+	Publisher   string
+}
+
+// How observations are weighed. The zero value weighs every observation equally.
+type Options struct {
+	// Observations lose half their weight every HalfLife before Now. Zero disables the decay.
+	Now      time.Time
+	HalfLife time.Duration
+	// Weights of publishers by name, publishers that aren't listed weigh 1
+	PublisherWeights map[string]float64
+	// Confidence level of the interval, DefaultConfidence if unset
+	Confidence float64
+}
+
+// The summary of a set of observations
+type Stats struct {
+	Count    int // Observations with a positive weight
+	Positive int
+	Neutral  int
+	Negative int
+	Mean     float64 // Weighted mean score, between -1 and 1
+	StdDev   float64 // Weighted sample standard deviation of the scores, 0 for a single observation
+	// Kish's effective sample size, which is Count when observations weigh the same and less otherwise
+	EffectiveCount float64
+	// Confidence interval of the mean, clamped to [-1, 1]. It spans the whole range unless there are more than
+	// one effective observations.
+	Low  float64
+	High float64
+}
+
+// Score returns the score of a sentiment label: 1 if positive, -1 if negative and 0 for any other label.
+// Empty labels are not ratings, so ok is false for them.
+func Score(label string) (score float64, ok bool) {
+	switch label {
+	case "":
+		return 0, false
+	case Positive:
+		return 1, true
+	case Negative:
+		return -1, true
+	default:
+		return 0, true
+	}
+}
+
+// Summarize weighs observations as set by options and returns their statistics
+//
+// Input:
+//   - observations: the rated articles, in any order
+//   - options: how observations are weighed
+//
+// Output:
+//   - Stats: the statistics of the observations
+//   - error: ErrNoObservations if there are no observations, or none with a positive weight
+func Summarize(observations []Observation, options Options) (Stats, error) {
+	confidence := options.Confidence
+	if confidence <= 0 || confidence >= 1 {
+		confidence = DefaultConfidence
+	}
+
+	var stats Stats
+	weights := make([]float64, len(observations))
+	var sumWeights, sumSquaredWeights, sumScores float64
+	for i, observation := range observations {
+		weights[i] = options.weight(observation)
+		if weights[i] <= 0 {
+			continue
+		}
+		stats.Count++
+		switch {
+		case observation.Score > 0:
+			stats.Positive++
+		case observation.Score < 0:
+			stats.Negative++
+		default:
+			stats.Neutral++
+		}
+		sumWeights += weights[i]
+		sumSquaredWeights += weights[i] * weights[i]
+		sumScores += weights[i] * observation.Score
+	}
+	if stats.Count == 0 {
+		return Stats{}, ErrNoObservations
+	}
+
+	stats.Mean = sumScores / sumWeights
+	stats.EffectiveCount = sumWeights * sumWeights / sumSquaredWeights
+	stats.Low, stats.High = -1, 1
+	if stats.Count == 1 {
+		return stats, nil
+	}
+
+	// Unbiased variance for reliability weights, which is the usual sample variance when weights are equal
+	var sumSquaredDifferences float64
+	for i, observation := range observations {
+		if weights[i] > 0 {
+			sumSquaredDifferences += weights[i] * (observation.Score - stats.Mean) * (observation.Score - stats.Mean)
+		}
+	}
+	stats.StdDev = math.Sqrt(sumSquaredDifferences / (sumWeights - sumSquaredWeights/sumWeights))
+
+	// Normal approximation of the mean's distribution
+	if stats.EffectiveCount > 1 {
+		margin := math.Sqrt2 * math.Erfinv(confidence) * stats.StdDev / math.Sqrt(stats.EffectiveCount)
+		stats.Low = math.Max(-1, stats.Mean-margin)
+		stats.High = math.Min(1, stats.Mean+margin)
+	}
+	return stats, nil
+}
+
+// Returns the weight of an observation, the product of its publisher's weight and its recency
+func (options Options) weight(observation Observation) float64 {
+	weight := 1.0
+	if publisherWeight, ok := options.PublisherWeights[observation.Publisher]; ok {
+		weight = publisherWeight
+	}
+	if options.HalfLife > 0 && !observation.PublishedAt.IsZero() {
+		// Articles dated after Now are as recent as can be
+		age := max(options.Now.Sub(observation.PublishedAt), 0)
+		weight *= math.Exp2(-float64(age) / float64(options.HalfLife))
+	}
+	return weight
+}
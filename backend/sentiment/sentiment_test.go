@@ -0,0 +1,116 @@
+package sentiment
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+var testNow = time.Date(2024, 10, 30, 12, 0, 0, 0, time.UTC)
+
+func scores(values ...float64) []Observation {
+	observations := make([]Observation, len(values))
+	for i, value := range values {
+		observations[i] = Observation{Score: value, PublishedAt: testNow}
+	}
+	return observations
+}
+
+func approximately(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestScore(t *testing.T) {
+	cases := map[string]struct {
+		score float64
+		ok    bool
+	}{
+		"positive": {1, true},
+		"negative": {-1, true},
+		"neutral":  {0, true},
+		"mixed":    {0, true},
+		"":         {0, false},
+	}
+	for label, expected := range cases {
+		if score, ok := Score(label); score != expected.score || ok != expected.ok {
+			t.Errorf("Score(%q) = %v, %t, expected %v, %t", label, score, ok, expected.score, expected.ok)
+		}
+	}
+}
+
+func TestSummarize_Empty(t *testing.T) {
+	if _, err := Summarize(nil, Options{}); !errors.Is(err, ErrNoObservations) {
+		t.Fatalf("expected ErrNoObservations, got %v", err)
+	}
+	// Observations without weight are ignored
+	options := Options{PublisherWeights: map[string]float64{"Ignored": 0}}
+	if _, err := Summarize([]Observation{{Score: 1, Publisher: "Ignored"}}, options); !errors.Is(err, ErrNoObservations) {
+		t.Fatalf("expected ErrNoObservations, got %v", err)
+	}
+}
+
+func TestSummarize_Unweighted(t *testing.T) {
+	stats, err := Summarize(scores(1, 1, 0, -1), Options{})
+	if err != nil {
+		t.Fatalf("Summarize error: %v", err)
+	}
+	if stats.Count != 4 || stats.Positive != 2 || stats.Neutral != 1 || stats.Negative != 1 || stats.EffectiveCount != 4 {
+		t.Fatalf("unexpected counts %+v", stats)
+	}
+	// Sample standard deviation of 1, 1, 0 and -1 around 0.25
+	expectedStdDev := math.Sqrt((0.5625 + 0.5625 + 0.0625 + 1.5625) / 3)
+	if !approximately(stats.Mean, 0.25) || !approximately(stats.StdDev, expectedStdDev) {
+		t.Fatalf("expected a mean of 0.25 and a standard deviation of %v, got %+v", expectedStdDev, stats)
+	}
+	margin := 1.959963984540054 * expectedStdDev / 2
+	if !approximately(stats.Low, 0.25-margin) || !approximately(stats.High, 1) {
+		t.Fatalf("expected the interval [%v, 1], got [%v, %v]", 0.25-margin, stats.Low, stats.High)
+	}
+}
+
+func TestSummarize_SingleObservation(t *testing.T) {
+	stats, err := Summarize(scores(-1), Options{})
+	if err != nil {
+		t.Fatalf("Summarize error: %v", err)
+	}
+	if stats.Mean != -1 || stats.StdDev != 0 || stats.Low != -1 || stats.High != 1 {
+		t.Fatalf("expected a single observation to leave the mean unbounded, got %+v", stats)
+	}
+}
+
+func TestSummarize_RecencyDecay(t *testing.T) {
+	observations := []Observation{
+		{Score: 1, PublishedAt: testNow},
+		{Score: -1, PublishedAt: testNow.Add(-48 * time.Hour)},
+	}
+	stats, err := Summarize(observations, Options{Now: testNow, HalfLife: 48 * time.Hour})
+	if err != nil {
+		t.Fatalf("Summarize error: %v", err)
+	}
+	// Weights of 1 and 0.5
+	if !approximately(stats.Mean, 1.0/3) || !approximately(stats.EffectiveCount, 2.25/1.25) {
+		t.Fatalf("expected the older article to weigh half, got %+v", stats)
+	}
+	if stats.Count != 2 || stats.Positive != 1 || stats.Negative != 1 {
+		t.Fatalf("expected counts to be unweighted, got %+v", stats)
+	}
+}
+
+func TestSummarize_PublisherWeights(t *testing.T) {
+	observations := []Observation{
+		{Score: 1, Publisher: "Trusted"},
+		{Score: -1, Publisher: "Promotional"},
+		{Score: -1, Publisher: "Unlisted"},
+	}
+	stats, err := Summarize(observations, Options{PublisherWeights: map[string]float64{"Trusted": 2, "Promotional": 0.5}})
+	if err != nil {
+		t.Fatalf("Summarize error: %v", err)
+	}
+	if !approximately(stats.Mean, (2-0.5-1)/3.5) {
+		t.Fatalf("unexpected weighted mean %v", stats.Mean)
+	}
+	if stats.Low < -1 || stats.High > 1 || stats.Low > stats.Mean || stats.High < stats.Mean {
+		t.Fatalf("expected the interval to contain the mean within [-1, 1], got [%v, %v]", stats.Low, stats.High)
+	}
+}
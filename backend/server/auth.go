@@ -0,0 +1,270 @@
+package server
+
+import (
+	"errors"
+	"financial-helper/mongodb"
+	"financial-helper/token"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// Token lifetimes used if ACCESS_TOKEN_DURATION or REFRESH_TOKEN_DURATION is not set
+	defaultAccessTokenDuration  = 15 * time.Minute
+	defaultRefreshTokenDuration = 7 * 24 * time.Hour
+
+	// The key of the token payload of an authenticated request in the gin context
+	authPayloadKey = "auth_payload"
+
+	minPasswordLength = 8
+	// bcrypt ignores anything past 72 bytes, so longer passwords are rejected rather than silently truncated
+	maxPasswordLength = 72
+)
+
+// Usernames are 3 to 32 lowercase letters, digits, dots, dashes or underscores
+var usernamePattern = regexp.MustCompile(`^[a-z0-9._-]{3,32}$`)
+
+// Compared against when logging in as an unknown user, so it takes as long as a wrong password. It has the cost of the
+// hashes of Register.
+var dummyPasswordHash = func() []byte {
+	hash, err := bcrypt.GenerateFromPassword([]byte("not the password of any user"), bcrypt.DefaultCost)
+	if err != nil {
+		panic(err)
+	}
+	return hash
+}()
+
+// The body of the register and login requests
+type credentialsRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// The body of the refresh request
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Converts a stored user into the user returned by the users endpoints
+func toServerUser(user mongodb.User) ServerUser {
+	return ServerUser{
+		ID:        user.ID.Hex(),
+		Username:  user.Username,
+		CreatedAt: user.CreatedAt.Time().Unix(),
+	}
+}
+
+// Register creates a user and logs them in
+//
+// POST /api/v1/users/register
+//
+// Input:
+//   - body: a credentialsRequest. Usernames are case-insensitive and passwords are 8 to 72 bytes long.
+//
+// Output:
+//   - ServerUserTokens: the user with their access and refresh tokens
+func (server *Server) Register(c *gin.Context) {
+	var request credentialsRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "the body must have a username and a password"})
+		return
+	}
+	username := strings.ToLower(strings.TrimSpace(request.Username))
+	switch {
+	case !usernamePattern.MatchString(username):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "username must be 3 to 32 letters, digits, dots, dashes or underscores"})
+		return
+	case len(request.Password) < minPasswordLength || len(request.Password) > maxPasswordLength:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "password must be 8 to 72 characters long"})
+		return
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(request.Password), bcrypt.DefaultCost)
+	if err != nil {
+		log.Println("Error hashing password", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating user"})
+		return
+	}
+	user := mongodb.User{
+		Username:     username,
+		PasswordHash: string(hash),
+		CreatedAt:    primitive.NewDateTimeFromTime(time.Now()),
+	}
+	user.ID, err = mongodb.InsertUserWithContext(c.Request.Context(), server.mongoClient, server.tickerDBName, user)
+	if errors.Is(err, mongodb.ErrDuplicateUser) {
+		c.JSON(http.StatusConflict, gin.H{"error": "the username is taken"})
+		return
+	}
+	if err != nil {
+		log.Println("Error storing user", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating user"})
+		return
+	}
+
+	server.respondWithTokens(c, http.StatusCreated, user)
+}
+
+// Login returns new tokens for a user
+//
+// POST /api/v1/users/login
+//
+// Input:
+//   - body: a credentialsRequest
+//
+// Output:
+//   - ServerUserTokens: the user with their access and refresh tokens
+func (server *Server) Login(c *gin.Context) {
+	var request credentialsRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "the body must have a username and a password"})
+		return
+	}
+
+	user, err := mongodb.GetUserByUsernameWithContext(c.Request.Context(), server.mongoClient, server.tickerDBName, strings.ToLower(strings.TrimSpace(request.Username)))
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		log.Println("Error getting user", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error logging in"})
+		return
+	}
+	// An unknown username and a wrong password get the same response after the same work, so usernames can't be
+	// probed by their response or its timing
+	passwordHash := dummyPasswordHash
+	if user != nil {
+		passwordHash = []byte(user.PasswordHash)
+	}
+	if err := bcrypt.CompareHashAndPassword(passwordHash, []byte(request.Password)); err != nil || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid username or password"})
+		return
+	}
+
+	server.respondWithTokens(c, http.StatusOK, *user)
+}
+
+// RefreshTokens exchanges a refresh token for new tokens
+//
+// POST /api/v1/users/refresh
+//
+// Input:
+//   - body: a refreshRequest
+//
+// Output:
+//   - ServerUserTokens: the user with their new access and refresh tokens
+func (server *Server) RefreshTokens(c *gin.Context) {
+	var request refreshRequest
+	if err := c.ShouldBindJSON(&request); err != nil || request.RefreshToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "the body must have a refresh_token"})
+		return
+	}
+	payload, err := server.tokenMaker.VerifyToken(request.RefreshToken, token.KindRefresh)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The user may have been deleted since the token was issued
+	user, ok := server.findUser(c, payload.UserID)
+	if !ok {
+		return
+	}
+	server.respondWithTokens(c, http.StatusOK, *user)
+}
+
+// GetCurrentUser returns the authenticated user
+//
+// GET /api/v1/users/me
+//
+// Output:
+//   - ServerUser: the user
+func (server *Server) GetCurrentUser(c *gin.Context) {
+	user, ok := server.findUser(c, authPayload(c).UserID)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, toServerUser(*user))
+}
+
+// Returns the user with the given hex id, or responds with an error and returns false. A token naming a user who
+// does not exist is unauthorized.
+func (server *Server) findUser(c *gin.Context, userID string) (*mongodb.User, bool) {
+	id, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": token.ErrInvalidToken.Error()})
+		return nil, false
+	}
+	user, err := mongodb.GetUserByIDWithContext(c.Request.Context(), server.mongoClient, server.tickerDBName, id)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "the user does not exist"})
+		return nil, false
+	}
+	if err != nil {
+		log.Println("Error getting user", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting user"})
+		return nil, false
+	}
+	return user, true
+}
+
+// Creates an access and a refresh token for user and responds with them
+func (server *Server) respondWithTokens(c *gin.Context, status int, user mongodb.User) {
+	accessToken, accessPayload, err := server.tokenMaker.CreateToken(user.ID.Hex(), user.Username, token.KindAccess, server.accessTokenDuration)
+	if err != nil {
+		log.Println("Error creating access token", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating tokens"})
+		return
+	}
+	refreshToken, refreshPayload, err := server.tokenMaker.CreateToken(user.ID.Hex(), user.Username, token.KindRefresh, server.refreshTokenDuration)
+	if err != nil {
+		log.Println("Error creating refresh token", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating tokens"})
+		return
+	}
+
+	c.JSON(status, ServerUserTokens{
+		User:                  toServerUser(user),
+		AccessToken:           accessToken,
+		AccessTokenExpiresAt:  accessPayload.ExpiresAt.Unix(),
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: refreshPayload.ExpiresAt.Unix(),
+	})
+}
+
+// authMiddleware only lets through requests with a valid access token in an "Authorization: Bearer <token>" header,
+// and stores the payload of the token in the context for authPayload
+//
+// Input:
+//   - maker: verifies the access tokens
+//
+// Output:
+//   - gin.HandlerFunc: the middleware, which aborts other requests with 401 Unauthorized
+func authMiddleware(maker token.Maker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scheme, accessToken, found := strings.Cut(c.GetHeader("Authorization"), " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") || accessToken == "" {
+			c.Header("WWW-Authenticate", "Bearer")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "an Authorization: Bearer header is required"})
+			return
+		}
+		payload, err := maker.VerifyToken(strings.TrimSpace(accessToken), token.KindAccess)
+		if err != nil {
+			c.Header("WWW-Authenticate", "Bearer")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Set(authPayloadKey, payload)
+		c.Next()
+	}
+}
+
+// Returns the token payload of a request let through by authMiddleware
+func authPayload(c *gin.Context) *token.Payload {
+	return c.MustGet(authPayloadKey).(*token.Payload)
+}
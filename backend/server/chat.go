@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"financial-helper/polygon"
 	"fmt"
 	"io"
 	"log"
@@ -20,6 +21,12 @@ import (
 func (server *Server) InitializeModel() {
 	ctx := context.Background()
 
+	// Chat is unavailable in demo mode without a Gemini key
+	if server.geminiKey == "" {
+		log.Println("No Gemini API key, the chat is disabled")
+		return
+	}
+
 	// Initialize Gemini client
 	client, err := genai.NewClient(ctx, option.WithAPIKey(server.geminiKey))
 	if err != nil {
@@ -35,6 +42,10 @@ func (server *Server) InitializeModel() {
 
 func (server *Server) GenerateContent(c *gin.Context) {
 	defaultErrMsg := "Error occurred when processing prompt"
+	if server.GeminiModel == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "chat is not configured"})
+		return
+	}
 
 	// Get prompt from request
 	//prompt := c.PostForm("prompt")
@@ -88,15 +99,14 @@ func (server *Server) GenerateContent(c *gin.Context) {
 	//print(unmarshalledHistory)
 
 	// Compile prompt
-	compiledPrompt, err := server.compilePrompt(prompt, unmarshalledHistory)
+	compiledPrompt, err := server.compilePrompt(c.Request.Context(), prompt, unmarshalledHistory)
 	if err != nil {
 		fmt.Println("Error compiling prompt", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error compiling prompt"})
 		return
 	}
 
-	ctx := context.Background()
-	resp, err := server.GeminiModel.GenerateContent(ctx, genai.Text(compiledPrompt))
+	resp, err := server.GeminiModel.GenerateContent(c.Request.Context(), genai.Text(compiledPrompt))
 	if err != nil {
 		fmt.Println("Error generating response", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error generating response"})
@@ -113,7 +123,7 @@ func (server *Server) GenerateContent(c *gin.Context) {
 
 // compilePrompt takes a prompt and a message history and compiles them into a single string
 // that can be used as a prompt for the AI model.
-func (server *Server) compilePrompt(prompt string, history []map[string]interface{}) (string, error) {
+func (server *Server) compilePrompt(ctx context.Context, prompt string, history []map[string]interface{}) (string, error) {
 	compiledPrompt := "Here is your message history with the most recent user:\n\n"
 
 	// Get the message history
@@ -148,13 +158,14 @@ func (server *Server) compilePrompt(prompt string, history []map[string]interfac
 		}
 	}
 
-	tickerInfo, err := server.getTickerNews(mentionedTickers)
+	tickerInfo, err := server.getTickerNews(ctx, mentionedTickers)
 	if err != nil {
 		return "", errors.New("could not get ticker info")
 	}
 	if tickerInfo != "" {
 		compiledPrompt += tickerInfo
 	}
+	compiledPrompt += server.getTickerFinancialsSummary(ctx, mentionedTickers)
 
 	// Add the prompt to the compiled prompt
 	compiledPrompt += "\nHere is the current prompt:\n"
@@ -165,278 +176,98 @@ func (server *Server) compilePrompt(prompt string, history []map[string]interfac
 	return compiledPrompt, nil
 }
 
-func (server *Server) getTickerNews(mentionedTickers []string) (string, error) {
+func (server *Server) getTickerNews(ctx context.Context, mentionedTickers []string) (string, error) {
 	if len(mentionedTickers) == 0 {
 		return "", nil
 	}
 
 	tickerInfo := "\nHere are some relevant, recent news stories about the mentioned stock tickers:\n" +
-		"Every article's sentiment towards the given company is rated on a scale of -1 to 1, with -1 being very negative, 0 being neutral, and 1 being very positive. The average sentiment and standard deviation between sentiments for many articles are provided, as well as the number of articles.\n" +
+		"Every article's sentiment towards the given company is rated on a scale of -1 to 1, with -1 being very negative, 0 being neutral, and 1 being very positive. The average sentiment, in which recent articles weigh more, and standard deviation between sentiments for many articles are provided, as well as the number of articles.\n" +
 		"In addition, some recent article headlines and descriptions relating to the companies are included.\n\n"
 
 	for _, ticker := range mentionedTickers {
-		url := fmt.Sprintf("https://api.polygon.io/v2/reference/news?ticker=%s&order=desc&limit=350&sort=published_utc&apiKey=%s&published_utc.gte=2024-10-11T19:01:33Z", ticker, server.polygonConnection.GetPolygonKey())
-		method := "GET"
-
-		client := &http.Client{}
-		req, err := http.NewRequest(method, url, nil)
-
-		if err != nil {
-			return "", errors.New("error generating request")
-		}
-		res, err := client.Do(req)
+		articles, err := server.getRecentTickerNews(ctx, ticker)
 		if err != nil {
-			return "", errors.New("error sending request")
-		}
-		defer res.Body.Close()
-
-		body, err := io.ReadAll(res.Body)
-		if err != nil {
-			return "", errors.New("error reading response body")
-		}
-		//fmt.Println(string(body))
-
-		// Unmarshall the unmarshalledBody
-		var unmarshalledBody map[string]interface{}
-		if err = json.Unmarshal(body, &unmarshalledBody); err != nil {
-			return "", errors.New("error unmarshalling response")
-		}
-
-		// Convert to correct data types
-		results, ok := unmarshalledBody["results"].([]interface{})
-		if !ok {
-			return "", errors.New("error converting results")
+			return "", errors.Join(errors.New("error getting ticker news"), err)
 		}
 
-		// Get number of articles
-		numArticles, ok := unmarshalledBody["count"].(float64)
-		if !ok {
-			return "", errors.New("error converting count")
-		}
-		numArticlesInt := int(numArticles)
-
-		// Convert each element to map[string]interface{}
-		var articles []map[string]interface{}
-		for _, result := range results {
-			resultMap, ok := result.(map[string]interface{})
-			if !ok {
-				return "", errors.New("error converting result element")
-			}
-			articles = append(articles, resultMap)
-		}
-
-		// Calculate average sentiment
-
 		tickerInfo += fmt.Sprintf("Ticker: %s\n", ticker)
-		var sentiments []float64
-		for index, article := range articles {
-			if index < 10 {
-				tickerInfo += fmt.Sprintf("Sample Article %d:\n", index+1)
-				if title, exists := article["title"]; exists {
-					titleConverted, ok := title.(string)
-					if !ok {
-						return "", errors.New("error converting title")
-					}
-					tickerInfo += fmt.Sprintf("Title: %s\n", titleConverted)
-				}
-				if description, exists := article["description"]; exists {
-					descriptionConverted, ok := description.(string)
-					if !ok {
-						return "", errors.New("error converting description")
-					}
-					tickerInfo += fmt.Sprintf("Description: %s\n", descriptionConverted)
-				}
-				if publisher, exists := article["publisher"]; exists {
-					publisherConverted, ok := publisher.(map[string]interface{})
-					if !ok {
-						return "", errors.New("error converting publisher")
-					}
-					if name, exists := publisherConverted["name"]; exists {
-						nameConverted, ok := name.(string)
-						if !ok {
-							return "", errors.New("error converting name")
-						}
-						tickerInfo += fmt.Sprintf("Publisher: %s\n", nameConverted)
-					}
-				}
-				if url, exists := article["article_url"]; exists {
-					urlConverted, ok := url.(string)
-					if !ok {
-						return "", errors.New("error converting url")
-					}
-					tickerInfo += fmt.Sprintf("URL: %s\n", urlConverted)
-				}
+		for index, article := range articles[:min(10, len(articles))] {
+			tickerInfo += fmt.Sprintf("Sample Article %d:\n", index+1)
+			tickerInfo += fmt.Sprintf("Title: %s\n", article.Title)
+			if article.Description != "" {
+				tickerInfo += fmt.Sprintf("Description: %s\n", article.Description)
 			}
-
-			if insights, exists := article["insights"]; exists {
-				insightsList, ok := insights.([]interface{})
-				if !ok {
-					return "", errors.New("error converting insights")
-				}
-
-				for _, singleTickerInsight := range insightsList {
-					convertedSingleTickerInsight, ok := singleTickerInsight.(map[string]interface{})
-					if !ok {
-						return "", errors.New("error converting singleTickerInsight")
-					}
-					if searchedTicker, exists := convertedSingleTickerInsight["ticker"]; exists {
-						if searchedTicker == ticker {
-							if sentiment, exists := convertedSingleTickerInsight["sentiment"]; exists {
-								sentimentString, ok := sentiment.(string)
-								if !ok {
-									return "", errors.New("error converting sentiment")
-								}
-
-								if sentimentString == "positive" {
-									sentiments = append(sentiments, 1)
-								} else if sentimentString == "negative" {
-									sentiments = append(sentiments, -1)
-								} else {
-									sentiments = append(sentiments, 0)
-								}
-							}
-						}
-					}
-				}
+			if article.Publisher.Name != "" {
+				tickerInfo += fmt.Sprintf("Publisher: %s\n", article.Publisher.Name)
+			}
+			if article.ArticleURL != "" {
+				tickerInfo += fmt.Sprintf("URL: %s\n", article.ArticleURL)
 			}
 		}
-
-		// Calculate average sentiment
-		var sumSentiment float64
-		for _, sentiment := range sentiments {
-			sumSentiment += sentiment
-		}
-		avgSentiment := sumSentiment / float64(len(sentiments))
-
-		// Calculate standard deviation
-		var sumSquaredDifferences float64
-		for _, sentiment := range sentiments {
-			sumSquaredDifferences += (sentiment - avgSentiment) * (sentiment - avgSentiment)
-		}
-		stdDevSentiment := sumSquaredDifferences / float64(len(sentiments))
-
-		news := TickerNews{
-			AverageSentiment: float32(avgSentiment),
-			StdDevSentiment:  float32(stdDevSentiment),
-			NumArticles:      numArticlesInt,
-		}
+		news := newsSentiment(articles, ticker, time.Now())
 
 		// Add sentiment data to tickerInfo
-		tickerInfo += fmt.Sprintf("Average sentiment: %.2f\n", news.AverageSentiment)
-		tickerInfo += fmt.Sprintf("Standard deviation of sentiment: %.2f\n", news.StdDevSentiment)
+		if news.NumRated == 0 {
+			tickerInfo += "No article rates the sentiment towards this company.\n"
+		} else {
+			tickerInfo += fmt.Sprintf("Average sentiment: %.2f (95%% confidence interval: %.2f to %.2f)\n", news.AverageSentiment, news.ConfidenceLow, news.ConfidenceHigh)
+			tickerInfo += fmt.Sprintf("Standard deviation of sentiment: %.2f\n", news.StdDevSentiment)
+			tickerInfo += fmt.Sprintf("Positive, neutral and negative articles: %d, %d and %d\n", news.NumPositive, news.NumNeutral, news.NumNegative)
+		}
 		tickerInfo += fmt.Sprintf("Number of articles: %d\n\n", news.NumArticles)
 
-		tickerAggregateInfo, err := server.getTickerAggregate(ticker)
-		if err != nil {
+		// Market data may not cover the month, e.g. in demo mode, which only leaves the aggregate out
+		tickerAggregateInfo, err := server.getTickerAggregate(ctx, ticker, time.Now())
+		if err != nil && !errors.Is(err, polygon.ErrNoResults) {
 			return "", errors.New("error getting ticker aggregate")
 		}
 		tickerInfo += tickerAggregateInfo
 
-		time.Sleep(server.polygonConnection.ThrottleTime * time.Second)
 	}
 	//fmt.Println(tickerInfo)
 
 	return tickerInfo, nil
 }
 
-func (server *Server) getTickerAggregate(ticker string) (string, error) {
-	time.Sleep(server.polygonConnection.ThrottleTime * time.Second)
-	url := fmt.Sprintf("https://api.polygon.io/v2/aggs/ticker/%s/range/1/month/2025-01-01/2025-02-01?adjusted=true&sort=asc&apiKey=%s", ticker, server.polygonConnection.GetPolygonKey())
-	method := "GET"
-
-	client := &http.Client{}
-	req, err := http.NewRequest(method, url, nil)
-
-	if err != nil {
-		fmt.Println("Error generating request", err)
+// getTickerAggregate describes the bar of a ticker over the last complete month at now, for the chat prompt
+func (server *Server) getTickerAggregate(ctx context.Context, ticker string, now time.Time) (string, error) {
+	monthStart, monthEnd := server.lastCompleteMonth(now)
+	aggregates, err := server.marketData.GetTickerAggregates(ctx, polygon.PolygonAggregatesRequest{
+		Symbol:     ticker,
+		Multiplier: 1,
+		Timespan:   polygon.TimespanMonth,
+		From:       monthStart,
+		To:         monthEnd,
+	})
+	if err != nil && !polygon.IsPartialPolygonData(err) {
+		log.Println("Error requesting data", err)
 		return "", err
 	}
-	res, err := client.Do(req)
-	if err != nil {
-		fmt.Println("Error requesting data", err)
-		return "", err
+	// Validation may have dropped every bar of a partial response
+	if aggregates == nil || aggregates.Results == nil || len(*aggregates.Results) == 0 {
+		return "", polygon.ErrNoResults
 	}
-	defer res.Body.Close()
 
-	body, err := io.ReadAll(res.Body)
-	if err != nil {
-		fmt.Println("Error reading request body", err)
-		return "", err
+	monthlyAggregate := (*aggregates.Results)[0]
+	tickerAggregate := fmt.Sprintf("Monthly aggregate data for %s in %s:\n", ticker, monthStart.Format("January 2006"))
+	if monthlyAggregate.Open != nil {
+		tickerAggregate += fmt.Sprintf("Open: %.2f\n", *monthlyAggregate.Open)
 	}
-
-	// Unmarshall the unmarshalledBody
-	var unmarshalledBody map[string]interface{}
-	if err = json.Unmarshal(body, &unmarshalledBody); err != nil {
-		fmt.Println("Error unmarshalling response", err)
-		return "", err
+	if monthlyAggregate.High != nil {
+		tickerAggregate += fmt.Sprintf("High: %.2f\n", *monthlyAggregate.High)
 	}
-
-	// Convert to correct data types
-	results, ok := unmarshalledBody["results"].([]interface{})
-	if !ok {
-		fmt.Println("Error converting results", err)
-		return "", err
+	if monthlyAggregate.Low != nil {
+		tickerAggregate += fmt.Sprintf("Low: %.2f\n", *monthlyAggregate.Low)
 	}
-
-	// Convert each element to map[string]interface{}
-	var monthlyAggregate []map[string]interface{}
-	for _, result := range results {
-		resultMap, ok := result.(map[string]interface{})
-		if !ok {
-			fmt.Println("Error converting result element", err)
-			return "", err
-		}
-		monthlyAggregate = append(monthlyAggregate, resultMap)
+	if monthlyAggregate.Close != nil {
+		tickerAggregate += fmt.Sprintf("Close: %.2f\n", *monthlyAggregate.Close)
 	}
-
-	tickerAggregate := fmt.Sprintf("Monthly aggregate data for %s:\n", ticker)
-	if monthlyAggregate[0]["o"] != nil {
-		convertedOpen, ok := monthlyAggregate[0]["o"].(float64)
-		if !ok {
-			fmt.Println("Error converting open", err)
-			return "", err
-		}
-		tickerAggregate += fmt.Sprintf("Open: %.2f\n", convertedOpen)
-	}
-	if monthlyAggregate[0]["h"] != nil {
-		convertedHigh, ok := monthlyAggregate[0]["h"].(float64)
-		if !ok {
-			fmt.Println("Error converting high", err)
-			return "", err
-		}
-		tickerAggregate += fmt.Sprintf("High: %.2f\n", convertedHigh)
-	}
-	if monthlyAggregate[0]["l"] != nil {
-		convertedLow, ok := monthlyAggregate[0]["l"].(float64)
-		if !ok {
-			fmt.Println("Error converting low", err)
-			return "", err
-		}
-		tickerAggregate += fmt.Sprintf("Low: %.2f\n", convertedLow)
+	if monthlyAggregate.Volume != nil {
+		tickerAggregate += fmt.Sprintf("Volume: %.2f\n", *monthlyAggregate.Volume)
 	}
-	if monthlyAggregate[0]["c"] != nil {
-		convertedClose, ok := monthlyAggregate[0]["c"].(float64)
-		if !ok {
-			fmt.Println("Error converting close", err)
-			return "", err
-		}
-		tickerAggregate += fmt.Sprintf("Close: %.2f\n", convertedClose)
-	}
-	if monthlyAggregate[0]["v"] != nil {
-		convertedVolume, ok := monthlyAggregate[0]["v"].(float64)
-		if !ok {
-			fmt.Println("Error converting volume", err)
-			return "", err
-		}
-		tickerAggregate += fmt.Sprintf("Volume: %.2f\n", convertedVolume)
-	}
-	if monthlyAggregate[0]["vw"] != nil {
-		convertedVWAP, ok := monthlyAggregate[0]["vw"].(float64)
-		if !ok {
-			fmt.Println("Error converting VWAP", err)
-			return "", err
-		}
-		tickerAggregate += fmt.Sprintf("Volume Weighted Average Price: %.2f\n", convertedVWAP)
+	if monthlyAggregate.VWAP != nil {
+		tickerAggregate += fmt.Sprintf("Volume Weighted Average Price: %.2f\n", *monthlyAggregate.VWAP)
 	}
 	tickerAggregate += "\n\n"
 	//fmt.Println(tickerAggregate)
@@ -0,0 +1,244 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"financial-helper/mongodb"
+	"financial-helper/polygon"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultFinancialsLimit = 8
+	maxFinancialsLimit     = 40
+	// How far back filings are fetched from Polygon when none are stored
+	financialsLookback = 3 * 365 * 24 * time.Hour
+)
+
+// GetTickerFinancials returns the latest financial statements of a company, with ratios derived from them
+//
+// GET /api/v1/stocks/tickers/:symbol/financials?timeframe=&limit=
+//
+// Input:
+//   - symbol: the ticker's symbol
+//   - timeframe: quarterly (default), annual or ttm
+//   - limit: the number of filings, 8 by default and at most 40
+//
+// Output:
+//   - ServerTickerFinancialsResponse: the filings, most recent first, and the P/E ratio over the last twelve months
+func (server *Server) GetTickerFinancials(c *gin.Context) {
+	symbol := strings.ToUpper(c.Param("symbol"))
+	if symbol == "" {
+		log.Println("Error: symbol is required")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "symbol is required"})
+		return
+	}
+	timeframe := polygon.FinancialsQuarterly
+	if value := c.Query("timeframe"); value != "" {
+		timeframe = polygon.PolygonFinancialsTimeframe(value)
+		if !timeframe.Valid() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "timeframe must be quarterly, annual or ttm"})
+			return
+		}
+	}
+	limit := defaultFinancialsLimit
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxFinancialsLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 40"})
+			return
+		}
+		limit = parsed
+	}
+
+	response, err := server.getTickerFinancials(c.Request.Context(), symbol, timeframe, limit)
+	if err != nil {
+		log.Println("Error getting ticker financials", err)
+		c.JSON(polygonErrorStatus(err), gin.H{"error": "Error getting ticker financials"})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// getTickerFinancials builds the financials response of a ticker
+//
+// Input:
+//   - ctx: bounds the requests to MongoDB and Polygon
+//   - symbol: the ticker's symbol
+//   - timeframe: the timeframe of the filings
+//   - limit: the number of filings returned
+//
+// Output:
+//   - *ServerTickerFinancialsResponse: the filings and ratios. P/E is left out if the last close or the trailing
+//     earnings are unknown, or if earnings are negative.
+//   - error: any error that occurred, polygon.ErrNoResults if the ticker has no filings
+func (server *Server) getTickerFinancials(ctx context.Context, symbol string, timeframe polygon.PolygonFinancialsTimeframe, limit int) (*ServerTickerFinancialsResponse, error) {
+	// Trailing earnings need four quarters, even when fewer filings are returned
+	filings, source, err := server.getStoredOrPolygonFinancials(ctx, symbol, timeframe, max(limit, 4))
+	if err != nil {
+		return nil, err
+	}
+	if len(filings) == 0 {
+		return nil, polygon.ErrNoResults
+	}
+
+	response := ServerTickerFinancialsResponse{Symbol: symbol, Timeframe: string(timeframe), Source: source, Filings: []ServerFinancialFiling{}}
+	if eps, ok := mongodb.TrailingEPS(filings); ok {
+		response.TrailingEPS = &eps
+		lastClose, err := server.marketData.GetPreviousClose(ctx, symbol)
+		if err != nil && !polygon.IsPartialPolygonData(err) {
+			log.Println("Error getting last close for P/E", err)
+		} else if results := *lastClose.Results; len(results) > 0 && results[0].Close != nil {
+			response.LastClose = results[0].Close
+			if eps > 0 {
+				pe := *results[0].Close / eps
+				response.PERatio = &pe
+			}
+		}
+	}
+
+	for _, filing := range filings[:min(limit, len(filings))] {
+		ratios := filing.Ratios()
+		response.Filings = append(response.Filings, ServerFinancialFiling{
+			FiscalPeriod:    filing.FiscalPeriod,
+			FiscalYear:      filing.FiscalYear,
+			StartDate:       formatFilingDate(filing.StartDate.Time()),
+			EndDate:         formatFilingDate(filing.EndDate.Time()),
+			FilingDate:      formatFilingDate(filing.FilingDate.Time()),
+			SourceFilingURL: filing.SourceFilingURL,
+			LineItems:       normalizeLineItems(filing),
+			Ratios: ServerFinancialRatios{
+				GrossMargin:     ratios.GrossMargin,
+				OperatingMargin: ratios.OperatingMargin,
+				NetMargin:       ratios.NetMargin,
+				DebtToEquity:    ratios.DebtToEquity,
+				CurrentRatio:    ratios.CurrentRatio,
+				ReturnOnEquity:  ratios.ReturnOnEquity,
+			},
+		})
+	}
+	return &response, nil
+}
+
+// getStoredOrPolygonFinancials returns the latest `limit` filings of a ticker, most recent first, from MongoDB if the
+// financials scraper stored any, or from Polygon otherwise. Filings fetched from Polygon are stored, and demo mode
+// only has the stored filings.
+// The second value is where the filings came from, "local" or "polygon".
+func (server *Server) getStoredOrPolygonFinancials(ctx context.Context, symbol string, timeframe polygon.PolygonFinancialsTimeframe, limit int) ([]mongodb.FinancialFiling, string, error) {
+	stored, err := mongodb.GetFinancialsByTickerWithContext(ctx, server.mongoClient, server.tickerDBName, symbol, string(timeframe), limit)
+	if err != nil {
+		log.Println("Error reading stored financials", err)
+	}
+	if len(stored) > 0 {
+		return stored, "local", nil
+	}
+	if server.polygonConnection == nil {
+		return nil, "", polygon.ErrNoResults
+	}
+
+	polygonFilings, err := server.polygonConnection.PolygonGetTickerFinancialsWithContext(ctx, symbol, timeframe, time.Now().Add(-financialsLookback), time.Time{})
+	if err != nil && !polygon.IsPartialPolygonData(err) {
+		return nil, "", err
+	}
+	filings, err := mongodb.PolygonFinancialsToFilings(symbol, polygonFilings)
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err := mongodb.InsertFinancialsWithContext(context.WithoutCancel(ctx), server.mongoClient, server.tickerDBName, filings); err != nil {
+		log.Println("Error storing financials", err)
+	}
+
+	sort.SliceStable(filings, func(i, j int) bool { return filings[i].EndDate > filings[j].EndDate })
+	return filings[:min(limit, len(filings))], "polygon", nil
+}
+
+// Picks the line items the API exposes out of Polygon's statements, under stable names
+func normalizeLineItems(filing mongodb.FinancialFiling) ServerFinancialLineItems {
+	value := func(statement map[string]float64, item string) *float64 {
+		if v, ok := statement[item]; ok {
+			return &v
+		}
+		return nil
+	}
+
+	return ServerFinancialLineItems{
+		Revenue:             value(filing.IncomeStatement, "revenues"),
+		CostOfRevenue:       value(filing.IncomeStatement, "cost_of_revenue"),
+		GrossProfit:         value(filing.IncomeStatement, "gross_profit"),
+		OperatingExpenses:   value(filing.IncomeStatement, "operating_expenses"),
+		OperatingIncome:     value(filing.IncomeStatement, "operating_income_loss"),
+		NetIncome:           value(filing.IncomeStatement, "net_income_loss"),
+		EPSBasic:            value(filing.IncomeStatement, "basic_earnings_per_share"),
+		EPSDiluted:          value(filing.IncomeStatement, "diluted_earnings_per_share"),
+		TotalAssets:         value(filing.BalanceSheet, "assets"),
+		CurrentAssets:       value(filing.BalanceSheet, "current_assets"),
+		TotalLiabilities:    value(filing.BalanceSheet, "liabilities"),
+		CurrentLiabilities:  value(filing.BalanceSheet, "current_liabilities"),
+		LongTermDebt:        value(filing.BalanceSheet, "long_term_debt"),
+		Equity:              value(filing.BalanceSheet, "equity"),
+		OperatingCashFlow:   value(filing.CashFlowStatement, "net_cash_flow_from_operating_activities"),
+		InvestingCashFlow:   value(filing.CashFlowStatement, "net_cash_flow_from_investing_activities"),
+		FinancingCashFlow:   value(filing.CashFlowStatement, "net_cash_flow_from_financing_activities"),
+		NetCashFlow:         value(filing.CashFlowStatement, "net_cash_flow"),
+		ComprehensiveIncome: value(filing.ComprehensiveIncome, "comprehensive_income_loss"),
+	}
+}
+
+// Formats the date of a filing as YYYY-MM-DD, and unset dates as an empty string
+func formatFilingDate(t time.Time) string {
+	if t.Unix() == 0 {
+		return ""
+	}
+	return t.UTC().Format("2006-01-02")
+}
+
+// getTickerFinancialsSummary describes the health of the mentioned companies from their latest quarterly filing,
+// for the chat bot. Tickers whose financials cannot be loaded are left out.
+func (server *Server) getTickerFinancialsSummary(ctx context.Context, mentionedTickers []string) string {
+	summary := ""
+	for _, ticker := range mentionedTickers {
+		financials, err := server.getTickerFinancials(ctx, ticker, polygon.FinancialsQuarterly, 1)
+		if err != nil {
+			if !errors.Is(err, polygon.ErrNoResults) {
+				log.Println("Error getting financials for chat", err)
+			}
+			continue
+		}
+
+		latest := financials.Filings[0]
+		facts := []string{}
+		if latest.LineItems.Revenue != nil {
+			facts = append(facts, fmt.Sprintf("revenue $%.2fB", *latest.LineItems.Revenue/1e9))
+		}
+		if latest.LineItems.NetIncome != nil {
+			facts = append(facts, fmt.Sprintf("net income $%.2fB", *latest.LineItems.NetIncome/1e9))
+		}
+		if latest.Ratios.GrossMargin != nil {
+			facts = append(facts, fmt.Sprintf("gross margin %.1f%%", *latest.Ratios.GrossMargin*100))
+		}
+		if latest.Ratios.NetMargin != nil {
+			facts = append(facts, fmt.Sprintf("net margin %.1f%%", *latest.Ratios.NetMargin*100))
+		}
+		if latest.Ratios.DebtToEquity != nil {
+			facts = append(facts, fmt.Sprintf("debt/equity %.2f", *latest.Ratios.DebtToEquity))
+		}
+		if financials.PERatio != nil {
+			facts = append(facts, fmt.Sprintf("P/E %.1f", *financials.PERatio))
+		}
+		if len(facts) > 0 {
+			summary += fmt.Sprintf("%s (%s %s, ended %s): %s\n", ticker, latest.FiscalPeriod, latest.FiscalYear, latest.EndDate, strings.Join(facts, ", "))
+		}
+	}
+	if summary == "" {
+		return ""
+	}
+	return "\nHere are the latest quarterly financials of the mentioned companies. Debt/equity is total liabilities over shareholders' equity, and P/E uses the last close and the diluted earnings of the last twelve months:\n" + summary
+}
@@ -0,0 +1,381 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"financial-helper/calendar"
+	"financial-helper/mongodb"
+	"financial-helper/polygon"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Candle intervals of the history endpoint, and the bars they are made of
+var historyIntervals = map[string]polygon.PolygonTimespan{
+	"1d":  polygon.TimespanDay,
+	"1w":  polygon.TimespanWeek,
+	"1mo": polygon.TimespanMonth,
+}
+
+const (
+	// Value of the X-Cache-Coverage header when the candles can't be served from MongoDB
+	historyCacheBypass = "bypass"
+	// Most requests sent to fill the gaps of a stored history, more gaps are filled with a single request
+	maxHistoryGapRequests = 4
+)
+
+// The candles selected by the query of the history endpoint
+type historyQuery struct {
+	From     time.Time
+	To       time.Time
+	Interval string
+	Adjusted bool
+}
+
+// GetTickerHistory returns the price candles of a stock
+//
+// GET /api/v1/stocks/tickers/:symbol/history?from=&to=&interval=&adjusted=
+//
+// Input:
+//   - symbol: the ticker's symbol
+//   - from, to: the first and last day of the range, as YYYY-MM-DD. The range ends on the last closed session and
+//     covers about 100 days by default.
+//   - interval: the length of a candle, 1d (default), 1w or 1mo
+//   - adjusted: whether prices are adjusted for splits, true by default
+//
+// Output:
+//   - ServerTickerHistoryResponse: the candles of the range, oldest first
+//   - X-Cache-Coverage header: the sessions of the range that were stored in MongoDB out of all of them, e.g. 45/69,
+//     or "bypass" for candles that are not stored
+func (server *Server) GetTickerHistory(c *gin.Context) {
+	symbol := strings.ToUpper(c.Param("symbol"))
+	if symbol == "" {
+		log.Println("Error: symbol is required")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "symbol is required"})
+		return
+	}
+	query, err := server.parseHistoryQuery(c, time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, coverage, err := server.getTickerHistory(c.Request.Context(), symbol, query, time.Now())
+	if err != nil {
+		log.Println("Error getting ticker history", err)
+		c.JSON(polygonErrorStatus(err), gin.H{"error": "Error getting ticker history"})
+		return
+	}
+
+	c.Header("X-Cache-Coverage", coverage)
+	c.JSON(http.StatusOK, response)
+}
+
+// Reads the query parameters of the history endpoint, filling in the defaults at now
+func (server *Server) parseHistoryQuery(c *gin.Context, now time.Time) (historyQuery, error) {
+	defaultFrom, defaultTo := server.historyRange(now)
+	query := historyQuery{From: defaultFrom, To: defaultTo, Interval: "1d", Adjusted: true}
+
+	if value := c.Query("interval"); value != "" {
+		if _, ok := historyIntervals[value]; !ok {
+			return query, errors.New("interval must be 1d, 1w or 1mo")
+		}
+		query.Interval = value
+	}
+	if value := c.Query("adjusted"); value != "" {
+		adjusted, err := strconv.ParseBool(value)
+		if err != nil {
+			return query, errors.New("adjusted must be true or false")
+		}
+		query.Adjusted = adjusted
+	}
+
+	if value := c.Query("to"); value != "" {
+		to, err := time.ParseInLocation("2006-01-02", value, calendar.Location)
+		if err != nil {
+			return query, errors.New("to must be a date formatted as YYYY-MM-DD")
+		}
+		query.To = to
+		// Without a start, the range keeps its default length
+		query.From = server.marketCalendar.AddSessions(to, -(historySessions - 1)).Date
+	}
+	if value := c.Query("from"); value != "" {
+		from, err := time.ParseInLocation("2006-01-02", value, calendar.Location)
+		if err != nil {
+			return query, errors.New("from must be a date formatted as YYYY-MM-DD")
+		}
+		query.From = from
+	}
+	if query.From.After(query.To) {
+		return query, errors.New("from cannot be after to")
+	}
+	return query, nil
+}
+
+// getTickerHistory returns the candles of a stock selected by query. Adjusted daily candles are read through the bars
+// of the "ticker_aggregates" collection (see getStoredDailyCandles), other candles come from the market data provider.
+//
+// Input:
+//   - ctx: bounds the requests to MongoDB and the market data provider
+//   - symbol: the ticker's symbol
+//   - query: the range, interval and adjustment of the candles
+//   - now: the time of the request
+//
+// Output:
+//   - *ServerTickerHistoryResponse: the candles, oldest first
+//   - string: the cache coverage, the number of sessions of the range served from MongoDB out of all of them
+//     (e.g. 45/69), or "bypass" if MongoDB was not read
+//   - error: any error that occurred, polygon.ErrNoResults if the ticker has no bars in the range
+func (server *Server) getTickerHistory(ctx context.Context, symbol string, query historyQuery, now time.Time) (*ServerTickerHistoryResponse, string, error) {
+	var candles []ServerCandle
+	coverage := historyCacheBypass
+	var err error
+	// Stored bars are daily bars from Polygon, which demo mode must not mix with its files
+	if query.Interval == "1d" && query.Adjusted && server.polygonConnection != nil {
+		candles, coverage, err = server.getStoredDailyCandles(ctx, symbol, query.From, query.To, now)
+	} else {
+		candles, err = server.getProviderCandles(ctx, symbol, historyIntervals[query.Interval], query.From, query.To, query.Adjusted)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if len(candles) == 0 {
+		return nil, "", polygon.ErrNoResults
+	}
+
+	return &ServerTickerHistoryResponse{
+		Symbol:   symbol,
+		Interval: query.Interval,
+		From:     query.From.Format("2006-01-02"),
+		To:       query.To.Format("2006-01-02"),
+		Adjusted: query.Adjusted,
+		Candles:  candles,
+	}, coverage, nil
+}
+
+// Returns the candles of the market data provider between the dates of from and to, none if it has no bars
+func (server *Server) getProviderCandles(ctx context.Context, symbol string, timespan polygon.PolygonTimespan, from, to time.Time, adjusted bool) ([]ServerCandle, error) {
+	history, err := server.marketData.GetTickerAggregates(ctx, polygon.PolygonAggregatesRequest{
+		Symbol:     symbol,
+		Multiplier: 1,
+		Timespan:   timespan,
+		From:       from,
+		To:         to,
+		Unadjusted: !adjusted,
+		Limit:      polygon.MaxAggregatesLimit,
+	})
+	if errors.Is(err, polygon.ErrNoResults) {
+		return nil, nil
+	}
+	if err != nil && !polygon.IsPartialPolygonData(err) {
+		return nil, errors.Join(errors.New("error getting ticker history"), err)
+	}
+
+	candles := []ServerCandle{}
+	for _, bar := range *history.Results {
+		// Bars read from files may have empty cells, Polygon's are complete
+		if bar.Timestamp == nil || bar.Open == nil || bar.High == nil || bar.Low == nil || bar.Close == nil {
+			continue
+		}
+		candle := ServerCandle{
+			Time:         *bar.Timestamp / 1000,
+			Date:         time.UnixMilli(*bar.Timestamp).In(calendar.Location).Format("2006-01-02"),
+			Open:         *bar.Open,
+			High:         *bar.High,
+			Low:          *bar.Low,
+			Close:        *bar.Close,
+			VWAP:         bar.VWAP,
+			Transactions: bar.Transactions,
+		}
+		if bar.Volume != nil {
+			candle.Volume = *bar.Volume
+		}
+		candles = append(candles, candle)
+	}
+	return candles, nil
+}
+
+// A range of consecutive sessions missing from MongoDB
+type historyGap struct {
+	From time.Time
+	To   time.Time
+}
+
+// getStoredDailyCandles returns the adjusted daily candles between the dates of from and to, reading the bars stored
+// in the "ticker_aggregates" collection and fetching only the sessions missing from it. Fetched bars of closed
+// sessions are stored, so the next request for the range is served from MongoDB alone.
+//
+// Bars are stored unadjusted and adjusted for the splits of the ticker when they are read, so a split executed after
+// a bar was stored doesn't leave it on a different basis than the bars fetched since. The adjusted bars stored by the
+// scrapers are served for the sessions without an unadjusted bar, unless a split executed after them, since they may
+// have been fetched before it. Closed sessions the provider
+// has no bar for, e.g. while the ticker was halted, are recorded in "ticker_empty_sessions" so they aren't requested
+// again.
+//
+// Input:
+//   - ctx: bounds the requests to MongoDB and the market data provider
+//   - symbol: the ticker's symbol
+//   - from, to: the first and last day of the range
+//   - now: the time of the request, sessions after it are not expected to have a bar
+//
+// Output:
+//   - []ServerCandle: the candles, oldest first
+//   - string: the number of sessions served from MongoDB, including recorded empty sessions, out of the sessions of
+//     the range, e.g. 45/69
+//   - error: any error that occurred
+func (server *Server) getStoredDailyCandles(ctx context.Context, symbol string, from, to time.Time, now time.Time) ([]ServerCandle, string, error) {
+	splits, err := server.getTickerSplits(ctx, symbol, from)
+	if err != nil {
+		return nil, "", errors.Join(errors.New("error getting ticker splits"), err)
+	}
+
+	end := to.AddDate(0, 0, 1).Add(-time.Millisecond)
+	stored, err := mongodb.GetUnadjustedAggregatesByTickerOverRangeWithContext(ctx, server.mongoClient, server.tickerDBName, symbol, from, end)
+	if err != nil {
+		// The provider can still serve the whole range
+		log.Println("Error reading stored ticker history", err)
+	}
+	bars := map[string]mongodb.TickerAggregate{}
+	for _, aggregate := range stored {
+		bars[historyDate(aggregate.Timestamp)] = aggregate
+	}
+	// The scrapers store adjusted bars, which are on the current basis unless a split executed after them
+	scraped, err := mongodb.GetAggregatesByTickerOverRangeWithContext(ctx, server.mongoClient, server.tickerDBName, symbol, from, end, 0, 0, 0)
+	if err != nil {
+		log.Println("Error reading stored ticker history", err)
+	}
+	for _, aggregate := range scraped {
+		date := historyDate(aggregate.Timestamp)
+		if _, ok := bars[date]; !ok && mongodb.SplitRatioBetween(splits, aggregate.Timestamp.Time(), now) == 1 {
+			bars[date] = aggregate
+		}
+	}
+
+	sessionsEnd := to
+	if sessionsEnd.After(now) {
+		sessionsEnd = now
+	}
+	// Sessions the provider was already asked for without returning a bar are not gaps
+	empty := map[string]bool{}
+	emptySessions, err := mongodb.GetEmptySessionsOverRangeWithContext(ctx, server.mongoClient, server.tickerDBName, symbol, from, to)
+	if err != nil {
+		log.Println("Error reading empty sessions", err)
+	}
+	for _, session := range emptySessions {
+		empty[historyDate(session.Date)] = true
+	}
+
+	sessions := server.marketCalendar.SessionsBetween(from, sessionsEnd)
+	covered := 0
+	gaps := []historyGap{}
+	missing := []time.Time{}
+	for i, session := range sessions {
+		date := session.Date.Format("2006-01-02")
+		if _, ok := bars[date]; ok || empty[date] {
+			covered++
+			continue
+		}
+		missing = append(missing, session.Date)
+		// Extend the gap of the previous session if it was missing too
+		if len(gaps) > 0 && i > 0 && gaps[len(gaps)-1].To.Equal(sessions[i-1].Date) {
+			gaps[len(gaps)-1].To = session.Date
+		} else {
+			gaps = append(gaps, historyGap{From: session.Date, To: session.Date})
+		}
+	}
+	// Scattered gaps are fetched at once rather than spending a request on each of them
+	if len(gaps) > maxHistoryGapRequests {
+		gaps = []historyGap{{From: gaps[0].From, To: gaps[len(gaps)-1].To}}
+	}
+
+	lastClosed := server.marketCalendar.LastClosedSession(now).Date
+	fetched := []mongodb.TickerAggregate{}
+	noBar := []time.Time{}
+	for _, gap := range gaps {
+		history, err := server.marketData.GetTickerAggregates(ctx, polygon.PolygonAggregatesRequest{
+			Symbol:     symbol,
+			Multiplier: 1,
+			Timespan:   polygon.TimespanDay,
+			From:       gap.From,
+			To:         gap.To,
+			Unadjusted: true,
+			Limit:      polygon.MaxAggregatesLimit,
+		})
+		// Sessions before the ticker was listed or while it was halted have no bar
+		if err != nil && !errors.Is(err, polygon.ErrNoResults) && !polygon.IsPartialPolygonData(err) {
+			return nil, "", errors.Join(errors.New("error getting ticker history"), err)
+		}
+		if history != nil {
+			aggregates, err := mongodb.PolygonAggregatesToTickerAggregates(*history, mongodb.DailyResolution)
+			if err != nil {
+				return nil, "", errors.Join(errors.New("error converting ticker history"), err)
+			}
+			for _, aggregate := range aggregates {
+				aggregate.Ticker = symbol
+				aggregate.Unadjusted = true
+				bars[historyDate(aggregate.Timestamp)] = aggregate
+				// The bar of the session in progress changes until the close
+				if !aggregate.Timestamp.Time().After(lastClosed) {
+					fetched = append(fetched, aggregate)
+				}
+			}
+		}
+		// A partial response may have dropped the bar of a session that has one
+		if polygon.IsPartialPolygonData(err) {
+			continue
+		}
+		for _, date := range missing {
+			if _, ok := bars[date.Format("2006-01-02")]; !ok && !date.Before(gap.From) && !date.After(gap.To) && !date.After(lastClosed) {
+				noBar = append(noBar, date)
+			}
+		}
+	}
+	if len(fetched) > 0 {
+		if _, err := mongodb.InsertAggregatesWithContext(context.WithoutCancel(ctx), server.mongoClient, server.tickerDBName, fetched); err != nil {
+			log.Println("Error storing ticker history", err)
+		}
+	}
+	if len(noBar) > 0 {
+		if _, err := mongodb.InsertEmptySessionsWithContext(context.WithoutCancel(ctx), server.mongoClient, server.tickerDBName, symbol, noBar); err != nil {
+			log.Println("Error storing empty sessions", err)
+		}
+	}
+
+	candles := make([]ServerCandle, 0, len(bars))
+	for date, aggregate := range bars {
+		aggregate = mongodb.AdjustForSplits(aggregate, splits, now)
+		candle := ServerCandle{
+			Time:   aggregate.Timestamp.Time().Unix(),
+			Date:   date,
+			Open:   aggregate.Open,
+			High:   aggregate.High,
+			Low:    aggregate.Low,
+			Close:  aggregate.Close,
+			Volume: aggregate.Volume,
+		}
+		// Stored bars have no way to tell a missing VWAP or transaction count from 0
+		if aggregate.VWAP != 0 {
+			candle.VWAP = &aggregate.VWAP
+		}
+		if aggregate.Transactions != 0 {
+			candle.Transactions = &aggregate.Transactions
+		}
+		candles = append(candles, candle)
+	}
+	sort.Slice(candles, func(i, j int) bool { return candles[i].Time < candles[j].Time })
+
+	return candles, fmt.Sprintf("%d/%d", covered, len(sessions)), nil
+}
+
+// Returns the day in New York of a daily bar, which starts at midnight there
+func historyDate(timestamp primitive.DateTime) string {
+	return timestamp.Time().In(calendar.Location).Format("2006-01-02")
+}
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"financial-helper/calendar"
+	"financial-helper/environment"
+	"financial-helper/marketdata"
+	"financial-helper/mongodb"
+	"financial-helper/polygon"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const testDBName = "test_stock_savvy"
+
+var testMongoClient *mongo.Client
+
+func TestMain(m *testing.M) {
+	client, err := func() (*mongo.Client, error) {
+		if err := environment.LoadEnvironment(); err != nil {
+			return nil, errors.Join(errors.New("failed to load environment for testing"), err)
+		}
+		vars, _, err := environment.LoadVars()
+		if err != nil {
+			return nil, errors.Join(errors.New("failed to load env variables"), err)
+		}
+		mongoPort, _ := strconv.Atoi(vars["MONGO_PORT"])
+		return mongodb.GetMongoDBInstance(vars["MONGO_INITDB_ROOT_USERNAME"], vars["MONGO_INITDB_ROOT_PASSWORD"], vars["MONGO_HOST"], mongoPort)
+	}()
+	if err != nil {
+		log.Printf("failed to initialize test mongo client, skipping database tests: %v\n", err)
+	}
+	testMongoClient = client
+
+	code := m.Run()
+
+	if testMongoClient != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		_ = testMongoClient.Disconnect(ctx)
+		cancel()
+	}
+	os.Exit(code)
+}
+
+// Serves the daily bars of bars and records the requests for them, other methods of the provider are not used
+type testBarsProvider struct {
+	marketdata.MarketDataProvider
+	bars     []polygon.PolygonAggregateBar
+	requests []polygon.PolygonAggregatesRequest
+}
+
+func (provider *testBarsProvider) GetTickerAggregates(ctx context.Context, request polygon.PolygonAggregatesRequest) (*polygon.PolygonGetTickerHistoryResponse, error) {
+	provider.requests = append(provider.requests, request)
+	bars := []polygon.PolygonAggregateBar{}
+	for _, bar := range provider.bars {
+		if start := time.UnixMilli(*bar.Timestamp); !start.Before(request.From) && !start.After(request.To) {
+			bars = append(bars, bar)
+		}
+	}
+	if len(bars) == 0 {
+		return nil, polygon.ErrNoResults
+	}
+	return &polygon.PolygonGetTickerHistoryResponse{Ticker: &request.Symbol, Results: &bars}, nil
+}
+
+func TestGetStoredDailyCandles_ServesScrapedBars(t *testing.T) {
+	if testMongoClient == nil {
+		t.Skip("test mongo client not initialized")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	symbol := fmt.Sprintf("TEST-HISTORY-%d", time.Now().UnixNano())
+	defer func() {
+		for _, collection := range []string{"ticker_aggregates", "ticker_empty_sessions"} {
+			if _, err := testMongoClient.Database(testDBName).Collection(collection).DeleteMany(ctx, bson.M{"ticker": symbol}); err != nil {
+				t.Logf("cleanup error: %v", err)
+			}
+		}
+	}()
+
+	// The 5 sessions of the week of October 7 2024, the scrapers stored the adjusted bars of the first 4
+	from := time.Date(2024, 10, 7, 0, 0, 0, 0, calendar.Location)
+	to := from.AddDate(0, 0, 4)
+	scraped := []mongodb.TickerAggregate{}
+	for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+		scraped = append(scraped, mongodb.TickerAggregate{Ticker: symbol, Multiplier: 1, Timespan: "day", Open: 10, High: 12, Low: 9, Close: 11, Volume: 1000, Timestamp: primitive.NewDateTimeFromTime(day)})
+	}
+	if _, err := mongodb.InsertAggregates(testMongoClient, testDBName, scraped); err != nil {
+		t.Fatalf("InsertAggregates error: %v", err)
+	}
+
+	timestamp, price, volume := to.UnixMilli(), 11.5, 2000.0
+	provider := &testBarsProvider{bars: []polygon.PolygonAggregateBar{{Timestamp: &timestamp, Open: &price, High: &price, Low: &price, Close: &price, Volume: &volume}}}
+	server := &Server{
+		marketData:     provider,
+		mongoClient:    testMongoClient,
+		tickerDBName:   testDBName,
+		marketCalendar: calendar.New(),
+	}
+
+	candles, coverage, err := server.getStoredDailyCandles(ctx, symbol, from, to, time.Date(2024, 10, 31, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("getStoredDailyCandles error: %v", err)
+	}
+	if coverage != "4/5" {
+		t.Fatalf("expected the scraped bars to be counted as stored, got coverage %s", coverage)
+	}
+	if len(provider.requests) != 1 || !provider.requests[0].From.Equal(to) || !provider.requests[0].To.Equal(to) {
+		t.Fatalf("expected only the last session to be requested, got %+v", provider.requests)
+	}
+	if len(candles) != 5 || candles[0].Date != "2024-10-07" || candles[0].Close != 11 || candles[4].Close != 11.5 {
+		t.Fatalf("unexpected candles %+v", candles)
+	}
+}
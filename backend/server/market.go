@@ -0,0 +1,55 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Number of sessions covered by the history endpoints, about 100 calendar days
+const historySessions = 69
+
+// GetMarketStatus returns the current state of the market
+//
+// GET /api/v1/market/status
+//
+// Output:
+//   - calendar.MarketStatus: whether the market is open, today's session or holiday, the surrounding sessions and
+//     the upcoming holidays. The live status comes from Polygon, and is computed from the calendar if Polygon fails.
+func (server *Server) GetMarketStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, server.marketCalendar.Status(c.Request.Context()))
+}
+
+// historyRange returns the dates of the first and last sessions of the history endpoints at now.
+// The range ends on the last closed session, so a request made before the open or on a holiday still has a last bar.
+//
+// Input:
+//   - now: the time of the request
+//
+// Output:
+//   - time.Time: the first session's date
+//   - time.Time: the last session's date
+func (server *Server) historyRange(now time.Time) (time.Time, time.Time) {
+	end := server.marketCalendar.LastClosedSession(now).Date
+	start := server.marketCalendar.AddSessions(end, -(historySessions - 1)).Date
+	return start, end
+}
+
+// lastCompleteMonth returns the first and last dates of the last calendar month whose sessions have all closed at now.
+// The month of the last closed session is only complete if no session of that month remains.
+//
+// Input:
+//   - now: the time of the request
+//
+// Output:
+//   - time.Time: the first date of the month, midnight in calendar.Location
+//   - time.Time: the last date of the month
+func (server *Server) lastCompleteMonth(now time.Time) (time.Time, time.Time) {
+	lastClosed := server.marketCalendar.LastClosedSession(now).Date
+	start := time.Date(lastClosed.Year(), lastClosed.Month(), 1, 0, 0, 0, 0, lastClosed.Location())
+	if server.marketCalendar.NextSession(lastClosed).Date.Month() == lastClosed.Month() {
+		start = start.AddDate(0, -1, 0)
+	}
+	return start, start.AddDate(0, 1, -1)
+}
@@ -0,0 +1,417 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"financial-helper/calendar"
+	"financial-helper/mongodb"
+	"financial-helper/polygon"
+	"financial-helper/sentiment"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// Days of news covered by the news endpoint and the chat when no range is given
+	defaultNewsDays = 30
+	// Number of articles rated per ticker by the chat, the most recent of the range, and of articles per news request
+	tickerNewsLimit = 350
+	// Articles listed per page by the news endpoint by default, and at most
+	defaultNewsPageSize = 20
+	maxNewsPageSize     = 100
+	// The news scraper runs about daily, so articles published since then may not be stored yet
+	newsTopUpWindow = 24 * time.Hour
+	// Articles weigh half as much in the sentiment of a ticker every week
+	newsSentimentHalfLife = 7 * 24 * time.Hour
+)
+
+// Weights of publishers in the sentiment of a ticker, publishers that aren't listed weigh 1
+var newsPublisherWeights = map[string]float64{
+	// Press releases are written by the companies themselves
+	"GlobeNewswire Inc.": 0.5,
+}
+
+// The articles selected by the query of the news endpoint
+type newsQuery struct {
+	From     time.Time // Midnight in New York of the first day
+	To       time.Time // Midnight in New York of the last day, whose articles are included
+	Page     int
+	PageSize int
+}
+
+// GetTickerNews returns the news sentiment of a stock and the articles it is rated from
+//
+// GET /api/v1/stocks/tickers/:symbol/news?from=&to=&page=&page_size=
+//
+// Input:
+//   - symbol: the ticker's symbol
+//   - from, to: the first and last day of the range, as YYYY-MM-DD. The range ends today and covers 30 days by default.
+//   - page: the page of articles, starting at 1
+//   - page_size: the articles per page, 20 by default and 100 at most
+//
+// Output:
+//   - TickerNews: the sentiment of the articles of the range, their number and a page of them, most recent first
+func (server *Server) GetTickerNews(c *gin.Context) {
+	symbol := strings.ToUpper(c.Param("symbol"))
+	if symbol == "" {
+		log.Println("Error: symbol is required")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "symbol is required"})
+		return
+	}
+	now := time.Now()
+	query, err := parseNewsQuery(c, now)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	news, err := server.getTickerNewsPage(c.Request.Context(), symbol, query, now)
+	if err != nil {
+		log.Println("Error getting ticker news", err)
+		c.JSON(polygonErrorStatus(err), gin.H{"error": "Error receiving ticker news"})
+		return
+	}
+
+	c.JSON(http.StatusOK, news)
+}
+
+// getTickerNewsPage returns the sentiment of every article about a ticker in the range of query, and the page of
+// them it selects. Stored articles are paginated by MongoDB after the latest ones are topped up, see
+// getStoredTickerNews. In demo mode the articles of the range are read whole and paginated here.
+//
+// Input:
+//   - ctx: bounds the requests to MongoDB and the market data provider
+//   - symbol: the ticker's symbol
+//   - query: the range and page of the articles
+//   - now: the time of the request
+//
+// Output:
+//   - TickerNews: the sentiment, the number of articles of the range and the page of them, most recent first
+//   - error: any error that occurred
+func (server *Server) getTickerNewsPage(ctx context.Context, symbol string, query newsQuery, now time.Time) (TickerNews, error) {
+	start, end := query.From, query.To.AddDate(0, 0, 1)
+	var rated, page []mongodb.Article
+	if server.polygonConnection == nil {
+		articles, err := server.getStoredTickerNews(ctx, symbol, start, end, now, 0)
+		if err != nil {
+			return TickerNews{}, err
+		}
+		first := min((query.Page-1)*query.PageSize, len(articles))
+		rated, page = articles, articles[first:min(first+query.PageSize, len(articles))]
+	} else {
+		// Stores the articles published since the newest stored one, so MongoDB has the whole range
+		if _, err := server.getStoredTickerNews(ctx, symbol, start, end, now, 1); err != nil {
+			return TickerNews{}, err
+		}
+		var err error
+		if rated, err = mongodb.GetArticleRatingsByTickerOverRangeWithContext(ctx, server.mongoClient, server.tickerDBName, symbol, start, end); err != nil {
+			return TickerNews{}, errors.Join(errors.New("error reading stored ticker news"), err)
+		}
+		if page, err = mongodb.GetArticlesByTickerOverRangeWithContext(ctx, server.mongoClient, server.tickerDBName, symbol, start, end, 0, query.Page, query.PageSize); err != nil {
+			return TickerNews{}, errors.Join(errors.New("error reading stored ticker news"), err)
+		}
+	}
+
+	news := newsSentiment(rated, symbol, now)
+	news.Symbol = symbol
+	news.From = query.From.Format("2006-01-02")
+	news.To = query.To.Format("2006-01-02")
+	news.Page = query.Page
+	news.PageSize = query.PageSize
+	news.HasMore = query.Page*query.PageSize < news.NumArticles
+	news.Articles = []ServerArticle{}
+	for _, article := range page {
+		news.Articles = append(news.Articles, toServerArticle(article, symbol))
+	}
+	return news, nil
+}
+
+// Reads the query parameters of the news endpoint, filling in the defaults at now
+func parseNewsQuery(c *gin.Context, now time.Time) (newsQuery, error) {
+	query := newsQuery{Page: 1, PageSize: defaultNewsPageSize}
+	var err error
+	if query.From, query.To, err = parseNewsRange(c, now); err != nil {
+		return query, err
+	}
+
+	if value := c.Query("page"); value != "" {
+		page, err := strconv.Atoi(value)
+		if err != nil || page < 1 {
+			return query, errors.New("page must be a positive integer")
+		}
+		query.Page = page
+	}
+	if value := c.Query("page_size"); value != "" {
+		pageSize, err := strconv.Atoi(value)
+		if err != nil || pageSize < 1 || pageSize > maxNewsPageSize {
+			return query, errors.New("page_size must be an integer between 1 and 100")
+		}
+		query.PageSize = pageSize
+	}
+	return query, nil
+}
+
+// Reads the from and to query parameters of the news and sentiment endpoints. The range ends today and covers
+// defaultNewsDays days by default.
+func parseNewsRange(c *gin.Context, now time.Time) (time.Time, time.Time, error) {
+	to := calendar.DateOf(now)
+	if value := c.Query("to"); value != "" {
+		var err error
+		to, err = time.ParseInLocation("2006-01-02", value, calendar.Location)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("to must be a date formatted as YYYY-MM-DD")
+		}
+	}
+	// Without a start, the range keeps its default length
+	from := to.AddDate(0, 0, -(defaultNewsDays - 1))
+	if value := c.Query("from"); value != "" {
+		var err error
+		from, err = time.ParseInLocation("2006-01-02", value, calendar.Location)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("from must be a date formatted as YYYY-MM-DD")
+		}
+	}
+	if from.After(to) {
+		return time.Time{}, time.Time{}, errors.New("from cannot be after to")
+	}
+	return from, to, nil
+}
+
+// getRecentTickerNews returns the latest articles about a ticker published over the last defaultNewsDays days,
+// most recent first
+//
+// Input:
+//   - ctx: bounds the requests to MongoDB and the market data provider
+//   - symbol: the ticker's symbol
+//
+// Output:
+//   - []mongodb.Article: up to tickerNewsLimit articles, empty if there are none
+//   - error: any error that occurred
+func (server *Server) getRecentTickerNews(ctx context.Context, symbol string) ([]mongodb.Article, error) {
+	now := time.Now()
+	today := calendar.DateOf(now)
+	return server.getStoredTickerNews(ctx, symbol, today.AddDate(0, 0, -(defaultNewsDays-1)), today.AddDate(0, 0, 1), now, tickerNewsLimit)
+}
+
+// GetTickerSentiment returns the news sentiment of a stock over time, to be shown along its price
+//
+// GET /api/v1/stocks/tickers/:symbol/sentiment?from=&to=&bucket=
+//
+// Input:
+//   - symbol: the ticker's symbol
+//   - from, to: the first and last day of the range, as YYYY-MM-DD. The range ends today and covers 30 days by default.
+//   - bucket: the length of a bucket, day (default) or week. Weeks start on Monday.
+//
+// Output:
+//   - TickerSentimentSeries: the sentiment of the stored articles of every bucket with articles, oldest first
+func (server *Server) GetTickerSentiment(c *gin.Context) {
+	symbol := strings.ToUpper(c.Param("symbol"))
+	if symbol == "" {
+		log.Println("Error: symbol is required")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "symbol is required"})
+		return
+	}
+	from, to, err := parseNewsRange(c, time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	bucket := mongodb.SentimentBucketDay
+	if value := c.Query("bucket"); value != "" {
+		bucket = mongodb.SentimentBucketSize(value)
+		if bucket != mongodb.SentimentBucketDay && bucket != mongodb.SentimentBucketWeek {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "bucket must be day or week"})
+			return
+		}
+	}
+
+	buckets, err := mongodb.GetSentimentSeriesByTickerWithContext(c.Request.Context(), server.mongoClient, server.tickerDBName, symbol, from, to.AddDate(0, 0, 1), bucket, calendar.Location)
+	if err != nil {
+		log.Println("Error getting ticker sentiment", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting ticker sentiment"})
+		return
+	}
+
+	series := TickerSentimentSeries{
+		Symbol:  symbol,
+		From:    from.Format("2006-01-02"),
+		To:      to.Format("2006-01-02"),
+		Bucket:  string(bucket),
+		Buckets: []ServerSentimentBucket{},
+	}
+	for _, bucket := range buckets {
+		series.Buckets = append(series.Buckets, ServerSentimentBucket{
+			Time:      bucket.Start.Unix(),
+			Date:      bucket.Start.Format("2006-01-02"),
+			Articles:  bucket.Articles,
+			Positive:  bucket.Positive,
+			Neutral:   bucket.Neutral,
+			Negative:  bucket.Negative,
+			MeanScore: bucket.MeanScore,
+		})
+	}
+
+	c.JSON(http.StatusOK, series)
+}
+
+// getStoredTickerNews returns the latest articles about a ticker published in [start, end), read from the
+// "ticker_news" collection the news scraper fills. Articles published over the last newsTopUpWindow may not be
+// scraped yet, so they are requested from Polygon and stored. In demo mode nothing is stored, and the whole range
+// is read from the market data provider.
+//
+// Input:
+//   - ctx: bounds the requests to MongoDB and the market data provider
+//   - symbol: the ticker's symbol
+//   - start, end: the range of publication times
+//   - now: the time of the request
+//   - limit: the number of articles returned, 0 for every article of the range
+//
+// Output:
+//   - []mongodb.Article: up to limit articles, most recent first
+//   - error: any error that occurred
+func (server *Server) getStoredTickerNews(ctx context.Context, symbol string, start, end time.Time, now time.Time, limit int) ([]mongodb.Article, error) {
+	articles, err := mongodb.GetArticlesByTickerOverRangeWithContext(ctx, server.mongoClient, server.tickerDBName, symbol, start, end, limit, 0, 0)
+	if err != nil {
+		return nil, errors.Join(errors.New("error reading stored ticker news"), err)
+	}
+
+	topUpStart := start
+	if server.polygonConnection != nil {
+		topUpStart = maxTime(topUpStart, now.Add(-newsTopUpWindow))
+		// Articles are sorted most recent first, so only those published after the newest stored one are missing
+		if len(articles) > 0 {
+			topUpStart = maxTime(topUpStart, articles[0].PublishedAt.Time())
+		}
+	}
+	topUpEnd := end
+	if topUpEnd.After(now) {
+		topUpEnd = now
+	}
+	if !topUpStart.Before(topUpEnd) {
+		return articles, nil
+	}
+
+	// Without a limit, demo mode reads every page of the range
+	maxPages := 1
+	if limit <= 0 {
+		maxPages = 0
+	}
+	news, _, err := server.marketData.GetTickerNews(ctx, symbol, topUpStart, topUpEnd, tickerNewsLimit, maxPages)
+	if errors.Is(err, polygon.ErrNoResults) {
+		return articles, nil
+	}
+	if err != nil && !polygon.IsPartialPolygonData(err) {
+		// The stored articles are still worth serving
+		log.Println("Error topping up ticker news", err)
+		return articles, nil
+	}
+	recent, err := mongodb.PolygonNewsToArticles(*news)
+	if err != nil {
+		return nil, errors.Join(errors.New("error converting ticker news"), err)
+	}
+	if server.polygonConnection != nil {
+		if _, err := mongodb.InsertArticlesWithContext(context.WithoutCancel(ctx), server.mongoClient, server.tickerDBName, recent); err != nil {
+			log.Println("Error storing ticker news", err)
+		}
+	}
+
+	return mergeArticles(articles, recent, limit), nil
+}
+
+// Merges the articles of a top-up into the stored ones, skipping those already stored, and keeps the limit most
+// recent, every article if limit is 0
+func mergeArticles(stored []mongodb.Article, recent []mongodb.Article, limit int) []mongodb.Article {
+	storedIDs := map[string]bool{}
+	for _, article := range stored {
+		storedIDs[article.PolygonID] = true
+	}
+	merged := stored
+	for _, article := range recent {
+		if article.PolygonID == "" || !storedIDs[article.PolygonID] {
+			merged = append(merged, article)
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool { return merged[i].PublishedAt > merged[j].PublishedAt })
+	if limit <= 0 {
+		return merged
+	}
+	return merged[:min(len(merged), limit)]
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// Converts a stored article into the article returned by the news endpoint, with its sentiment towards symbol
+func toServerArticle(article mongodb.Article, symbol string) ServerArticle {
+	serverArticle := ServerArticle{
+		ID:          article.ID.Hex(),
+		Source:      article.Source,
+		Title:       article.Title,
+		Author:      article.Author,
+		Publisher:   article.Publisher.Name,
+		PublishedAt: article.PublishedAt.Time().Unix(),
+		URL:         article.ArticleURL,
+		ImageURL:    article.ImageURL,
+		Description: article.Description,
+	}
+	for _, insight := range article.Insights {
+		if insight.Ticker == symbol {
+			serverArticle.Sentiment = insight.Sentiment
+			serverArticle.SentimentReasoning = insight.SentimentReasoning
+			break
+		}
+	}
+	return serverArticle
+}
+
+// newsSentiment rates the sentiment of articles towards symbol from their insights about it. Recent articles weigh
+// more, see newsSentimentHalfLife and newsPublisherWeights. Articles without an insight about symbol are only
+// counted in NumArticles.
+func newsSentiment(articles []mongodb.Article, symbol string, now time.Time) TickerNews {
+	observations := []sentiment.Observation{}
+	for _, article := range articles {
+		for _, insight := range article.Insights {
+			if insight.Ticker != symbol {
+				continue
+			}
+			if score, ok := sentiment.Score(insight.Sentiment); ok {
+				observations = append(observations, sentiment.Observation{
+					Score:       score,
+					PublishedAt: article.PublishedAt.Time(),
+					Publisher:   article.Publisher.Name,
+				})
+			}
+			break
+		}
+	}
+
+	news := TickerNews{NumArticles: len(articles)}
+	stats, err := sentiment.Summarize(observations, sentiment.Options{
+		Now:              now,
+		HalfLife:         newsSentimentHalfLife,
+		PublisherWeights: newsPublisherWeights,
+	})
+	if err != nil {
+		// No article is rated, NumRated tells it apart from a neutral sentiment
+		return news
+	}
+	news.AverageSentiment = float32(stats.Mean)
+	news.StdDevSentiment = float32(stats.StdDev)
+	news.ConfidenceLow = float32(stats.Low)
+	news.ConfidenceHigh = float32(stats.High)
+	news.NumRated = stats.Count
+	news.NumPositive = stats.Positive
+	news.NumNeutral = stats.Neutral
+	news.NumNegative = stats.Negative
+	return news
+}
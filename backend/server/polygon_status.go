@@ -0,0 +1,25 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetPolygonStatus returns the state of the Polygon connection
+//
+// GET /api/v1/polygon/status
+//
+// Output:
+//   - ServerPolygonStatusResponse: the health of every API key, and the hits and misses of the response cache
+//     since the server started. 404 in demo mode, which has no Polygon connection. Requires an access token.
+func (server *Server) GetPolygonStatus(c *gin.Context) {
+	if server.polygonConnection == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Market data is read from files, there is no Polygon connection"})
+		return
+	}
+	c.JSON(http.StatusOK, ServerPolygonStatusResponse{
+		Keys:  server.polygonConnection.KeyHealth(),
+		Cache: server.polygonConnection.CacheStats(),
+	})
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"financial-helper/mongodb"
+	"financial-helper/polygon"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// SearchTickers returns the tickers whose symbol or company name matches a search term, best matches first
+//
+// GET /api/v1/stocks/search?q=&market=&type=&exchange=&limit=
+//
+// Input:
+//   - q: the search term, matched against symbols and names by prefix, substring and with a typo or two
+//   - market, type, exchange: optional filters, e.g. market=stocks, type=CS or ETF, exchange=XNAS
+//   - limit: the number of results, 20 by default and at most 100
+//
+// Output:
+//   - ServerTickerSearchResponse: the matching tickers. Searches run against the tickers synced locally, and go to
+//     the market data provider only when none match, storing what it returns for the next searches.
+func (server *Server) SearchTickers(c *gin.Context) {
+	query := mongodb.TickerSearchQuery{
+		Query:    strings.TrimSpace(c.Query("q")),
+		Market:   c.Query("market"),
+		Type:     c.Query("type"),
+		Exchange: c.Query("exchange"),
+	}
+	if query.Query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+		return
+	}
+	limit := defaultSearchLimit
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxSearchLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+			return
+		}
+		limit = parsed
+	}
+
+	matches, err := mongodb.SearchTickerReferencesWithContext(c.Request.Context(), server.mongoClient, server.tickerDBName, query, limit)
+	if err != nil {
+		log.Println("Error searching stored tickers", err)
+	}
+	source := "local"
+	if len(matches) == 0 {
+		source = server.marketData.Name()
+		matches, err = server.searchProviderTickers(c.Request.Context(), query, limit)
+		if err != nil && !errors.Is(err, polygon.ErrNoResults) {
+			log.Println("Error searching tickers with the market data provider", err)
+			c.JSON(polygonErrorStatus(err), gin.H{"error": "Error searching tickers"})
+			return
+		}
+	}
+
+	response := ServerTickerSearchResponse{Query: query.Query, Source: source, Results: []ServerTickerSearchResult{}}
+	for _, match := range matches {
+		response.Results = append(response.Results, ServerTickerSearchResult{
+			Symbol:          match.Ticker,
+			Name:            match.Name,
+			Market:          match.Market,
+			Locale:          match.Locale,
+			Type:            match.Type,
+			PrimaryExchange: match.PrimaryExchange,
+			Currency:        match.CurrencyName,
+			Score:           match.Score,
+		})
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// searchProviderTickers searches the tickers of the market data provider (Polygon's ticker list outside of demo mode),
+// stores the results in the local ticker reference and ranks them like local results
+//
+// Input:
+//   - ctx: bounds the request to the provider
+//   - query: the search term and filters
+//   - limit: the number of results
+//
+// Output:
+//   - []mongodb.TickerSearchMatch: the matching tickers, best first
+//   - error: any error that occurred, polygon.ErrNoResults if no ticker matches
+func (server *Server) searchProviderTickers(ctx context.Context, query mongodb.TickerSearchQuery, limit int) ([]mongodb.TickerSearchMatch, error) {
+	response, err := server.marketData.SearchTickers(ctx, polygon.PolygonTickerSearchRequest{
+		Search:   query.Query,
+		Market:   query.Market,
+		Type:     query.Type,
+		Exchange: query.Exchange,
+		Limit:    limit,
+	})
+	if err != nil && !polygon.IsPartialPolygonData(err) {
+		return nil, err
+	}
+
+	references := mongodb.PolygonTickersToTickerReferences(*response)
+	if _, err := mongodb.UpsertTickerReferencesWithContext(context.WithoutCancel(ctx), server.mongoClient, server.tickerDBName, references); err != nil {
+		log.Println("Error storing searched tickers", err)
+	}
+
+	// The provider may match on words the local ranking scores as 0, keep those after the ranked ones
+	ranked := mongodb.RankTickerMatches(query.Query, references, limit)
+	if len(ranked) < len(references) && len(ranked) < limit {
+		included := map[string]bool{}
+		for _, match := range ranked {
+			included[match.Ticker] = true
+		}
+		for _, reference := range references {
+			if len(ranked) == limit {
+				break
+			}
+			if !included[reference.Ticker] {
+				ranked = append(ranked, mongodb.TickerSearchMatch{TickerReference: reference})
+			}
+		}
+	}
+	return ranked, nil
+}
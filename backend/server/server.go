@@ -2,11 +2,18 @@ package server
 
 import (
 	"errors"
+	"financial-helper/calendar"
 	"financial-helper/environment"
+	"financial-helper/marketdata"
 	"financial-helper/mongodb"
 	"financial-helper/polygon"
+	"financial-helper/token"
+	"fmt"
+	"log"
 	"net/http"
+	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-contrib/cors"
@@ -16,15 +23,36 @@ import (
 )
 
 type Server struct {
-	Router            *gin.Engine
-	GeminiClient      *genai.Client
-	GeminiModel       *genai.GenerativeModel
-	nytKey            string
-	geminiKey         string
-	polygonConnection *polygon.PolygonConnection
-	mongoClient       *mongo.Client
+	Router               *gin.Engine
+	GeminiClient         *genai.Client
+	GeminiModel          *genai.GenerativeModel
+	nytKey               string
+	geminiKey            string
+	marketData           marketdata.MarketDataProvider
+	polygonConnection    *polygon.PolygonConnection // Unset in demo mode, which disables the Polygon-only endpoints
+	mongoClient          *mongo.Client
+	tickerDBName         string
+	marketCalendar       *calendar.Calendar
+	tokenMaker           token.Maker
+	accessTokenDuration  time.Duration
+	refreshTokenDuration time.Duration
+	transactionLocks     userLocks // Serializes the writes to the transactions of a user
 }
 
+const (
+	// Environment variable with the secret signing the tokens of users, at least 32 characters long. It must stay the
+	// same across restarts to keep users logged in.
+	TokenSymmetricKeyEnv = "TOKEN_SYMMETRIC_KEY"
+	// Environment variables with the lifetimes of the tokens, e.g. "15m" or "168h"
+	AccessTokenDurationEnv  = "ACCESS_TOKEN_DURATION"
+	RefreshTokenDurationEnv = "REFRESH_TOKEN_DURATION"
+	// Environment variable with the comma-separated origins allowed by CORS, every origin if unset
+	CORSAllowedOriginsEnv = "CORS_ALLOWED_ORIGINS"
+	// Environment variable with the username given the transactions stored before users existed, see
+	// assignUnownedTransactions
+	TransactionsOwnerEnv = "TRANSACTIONS_OWNER"
+)
+
 func GetNewServer() (*Server, error) {
 	if err := environment.LoadEnvironment(); err != nil {
 		return nil, errors.Join(errors.New("couldn't load environment for server"), err)
@@ -39,9 +67,7 @@ func GetNewServer() (*Server, error) {
 	router := gin.Default()
 
 	// Set CORS rules
-	config := cors.DefaultConfig()
-	config.AllowAllOrigins = true
-	router.Use(cors.New(config))
+	router.Use(cors.New(corsConfigFromEnv()))
 
 	// Initialize MongoDB connection
 	mongoPort, _ := strconv.Atoi(vars["MONGO_PORT"]) // Don't need to check that this works because LoadVars() already did
@@ -50,16 +76,56 @@ func GetNewServer() (*Server, error) {
 		return nil, errors.Join(errors.New("failed to initialize mongodb connection"), err)
 	}
 
-	// Initialize Polygon connection
-	throttleTimeInt, _ := strconv.Atoi(vars["THROTTLE_TIME"]) // Don't need to check that this works because LoadVars() already did
-	polygonConnection := polygon.GetPolygonConnection(polygonKeys, time.Duration(throttleTimeInt)*time.Second)
+	// Load token maker
+	tokenMaker, err := token.NewJWTMaker(os.Getenv(TokenSymmetricKeyEnv))
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to create token maker from "+TokenSymmetricKeyEnv), err)
+	}
+	accessTokenDuration, err := durationFromEnv(AccessTokenDurationEnv, defaultAccessTokenDuration)
+	if err != nil {
+		return nil, err
+	}
+	refreshTokenDuration, err := durationFromEnv(RefreshTokenDurationEnv, defaultRefreshTokenDuration)
+	if err != nil {
+		return nil, err
+	}
 
 	server := &Server{
-		Router:            router,
-		nytKey:            vars["NYT_API_KEY"],
-		geminiKey:         vars["GOOGLE_GEMINI_API_KEY"],
-		polygonConnection: polygonConnection,
-		mongoClient:       mongoClient,
+		Router:               router,
+		nytKey:               vars["NYT_API_KEY"],
+		geminiKey:            vars["GOOGLE_GEMINI_API_KEY"],
+		mongoClient:          mongoClient,
+		tickerDBName:         os.Getenv("MONGO_INITDB_DATABASE"),
+		tokenMaker:           tokenMaker,
+		accessTokenDuration:  accessTokenDuration,
+		refreshTokenDuration: refreshTokenDuration,
+	}
+	if err := mongodb.CreateUsersIndex(mongoClient, server.tickerDBName); err != nil {
+		return nil, errors.Join(errors.New("failed to initialize the users collection"), err)
+	}
+	if err := server.assignUnownedTransactions(os.Getenv(TransactionsOwnerEnv)); err != nil {
+		return nil, err
+	}
+
+	if vars["MARKET_DATA_DIR"] != "" {
+		// Demo mode, market data is read from files and the calendar only knows the built-in holidays
+		server.marketData = marketdata.NewFileProvider(vars["MARKET_DATA_DIR"])
+		server.marketCalendar = calendar.New()
+	} else {
+		// Initialize Polygon connection
+		throttleTimeInt, _ := strconv.Atoi(vars["THROTTLE_TIME"]) // Don't need to check that this works because LoadVars() already did
+		polygonOptions := []polygon.PolygonConnectionOption{polygon.WithThrottle(time.Duration(throttleTimeInt) * time.Second), polygon.WithCassetteFromEnv()}
+		cache, err := mongodb.PolygonCacheFromEnv(mongoClient, server.tickerDBName)
+		if err != nil {
+			return nil, errors.Join(errors.New("failed to open the polygon response cache"), err)
+		}
+		if cache != nil {
+			polygonOptions = append(polygonOptions, polygon.WithCache(cache, nil))
+		}
+		polygonConnection := polygon.GetPolygonConnection(polygonKeys, polygonOptions...)
+		server.marketData = marketdata.NewPolygonProvider(polygonConnection)
+		server.polygonConnection = polygonConnection
+		server.marketCalendar = calendar.NewWithPolygon(polygonConnection)
 	}
 
 	server.InitializeModel()
@@ -83,6 +149,8 @@ func GetNewServer() (*Server, error) {
 			// Contains all routes relating to stocks
 			stocks := v1.Group("/stocks")
 			{
+				// Returns the tickers matching a search term
+				stocks.GET("/search", server.SearchTickers)
 
 				// Contains all routes relating to tickers
 				tickers := stocks.Group("/tickers")
@@ -99,22 +167,74 @@ func GetNewServer() (*Server, error) {
 
 						// Returns the news sentiment of a ticker
 						searchTicker.GET("/news", server.GetTickerNews)
+
+						// Returns the news sentiment of a ticker over time
+						searchTicker.GET("/sentiment", server.GetTickerSentiment)
+
+						// Returns the financial statements and ratios of a ticker
+						searchTicker.GET("/financials", server.GetTickerFinancials)
 					}
 				}
 
-				// Contains all routes relating to holdings
-				holdings := stocks.Group("/holdings")
+				// Contains all routes relating to holdings, which belong to the authenticated user
+				holdings := stocks.Group("/holdings", authMiddleware(server.tokenMaker))
 				{
 					// Returns all the holdings of a user
 					holdings.GET("", server.GetHoldings)
 
 					// Returns historical data about a holding
 					holdings.GET("/:symbol", server.GetHoldingInfo)
+
+					// Contains all routes relating to the transactions the holdings are derived from
+					transactions := holdings.Group("/transactions")
+					{
+						// Returns the transactions of a user, or of one of their holdings
+						transactions.GET("", server.GetTransactions)
+
+						// Records a purchase or sale
+						transactions.POST("", server.CreateTransaction)
+
+						// Returns, replaces or deletes a transaction
+						transactions.GET("/:id", server.GetTransaction)
+						transactions.PUT("/:id", server.UpdateTransaction)
+						transactions.DELETE("/:id", server.DeleteTransaction)
+					}
 				}
 			}
 
-			// Contains all routes relating to the AI chat
-			chat := v1.Group("/chat")
+			// Contains all routes relating to the market as a whole
+			market := v1.Group("/market")
+			{
+				// Returns whether the market is open, and its upcoming sessions and holidays
+				market.GET("/status", server.GetMarketStatus)
+			}
+
+			// Contains all routes relating to the Polygon connection, only available to authenticated users since they
+			// reveal the state of the API keys
+			polygonGroup := v1.Group("/polygon", authMiddleware(server.tokenMaker))
+			{
+				// Returns the health of the API keys and the response cache stats
+				polygonGroup.GET("/status", server.GetPolygonStatus)
+			}
+
+			// Contains all routes relating to users
+			users := v1.Group("/users")
+			{
+				// Creates a user, and returns their tokens
+				users.POST("/register", server.Register)
+
+				// Returns the tokens of a user
+				users.POST("/login", server.Login)
+
+				// Exchanges a refresh token for new tokens
+				users.POST("/refresh", server.RefreshTokens)
+
+				// Returns the authenticated user
+				users.GET("/me", authMiddleware(server.tokenMaker), server.GetCurrentUser)
+			}
+
+			// Contains all routes relating to the AI chat, available to authenticated users
+			chat := v1.Group("/chat", authMiddleware(server.tokenMaker))
 			{
 				// Returns a response from the AI chat
 				chat.POST("", server.GenerateContent)
@@ -122,11 +242,62 @@ func GetNewServer() (*Server, error) {
 		}
 	}
 
-	// load router
-	// load token maker
 	return server, nil
 }
 
+// Returns the CORS rules: the origins of CORS_ALLOWED_ORIGINS, or every origin if it is not set.
+// Browsers send the access token in the Authorization header, so it is allowed.
+func corsConfigFromEnv() cors.Config {
+	config := cors.DefaultConfig()
+	config.AddAllowHeaders("Authorization")
+
+	origins := []string{}
+	for _, origin := range strings.Split(os.Getenv(CORSAllowedOriginsEnv), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, strings.TrimSuffix(origin, "/"))
+		}
+	}
+	if len(origins) == 0 {
+		config.AllowAllOrigins = true
+	} else {
+		config.AllowOrigins = origins
+	}
+	return config
+}
+
+// Returns the positive duration of the environment variable name, or fallback if it is not set
+func durationFromEnv(name string, fallback time.Duration) (time.Duration, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		return 0, fmt.Errorf("%s must be a positive duration such as \"15m\", got %q", name, value)
+	}
+	return duration, nil
+}
+
 func (server *Server) NotImplemented(c *gin.Context) {
 	c.JSON(http.StatusNotImplemented, gin.H{"status": "This resource is not yet implemented, but will be in the future"})
 }
+
+// Gives the transactions stored without a user_id, from before users existed, to the user named `username`, since
+// they are invisible to every user otherwise. Does nothing if `username` is empty.
+func (server *Server) assignUnownedTransactions(username string) error {
+	if username == "" {
+		return nil
+	}
+	user, err := mongodb.GetUserByUsername(server.mongoClient, server.tickerDBName, strings.ToLower(strings.TrimSpace(username)))
+	if err != nil {
+		return errors.Join(fmt.Errorf("failed to find the user %q of %s", username, TransactionsOwnerEnv), err)
+	}
+	assigned, err := mongodb.AssignUnownedTransactions(server.mongoClient, server.tickerDBName, user.ID.Hex())
+	if err != nil {
+		return errors.Join(errors.New("failed to assign the transactions without an owner"), err)
+	}
+	if assigned > 0 {
+		log.Printf("Assigned %d transactions without an owner to %s", assigned, user.Username)
+	}
+	return nil
+}
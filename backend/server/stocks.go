@@ -1,16 +1,18 @@
 package server
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
+	"financial-helper/mongodb"
+	"financial-helper/polygon"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
-	"sort"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // GetTickerInfo returns information about a stock
@@ -30,360 +32,328 @@ func (server *Server) GetTickerInfo(c *gin.Context) {
 		return
 	}
 
-	info, err := server.getTickerInfo(symbol)
+	info, err := server.getTickerInfo(c.Request.Context(), symbol)
 	if err != nil {
 		log.Println("Error getting ticker info", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting ticker info"})
+		c.JSON(polygonErrorStatus(err), gin.H{"error": "Error getting ticker info"})
 		return
 	}
 
 	c.JSON(http.StatusOK, info)
 }
 
-func (server *Server) getTickerInfo(symbol string) (*ServerTickerInfoResponse, error) {
-	tickerSummary, err := server.polygonConnection.PolygonGetTicker(symbol)
+func (server *Server) getTickerInfo(ctx context.Context, symbol string) (*ServerTickerInfoResponse, error) {
+	details, err := server.getTickerDetails(ctx, symbol)
 	if err != nil {
 		return nil, errors.Join(errors.New("error getting ticker info"), err)
 	}
 
-	tickerLastHistory, err := server.polygonConnection.PolygonGetTickerDailyClose(symbol)
-	if err != nil {
+	tickerLastHistory, err := server.marketData.GetPreviousClose(ctx, symbol)
+	if err != nil && !polygon.IsPartialPolygonData(err) {
 		return nil, errors.Join(errors.New("error getting ticker aggregate"), err)
 	}
 
+	// Polygon's bars always have prices once validated, but the bars of a file provider may have empty cells
+	lastBar := (*tickerLastHistory.Results)[0]
+	if lastBar.Open == nil || lastBar.Close == nil {
+		return nil, fmt.Errorf("the last bar of %s has no open or close price", symbol)
+	}
 	info := ServerTickerInfoResponse{
-		Symbol:          *(*(*tickerSummary).Results)[0].Ticker,
-		Name:            *(*(*tickerSummary).Results)[0].Name,
-		Industry:        "Not yet set",
-		Locale:          *(*(*tickerSummary).Results)[0].Locale,
-		PrimaryExchange: *(*(*tickerSummary).Results)[0].PrimaryExchange,
-		OpenPrice:       *(*(*tickerLastHistory).Results)[0].Open,
-		ClosePrice:      *(*(*tickerLastHistory).Results)[0].Close,
+		Symbol:            details.Ticker,
+		Name:              details.Name,
+		Industry:          details.Industry,
+		Locale:            details.Locale,
+		PrimaryExchange:   details.PrimaryExchange,
+		OpenPrice:         *lastBar.Open,
+		ClosePrice:        *lastBar.Close,
+		MarketCap:         details.MarketCap,
+		SharesOutstanding: details.ShareClassSharesOutstanding,
+		Description:       details.Description,
+		HomepageURL:       details.HomepageURL,
+		LogoURL:           details.Branding.LogoURL,
+		IconURL:           details.Branding.IconURL,
+	}
+	if details.ListDate != 0 {
+		info.ListDate = details.ListDate.Time().UTC().Format("2006-01-02")
 	}
 
 	return &info, nil
 }
 
-// GetTickerHistory returns the historical prices of a stock
-//
-// GET /api/v1/stocks/tickers/:symbol/history
-//
-// Input:
-//   - symbol: the ticker's symbol
-//
-// Output:
-//   - TickerHistory: the ticker history struct
-func (server *Server) GetTickerHistory(c *gin.Context) {
-	symbol := c.Param("symbol")
-	if symbol == "" {
-		log.Println("Error: symbol is required")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "symbol is required"})
-		return
+// How long ticker details stored in the "tickers" collection are served before being fetched from Polygon again
+const tickerDetailsMaxAge = 24 * time.Hour
+
+// getTickerDetails returns the details of a ticker from the "tickers" collection, fetching and storing them
+// first if they are missing or older than tickerDetailsMaxAge. Stale details are served if Polygon fails.
+func (server *Server) getTickerDetails(ctx context.Context, symbol string) (*mongodb.TickerDetails, error) {
+	stored, err := mongodb.GetTickerDetailsWithContext(ctx, server.mongoClient, server.tickerDBName, symbol)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		log.Println("Error reading stored ticker details", err)
+	}
+	if stored != nil && time.Since(stored.UpdatedAt.Time()) < tickerDetailsMaxAge {
+		return stored, nil
 	}
 
-	historyMap, err := server.getTickerHistory(symbol)
+	response, err := server.marketData.GetTickerDetails(ctx, symbol)
+	if err != nil && !polygon.IsPartialPolygonData(err) {
+		if stored != nil {
+			log.Println("Error refreshing ticker details, serving stored details", err)
+			return stored, nil
+		}
+		return nil, err
+	}
+	details, err := mongodb.PolygonTickerDetailsToTickerDetails(*response)
 	if err != nil {
-		log.Println("Error getting ticker history", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting ticker history"})
-		return
+		return nil, err
 	}
 
-	serverHistory := ServerTickerHistoryResponse{
-		History: historyMap,
+	if err := mongodb.UpsertTickerDetailsWithContext(context.WithoutCancel(ctx), server.mongoClient, server.tickerDBName, details); err != nil {
+		log.Println("Error storing ticker details", err)
 	}
+	return &details, nil
+}
 
-	c.JSON(http.StatusOK, serverHistory)
+// Maps an error returned by the polygon package to the HTTP status code the API should respond with
+func polygonErrorStatus(err error) int {
+	var notFoundErr *polygon.PolygonNotFoundError
+	var rateLimitErr *polygon.PolygonRateLimitError
+	var unauthorizedErr *polygon.PolygonUnauthorizedError
+	var serverErr *polygon.PolygonServerError
+	switch {
+	case errors.Is(err, polygon.ErrNoResults) || errors.As(err, &notFoundErr):
+		return http.StatusNotFound
+	case errors.As(err, &rateLimitErr):
+		return http.StatusServiceUnavailable
+	case errors.As(err, &unauthorizedErr) || errors.As(err, &serverErr):
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
 }
 
-// getTickerHistory returns the historical prices of a stock
+// GetHoldings returns the holdings of the authenticated user
 //
-// Input:
-//   - symbol: the ticker's symbol
+// GET /api/v1/stocks/holdings
 //
 // Output:
-//   - []map[string]interface{}: the ticker history struct
-//   - error: any error that occurred
-func (server *Server) getTickerHistory(symbol string) ([]map[string]interface{}, error) {
-	//fmt.Println("Date: ", time.Now().AddDate(0, 0, -100).Format("2006-01-30"))
-	polygonHistory, err := server.polygonConnection.PolygonGetTickerHistory(symbol, time.Now().AddDate(0, 0, -100), time.Now(), -1)
+//   - []Holding: the shares currently held of every ticker with transactions, except those sold entirely
+func (server *Server) GetHoldings(c *gin.Context) {
+	transactions, err := mongodb.GetTransactionsWithContext(c.Request.Context(), server.mongoClient, server.tickerDBName, authPayload(c).UserID, "")
 	if err != nil {
-		return nil, errors.Join(errors.New("error getting ticker history"), err)
+		log.Println("Error getting transactions", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting transactions"})
+		return
 	}
 
-	serverHistory := []map[string]interface{}{}
+	// Group the transactions by symbol, in the order symbols were first traded
+	symbols := []string{}
+	bySymbol := map[string][]mongodb.Transaction{}
+	for _, transaction := range transactions {
+		if _, ok := bySymbol[transaction.Symbol]; !ok {
+			symbols = append(symbols, transaction.Symbol)
+		}
+		bySymbol[transaction.Symbol] = append(bySymbol[transaction.Symbol], transaction)
+	}
 
-	for _, polygonRecord := range *polygonHistory.Results {
-		newRecord := map[string]interface{}{}
-		newRecord["time"] = polygonRecord.Timestamp
-		newRecord["value"] = polygonRecord.Volume
-		serverHistory = append(serverHistory, newRecord)
+	holdings := []Holding{}
+	for _, symbol := range symbols {
+		stockTransactions, splits, err := server.replayTransactions(c.Request.Context(), symbol, bySymbol[symbol])
+		if err != nil {
+			log.Println("Error replaying transactions", err)
+			c.JSON(polygonErrorStatus(err), gin.H{"error": "Error getting holdings"})
+			return
+		}
+		if shares := currentShares(stockTransactions, splits, time.Now()); shares > 0 {
+			holdings = append(holdings, Holding{Symbol: symbol, CurrentShares: shares})
+		}
 	}
 
-	return serverHistory, nil
+	c.JSON(http.StatusOK, holdings)
 }
 
-// GetTickerNews returns the news sentiment of a stock
+// GetHoldingInfo returns the authenticated user's holding of a stock
 //
-// GET /api/v1/stocks/tickers/{symbol}/news
+// GET /api/v1/stocks/holdings/:symbol
 //
 // Input:
 //   - symbol: the ticker's symbol
 //
 // Output:
-//   - TickerNews: the ticker news struct
-func (server *Server) GetTickerNews(c *gin.Context) {
-	symbol := c.Param("symbol")
-	url := fmt.Sprintf("https://api.polygon.io/v2/reference/news?ticker=%s&order=desc&limit=350&sort=published_utc&apiKey=%s&published_utc.gte=2024-10-11T19:01:33Z", symbol, server.polygonConnection.GetPolygonKey())
-	method := "GET"
-
-	defaultErrMsg := "Error receiving ticker news"
-
-	client := &http.Client{}
-	req, err := http.NewRequest(method, url, nil)
+//   - TickerHoldings: the ticker holdings struct
+func (server *Server) GetHoldingInfo(c *gin.Context) {
+	symbol := strings.ToUpper(c.Param("symbol"))
 
+	// Get the transactions for the holding
+	stored, err := mongodb.GetTransactionsWithContext(c.Request.Context(), server.mongoClient, server.tickerDBName, authPayload(c).UserID, symbol)
 	if err != nil {
-		fmt.Println("Error generating request", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": defaultErrMsg})
+		log.Println("Error getting transactions", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting transactions"})
 		return
 	}
-	res, err := client.Do(req)
-	if err != nil {
-		fmt.Println("Error sending request", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": defaultErrMsg})
+	if len(stored) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "the requested holding does not exist in the portfolio"})
 		return
 	}
-	defer res.Body.Close()
-
-	body, err := io.ReadAll(res.Body)
+	transactions, splits, err := server.replayTransactions(c.Request.Context(), symbol, stored)
 	if err != nil {
-		fmt.Println("Error reading response body", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": defaultErrMsg})
+		log.Println("Error replaying transactions", err)
+		c.JSON(polygonErrorStatus(err), gin.H{"error": "Error replaying transactions"})
 		return
 	}
-	//fmt.Println(string(body))
 
-	// Unmarshall the unmarshalledBody
-	var unmarshalledBody map[string]interface{}
-	if err = json.Unmarshal(body, &unmarshalledBody); err != nil {
-		fmt.Println("Error unmarshalling response", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": defaultErrMsg})
-		return
-	}
-
-	// Convert to correct data types
-	results, ok := unmarshalledBody["results"].([]interface{})
-	if !ok {
-		fmt.Println("Error: results not converted")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": defaultErrMsg})
+	holdingData, err := server.getTickerInfo(c.Request.Context(), symbol)
+	if err != nil {
+		log.Println("Error getting ticker info", err)
+		c.JSON(polygonErrorStatus(err), gin.H{"error": "Error getting ticker info"})
 		return
 	}
-
-	// Get number of articles
-	numArticles, ok := unmarshalledBody["count"].(float64)
-	if !ok {
-		fmt.Println("Error: count not converted")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": defaultErrMsg})
+	firstTransaction := time.Unix(transactions[0].Date, 0).UTC()
+	dividends, err := server.getTickerDividends(c.Request.Context(), symbol, firstTransaction)
+	if err != nil {
+		c.JSON(polygonErrorStatus(err), gin.H{"error": "Error getting ticker dividends"})
 		return
 	}
-	numArticlesInt := int(numArticles)
-
-	// Convert each element to map[string]interface{}
-	var articles []map[string]interface{}
-	for _, result := range results {
-		resultMap, ok := result.(map[string]interface{})
-		if !ok {
-			fmt.Println("Error: result element is not a map")
-			c.JSON(http.StatusInternalServerError, gin.H{"error": defaultErrMsg})
-			return
-		}
-		articles = append(articles, resultMap)
-	}
-
-	// Calculate average sentiment
-
-	var sentiments []float64
-	for _, article := range articles {
-		if insights, exists := article["insights"]; exists {
-			insightsList, ok := insights.([]interface{})
-			if !ok {
-				fmt.Println("Error: insights not converted")
-				c.JSON(http.StatusInternalServerError, gin.H{"error": defaultErrMsg})
-				return
-			}
-
-			for _, singleTickerInsight := range insightsList {
-				convertedSingleTickerInsight, ok := singleTickerInsight.(map[string]interface{})
-				if !ok {
-					fmt.Println("Error: result element is not a map")
-					c.JSON(http.StatusInternalServerError, gin.H{"error": defaultErrMsg})
-					return
-				}
-				if ticker, exists := convertedSingleTickerInsight["ticker"]; exists {
-					if ticker == symbol {
-						if sentiment, exists := convertedSingleTickerInsight["sentiment"]; exists {
-							sentimentString, ok := sentiment.(string)
-							if !ok {
-								fmt.Println("Error: sentiment not converted")
-								c.JSON(http.StatusInternalServerError, gin.H{"error": defaultErrMsg})
-								return
-							}
-
-							if sentimentString == "positive" {
-								sentiments = append(sentiments, 1)
-							} else if sentimentString == "negative" {
-								sentiments = append(sentiments, -1)
-							} else {
-								sentiments = append(sentiments, 0)
-							}
-						}
-					}
-				}
-			}
-		}
-	}
-
-	// Calculate average sentiment
-	var sumSentiment float64
-	for _, sentiment := range sentiments {
-		sumSentiment += sentiment
-	}
-	avgSentiment := sumSentiment / float64(len(sentiments))
 
-	// Calculate standard deviation
-	var sumSquaredDifferences float64
-	for _, sentiment := range sentiments {
-		sumSquaredDifferences += (sentiment - avgSentiment) * (sentiment - avgSentiment)
-	}
-	stdDevSentiment := sumSquaredDifferences / float64(len(sentiments))
+	holding := HoldingInfo{Symbol: symbol, CurrentShares: currentShares(transactions, splits, time.Now())}
 
-	news := TickerNews{
-		AverageSentiment: float32(avgSentiment),
-		StdDevSentiment:  float32(stdDevSentiment),
-		NumArticles:      numArticlesInt,
+	// Get the history for the holding
+	historyStart, historyEnd := server.historyRange(time.Now())
+	polygonHistory, err := server.marketData.GetTickerHistory(c.Request.Context(), holding.Symbol, historyStart, historyEnd, -1)
+	if err != nil && !polygon.IsPartialPolygonData(err) {
+		log.Println("Error getting ticker history", err)
+		c.JSON(polygonErrorStatus(err), gin.H{"error": "Error getting ticker history"})
+		return
 	}
 
-	c.JSON(http.StatusOK, news)
-	time.Sleep(server.polygonConnection.ThrottleTime * time.Second)
+	holding.History, holding.Dividends = getHoldingHistory(*polygonHistory, transactions, splits, dividends)
+	holding.ShareInfo = *holdingData
+	c.JSON(http.StatusOK, holding)
 }
 
-// GetHoldings returns the holdings of a user
-//
-// GET /api/v1/stocks/holdings
-//
-// Output:
-//   - TickerHoldings: the ticker holdings struct
-func (server *Server) GetHoldings(c *gin.Context) {
-	holdingsInfo := getUniqueHoldings(testTickerPurchases)
-
-	// Convert to Holding struct
-	holdings := []Holding{}
-	for _, holding := range holdingsInfo {
-		holdings = append(holdings, Holding{Symbol: holding.Symbol, CurrentShares: holding.CurrentShares})
+// Returns the shares of a holding held at now. Transactions record the share count at the time, so later splits
+// multiply it.
+func currentShares(transactions []StockTransaction, splits []mongodb.Split, now time.Time) float32 {
+	if len(transactions) == 0 {
+		return 0
 	}
-
-	c.JSON(http.StatusOK, holdings)
+	lastTransaction := transactions[len(transactions)-1]
+	return float32(float64(lastTransaction.TotalShares) * mongodb.SplitRatioBetween(splits, time.Unix(lastTransaction.Date, 0), now))
 }
 
-// GetHoldingInfo returns the holdings of a stock
+// getHoldingHistory returns the value of a holding at every bar of its ticker's history, and the dividends it received.
 //
-// GET /api/v1/stocks/holdings/:symbol
+// Polygon's prices are adjusted for every later split, so they are scaled back to the price actually paid at the time
+// of each bar, and the share counts of transactions are scaled by the splits executed since. Dividends are owed on the
+// shares held the day before their ex-dividend date and are added to the "dividends" of every bar from their pay date.
 //
 // Input:
-//   - symbol: the ticker's symbol
+//   - history: the daily, split-adjusted history of the ticker
+//   - transactions: the transactions of the holding, sorted by date
+//   - splits: the splits of the ticker since the first transaction
+//   - dividends: the dividends of the ticker since the first transaction
 //
 // Output:
-//   - TickerHoldings: the ticker holdings struct
-func (server *Server) GetHoldingInfo(c *gin.Context) {
-	// Get the user's holdings
-	holdingsInfo := getUniqueHoldings(testTickerPurchases)
-
-	// Get symbol parameter
-	symbol := c.Param("symbol")
-
-	for i, holding := range holdingsInfo {
-		if holding.Symbol == symbol {
-			fmt.Println("Processing holding", i, holding)
-			holdingData, err := server.getTickerInfo(symbol)
-			if err != nil {
-				return
-			}
-			// Get the transactions for the holding
-			transactions := getTransactionsByHolding(testTickerPurchases, holding)
-			holdingsInfo[i].CurrentShares = transactions[len(transactions)-1].TotalShares
-
-			// Get the history for the holding
-			history, err := server.getTickerHistory(holding.Symbol)
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting ticker history"})
-				return
+//   - []map[string]interface{}: time (ms), price, shares, value and cumulative dividends of every bar
+//   - []HoldingDividend: the dividends owed to the holding
+func getHoldingHistory(history polygon.PolygonGetTickerHistoryResponse, transactions []StockTransaction, splits []mongodb.Split, dividends []mongodb.Dividend) ([]map[string]interface{}, []HoldingDividend) {
+	now := time.Now()
+
+	// Returns the number of shares held at t
+	sharesAt := func(t time.Time) float64 {
+		shares := 0.0
+		for _, transaction := range transactions {
+			transactionTime := time.Unix(transaction.Date, 0)
+			if transactionTime.After(t) {
+				break
 			}
-			//fmt.Print("History: ", history)
-
-			currentTransaction := 0
-			// Adjust the value of the holding by the price and the number of shares, according to date
-			for _, record := range history {
-				//fmt.Println("Old Record: ", i, record)
-				if currentTransaction+1 < len(transactions) && record["time"].(float64) > float64(transactions[currentTransaction+1].Date) {
-					record["value"] = float64(transactions[currentTransaction+1].TotalShares) * record["value"].(float64)
-					currentTransaction++
-					record["shares"] = transactions[currentTransaction].TotalShares
-				} else if record["time"].(float64) > float64(transactions[currentTransaction].Date) {
-					record["value"] = float64(transactions[currentTransaction].TotalShares) * record["value"].(float64)
-					record["shares"] = transactions[currentTransaction].TotalShares
-				} else {
-					record["value"] = 0
-					record["shares"] = 0
-				}
-				//fmt.Println("New Record: ", i, record)
-			}
-
-			holding.History = history
-			holding.ShareInfo = *holdingData
-			c.JSON(http.StatusOK, holding)
-			return
+			shares = float64(transaction.TotalShares) * mongodb.SplitRatioBetween(splits, transactionTime, t)
 		}
+		return shares
 	}
 
-	c.JSON(http.StatusBadRequest, gin.H{"error": "the requested holding does not exist in the portfolio"})
-}
-
-// Gets the unique holdings from a list of transactions
-func getUniqueHoldings(transactions []StockTransaction) []HoldingInfo {
-	sort.Slice(transactions, func(i, j int) bool {
-		return transactions[i].Date < transactions[j].Date
-	})
-	uniqueHoldings := []HoldingInfo{}
-	seen := map[string]bool{}
+	holdingDividends := []HoldingDividend{}
+	for _, dividend := range dividends {
+		exDate := dividend.ExDividendDate.Time()
+		shares := sharesAt(exDate.Add(-time.Nanosecond))
+		if shares == 0 {
+			continue
+		}
+		payDate := dividend.PayDate.Time()
+		if dividend.PayDate == 0 {
+			payDate = exDate
+		}
+		holdingDividends = append(holdingDividends, HoldingDividend{
+			ExDividendDate: exDate.Unix(),
+			PayDate:        payDate.Unix(),
+			CashAmount:     dividend.CashAmount,
+			Shares:         shares,
+			Total:          shares * dividend.CashAmount,
+		})
+	}
 
-	for _, transaction := range transactions {
-		if _, ok := seen[transaction.Symbol]; !ok {
-			seen[transaction.Symbol] = true
-			uniqueHoldings = append(uniqueHoldings, HoldingInfo{Symbol: transaction.Symbol, CurrentShares: transaction.TotalShares})
-		} else {
-			for i, holding := range uniqueHoldings {
-				if holding.Symbol == transaction.Symbol {
-					uniqueHoldings[i].CurrentShares = transaction.TotalShares
-				}
+	serverHistory := []map[string]interface{}{}
+	if history.Results == nil {
+		return serverHistory, holdingDividends
+	}
+	for _, bar := range *history.Results {
+		if bar.Timestamp == nil || bar.Close == nil {
+			continue
+		}
+		barTime := time.UnixMilli(*bar.Timestamp)
+		price := *bar.Close * mongodb.SplitRatioBetween(splits, barTime, now)
+		shares := sharesAt(barTime)
+
+		cumulativeDividends := 0.0
+		for _, dividend := range holdingDividends {
+			if dividend.PayDate <= barTime.Unix() {
+				cumulativeDividends += dividend.Total
 			}
 		}
+
+		serverHistory = append(serverHistory, map[string]interface{}{
+			"time":      *bar.Timestamp,
+			"price":     price,
+			"shares":    shares,
+			"value":     shares * price,
+			"dividends": cumulativeDividends,
+		})
 	}
 
-	return uniqueHoldings
+	return serverHistory, holdingDividends
 }
 
-// Gets the transactions for a specific holding, and sort them by date
-func getTransactionsByHolding(transactions []StockTransaction, holding HoldingInfo) []StockTransaction {
-	transactionsByHolding := []StockTransaction{}
+// getTickerSplits returns the splits of a ticker executed since `since`, from MongoDB if the splits scraper stored
+// any, or from Polygon otherwise. In demo mode only stored splits are returned.
+func (server *Server) getTickerSplits(ctx context.Context, symbol string, since time.Time) ([]mongodb.Split, error) {
+	stored, err := mongodb.GetSplitsByTickerOverRangeWithContext(ctx, server.mongoClient, server.tickerDBName, symbol, since, time.Time{})
+	if err != nil {
+		log.Println("Error reading stored splits", err)
+	}
+	if len(stored) > 0 || server.polygonConnection == nil {
+		return stored, nil
+	}
 
-	for _, transaction := range transactions {
-		if transaction.Symbol == holding.Symbol {
-			transactionsByHolding = append(transactionsByHolding, transaction)
-		}
+	polygonSplits, err := server.polygonConnection.PolygonGetTickerSplitsWithContext(ctx, symbol, since, time.Time{})
+	if err != nil && !polygon.IsPartialPolygonData(err) {
+		return nil, err
 	}
+	return mongodb.PolygonSplitsToSplits(polygonSplits)
+}
 
-	sort.Slice(transactionsByHolding, func(i, j int) bool {
-		return transactionsByHolding[i].Date < transactionsByHolding[j].Date
-	})
+// getTickerDividends returns the dividends of a ticker whose ex-dividend date is since `since`, from MongoDB if the
+// dividends scraper stored any, or from Polygon otherwise. In demo mode only stored dividends are returned.
+func (server *Server) getTickerDividends(ctx context.Context, symbol string, since time.Time) ([]mongodb.Dividend, error) {
+	stored, err := mongodb.GetDividendsByTickerOverRangeWithContext(ctx, server.mongoClient, server.tickerDBName, symbol, since, time.Time{})
+	if err != nil {
+		log.Println("Error reading stored dividends", err)
+	}
+	if len(stored) > 0 || server.polygonConnection == nil {
+		return stored, nil
+	}
 
-	return transactionsByHolding
+	polygonDividends, err := server.polygonConnection.PolygonGetTickerDividendsWithContext(ctx, symbol, since, time.Time{})
+	if err != nil && !polygon.IsPartialPolygonData(err) {
+		return nil, err
+	}
+	return mongodb.PolygonDividendsToDividends(polygonDividends)
 }
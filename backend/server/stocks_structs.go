@@ -1,26 +1,178 @@
 package server
 
+import "financial-helper/polygon"
+
 // Returned by /api/v1/stocks/tickers/:symbol
 type ServerTickerInfoResponse struct {
-	Symbol          string  `json:"symbol"`
-	Name            string  `json:"name"`
-	Industry        string  `json:"industry"`
-	Locale          string  `json:"locale"`
-	PrimaryExchange string  `json:"primary_exchange"`
-	OpenPrice       float64 `json:"open_price"`
-	ClosePrice      float64 `json:"close_price"`
+	Symbol            string  `json:"symbol"`
+	Name              string  `json:"name"`
+	Industry          string  `json:"industry"`
+	Locale            string  `json:"locale"`
+	PrimaryExchange   string  `json:"primary_exchange"`
+	OpenPrice         float64 `json:"open_price"`
+	ClosePrice        float64 `json:"close_price"`
+	MarketCap         float64 `json:"market_cap,omitempty"`
+	SharesOutstanding float64 `json:"shares_outstanding,omitempty"`
+	Description       string  `json:"description,omitempty"`
+	HomepageURL       string  `json:"homepage_url,omitempty"`
+	LogoURL           string  `json:"logo_url,omitempty"`
+	IconURL           string  `json:"icon_url,omitempty"`
+	ListDate          string  `json:"list_date,omitempty"` // YYYY-MM-DD
+}
+
+// Returned by /api/v1/stocks/search
+type ServerTickerSearchResponse struct {
+	Query   string                     `json:"query"`
+	Source  string                     `json:"source"` // "local", or the market data provider, e.g. "polygon"
+	Results []ServerTickerSearchResult `json:"results"`
+}
+
+type ServerTickerSearchResult struct {
+	Symbol          string `json:"symbol"`
+	Name            string `json:"name"`
+	Market          string `json:"market"`
+	Locale          string `json:"locale"`
+	Type            string `json:"type"`
+	PrimaryExchange string `json:"primary_exchange"`
+	Currency        string `json:"currency"`
+	Score           int    `json:"score"` // Relevance, higher is better
+}
+
+// Returned by /api/v1/stocks/tickers/:symbol/financials
+type ServerTickerFinancialsResponse struct {
+	Symbol      string                  `json:"symbol"`
+	Timeframe   string                  `json:"timeframe"`
+	Source      string                  `json:"source"` // "local" or "polygon"
+	LastClose   *float64                `json:"last_close,omitempty"`
+	TrailingEPS *float64                `json:"trailing_eps,omitempty"` // Diluted, over the last twelve months
+	PERatio     *float64                `json:"pe_ratio,omitempty"`
+	Filings     []ServerFinancialFiling `json:"filings"` // Most recent first
+}
+
+type ServerFinancialFiling struct {
+	FiscalPeriod    string                   `json:"fiscal_period"`
+	FiscalYear      string                   `json:"fiscal_year"`
+	StartDate       string                   `json:"start_date"` // YYYY-MM-DD
+	EndDate         string                   `json:"end_date"`
+	FilingDate      string                   `json:"filing_date,omitempty"`
+	SourceFilingURL string                   `json:"source_filing_url,omitempty"`
+	LineItems       ServerFinancialLineItems `json:"line_items"`
+	Ratios          ServerFinancialRatios    `json:"ratios"`
+}
+
+// In USD, or USD per share for earnings per share. Items missing from the filing are left out.
+type ServerFinancialLineItems struct {
+	Revenue             *float64 `json:"revenue,omitempty"`
+	CostOfRevenue       *float64 `json:"cost_of_revenue,omitempty"`
+	GrossProfit         *float64 `json:"gross_profit,omitempty"`
+	OperatingExpenses   *float64 `json:"operating_expenses,omitempty"`
+	OperatingIncome     *float64 `json:"operating_income,omitempty"`
+	NetIncome           *float64 `json:"net_income,omitempty"`
+	EPSBasic            *float64 `json:"eps_basic,omitempty"`
+	EPSDiluted          *float64 `json:"eps_diluted,omitempty"`
+	TotalAssets         *float64 `json:"total_assets,omitempty"`
+	CurrentAssets       *float64 `json:"current_assets,omitempty"`
+	TotalLiabilities    *float64 `json:"total_liabilities,omitempty"`
+	CurrentLiabilities  *float64 `json:"current_liabilities,omitempty"`
+	LongTermDebt        *float64 `json:"long_term_debt,omitempty"`
+	Equity              *float64 `json:"equity,omitempty"`
+	OperatingCashFlow   *float64 `json:"operating_cash_flow,omitempty"`
+	InvestingCashFlow   *float64 `json:"investing_cash_flow,omitempty"`
+	FinancingCashFlow   *float64 `json:"financing_cash_flow,omitempty"`
+	NetCashFlow         *float64 `json:"net_cash_flow,omitempty"`
+	ComprehensiveIncome *float64 `json:"comprehensive_income,omitempty"`
+}
+
+// Ratios of the filing's own period, null when a line item they need is missing
+type ServerFinancialRatios struct {
+	GrossMargin     *float64 `json:"gross_margin"`
+	OperatingMargin *float64 `json:"operating_margin"`
+	NetMargin       *float64 `json:"net_margin"`
+	DebtToEquity    *float64 `json:"debt_to_equity"`
+	CurrentRatio    *float64 `json:"current_ratio"`
+	ReturnOnEquity  *float64 `json:"return_on_equity"`
 }
 
 // Returned by /api/v1/stocks/tickers/:symbol/history
 type ServerTickerHistoryResponse struct {
-	History []map[string]interface{} `json:"history"`
+	Symbol   string         `json:"symbol"`
+	Interval string         `json:"interval"` // 1d, 1w or 1mo
+	From     string         `json:"from"`     // YYYY-MM-DD
+	To       string         `json:"to"`
+	Adjusted bool           `json:"adjusted"` // Whether prices and volumes are adjusted for splits
+	Candles  []ServerCandle `json:"candles"`  // Oldest first
 }
 
-// Returned by /api/v1/stocks/tickers/:symbol
+// A single bar of the history. Time is the start of the bar in Unix seconds and Date its day in New York,
+// either of which can be used as a chart's time.
+type ServerCandle struct {
+	Time         int64    `json:"time"`
+	Date         string   `json:"date"` // YYYY-MM-DD
+	Open         float64  `json:"open"`
+	High         float64  `json:"high"`
+	Low          float64  `json:"low"`
+	Close        float64  `json:"close"`
+	Volume       float64  `json:"volume"`
+	VWAP         *float64 `json:"vwap,omitempty"`
+	Transactions *int     `json:"transactions,omitempty"`
+}
+
+// Returned by /api/v1/stocks/tickers/:symbol/news. The sentiment is rated from every article of the range, which are
+// listed a page at a time. Sentiment statistics are 0 when no article is rated.
 type TickerNews struct {
-	AverageSentiment float32 `json:"avg_sentiment"`
-	StdDevSentiment  float32 `json:"std_dev_sentiment"`
-	NumArticles      int     `json:"num_articles"`
+	Symbol           string          `json:"symbol,omitempty"`
+	From             string          `json:"from,omitempty"` // YYYY-MM-DD
+	To               string          `json:"to,omitempty"`
+	AverageSentiment float32         `json:"avg_sentiment"`     // Weighted mean score, from -1 to 1
+	StdDevSentiment  float32         `json:"std_dev_sentiment"` // Weighted standard deviation of the scores
+	ConfidenceLow    float32         `json:"confidence_low"`    // 95% confidence interval of the mean
+	ConfidenceHigh   float32         `json:"confidence_high"`
+	NumArticles      int             `json:"num_articles"` // Articles of the range, across every page
+	NumRated         int             `json:"num_rated"`    // Articles with a sentiment, the others don't count
+	NumPositive      int             `json:"num_positive"`
+	NumNeutral       int             `json:"num_neutral"`
+	NumNegative      int             `json:"num_negative"`
+	Page             int             `json:"page,omitempty"`
+	PageSize         int             `json:"page_size,omitempty"`
+	HasMore          bool            `json:"has_more"`           // Whether pages after this one have articles
+	Articles         []ServerArticle `json:"articles,omitempty"` // Most recent first
+}
+
+// An article about a ticker. Sentiment is positive, neutral or negative towards the ticker, empty if the article
+// wasn't rated.
+type ServerArticle struct {
+	ID                 string `json:"id"`
+	Source             string `json:"source"` // polygon, nyt or feed
+	Title              string `json:"title"`
+	Author             string `json:"author,omitempty"`
+	Publisher          string `json:"publisher,omitempty"`
+	PublishedAt        int64  `json:"published_at"` // Unix seconds
+	URL                string `json:"url"`
+	ImageURL           string `json:"image_url,omitempty"`
+	Description        string `json:"description,omitempty"`
+	Sentiment          string `json:"sentiment,omitempty"`
+	SentimentReasoning string `json:"sentiment_reasoning,omitempty"`
+}
+
+// Returned by /api/v1/stocks/tickers/:symbol/sentiment
+type TickerSentimentSeries struct {
+	Symbol  string                  `json:"symbol"`
+	From    string                  `json:"from"` // YYYY-MM-DD
+	To      string                  `json:"to"`
+	Bucket  string                  `json:"bucket"`  // day or week
+	Buckets []ServerSentimentBucket `json:"buckets"` // Oldest first, buckets without articles are left out
+}
+
+// The sentiment of the articles published in a bucket. Like a ServerCandle, Time is the start of the bucket in Unix
+// seconds and Date its first day in New York. Rated articles score 1 if positive, -1 if negative and 0 otherwise.
+type ServerSentimentBucket struct {
+	Time      int64    `json:"time"`
+	Date      string   `json:"date"` // YYYY-MM-DD
+	Articles  int      `json:"articles"`
+	Positive  int      `json:"positive"`
+	Neutral   int      `json:"neutral"`
+	Negative  int      `json:"negative"`
+	MeanScore *float64 `json:"mean_score"` // Null if no article of the bucket is rated
 }
 
 // Returned by /api/v1/stocks/tickers/:symbol/holdings
@@ -32,15 +184,26 @@ type HoldingInfo struct {
 	Symbol        string                   `json:"symbol"`
 	CurrentShares float32                  `json:"current_shares"`
 	History       []map[string]interface{} `json:"history"`
+	Dividends     []HoldingDividend        `json:"dividends,omitempty"`
 	ShareInfo     ServerTickerInfoResponse `json:"share_info"`
 }
 
+// A dividend owed to a holding. Dates are unix timestamps in seconds, like StockTransaction.Date.
+type HoldingDividend struct {
+	ExDividendDate int64   `json:"ex_dividend_date"`
+	PayDate        int64   `json:"pay_date"`
+	CashAmount     float64 `json:"cash_amount"` // Per share
+	Shares         float64 `json:"shares"`      // Shares held the day before the ex-dividend date
+	Total          float64 `json:"total"`
+}
+
 type Holding struct {
 	Symbol        string  `json:"symbol"`
 	CurrentShares float32 `json:"current_shares"`
 }
 
-// Dummy data
+// A transaction of a holding with the shares held after it, in shares of its time, replayed from the stored
+// transactions by replayTransactions
 type StockTransaction struct {
 	Symbol      string  `json:"symbol"`
 	TotalShares float32 `json:"total_shares"`
@@ -48,77 +211,16 @@ type StockTransaction struct {
 	Date        int64   `json:"date"`
 }
 
-var testTickerPurchases = []StockTransaction{
-	{Symbol: "AAPL", TotalShares: 0.05, ShareChange: 0.05, Date: 1721985246},
-	{Symbol: "AAPL", TotalShares: 0.1, ShareChange: 0.05, Date: 1722285246},
-	{Symbol: "AAPL", TotalShares: 0.15, ShareChange: 0.05, Date: 1722585246},
-	{Symbol: "AAPL", TotalShares: 0.1, ShareChange: -0.05, Date: 1728885246},
-	{Symbol: "GOOGL", TotalShares: 0.1, ShareChange: 0.1, Date: 1723185246},
-	{Symbol: "GOOGL", TotalShares: 0.2, ShareChange: 0.1, Date: 1723485246},
-	{Symbol: "GOOGL", TotalShares: 0.15, ShareChange: -0.05, Date: 1723785246},
-	{Symbol: "MSFT", TotalShares: 0.15, ShareChange: 0.15, Date: 1724085246},
-	{Symbol: "MSFT", TotalShares: 0.3, ShareChange: 0.15, Date: 1724385246},
-	{Symbol: "MSFT", TotalShares: 0.25, ShareChange: -0.05, Date: 1724685246},
-	{Symbol: "AMZN", TotalShares: 0.2, ShareChange: 0.2, Date: 1733985246},
-	{Symbol: "AMZN", TotalShares: 0.4, ShareChange: 0.2, Date: 1725285246},
-	{Symbol: "AMZN", TotalShares: 0.35, ShareChange: -0.05, Date: 1733085246},
-	{Symbol: "TSLA", TotalShares: 0.25, ShareChange: 0.25, Date: 1725885246},
-	{Symbol: "TSLA", TotalShares: 0.5, ShareChange: 0.25, Date: 1726185246},
-	{Symbol: "TSLA", TotalShares: 0.45, ShareChange: -0.05, Date: 1726485246},
-	{Symbol: "META", TotalShares: 0.075, ShareChange: 0.075, Date: 1726785246},
-	{Symbol: "META", TotalShares: 0.15, ShareChange: 0.075, Date: 1730685246},
-	{Symbol: "META", TotalShares: 0.125, ShareChange: -0.025, Date: 1727385246},
-	{Symbol: "NFLX", TotalShares: 0.125, ShareChange: 0.125, Date: 1727685246},
-	{Symbol: "NFLX", TotalShares: 0.25, ShareChange: 0.125, Date: 1727985246},
-	{Symbol: "NFLX", TotalShares: 0.225, ShareChange: -0.025, Date: 1728285246},
-	{Symbol: "NVDA", TotalShares: 0.175, ShareChange: 0.175, Date: 1728585246},
-	{Symbol: "NVDA", TotalShares: 0.35, ShareChange: 0.175, Date: 1728885246},
-	{Symbol: "NVDA", TotalShares: 0.325, ShareChange: -0.025, Date: 1729185246},
-	{Symbol: "BABA", TotalShares: 0.225, ShareChange: 0.225, Date: 1729485246},
-	{Symbol: "BABA", TotalShares: 0.45, ShareChange: 0.225, Date: 1729785246},
-	{Symbol: "BABA", TotalShares: 0.425, ShareChange: -0.025, Date: 1730085246},
-	{Symbol: "V", TotalShares: 0.275, ShareChange: 0.275, Date: 1730385246},
-	{Symbol: "V", TotalShares: 0.55, ShareChange: 0.275, Date: 1730685246},
-	{Symbol: "V", TotalShares: 0.525, ShareChange: -0.025, Date: 1722585246},
-	{Symbol: "JPM", TotalShares: 0.05, ShareChange: 0.05, Date: 1731285246},
-	{Symbol: "JPM", TotalShares: 0.1, ShareChange: 0.05, Date: 1731585246},
-	{Symbol: "JPM", TotalShares: 0.075, ShareChange: -0.025, Date: 1731885246},
-	{Symbol: "JNJ", TotalShares: 0.1, ShareChange: 0.1, Date: 1732185246},
-	{Symbol: "JNJ", TotalShares: 0.2, ShareChange: 0.1, Date: 1732485246},
-	{Symbol: "JNJ", TotalShares: 0.175, ShareChange: -0.025, Date: 1732785246},
-	{Symbol: "WMT", TotalShares: 0.15, ShareChange: 0.15, Date: 1733085246},
-	{Symbol: "WMT", TotalShares: 0.3, ShareChange: 0.15, Date: 1733385246},
-	{Symbol: "WMT", TotalShares: 0.275, ShareChange: -0.025, Date: 1733685246},
-	{Symbol: "PG", TotalShares: 0.2, ShareChange: 0.2, Date: 1733985246},
-	{Symbol: "PG", TotalShares: 0.4, ShareChange: 0.2, Date: 1734285246},
-	{Symbol: "PG", TotalShares: 0.375, ShareChange: -0.025, Date: 1734585246},
-	{Symbol: "DIS", TotalShares: 0.25, ShareChange: 0.25, Date: 1734885246},
-	{Symbol: "DIS", TotalShares: 0.5, ShareChange: 0.25, Date: 1735185246},
-	{Symbol: "DIS", TotalShares: 0.475, ShareChange: -0.025, Date: 1735485246},
-	{Symbol: "MA", TotalShares: 0.075, ShareChange: 0.075, Date: 1735785246},
-	{Symbol: "MA", TotalShares: 0.15, ShareChange: 0.075, Date: 1736085246},
-	{Symbol: "MA", TotalShares: 0.125, ShareChange: -0.025, Date: 1736385246},
-	{Symbol: "HD", TotalShares: 0.125, ShareChange: 0.125, Date: 1736685246},
-	{Symbol: "HD", TotalShares: 0.25, ShareChange: 0.125, Date: 1736985246},
-	{Symbol: "HD", TotalShares: 0.225, ShareChange: -0.025, Date: 1737285246},
-	{Symbol: "VZ", TotalShares: 0.175, ShareChange: 0.175, Date: 1735478270},
-	{Symbol: "VZ", TotalShares: 0.35, ShareChange: 0.175, Date: 1737885246},
-	{Symbol: "VZ", TotalShares: 0.325, ShareChange: -0.025, Date: 1737585246},
-	{Symbol: "PYPL", TotalShares: 0.225, ShareChange: 0.225, Date: 1733085246},
-	{Symbol: "PYPL", TotalShares: 0.45, ShareChange: 0.225, Date: 1729485246},
-	{Symbol: "PYPL", TotalShares: 0.425, ShareChange: -0.025, Date: 1730685246},
-	{Symbol: "ADBE", TotalShares: 0.275, ShareChange: 0.275, Date: 1734885246},
-	{Symbol: "ADBE", TotalShares: 0.55, ShareChange: 0.275, Date: 1729185246},
-	{Symbol: "ADBE", TotalShares: 0.525, ShareChange: -0.025, Date: 1734285246},
-	{Symbol: "INTC", TotalShares: 0.3, ShareChange: 0.3, Date: 1733585246},
-	{Symbol: "INTC", TotalShares: 0.6, ShareChange: 0.3, Date: 1736478270},
-	{Symbol: "INTC", TotalShares: 0.575, ShareChange: -0.025, Date: 1733185246},
-	{Symbol: "CSCO", TotalShares: 0.35, ShareChange: 0.35, Date: 1735485246},
-	{Symbol: "CSCO", TotalShares: 0.7, ShareChange: 0.35, Date: 1734285246},
-	{Symbol: "CSCO", TotalShares: 0.675, ShareChange: -0.025, Date: 1730985246},
-	{Symbol: "ORCL", TotalShares: 0.4, ShareChange: 0.4, Date: 1732478270},
-	{Symbol: "ORCL", TotalShares: 0.8, ShareChange: 0.4, Date: 1724885246},
-	{Symbol: "ORCL", TotalShares: 0.775, ShareChange: -0.025, Date: 1726485246},
+// Returned by /api/v1/stocks/holdings/transactions. Timestamp is in Unix seconds.
+type ServerTransaction struct {
+	ID        string  `json:"id"`
+	Symbol    string  `json:"symbol"`
+	Side      string  `json:"side"` // buy or sell
+	Quantity  float64 `json:"quantity"`
+	Price     float64 `json:"price"` // Per share
+	Fees      float64 `json:"fees"`
+	Timestamp int64   `json:"timestamp"`
+	Note      string  `json:"note,omitempty"`
 }
 
 /* var testTickerHistory = TickerHistory{
@@ -176,3 +278,25 @@ var testTickerPurchases = []StockTransaction{
 	},
 }
 */
+
+// Returned by /api/v1/polygon/status
+type ServerPolygonStatusResponse struct {
+	Keys  []polygon.PolygonKeyHealth `json:"keys"`
+	Cache polygon.PolygonCacheStats  `json:"cache"`
+}
+
+// Returned by /api/v1/users/me, and with the tokens of a user
+type ServerUser struct {
+	ID        string `json:"id"`
+	Username  string `json:"username"`
+	CreatedAt int64  `json:"created_at"` // Unix seconds
+}
+
+// Returned by /api/v1/users/register, /login and /refresh. Expiries are in Unix seconds.
+type ServerUserTokens struct {
+	User                  ServerUser `json:"user"`
+	AccessToken           string     `json:"access_token"`
+	AccessTokenExpiresAt  int64      `json:"access_token_expires_at"`
+	RefreshToken          string     `json:"refresh_token"`
+	RefreshTokenExpiresAt int64      `json:"refresh_token_expires_at"`
+}
@@ -0,0 +1,372 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"financial-helper/mongodb"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// The body of the requests creating or replacing a transaction
+type transactionRequest struct {
+	Symbol    string  `json:"symbol"`
+	Side      string  `json:"side"`
+	Quantity  float64 `json:"quantity"`
+	Price     float64 `json:"price"`
+	Fees      float64 `json:"fees"`
+	Timestamp int64   `json:"timestamp"` // Unix seconds, now if unset
+	Note      string  `json:"note"`
+}
+
+// Longest note stored with a transaction
+const maxTransactionNoteLength = 500
+
+// Serializes the writes to the transactions of every user. A write reads the user's transactions, checks that no sale
+// exceeds the shares held and then stores the change, so two concurrent sales could otherwise both pass the check.
+// Locking the user rather than a symbol also covers a transaction moved from one symbol to another. MongoDB runs
+// standalone in compose.yaml, which rules out multi-document transactions, so this only guards a single server.
+type userLocks struct {
+	mu    sync.Mutex
+	locks map[string]*userLock
+}
+
+type userLock struct {
+	sync.Mutex
+	holders int // Requests holding or waiting for the lock, which is forgotten when none are left
+}
+
+// Blocks until no other write of userID is in progress, and returns the function ending this one
+func (locks *userLocks) lock(userID string) func() {
+	locks.mu.Lock()
+	if locks.locks == nil {
+		locks.locks = map[string]*userLock{}
+	}
+	lock, ok := locks.locks[userID]
+	if !ok {
+		lock = &userLock{}
+		locks.locks[userID] = lock
+	}
+	lock.holders++
+	locks.mu.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+		locks.mu.Lock()
+		defer locks.mu.Unlock()
+		if lock.holders--; lock.holders == 0 {
+			delete(locks.locks, userID)
+		}
+	}
+}
+
+// Validates a transaction request on its own and converts it into a transaction. Whether a sale exceeds the
+// shares held is checked against the other transactions by checkSymbolTransactions.
+func (request transactionRequest) toTransaction(now time.Time) (mongodb.Transaction, error) {
+	transaction := mongodb.Transaction{
+		Symbol:   strings.ToUpper(strings.TrimSpace(request.Symbol)),
+		Side:     strings.ToLower(request.Side),
+		Quantity: request.Quantity,
+		Price:    request.Price,
+		Fees:     request.Fees,
+		Note:     strings.TrimSpace(request.Note),
+	}
+	switch {
+	case transaction.Symbol == "":
+		return transaction, errors.New("symbol is required")
+	case transaction.Side != mongodb.TransactionBuy && transaction.Side != mongodb.TransactionSell:
+		return transaction, errors.New("side must be buy or sell")
+	case !(transaction.Quantity > 0):
+		return transaction, errors.New("quantity must be positive")
+	case !(transaction.Price >= 0):
+		return transaction, errors.New("price cannot be negative")
+	case !(transaction.Fees >= 0):
+		return transaction, errors.New("fees cannot be negative")
+	case len(transaction.Note) > maxTransactionNoteLength:
+		return transaction, errors.New("note cannot be longer than 500 characters")
+	}
+
+	timestamp := now
+	if request.Timestamp != 0 {
+		timestamp = time.Unix(request.Timestamp, 0)
+	}
+	if timestamp.After(now) {
+		return transaction, errors.New("timestamp cannot be in the future")
+	}
+	transaction.Timestamp = primitive.NewDateTimeFromTime(timestamp)
+	return transaction, nil
+}
+
+// Converts a stored transaction into the transaction returned by the transactions endpoints
+func toServerTransaction(transaction mongodb.Transaction) ServerTransaction {
+	return ServerTransaction{
+		ID:        transaction.ID.Hex(),
+		Symbol:    transaction.Symbol,
+		Side:      transaction.Side,
+		Quantity:  transaction.Quantity,
+		Price:     transaction.Price,
+		Fees:      transaction.Fees,
+		Timestamp: transaction.Timestamp.Time().Unix(),
+		Note:      transaction.Note,
+	}
+}
+
+// GetTransactions returns the transactions of the authenticated user's portfolio
+//
+// GET /api/v1/stocks/holdings/transactions?symbol=
+//
+// Input:
+//   - symbol: only returns the transactions of this ticker if set
+//
+// Output:
+//   - []ServerTransaction: the transactions, oldest first
+func (server *Server) GetTransactions(c *gin.Context) {
+	transactions, err := mongodb.GetTransactionsWithContext(c.Request.Context(), server.mongoClient, server.tickerDBName, authPayload(c).UserID, strings.ToUpper(c.Query("symbol")))
+	if err != nil {
+		log.Println("Error getting transactions", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting transactions"})
+		return
+	}
+
+	serverTransactions := []ServerTransaction{}
+	for _, transaction := range transactions {
+		serverTransactions = append(serverTransactions, toServerTransaction(transaction))
+	}
+	c.JSON(http.StatusOK, serverTransactions)
+}
+
+// GetTransaction returns a transaction of the authenticated user's portfolio
+//
+// GET /api/v1/stocks/holdings/transactions/:id
+//
+// Input:
+//   - id: the transaction's id
+//
+// Output:
+//   - ServerTransaction: the transaction
+func (server *Server) GetTransaction(c *gin.Context) {
+	transaction, ok := server.findTransaction(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, toServerTransaction(*transaction))
+}
+
+// CreateTransaction records a purchase or sale of shares in the authenticated user's portfolio
+//
+// POST /api/v1/stocks/holdings/transactions
+//
+// Input:
+//   - body: a transactionRequest. A sale cannot exceed the shares held at its time.
+//
+// Output:
+//   - ServerTransaction: the stored transaction
+func (server *Server) CreateTransaction(c *gin.Context) {
+	var request transactionRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "the body must be a transaction"})
+		return
+	}
+	transaction, err := request.toTransaction(time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	transaction.UserID = authPayload(c).UserID
+
+	unlock := server.transactionLocks.lock(transaction.UserID)
+	defer unlock()
+	if !server.checkSymbolTransactions(c, transaction.Symbol, func(transactions []mongodb.Transaction) []mongodb.Transaction {
+		return append(transactions, transaction)
+	}) {
+		return
+	}
+	if transaction.ID, err = mongodb.InsertTransactionWithContext(c.Request.Context(), server.mongoClient, server.tickerDBName, transaction); err != nil {
+		log.Println("Error storing transaction", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error storing transaction"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, toServerTransaction(transaction))
+}
+
+// UpdateTransaction replaces a transaction of the authenticated user's portfolio
+//
+// PUT /api/v1/stocks/holdings/transactions/:id
+//
+// Input:
+//   - id: the transaction's id
+//   - body: a transactionRequest. No sale of the old or new symbol can exceed the shares held at its time.
+//
+// Output:
+//   - ServerTransaction: the stored transaction
+func (server *Server) UpdateTransaction(c *gin.Context) {
+	var request transactionRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "the body must be a transaction"})
+		return
+	}
+	transaction, err := request.toTransaction(time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	unlock := server.transactionLocks.lock(authPayload(c).UserID)
+	defer unlock()
+	stored, ok := server.findTransaction(c)
+	if !ok {
+		return
+	}
+	transaction.ID = stored.ID
+	transaction.UserID = stored.UserID
+
+	if !server.checkSymbolTransactions(c, transaction.Symbol, func(transactions []mongodb.Transaction) []mongodb.Transaction {
+		return append(withoutTransaction(transactions, stored.ID), transaction)
+	}) {
+		return
+	}
+	// Moving a transaction to another symbol takes it out of the holding of its old one
+	if stored.Symbol != transaction.Symbol && !server.checkSymbolTransactions(c, stored.Symbol, func(transactions []mongodb.Transaction) []mongodb.Transaction {
+		return withoutTransaction(transactions, stored.ID)
+	}) {
+		return
+	}
+	if err := mongodb.ReplaceTransactionWithContext(c.Request.Context(), server.mongoClient, server.tickerDBName, transaction); err != nil {
+		log.Println("Error replacing transaction", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error storing transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, toServerTransaction(transaction))
+}
+
+// DeleteTransaction removes a transaction from the authenticated user's portfolio
+//
+// DELETE /api/v1/stocks/holdings/transactions/:id
+//
+// Input:
+//   - id: the transaction's id. A purchase cannot be deleted if later sales would exceed the shares held without it.
+func (server *Server) DeleteTransaction(c *gin.Context) {
+	unlock := server.transactionLocks.lock(authPayload(c).UserID)
+	defer unlock()
+	stored, ok := server.findTransaction(c)
+	if !ok {
+		return
+	}
+	if !server.checkSymbolTransactions(c, stored.Symbol, func(transactions []mongodb.Transaction) []mongodb.Transaction {
+		return withoutTransaction(transactions, stored.ID)
+	}) {
+		return
+	}
+	if err := mongodb.DeleteTransactionWithContext(c.Request.Context(), server.mongoClient, server.tickerDBName, stored.UserID, stored.ID); err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		log.Println("Error deleting transaction", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting transaction"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Returns the authenticated user's transaction of the id parameter, or responds with an error and returns false.
+// The transactions of other users are not found.
+func (server *Server) findTransaction(c *gin.Context) (*mongodb.Transaction, bool) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id must be a transaction id"})
+		return nil, false
+	}
+	transaction, err := mongodb.GetTransactionWithContext(c.Request.Context(), server.mongoClient, server.tickerDBName, authPayload(c).UserID, id)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "the transaction does not exist"})
+		return nil, false
+	}
+	if err != nil {
+		log.Println("Error getting transaction", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting transaction"})
+		return nil, false
+	}
+	return transaction, true
+}
+
+// Checks that the authenticated user's transactions of symbol, once changed by change, never sell more shares than are held.
+// Otherwise it responds with an error and returns false.
+func (server *Server) checkSymbolTransactions(c *gin.Context, symbol string, change func([]mongodb.Transaction) []mongodb.Transaction) bool {
+	transactions, err := mongodb.GetTransactionsWithContext(c.Request.Context(), server.mongoClient, server.tickerDBName, authPayload(c).UserID, symbol)
+	if err != nil {
+		log.Println("Error getting transactions", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting transactions"})
+		return false
+	}
+
+	_, _, err = server.replayTransactions(c.Request.Context(), symbol, change(transactions))
+	if errors.Is(err, mongodb.ErrOversold) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	if err != nil {
+		log.Println("Error validating transactions", err)
+		c.JSON(polygonErrorStatus(err), gin.H{"error": "Error validating transactions against the ticker's splits"})
+		return false
+	}
+	return true
+}
+
+// replayTransactions returns the transactions of a holding with the shares held after each of them, accounting
+// for the splits of its ticker
+//
+// Input:
+//   - ctx: bounds the requests for the splits of symbol
+//   - symbol: the ticker's symbol
+//   - transactions: the transactions of symbol, in any order
+//
+// Output:
+//   - []StockTransaction: the transactions, oldest first
+//   - []mongodb.Split: the splits of symbol since the first transaction
+//   - error: any error that occurred, one wrapping mongodb.ErrOversold if a sale exceeds the shares held
+func (server *Server) replayTransactions(ctx context.Context, symbol string, transactions []mongodb.Transaction) ([]StockTransaction, []mongodb.Split, error) {
+	if len(transactions) == 0 {
+		return []StockTransaction{}, []mongodb.Split{}, nil
+	}
+	sorted := append([]mongodb.Transaction{}, transactions...)
+	mongodb.SortTransactions(sorted)
+
+	splits, err := server.getTickerSplits(ctx, symbol, sorted[0].Timestamp.Time())
+	if err != nil {
+		return nil, nil, errors.Join(errors.New("error getting ticker splits"), err)
+	}
+	held, err := mongodb.ValidateTransactions(sorted, splits)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stockTransactions := make([]StockTransaction, len(sorted))
+	for i, transaction := range sorted {
+		stockTransactions[i] = StockTransaction{
+			Symbol:      symbol,
+			TotalShares: float32(held[i]),
+			ShareChange: float32(transaction.SignedQuantity()),
+			Date:        transaction.Timestamp.Time().Unix(),
+		}
+	}
+	return stockTransactions, splits, nil
+}
+
+// Returns transactions without the one with the given id
+func withoutTransaction(transactions []mongodb.Transaction, id primitive.ObjectID) []mongodb.Transaction {
+	kept := []mongodb.Transaction{}
+	for _, transaction := range transactions {
+		if transaction.ID != id {
+			kept = append(kept, transaction)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,105 @@
+package token
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Shortest secret accepted by NewJWTMaker, as long as the output of HMAC-SHA256
+const MinSecretKeySize = 32
+
+// The name of the service issuing tokens, checked when they are verified
+const jwtIssuer = "stock-savvy"
+
+// JWTMaker is a Maker of JSON Web Tokens signed with HMAC-SHA256
+type JWTMaker struct {
+	secretKey []byte
+	now       func() time.Time
+}
+
+// The claims of a token: the standard subject, id and times, plus the username and kind of the token
+type jwtClaims struct {
+	jwt.RegisteredClaims
+	Username string `json:"username"`
+	Kind     Kind   `json:"kind"`
+}
+
+// NewJWTMaker returns a JWTMaker signing tokens with secretKey, which must be at least MinSecretKeySize bytes long
+func NewJWTMaker(secretKey string) (*JWTMaker, error) {
+	if len(secretKey) < MinSecretKeySize {
+		return nil, errors.New("the token secret key must be at least 32 characters long")
+	}
+	return &JWTMaker{secretKey: []byte(secretKey), now: time.Now}, nil
+}
+
+// CreateToken returns a signed token of the given kind for a user, valid for duration
+//
+// Input:
+//   - userID: the id of the user, the subject of the token
+//   - username: the name of the user
+//   - kind: KindAccess or KindRefresh
+//   - duration: how long the token is valid for
+//
+// Output:
+//   - string: the signed token
+//   - *Payload: the data carried by the token
+//   - error: any error that occurred
+func (maker *JWTMaker) CreateToken(userID string, username string, kind Kind, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(userID, username, kind, duration, maker.now().Truncate(time.Second))
+	if err != nil {
+		return "", nil, err
+	}
+	claims := jwtClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        payload.ID,
+			Issuer:    jwtIssuer,
+			Subject:   payload.UserID,
+			IssuedAt:  jwt.NewNumericDate(payload.IssuedAt),
+			ExpiresAt: jwt.NewNumericDate(payload.ExpiresAt),
+		},
+		Username: payload.Username,
+		Kind:     payload.Kind,
+	}
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(maker.secretKey)
+	if err != nil {
+		return "", nil, errors.Join(errors.New("error signing token"), err)
+	}
+	return signed, payload, nil
+}
+
+// VerifyToken checks the signature, expiration and kind of a token and returns its payload
+//
+// Input:
+//   - token: a token created by CreateToken
+//   - kind: the kind of token expected
+//
+// Output:
+//   - *Payload: the data carried by the token
+//   - error: ErrExpiredToken if the token has expired, ErrInvalidToken if it is otherwise not acceptable
+func (maker *JWTMaker) VerifyToken(token string, kind Kind) (*Payload, error) {
+	var claims jwtClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (interface{}, error) { return maker.secretKey, nil },
+		// Pinning the method rejects tokens claiming to be unsigned or signed with a public key
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(jwtIssuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(maker.now),
+	)
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return nil, ErrExpiredToken
+	}
+	if err != nil || claims.Kind != kind || claims.Subject == "" {
+		return nil, ErrInvalidToken
+	}
+
+	return &Payload{
+		ID:        claims.ID,
+		UserID:    claims.Subject,
+		Username:  claims.Username,
+		Kind:      claims.Kind,
+		IssuedAt:  claims.IssuedAt.Time,
+		ExpiresAt: claims.ExpiresAt.Time,
+	}, nil
+}
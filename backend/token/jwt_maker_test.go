@@ -0,0 +1,89 @@
+package token
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+var testNow = time.Date(2024, 10, 30, 12, 0, 0, 0, time.UTC)
+
+func newTestMaker(t *testing.T, now *time.Time) *JWTMaker {
+	t.Helper()
+	maker, err := NewJWTMaker(testSecret)
+	if err != nil {
+		t.Fatalf("NewJWTMaker error: %v", err)
+	}
+	maker.now = func() time.Time { return *now }
+	return maker
+}
+
+func TestNewJWTMaker_ShortSecret(t *testing.T) {
+	if _, err := NewJWTMaker("too short"); err == nil {
+		t.Fatal("expected a short secret to be rejected")
+	}
+}
+
+func TestJWTMaker_CreateAndVerify(t *testing.T) {
+	now := testNow
+	maker := newTestMaker(t, &now)
+
+	token, created, err := maker.CreateToken("user-1", "alice", KindAccess, 15*time.Minute)
+	if err != nil {
+		t.Fatalf("CreateToken error: %v", err)
+	}
+	payload, err := maker.VerifyToken(token, KindAccess)
+	if err != nil {
+		t.Fatalf("VerifyToken error: %v", err)
+	}
+	if payload.ID != created.ID || payload.UserID != "user-1" || payload.Username != "alice" || !payload.ExpiresAt.Equal(testNow.Add(15*time.Minute)) {
+		t.Fatalf("unexpected payload %+v, created %+v", payload, created)
+	}
+
+	// An access token is not a refresh token
+	if _, err := maker.VerifyToken(token, KindRefresh); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected ErrInvalidToken for the wrong kind, got %v", err)
+	}
+
+	now = testNow.Add(16 * time.Minute)
+	if _, err := maker.VerifyToken(token, KindAccess); !errors.Is(err, ErrExpiredToken) {
+		t.Fatalf("expected ErrExpiredToken, got %v", err)
+	}
+}
+
+func TestJWTMaker_RejectsForgedTokens(t *testing.T) {
+	now := testNow
+	maker := newTestMaker(t, &now)
+	other, err := NewJWTMaker(strings.Repeat("x", MinSecretKeySize))
+	if err != nil {
+		t.Fatalf("NewJWTMaker error: %v", err)
+	}
+	other.now = maker.now
+
+	forged, _, err := other.CreateToken("user-1", "alice", KindAccess, time.Hour)
+	if err != nil {
+		t.Fatalf("CreateToken error: %v", err)
+	}
+	if _, err := maker.VerifyToken(forged, KindAccess); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected a token signed with another secret to be invalid, got %v", err)
+	}
+
+	unsigned, err := jwt.NewWithClaims(jwt.SigningMethodNone, jwtClaims{
+		RegisteredClaims: jwt.RegisteredClaims{Subject: "user-1", Issuer: jwtIssuer, ExpiresAt: jwt.NewNumericDate(testNow.Add(time.Hour))},
+		Kind:             KindAccess,
+	}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatalf("SignedString error: %v", err)
+	}
+	if _, err := maker.VerifyToken(unsigned, KindAccess); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected an unsigned token to be invalid, got %v", err)
+	}
+	if _, err := maker.VerifyToken("not a token", KindAccess); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected garbage to be invalid, got %v", err)
+	}
+}
@@ -0,0 +1,59 @@
+// Package token creates and verifies the tokens that authenticate users of the API.
+//
+// A user logging in receives a short-lived access token, sent with every request, and a long-lived refresh token,
+// only accepted in exchange for new tokens. Both are signed by a Maker and carry a Payload naming the user.
+package token
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// The kinds of tokens, so a refresh token can't be used as an access token or the other way around
+type Kind string
+
+const (
+	KindAccess  Kind = "access"
+	KindRefresh Kind = "refresh"
+)
+
+var (
+	ErrInvalidToken = errors.New("token is invalid")
+	ErrExpiredToken = errors.New("token has expired")
+)
+
+// Payload is the data carried by a token
+type Payload struct {
+	ID        string    `json:"id"` // Unique to the token
+	UserID    string    `json:"user_id"`
+	Username  string    `json:"username"`
+	Kind      Kind      `json:"kind"`
+	IssuedAt  time.Time `json:"issued_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Maker creates and verifies tokens
+type Maker interface {
+	// CreateToken returns a token of the given kind for a user, valid for duration
+	CreateToken(userID string, username string, kind Kind, duration time.Duration) (string, *Payload, error)
+	// VerifyToken returns the payload of a token of the given kind, or ErrInvalidToken or ErrExpiredToken
+	VerifyToken(token string, kind Kind) (*Payload, error)
+}
+
+// NewPayload returns the payload of a new token of the given kind for a user, valid for duration from now
+func NewPayload(userID string, username string, kind Kind, duration time.Duration, now time.Time) (*Payload, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, errors.Join(errors.New("error generating token id"), err)
+	}
+	return &Payload{
+		ID:        hex.EncodeToString(id),
+		UserID:    userID,
+		Username:  username,
+		Kind:      kind,
+		IssuedAt:  now,
+		ExpiresAt: now.Add(duration),
+	}, nil
+}
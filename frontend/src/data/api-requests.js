@@ -10,15 +10,74 @@ const axiosConfig = {
     }
 };
 
+// Tokens of the logged in user, kept across page loads
+const ACCESS_TOKEN_KEY = 'access_token';
+const REFRESH_TOKEN_KEY = 'refresh_token';
+
+// Send the access token with every request, the holdings and chat routes require it
+axios.interceptors.request.use((config) => {
+    const accessToken = localStorage.getItem(ACCESS_TOKEN_KEY);
+    if (accessToken) {
+        config.headers.Authorization = `Bearer ${accessToken}`;
+    }
+    return config;
+});
+
+const storeTokens = (data) => {
+    localStorage.setItem(ACCESS_TOKEN_KEY, data.access_token);
+    localStorage.setItem(REFRESH_TOKEN_KEY, data.refresh_token);
+    return data.user;
+};
+
+/**
+ * Create a user and log them in
+ * @param {string} username - 3 to 32 letters, digits, dots, dashes or underscores
+ * @param {string} password - 8 to 72 characters
+ * @returns {Promise} - Returns the user (id, username, created_at), or throws with the server's error (e.g. a taken username)
+ */
+export const register = async (username, password) => {
+    const response = await axios.post(`${BASE_URL}/users/register`, { username, password }, axiosConfig);
+    return storeTokens(response.data);
+};
+
+/**
+ * Log a user in
+ * @param {string} username - The user's name
+ * @param {string} password - The user's password
+ * @returns {Promise} - Returns the user, or throws with the server's error
+ */
+export const login = async (username, password) => {
+    const response = await axios.post(`${BASE_URL}/users/login`, { username, password }, axiosConfig);
+    return storeTokens(response.data);
+};
+
+/**
+ * Exchange the stored refresh token for new tokens, once the access token has expired
+ * @returns {Promise} - Returns the user, or throws if the refresh token is missing or has expired too
+ */
+export const refreshTokens = async () => {
+    const response = await axios.post(`${BASE_URL}/users/refresh`, { refresh_token: localStorage.getItem(REFRESH_TOKEN_KEY) }, axiosConfig);
+    return storeTokens(response.data);
+};
+
+/**
+ * Log the user out by forgetting their tokens
+ */
+export const logout = () => {
+    localStorage.removeItem(ACCESS_TOKEN_KEY);
+    localStorage.removeItem(REFRESH_TOKEN_KEY);
+};
+
 /**
  * Get historical data for a specific ticker
  * @param {string} symbol - The stock symbol
- * @returns {Promise} - Returns historical price data
+ * @param {Object} params - Optional from/to dates (YYYY-MM-DD), interval (1d, 1w or 1mo) and adjusted
+ * @returns {Promise} - Returns price candles (time, open, high, low, close, volume), oldest first
  */
-export const getTickerHistory = async (symbol) => {
+export const getTickerHistory = async (symbol, params = {}) => {
     try {
-        const response = await axios.get(`${BASE_URL}/stocks/tickers/${symbol}/history`, axiosConfig);
-        return response.data.history;
+        const response = await axios.get(`${BASE_URL}/stocks/tickers/${symbol}/history`, { ...axiosConfig, params });
+        return response.data.candles;
     } catch (error) {
         console.error('Error fetching ticker history:', error);
         return []; // Fallback to fake data if API call fails
@@ -43,11 +102,12 @@ export const getHoldingHistory = async (symbol) => {
 /**
  * Get news sentiment for a specific ticker
  * @param {string} symbol - The stock symbol
- * @returns {Promise} - Returns news sentiment data
+ * @param {Object} params - Optional from/to dates (YYYY-MM-DD), page and page_size of the listed articles
+ * @returns {Promise} - Returns news sentiment data and a page of articles
  */
-export const getTickerNews = async (symbol) => {
+export const getTickerNews = async (symbol, params = {}) => {
     try {
-        const response = await axios.get(`${BASE_URL}/stocks/tickers/${symbol}/news`, axiosConfig);
+        const response = await axios.get(`${BASE_URL}/stocks/tickers/${symbol}/news`, { ...axiosConfig, params });
         return JSON.stringify(response.data);
     } catch (error) {
         console.error('Error fetching ticker news:', error);
@@ -55,6 +115,22 @@ export const getTickerNews = async (symbol) => {
     }
 };
 
+/**
+ * Get the news sentiment of a ticker over time
+ * @param {string} symbol - The stock symbol
+ * @param {Object} params - Optional from/to dates (YYYY-MM-DD) and bucket (day or week)
+ * @returns {Promise} - Returns sentiment buckets (time, articles, positive, neutral, negative, mean_score), oldest first
+ */
+export const getTickerSentiment = async (symbol, params = {}) => {
+    try {
+        const response = await axios.get(`${BASE_URL}/stocks/tickers/${symbol}/sentiment`, { ...axiosConfig, params });
+        return response.data.buckets;
+    } catch (error) {
+        console.error('Error fetching ticker sentiment:', error);
+        return [];
+    }
+};
+
 /**
  * Get basic information about a ticker
  * @param {string} symbol - The stock symbol
@@ -84,6 +160,51 @@ export const getHoldings = async () => {
     }
 };
 
+/**
+ * Get the transactions the holdings are derived from
+ * @param {string} symbol - Optional stock symbol to only get the transactions of one holding
+ * @returns {Promise} - Returns transactions (id, symbol, side, quantity, price, fees, timestamp, note), oldest first
+ */
+export const getTransactions = async (symbol) => {
+    try {
+        const response = await axios.get(`${BASE_URL}/stocks/holdings/transactions`, { ...axiosConfig, params: symbol ? { symbol } : {} });
+        return response.data;
+    } catch (error) {
+        console.error('Error fetching transactions:', error);
+        return [];
+    }
+};
+
+/**
+ * Record a purchase or sale
+ * @param {Object} transaction - symbol, side (buy or sell), quantity, price, fees, timestamp (Unix seconds) and note
+ * @returns {Promise} - Returns the stored transaction, or throws with the server's error (e.g. a sale exceeding the shares held)
+ */
+export const createTransaction = async (transaction) => {
+    const response = await axios.post(`${BASE_URL}/stocks/holdings/transactions`, transaction, axiosConfig);
+    return response.data;
+};
+
+/**
+ * Replace a transaction
+ * @param {string} id - The transaction's id
+ * @param {Object} transaction - The new transaction, as for createTransaction
+ * @returns {Promise} - Returns the stored transaction, or throws with the server's error
+ */
+export const updateTransaction = async (id, transaction) => {
+    const response = await axios.put(`${BASE_URL}/stocks/holdings/transactions/${id}`, transaction, axiosConfig);
+    return response.data;
+};
+
+/**
+ * Delete a transaction
+ * @param {string} id - The transaction's id
+ * @returns {Promise} - Resolves once deleted, or throws with the server's error
+ */
+export const deleteTransaction = async (id) => {
+    await axios.delete(`${BASE_URL}/stocks/holdings/transactions/${id}`, axiosConfig);
+};
+
 /**
  * Send a message to the chatbot and receive a response
  * @param {string} message - The user's message